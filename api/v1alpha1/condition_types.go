@@ -4,10 +4,29 @@
 
 package v1alpha1
 
+// SuspendedCondition is true while the reconciliation of an object is suspended.
+const SuspendedCondition = "Suspended"
+
+// UnpinnedReferenceCondition is true if a resource is an image referenced by a tag without a digest. The data
+// behind the tag may change without the component version changing.
+const UnpinnedReferenceCondition = "UnpinnedReference"
+
+// SnapshotWrittenCondition is true if the last reconciliation of a Resource wrote the data of its snapshots to the
+// registry, and false with SnapshotUnchangedReason if the data was already stored at the references of the snapshots.
+const SnapshotWrittenCondition = "SnapshotWritten"
+
+// ComponentRefUnresolvedCondition is true if a Resource has been waiting longer than the component reference
+// timeout of the controller for the component descriptor of its component, for example because the reference
+// path names a component that isn't referenced.
+const ComponentRefUnresolvedCondition = "ComponentRefUnresolved"
+
 const (
 	// AuthenticatedContextCreationFailedReason is used when the controller failed to create an authenticated context.
 	AuthenticatedContextCreationFailedReason = "AuthenticatedContextCreationFailed"
 
+	// ConfigureCredentialsFailedReason is used when the controller failed to configure credentials from a secret.
+	ConfigureCredentialsFailedReason = "ConfigureCredentialsFailed"
+
 	// CheckVersionFailedReason is used when the controller failed to check for new versions.
 	CheckVersionFailedReason = "CheckVersionFailedReason"
 
@@ -38,12 +57,66 @@ const (
 	// GetResourceFailedReason is used when the resource cannot be retrieved.
 	GetResourceFailedReason = "GetResourceFailed"
 
+	// ResourceNotFoundReason is used when the referenced resource doesn't exist in the component descriptor.
+	ResourceNotFoundReason = "ResourceNotFound"
+
+	// AmbiguousResourceReason is used when several resources of the component descriptor match the reference.
+	AmbiguousResourceReason = "AmbiguousResource"
+
+	// DigestMismatchReason is used when the fetched resource data doesn't match the digest in the component descriptor.
+	DigestMismatchReason = "DigestMismatch"
+
+	// ExtractFailedReason is used when none of the files of the resource match the extract path.
+	ExtractFailedReason = "ExtractFailed"
+
+	// TransformFailedReason is used when the transformation webhook of the resource fails or rejects it.
+	TransformFailedReason = "TransformFailed"
+
+	// TransformNotAllowedReason is used when the transformation webhook of the resource isn't served from one of
+	// the hosts the controller allows.
+	TransformNotAllowedReason = "TransformNotAllowed"
+
+	// ComponentNotFoundReason is used when the component version doesn't exist in the repository.
+	ComponentNotFoundReason = "ComponentNotFound"
+
+	// UnsupportedAccessReason is used when the access type of the resource is unknown or doesn't support the
+	// requested operation.
+	UnsupportedAccessReason = "UnsupportedAccess"
+
+	// RegistryAuthFailedReason is used when a registry rejects the credentials used to access it.
+	RegistryAuthFailedReason = "RegistryAuthFailed"
+
+	// RegistryNotAllowedReason is used when a resource would be fetched from a registry that isn't allowed.
+	RegistryNotAllowedReason = "RegistryNotAllowed"
+
+	// RateLimitedReason is used when a registry rejects requests because too many have been sent.
+	RateLimitedReason = "RateLimited"
+
+	// ReconcileTimeoutReason is used when a reconciliation is aborted because it didn't finish within its timeout.
+	ReconcileTimeoutReason = "ReconcileTimeout"
+
+	// RegistryProxyUnavailableReason is used when the registry snapshots are pushed to, usually the in-cluster
+	// registry, can't be reached. It is set on all Resources pushing to the registry until it is reachable again.
+	RegistryProxyUnavailableReason = "RegistryProxyUnavailable"
+
 	// GetComponentDescriptorFailedReason is used when the component descriptor cannot be retrieved.
 	GetComponentDescriptorFailedReason = "GetComponentDescriptorFailed"
 
 	// ComponentDescriptorNotFoundReason is used when the component descriptor cannot be found.
 	ComponentDescriptorNotFoundReason = "ComponentDescriptorNotFound"
 
+	// ComponentDescriptorNotCreatedReason is used when the component descriptor of a referenced component hasn't
+	// been created yet.
+	ComponentDescriptorNotCreatedReason = "ComponentDescriptorNotCreated"
+
+	// ComponentDescriptorChangedReason is used when the component descriptor of a resource changed while the
+	// resource was fetched.
+	ComponentDescriptorChangedReason = "ComponentDescriptorChanged"
+
+	// ComponentRefUnresolvedReason is used when the component descriptor of the component of a Resource hasn't
+	// been created within the component reference timeout of the controller.
+	ComponentRefUnresolvedReason = "ComponentRefUnresolved"
+
 	// ComponentVersionNotFoundReason is used when the component version cannot be found.
 	ComponentVersionNotFoundReason = "ComponentVersionNotFound"
 
@@ -53,6 +126,32 @@ const (
 	// CreateOrUpdateSnapshotFailedReason is used when the snapshot cannot be created or updated.
 	CreateOrUpdateSnapshotFailedReason = "CreateOrUpdateSnapshotFailed"
 
+	// SnapshotVerificationFailedReason is used when the data of a snapshot cannot be found in the registry after it has been pushed.
+	SnapshotVerificationFailedReason = "SnapshotVerificationFailed"
+
+	// SnapshotDataNotFoundReason is used when the data a Snapshot points at isn't present in the registry.
+	SnapshotDataNotFoundReason = "SnapshotDataNotFound"
+
+	// TagSnapshotFailedReason is used when the snapshot data couldn't be tagged with its digest.
+	TagSnapshotFailedReason = "TagSnapshotFailed"
+
+	// PushReferrersFailedReason is used when the referrers of a resource couldn't be pushed to its snapshot.
+	PushReferrersFailedReason = "PushReferrersFailed"
+
+	// SnapshotResourcesFailedReason is used when some of the resources selected by a Resource couldn't be
+	// written to their Snapshots.
+	SnapshotResourcesFailedReason = "SnapshotResourcesFailed"
+
+	// SnapshotCreatedReason is used when a new Snapshot has been created for an object.
+	SnapshotCreatedReason = "SnapshotCreated"
+
+	// SnapshotPushedReason is used when the data of a snapshot has been written to the registry.
+	SnapshotPushedReason = "SnapshotPushed"
+
+	// SnapshotUnchangedReason is used when the data of a snapshot was already stored in the registry and nothing
+	// was written.
+	SnapshotUnchangedReason = "SnapshotUnchanged"
+
 	// CreateOrUpdateKustomizationFailedReason is used when the Kustomization cannot be created or updated.
 	CreateOrUpdateKustomizationFailedReason = "CreateOrUpdateKustomizationFailed"
 
@@ -80,6 +179,31 @@ const (
 	// PatchStrategicMergeSourceRefNotReadyReason is used when source ref for patch strategic merge is not ready and there was no error.
 	PatchStrategicMergeSourceRefNotReadyReason = "PatchStrategicMergeSourceRefNotReady"
 
+	// SnapshotNameConflictReason is used when the Snapshot of a Resource is already owned by another Resource.
+	SnapshotNameConflictReason = "SnapshotNameConflict"
+
+	// SnapshotConflictReason is used when the reference of a new Snapshot points at an image that wasn't pushed
+	// by the controller.
+	SnapshotConflictReason = "SnapshotConflict"
+
+	// InvalidSnapshotTagReason is used when the version of a resource isn't a valid tag for its snapshot.
+	InvalidSnapshotTagReason = "InvalidSnapshotTag"
+
+	// InvalidReferenceReason is used when the access of a resource holds a reference or URL that can't be parsed.
+	InvalidReferenceReason = "InvalidReference"
+
 	// SnapshotNameEmptyReason is used for a failure to generate a snapshot name.
 	SnapshotNameEmptyReason = "SnapshotNameEmpty"
+
+	// SuspendedReason is used when the reconciliation of an object is suspended.
+	SuspendedReason = "Suspended"
+
+	// InvalidSnapshotRegistryReason is used when the registry a snapshot is pushed to isn't a valid address.
+	InvalidSnapshotRegistryReason = "InvalidSnapshotRegistry"
+
+	// ResourceTooLargeReason is used when a resource exceeds the maximum size of a resource.
+	ResourceTooLargeReason = "ResourceTooLarge"
+
+	// TagReferenceReason is used when a resource is an image referenced by a tag without a digest.
+	TagReferenceReason = "TagReference"
 )
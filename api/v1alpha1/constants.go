@@ -11,11 +11,23 @@ const (
 	ComponentVersionKey       = "component-version"
 	ResourceNameKey           = "resource-name"
 	ResourceVersionKey        = "resource-version"
+	ResourcePlatformKey       = "resource-platform"
+	ResourceExtractPathKey    = "resource-extract-path"
+	ResourceCompressionKey    = "resource-compression"
+	ResourceKindKey           = "resource-kind"
+	ResourceConfigKey         = "resource-config"
+	ResourceArtifactTypeKey   = "resource-artifact-type"
+	ResourceTransformKey      = "resource-transform"
+	SnapshotNamespaceKey      = "snapshot-namespace"
 	SourceNameKey             = "source-name"
 	SourceNamespaceKey        = "source-namespace"
 	SourceArtifactChecksumKey = "source-artifact-checksum"
 )
 
+// ResourceKindSource is the value of the ResourceKindKey of the identity of a source. The identity of a resource
+// doesn't carry the key, so a source doesn't share the data of a resource with the same name.
+const ResourceKindSource = "source"
+
 // Externally defined extra identity keys.
 const (
 	// ResourceHelmChartNameKey if defined, means the resource is a helm resource and the chart should be added
@@ -23,8 +35,41 @@ const (
 	ResourceHelmChartNameKey = "helmChart"
 )
 
+// Resource labels.
+const (
+	// ReferrerSubjectLabel is set on resources that describe another resource of the same component, for example
+	// an SBOM. Its value is the name of the described resource. Such resources are pushed as OCI referrers of the
+	// described resource's snapshot if the Resource includes referrers.
+	ReferrerSubjectLabel = "delivery.ocm.software/referrer-subject"
+)
+
+// ReconcileRequestAnnotation requests a reconciliation of the annotated object when its value changes, even if the
+// object is otherwise unchanged. It mirrors the reconcile.fluxcd.io/requestedAt annotation of Flux.
+const ReconcileRequestAnnotation = "reconcile.delivery.ocm.software/requestedAt"
+
+// SuspendAnnotation set to "true" suspends the reconciliation of a Resource like Spec.Suspend, but can be set
+// without changing the spec of the Resource.
+const SuspendAnnotation = "delivery.ocm.software/suspend"
+
 // Log levels.
 const (
 	// LevelDebug defines the depth at witch debug information is displayed.
 	LevelDebug = 4
+	// LevelTrace defines the depth at which complete objects, like component descriptors, are logged.
+	LevelTrace = 6
+)
+
+// Log keys. Log lines about a resource carry these keys with the same meaning everywhere, so the logs of a
+// component, resource or snapshot can be found by them.
+const (
+	// LogKeyComponent is the name of the component.
+	LogKeyComponent = "component"
+	// LogKeyResourceName is the name of the resource of the component.
+	LogKeyResourceName = "resourceName"
+	// LogKeySnapshotRef is the reference of the snapshot data in the form repository:tag.
+	LogKeySnapshotRef = "snapshotRef"
+	// LogKeySourceDigest is the digest of the resource data.
+	LogKeySourceDigest = "sourceDigest"
+	// LogKeySourceRef is the reference of the image a resource is copied from.
+	LogKeySourceRef = "sourceRef"
 )
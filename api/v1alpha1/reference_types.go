@@ -28,6 +28,8 @@ type ResourceReference struct {
 	// +required
 	ElementMeta `json:",inline"`
 
+	// ReferencePath selects the component the element belongs to by the names of the component references
+	// leading to it. An identity may contain a version to select a specific version of a referenced component.
 	// +optional
 	ReferencePath []ocmmetav1.Identity `json:"referencePath,omitempty"`
 }
@@ -35,8 +37,15 @@ type ResourceReference struct {
 type ElementMeta struct {
 	Name string `json:"name"`
 
+	// Version selects a specific version of the element. If not set, the highest version
+	// of the elements with the given name is used.
+	// +optional
 	Version string `json:"version,omitempty"`
 
+	// ExtraIdentity selects the element among elements with the same name by their extra identity, for example
+	// the variant of a resource for a platform. An element matches if its extra identity contains all of the
+	// given attributes.
+	// +optional
 	ExtraIdentity ocmmetav1.Identity `json:"extraIdentity,omitempty"`
 
 	Labels ocmmetav1.Labels `json:"labels,omitempty"`
@@ -8,26 +8,242 @@ import (
 	"time"
 
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ResourceKind is the string representation of a Resource.
+	ResourceKind = "Resource"
+)
+
+const (
+	// UpdatePolicyAlways checks on every reconciliation that the Snapshot still holds the resource and writes it
+	// again if it doesn't.
+	UpdatePolicyAlways = "Always"
+	// UpdatePolicyOnNewVersion only writes the Snapshot when a new version of the component or the resource is
+	// applied.
+	UpdatePolicyOnNewVersion = "OnNewVersion"
+)
+
+// DefaultResourceInterval is the default interval at which Resources without an interval are reconciled. The
+// controller overrides it with its --default-requeue-interval flag.
+const DefaultResourceInterval = 10 * time.Minute
+
 // ResourceSpec defines the desired state of Resource.
 type ResourceSpec struct {
-	// Interval specifies the interval at which the Repository will be checked for updates.
-	// +required
-	Interval metav1.Duration `json:"interval"`
+	// Interval specifies the interval at which the Repository will be checked for updates. If not set, the
+	// default interval of the controller is used.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
 
 	// SourceRef specifies the source object from which the resource should be retrieved.
 	// +required
 	SourceRef ObjectReference `json:"sourceRef"`
 
-	// Suspend can be used to temporarily pause the reconciliation of the Resource.
+	// Resources selects several resources of the component referenced by SourceRef. Each resource is written
+	// to its own Snapshot, named after the snapshot name of the Resource and the name of the selected resource.
+	// Mutually exclusive with SourceRef.ResourceRef.
+	// +optional
+	Resources []ResourceSelector `json:"resources,omitempty"`
+
+	// Source selects one of the sources of the component referenced by SourceRef instead of a resource, for example
+	// the git archive the component was built from, and writes it to the Snapshot of the Resource. Mutually
+	// exclusive with SourceRef.ResourceRef and Resources.
+	// +optional
+	Source *ResourceReference `json:"source,omitempty"`
+
+	// SecretRef specifies a Secret of type kubernetes.io/dockerconfigjson holding the credentials that are used
+	// to access the registries the resource is stored in. This is in addition to the credentials configured on
+	// the ComponentVersion.
+	// +optional
+	SecretRef *v1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// SnapshotTemplate defines the name, labels and annotations of the snapshot that is created for the resource.
+	// +optional
+	SnapshotTemplate *SnapshotTemplateSpec `json:"snapshotTemplate,omitempty"`
+
+	// Platform selects a single platform of a multi-arch image in the form os/arch[/variant], for example
+	// linux/amd64. If not set, an image index is copied with all of its platforms. Only supported for
+	// resources with an ociArtifact access.
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
+	// Extract selects files of a tar archive resource. Only the matching files are written to the snapshot
+	// instead of the complete resource.
+	// +optional
+	Extract *ExtractSpec `json:"extract,omitempty"`
+
+	// Transform sends the data of the resource to a webhook before it is written to the snapshot, and writes the
+	// data the webhook returns instead, for example a Helm chart with relocated image references. Images are
+	// copied as they are and can't be transformed.
+	// +optional
+	Transform *TransformSpec `json:"transform,omitempty"`
+
+	// IncludeReferrers pushes the resources of the component that describe the snapshotted resource, for example
+	// its SBOM, as OCI referrers of the snapshot. A resource describes another resource if it carries the
+	// delivery.ocm.software/referrer-subject label with the name of that resource. Registries without support for
+	// the referrers API are served with the referrers tag schema.
+	// +optional
+	IncludeReferrers bool `json:"includeReferrers,omitempty"`
+
+	// ForceOverwrite overwrites an image that already exists at the reference of a new Snapshot but wasn't pushed
+	// by the controller. By default, such an image is left alone and the Resource is marked with the
+	// SnapshotConflict reason.
+	// +optional
+	ForceOverwrite bool `json:"forceOverwrite,omitempty"`
+
+	// Verify specifies a list of signatures of the component that have to be valid before the
+	// resource is written to a snapshot. Public keys referenced by a secret are looked up in the
+	// namespace of the Resource.
+	// +optional
+	Verify []Signature `json:"verify,omitempty"`
+
+	// MaxSize is the maximum size of the resource, for example 500Mi. A resource exceeding it isn't written to a
+	// snapshot. If the controller limits the size of resources with --max-resource-size, the smaller of both
+	// applies.
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+
+	// Timeout is how long a reconciliation of the Resource may take, for example 30m for a large image. A
+	// reconciliation that doesn't finish in time, like a stuck copy, is aborted and retried with a backoff, so it
+	// doesn't hold a worker of the controller. If not set, the timeout of the controller is used.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Suspend can be used to temporarily pause the reconciliation of the Resource. Setting the
+	// delivery.ocm.software/suspend annotation to "true" has the same effect.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
+
+	// UpdatePolicy defines when the Snapshot is written. Always, the default, checks on every reconciliation
+	// that the data of the Snapshot is still in the registry and fetches the resource again if it isn't.
+	// OnNewVersion only fetches the resource when the ComponentVersion reconciles a new version or the requested
+	// version of the resource changes, and doesn't access the registry otherwise. A reconcile request annotation
+	// writes the Snapshot regardless of the policy.
+	// +kubebuilder:validation:Enum=Always;OnNewVersion
+	// +optional
+	UpdatePolicy string `json:"updatePolicy,omitempty"`
+
+	// DryRun resolves the resource and reports the snapshot that would be created for it in
+	// the status, without pushing any data to the registry or creating the Snapshot.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
-// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
-// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
+// ResourceSelector selects one of the resources that are snapshotted by a Resource.
+type ResourceSelector struct {
+	ResourceReference `json:",inline"`
+}
+
+// ExtractSpec defines the files that are extracted from a resource.
+type ExtractSpec struct {
+	// Path is a glob pattern matched against the paths of the files in the archive, for example
+	// manifests/*.yaml. Gzip compressed archives are decompressed before matching.
+	// +required
+	Path string `json:"path"`
+}
+
+// TransformSpec defines the webhook the data of a resource is transformed with.
+type TransformSpec struct {
+	// URL is the http or https URL the data of the resource is posted to. The webhook has to respond with
+	// status 200 and the transformed data as the body.
+	// +required
+	URL string `json:"url"`
+
+	// Timeout is how long the webhook may take to transform the resource, including reading and returning its
+	// data. Defaults to one minute.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ResourceSnapshotStatus describes the Snapshot of one of the resources selected by Spec.Resources.
+type ResourceSnapshotStatus struct {
+	// Name is the name of the selected resource.
+	Name string `json:"name"`
+
+	// SnapshotName is the name of the Snapshot the resource is written to.
+	SnapshotName string `json:"snapshotName"`
+
+	// Digest is the digest of the resource data in the Snapshot.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// Ready is true if the Snapshot contains the requested version of the resource.
+	Ready bool `json:"ready"`
+
+	// ComponentDescriptor is the revision of the ComponentDescriptor the resource in the Snapshot was fetched from.
+	// +optional
+	ComponentDescriptor *ComponentDescriptorRevision `json:"componentDescriptor,omitempty"`
+
+	// SourceRegistry is the upstream registry the resource in the Snapshot was fetched from.
+	// +optional
+	SourceRegistry string `json:"sourceRegistry,omitempty"`
+
+	// SourceRepository is the repository in SourceRegistry the resource in the Snapshot was fetched from.
+	// +optional
+	SourceRepository string `json:"sourceRepository,omitempty"`
+
+	// Transformed is true if the resource was transformed by the webhook of Spec.Transform before it was written
+	// to the Snapshot.
+	// +optional
+	Transformed bool `json:"transformed,omitempty"`
+
+	// Message describes why the resource couldn't be written to its Snapshot.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentDescriptorRevision identifies the revision of a ComponentDescriptor a Snapshot was written from.
+type ComponentDescriptorRevision struct {
+	// Name is the name of the ComponentDescriptor.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ComponentDescriptor.
+	Namespace string `json:"namespace"`
+
+	// ResourceVersion is the resource version of the ComponentDescriptor when the resource was fetched. The
+	// Snapshot isn't written if the ComponentDescriptor changes while the resource is fetched.
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// DryRunResult describes the snapshot that would be created for a Resource.
+type DryRunResult struct {
+	// SnapshotName is the name of the Snapshot that would be created.
+	SnapshotName string `json:"snapshotName"`
+
+	// Repository is the name of the repository in the in-cluster registry the resource would be pushed to.
+	Repository string `json:"repository"`
+
+	// Tag is the tag the resource would be pushed with, empty if the snapshot is pushed without a tag.
+	Tag string `json:"tag"`
+
+	// Digest is the digest the resource data would be pushed with, the same as the Snapshot digest once it is pushed.
+	Digest string `json:"digest"`
+}
+
+// SnapshotHistoryEntry describes snapshot data pushed for a Resource.
+type SnapshotHistoryEntry struct {
+	// SnapshotName is the name of the Snapshot the data was pushed for.
+	SnapshotName string `json:"snapshotName"`
+
+	// Registry is the registry the data was pushed to, empty for the in-cluster registry.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+
+	// Repository is the name of the repository the data was pushed to.
+	Repository string `json:"repository"`
+
+	// Tags are the tags the data was pushed with, the manifest digest for data pushed without a tag.
+	Tags []string `json:"tags"`
+
+	// Digest is the digest of the resource data.
+	Digest string `json:"digest"`
+
+	// PushedAt is the time the data was last pushed.
+	PushedAt metav1.Time `json:"pushedAt"`
+}
 
 // ResourceStatus defines the observed state of Resource.
 type ResourceStatus struct {
@@ -35,7 +251,7 @@ type ResourceStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
-	// Conditions holds the conditions for the ComponentVersion.
+	// Conditions holds the conditions for the Resource.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -47,14 +263,79 @@ type ResourceStatus struct {
 	// +optional
 	LastAppliedComponentVersion string `json:"lastAppliedComponentVersion,omitempty"`
 
+	// LastAppliedComponentDescriptor is the revision of the ComponentDescriptor the resource in the Snapshot was
+	// fetched from.
+	// +optional
+	LastAppliedComponentDescriptor *ComponentDescriptorRevision `json:"lastAppliedComponentDescriptor,omitempty"`
+
 	// SnapshotName specifies the name of the Snapshot that has been created to store the resource
 	// within the cluster and make it available for consumption by Flux controllers.
 	// +optional
 	SnapshotName string `json:"snapshotName,omitempty"`
 
 	// LatestSnapshotDigest is a string representation of the digest for the most recent Resource snapshot.
+	// Consumers can use it to pin the exact content of the snapshot, as the data in the in-cluster registry is
+	// addressed by this digest.
 	// +optional
 	LatestSnapshotDigest string `json:"latestSnapshotDigest,omitempty"`
+
+	// SourceRegistry is the upstream registry the resource in the Snapshot was fetched from, for example the
+	// registry of its image reference, the host of its download URL or, for local blobs, the registry of the
+	// component.
+	// +optional
+	SourceRegistry string `json:"sourceRegistry,omitempty"`
+
+	// SourceRepository is the repository in SourceRegistry the resource in the Snapshot was fetched from, or the
+	// path of its download URL.
+	// +optional
+	SourceRepository string `json:"sourceRepository,omitempty"`
+
+	// Transformed is true if the resource was transformed by the webhook of Spec.Transform before it was written
+	// to the Snapshot.
+	// +optional
+	Transformed bool `json:"transformed,omitempty"`
+
+	// ResolvedDigest is the digest the tag of the image reference of the resource was resolved to, if the
+	// component descriptor references the image by tag without a digest.
+	// +optional
+	ResolvedDigest string `json:"resolvedDigest,omitempty"`
+
+	// FailureCount is the number of consecutive reconciliations that failed to fetch the resource because of
+	// transient registry errors. It is used to back off retries and is reset once the resource has been fetched.
+	// +optional
+	FailureCount int `json:"failureCount,omitempty"`
+
+	// ComponentDescriptorWaitStart is the time the Resource started waiting for the component descriptor of its
+	// component to be created. It is cleared once the descriptor has been found.
+	// +optional
+	ComponentDescriptorWaitStart *metav1.Time `json:"componentDescriptorWaitStart,omitempty"`
+
+	// LastReconcileTime is the time the most recent reconciliation of the Resource started.
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// LastError is the error of the most recent reconciliation if it failed. It is cleared once a reconciliation
+	// succeeds.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// LastHandledReconcileAt holds the value of the most recent reconcile request annotation that has been
+	// handled.
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
+	// DryRunResult holds the snapshot that would be created for the resource if DryRun is set.
+	// +optional
+	DryRunResult *DryRunResult `json:"dryRunResult,omitempty"`
+
+	// Resources holds the Snapshots of the resources selected by Spec.Resources.
+	// +optional
+	Resources []ResourceSnapshotStatus `json:"resources,omitempty"`
+
+	// SnapshotHistory holds the snapshot data pushed for the Resource, most recent first, while
+	// Spec.SnapshotTemplate.Retention is set. The data of entries beyond the retention is deleted from the registry.
+	// +optional
+	SnapshotHistory []SnapshotHistoryEntry `json:"snapshotHistory,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -63,6 +344,7 @@ type ResourceStatus struct {
 //+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
 //+kubebuilder:printcolumn:name="Source Version",type="string",JSONPath=".status.latestSourceVersion",description=""
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
 
 // Resource is the Schema for the resources API.
 type Resource struct {
@@ -82,6 +364,79 @@ func (in *Resource) GetVID() map[string]string {
 	return metadata
 }
 
+// IsSuspended returns true if the reconciliation of the Resource is suspended by its spec or annotation.
+func (in *Resource) IsSuspended() bool {
+	return in.Spec.Suspend || in.GetAnnotations()[SuspendAnnotation] == "true"
+}
+
+// GetUpdatePolicy returns the policy that defines when the Snapshot is written, Always if the Resource doesn't set
+// one.
+func (in *Resource) GetUpdatePolicy() string {
+	if in.Spec.UpdatePolicy == "" {
+		return UpdatePolicyAlways
+	}
+
+	return in.Spec.UpdatePolicy
+}
+
+// GetSnapshotCompression returns the compression of the snapshot layer, gzip if the snapshot template doesn't
+// set one.
+func (in *Resource) GetSnapshotCompression() string {
+	if in.Spec.SnapshotTemplate == nil || in.Spec.SnapshotTemplate.Compression == "" {
+		return CompressionGzip
+	}
+
+	return in.Spec.SnapshotTemplate.Compression
+}
+
+// GetSnapshotConfig returns the image config of the snapshot, nil if the snapshot template doesn't set one.
+func (in *Resource) GetSnapshotConfig() *SnapshotConfig {
+	if in.Spec.SnapshotTemplate == nil {
+		return nil
+	}
+
+	return in.Spec.SnapshotTemplate.Config
+}
+
+// GetSnapshotRegistry returns the address of the registry the snapshot is pushed to, empty for the in-cluster
+// registry.
+func (in *Resource) GetSnapshotRegistry() string {
+	if in.Spec.SnapshotTemplate == nil {
+		return ""
+	}
+
+	return in.Spec.SnapshotTemplate.Registry
+}
+
+// GetSnapshotArtifactType returns the artifact type of the snapshot, empty if the snapshot is an image.
+func (in *Resource) GetSnapshotArtifactType() string {
+	if in.Spec.SnapshotTemplate == nil {
+		return ""
+	}
+
+	return in.Spec.SnapshotTemplate.ArtifactType
+}
+
+// GetSnapshotReuseLayers returns whether the layer of the snapshot is mounted from the previous version of the
+// snapshot if the registry already holds it.
+func (in *Resource) GetSnapshotReuseLayers() bool {
+	return in.Spec.SnapshotTemplate != nil && in.Spec.SnapshotTemplate.ReuseLayers
+}
+
+// GetSnapshotRetention returns the number of snapshots kept in the registry, zero if all of them are kept.
+func (in *Resource) GetSnapshotRetention() int {
+	if in.Spec.SnapshotTemplate == nil || in.Spec.SnapshotTemplate.Retention == nil {
+		return 0
+	}
+
+	return in.Spec.SnapshotTemplate.Retention.KeepLast
+}
+
+// IsSnapshotTagless returns whether the snapshots of the Resource are pushed by their manifest digest only.
+func (in *Resource) IsSnapshotTagless() bool {
+	return in.Spec.SnapshotTemplate != nil && in.Spec.SnapshotTemplate.Tagless
+}
+
 func (in *Resource) SetObservedGeneration(v int64) {
 	in.Status.ObservedGeneration = v
 }
@@ -96,14 +451,42 @@ func (in *Resource) SetConditions(conditions []metav1.Condition) {
 	in.Status.Conditions = conditions
 }
 
-// GetRequeueAfter returns the duration after which the Resource should be reconciled.
-func (in Resource) GetRequeueAfter() time.Duration {
+// GetRequeueAfter returns the duration after which the Resource should be reconciled. Falls back to the default
+// interval of the controller if the Resource doesn't set an interval.
+func (in Resource) GetRequeueAfter(defaultInterval time.Duration) time.Duration {
+	if in.Spec.Interval.Duration <= 0 {
+		return defaultInterval
+	}
+
 	return in.Spec.Interval.Duration
 }
 
+// GetElementRef returns the reference of the single resource or source written to the Snapshot of the Resource,
+// nil if the Resource selects several resources.
+func (in Resource) GetElementRef() *ResourceReference {
+	if in.Spec.Source != nil {
+		return in.Spec.Source
+	}
+
+	return in.Spec.SourceRef.ResourceRef
+}
+
+// GetElementVersion returns the version of the resource or source selected by GetElementRef, if any.
+func (in Resource) GetElementVersion() string {
+	if ref := in.GetElementRef(); ref != nil {
+		return ref.Version
+	}
+
+	return ""
+}
+
 // GetReferencePath returns the component reference path for the Resource.
 func (in Resource) GetReferencePath() []ocmmetav1.Identity {
-	return in.Spec.SourceRef.ResourceRef.ReferencePath
+	if ref := in.GetElementRef(); ref != nil {
+		return ref.ReferencePath
+	}
+
+	return nil
 }
 
 // GetSnapshotDigest returns the digest of the Resource's associated Snapshot.
@@ -116,6 +499,25 @@ func (in Resource) GetSnapshotName() string {
 	return in.Status.SnapshotName
 }
 
+// GetResourceSnapshotName returns the name of the Snapshot of a resource selected by Spec.Resources.
+func (in Resource) GetResourceSnapshotName(resource string) string {
+	return in.GetSnapshotName() + "-" + resource
+}
+
+// GetSnapshotNames returns the names of all Snapshots written by the Resource.
+func (in Resource) GetSnapshotNames() []string {
+	if len(in.Spec.Resources) == 0 {
+		return []string{in.GetSnapshotName()}
+	}
+
+	names := make([]string, 0, len(in.Spec.Resources))
+	for _, selector := range in.Spec.Resources {
+		names = append(names, in.GetResourceSnapshotName(selector.Name))
+	}
+
+	return names
+}
+
 //+kubebuilder:object:root=true
 
 // ResourceList contains a list of Resource.
@@ -0,0 +1,21 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResourceGetRequeueAfter(t *testing.T) {
+	res := validResource()
+	assert.Equal(t, 10*time.Minute, res.GetRequeueAfter(5*time.Minute))
+
+	res.Spec.Interval = metav1.Duration{}
+	assert.Equal(t, 5*time.Minute, res.GetRequeueAfter(5*time.Minute))
+}
@@ -0,0 +1,446 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ResourceWebhook defaults and validates Resources. The allowed hosts are set by the controller from its flags.
+// +kubebuilder:object:generate=false
+type ResourceWebhook struct {
+	// AllowedSnapshotRegistries are the hosts of the registries Resources may push their snapshots to instead of
+	// the in-cluster registry. No registry is allowed if empty.
+	AllowedSnapshotRegistries []string
+
+	// AllowedTransformHosts are the hosts transformation webhooks may be served from, see CheckTransformURL. No
+	// host is allowed if empty.
+	AllowedTransformHosts []string
+}
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks of the Resource with the manager.
+func (w *ResourceWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&Resource{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-delivery-ocm-software-v1alpha1-resource,mutating=true,failurePolicy=fail,sideEffects=None,groups=delivery.ocm.software,resources=resources,verbs=create,versions=v1alpha1,name=mresource.delivery.ocm.software,admissionReviewVersions=v1
+
+var _ admission.CustomDefaulter = &ResourceWebhook{}
+
+// Default implements admission.CustomDefaulter. It sets the name of the snapshot to the name of the Resource if
+// the snapshot template doesn't set one, so the snapshot can be found by name instead of getting a generated name.
+// The webhook is only called on create, the snapshot of an existing Resource keeps its name. The tag of the
+// snapshot isn't part of the template, it is always derived from the version of the resource or, with
+// tagFromDigest, from the digest of its data.
+func (w *ResourceWebhook) Default(_ context.Context, obj runtime.Object) error {
+	in, ok := obj.(*Resource)
+	if !ok {
+		return fmt.Errorf("expected a Resource but got %T", obj)
+	}
+
+	// The name isn't known yet if it is generated by the API server.
+	if in.GetSnapshotTemplateName() != "" || in.Name == "" {
+		return nil
+	}
+
+	if in.Spec.SnapshotTemplate == nil {
+		in.Spec.SnapshotTemplate = &SnapshotTemplateSpec{}
+	}
+
+	in.Spec.SnapshotTemplate.Name = in.Name
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-delivery-ocm-software-v1alpha1-resource,mutating=false,failurePolicy=fail,sideEffects=None,groups=delivery.ocm.software,resources=resources,verbs=create;update,versions=v1alpha1,name=vresource.delivery.ocm.software,admissionReviewVersions=v1
+
+var _ admission.CustomValidator = &ResourceWebhook{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (w *ResourceWebhook) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	in, ok := obj.(*Resource)
+	if !ok {
+		return fmt.Errorf("expected a Resource but got %T", obj)
+	}
+
+	return in.toInvalidError(in.validateSpec(w.AllowedSnapshotRegistries, w.AllowedTransformHosts))
+}
+
+// ValidateUpdate implements admission.CustomValidator. In addition to the checks done on create, it
+// rejects renaming the snapshot once it has been created, as the new name would be ignored.
+func (w *ResourceWebhook) ValidateUpdate(_ context.Context, old, obj runtime.Object) error {
+	in, ok := obj.(*Resource)
+	if !ok {
+		return fmt.Errorf("expected a Resource but got %T", obj)
+	}
+
+	allErrs := in.validateSpec(w.AllowedSnapshotRegistries, w.AllowedTransformHosts)
+
+	if oldObj, ok := old.(*Resource); ok && oldObj.Status.SnapshotName != "" {
+		if name := in.GetSnapshotTemplateName(); name != "" && name != oldObj.Status.SnapshotName {
+			allErrs = append(allErrs, field.Forbidden(
+				field.NewPath("spec", "snapshotTemplate", "name"),
+				fmt.Sprintf("snapshot %s has already been created for this resource; delete and recreate the Resource to use a different name", oldObj.Status.SnapshotName),
+			))
+		}
+	}
+
+	return in.toInvalidError(allErrs)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (w *ResourceWebhook) ValidateDelete(context.Context, runtime.Object) error {
+	return nil
+}
+
+// GetSnapshotTemplateName returns the name set in the snapshot template, if any.
+func (in *Resource) GetSnapshotTemplateName() string {
+	if in.Spec.SnapshotTemplate == nil {
+		return ""
+	}
+
+	return in.Spec.SnapshotTemplate.Name
+}
+
+func (in *Resource) validateSpec(allowedRegistries, allowedTransformHosts []string) field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	if in.Spec.Interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("interval"), in.Spec.Interval.Duration.String(), "must not be negative, for example 10m, or unset to use the default interval"))
+	}
+
+	allErrs = append(allErrs, validateSourceRef(specPath.Child("sourceRef"), in.Spec.SourceRef, len(in.Spec.Resources) == 0 && in.Spec.Source == nil)...)
+	allErrs = append(allErrs, in.validateResources(specPath)...)
+	allErrs = append(allErrs, in.validateSource(specPath)...)
+
+	if name := in.GetSnapshotTemplateName(); name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("snapshotTemplate", "name"), name, msg))
+		}
+	}
+
+	if template := in.Spec.SnapshotTemplate; template != nil && template.Tagless && template.TagFromDigest {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("snapshotTemplate", "tagless"), "tagless and tagFromDigest are mutually exclusive"))
+	}
+
+	if registry := in.GetSnapshotRegistry(); registry != "" {
+		if err := validateRegistry(registry, allowedRegistries); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("snapshotTemplate", "registry"), registry, err.Error()))
+		}
+	}
+
+	if artifactType := in.GetSnapshotArtifactType(); artifactType != "" && !mediaTypePattern.MatchString(artifactType) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("snapshotTemplate", "artifactType"), artifactType,
+			"must be a media type of the form type/subtype, for example application/vnd.ocm.software.snapshot.v1"))
+	}
+
+	if config := in.GetSnapshotConfig(); config != nil {
+		configPath := specPath.Child("snapshotTemplate", "config")
+		if config.OS == "" && config.Architecture != "" {
+			allErrs = append(allErrs, field.Required(configPath.Child("os"), "must be set together with architecture"))
+		}
+		if config.Architecture == "" && (config.OS != "" || config.Variant != "") {
+			allErrs = append(allErrs, field.Required(configPath.Child("architecture"), "must be set together with os and variant"))
+		}
+	}
+
+	if in.Spec.Platform != "" {
+		if err := validatePlatform(in.Spec.Platform); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("platform"), in.Spec.Platform, err.Error()))
+		}
+	}
+
+	if maxSize := in.Spec.MaxSize; maxSize != nil && maxSize.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxSize"), maxSize.String(), "must be positive, for example 500Mi, or unset to not limit the size"))
+	}
+
+	if timeout := in.Spec.Timeout; timeout != nil && timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("timeout"), timeout.Duration.String(), "must be positive, for example 30m, or unset to use the timeout of the controller"))
+	}
+
+	if extract := in.Spec.Extract; extract != nil {
+		if extract.Path == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("extract", "path"), "a glob pattern selecting the files to extract must be set, for example manifests/*.yaml"))
+		} else if _, err := path.Match(extract.Path, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("extract", "path"), extract.Path, err.Error()))
+		}
+	}
+
+	if transform := in.Spec.Transform; transform != nil {
+		transformPath := specPath.Child("transform")
+		if transform.URL == "" {
+			allErrs = append(allErrs, field.Required(transformPath.Child("url"), "the URL of the transformation webhook must be set"))
+		} else if err := validateTransformURL(transform.URL, allowedTransformHosts); err != nil {
+			allErrs = append(allErrs, field.Invalid(transformPath.Child("url"), transform.URL, err.Error()))
+		}
+
+		if timeout := transform.Timeout; timeout != nil && timeout.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(transformPath.Child("timeout"), timeout.Duration.String(), "must be positive, or unset to use the default of one minute"))
+		}
+	}
+
+	for i, signature := range in.Spec.Verify {
+		signaturePath := specPath.Child("verify").Index(i)
+		if signature.Name == "" {
+			allErrs = append(allErrs, field.Required(signaturePath.Child("name"), "the name of the signature to verify must be set"))
+		}
+		if signature.PublicKey.Value == "" && signature.PublicKey.SecretRef == nil {
+			allErrs = append(allErrs, field.Required(signaturePath.Child("publicKey"), "either a value or a secretRef must be set"))
+		}
+		if signature.PublicKey.Value != "" && signature.PublicKey.SecretRef != nil {
+			allErrs = append(allErrs, field.Forbidden(signaturePath.Child("publicKey"), "value and secretRef are mutually exclusive"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateResources checks the resources selected by Spec.Resources. Options that only make sense for a single
+// resource can't be combined with them.
+func (in *Resource) validateResources(specPath *field.Path) field.ErrorList {
+	if len(in.Spec.Resources) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+
+	resourcesPath := specPath.Child("resources")
+	if in.Spec.SourceRef.ResourceRef != nil {
+		allErrs = append(allErrs, field.Forbidden(resourcesPath, "resources and sourceRef.resourceRef are mutually exclusive"))
+	}
+
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "dryRun", set: in.Spec.DryRun},
+		{name: "platform", set: in.Spec.Platform != ""},
+		{name: "extract", set: in.Spec.Extract != nil},
+	} {
+		if option.set {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child(option.name), "can't be used together with resources"))
+		}
+	}
+
+	seen := make(map[string]struct{}, len(in.Spec.Resources))
+	for i, selector := range in.Spec.Resources {
+		selectorPath := resourcesPath.Index(i)
+		if selector.Name == "" {
+			allErrs = append(allErrs, field.Required(selectorPath.Child("name"), "the name of the resource must be set"))
+
+			continue
+		}
+
+		if _, ok := seen[selector.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(selectorPath.Child("name"), selector.Name))
+		}
+		seen[selector.Name] = struct{}{}
+
+		// The name of the resource is part of the name of its Snapshot.
+		for _, msg := range validation.IsDNS1123Subdomain(selector.Name) {
+			allErrs = append(allErrs, field.Invalid(selectorPath.Child("name"), selector.Name, msg))
+		}
+
+		for j, identity := range selector.ReferencePath {
+			if identity["name"] == "" {
+				allErrs = append(allErrs, field.Required(selectorPath.Child("referencePath").Index(j).Child("name"), "the name of the component reference must be set"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// validateSource checks the source selected by Spec.Source. Exactly one of sourceRef.resourceRef, resources and
+// source selects what is written to the snapshot.
+func (in *Resource) validateSource(specPath *field.Path) field.ErrorList {
+	source := in.Spec.Source
+	if source == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+
+	sourcePath := specPath.Child("source")
+	if in.Spec.SourceRef.ResourceRef != nil {
+		allErrs = append(allErrs, field.Forbidden(sourcePath, "source and sourceRef.resourceRef are mutually exclusive"))
+	}
+
+	if len(in.Spec.Resources) > 0 {
+		allErrs = append(allErrs, field.Forbidden(sourcePath, "source and resources are mutually exclusive"))
+	}
+
+	// Referrers describe resources, a source has none.
+	if in.Spec.IncludeReferrers {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("includeReferrers"), "can't be used together with source"))
+	}
+
+	if source.Name == "" {
+		allErrs = append(allErrs, field.Required(sourcePath.Child("name"), "the name of the source must be set"))
+	}
+
+	for i, identity := range source.ReferencePath {
+		if identity["name"] == "" {
+			allErrs = append(allErrs, field.Required(sourcePath.Child("referencePath").Index(i).Child("name"), "the name of the component reference must be set"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSourceRef(fldPath *field.Path, ref ObjectReference, requireResourceRef bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if ref.Kind != ComponentVersionKind {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("kind"), ref.Kind, []string{ComponentVersionKind}))
+	}
+
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "the name of the ComponentVersion must be set"))
+	}
+
+	if ref.ResourceRef == nil {
+		if !requireResourceRef {
+			return allErrs
+		}
+
+		return append(allErrs, field.Required(fldPath.Child("resourceRef"), "one of the resource to fetch from the component, resources or source must be set"))
+	}
+
+	resourceRefPath := fldPath.Child("resourceRef")
+	if ref.ResourceRef.Name == "" {
+		allErrs = append(allErrs, field.Required(resourceRefPath.Child("name"), "the name of the resource must be set"))
+	}
+
+	for i, identity := range ref.ResourceRef.ReferencePath {
+		if identity["name"] == "" {
+			allErrs = append(allErrs, field.Required(resourceRefPath.Child("referencePath").Index(i).Child("name"), "the name of the component reference must be set"))
+		}
+	}
+
+	return allErrs
+}
+
+// mediaTypePattern matches media types as defined by the OCI image specification.
+var mediaTypePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}/[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}$`)
+
+// validatePlatform checks that the platform is of the form os/arch[/variant].
+func validatePlatform(platform string) error {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("must be of the form os/arch[/variant], for example linux/amd64")
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("must be of the form os/arch[/variant], for example linux/amd64")
+		}
+	}
+
+	return nil
+}
+
+// validateRegistry checks that the address is of the form [scheme://]host[:port] and that the host is one of the
+// allowed registries. The host and port are validated by the controller when the address is used.
+func validateRegistry(address string, allowed []string) error {
+	host := address
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("unsupported scheme %q, must be http or https", scheme)
+		}
+
+		host = rest
+	}
+
+	host = strings.TrimRight(host, "/")
+	if host == "" || strings.ContainsAny(host, "/?#@ ") {
+		return fmt.Errorf("must be of the form [scheme://]host[:port], for example registry.example.com:5000")
+	}
+
+	if !slices.ContainsFunc(allowed, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimSpace(allowed), host)
+	}) {
+		return fmt.Errorf("registry %s is not allowed by the controller", host)
+	}
+
+	return nil
+}
+
+// validateTransformURL checks that the URL of a transformation webhook is an absolute http or https URL served
+// from one of the allowed hosts.
+func validateTransformURL(raw string, allowed []string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q, must be http or https", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("must be an absolute URL, for example https://relocator.ocm-system.svc/transform")
+	}
+
+	return CheckTransformURL(raw, allowed)
+}
+
+// CheckTransformURL returns an error if the URL of a transformation webhook isn't served from one of the allowed
+// hosts. Hosts are of the form host[:port] and compared with the host of the URL case-insensitively. They only
+// allow https, a host prefixed with http:// may be accessed over plain http as well. No URL is allowed if there
+// are no allowed hosts.
+func CheckTransformURL(raw string, allowed []string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range allowed {
+		host = strings.TrimSpace(host)
+		plain := strings.HasPrefix(strings.ToLower(host), "http://")
+		if plain {
+			host = host[len("http://"):]
+		}
+
+		if host == "" || !strings.EqualFold(host, u.Host) {
+			continue
+		}
+
+		if u.Scheme != "https" && !plain {
+			return fmt.Errorf("host %s may only be accessed over https", u.Host)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("host %s is not allowed by the controller", u.Host)
+}
+
+func (in *Resource) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind(ResourceKind).GroupKind(), in.Name, allErrs)
+}
@@ -0,0 +1,454 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResourceValidateCreate(t *testing.T) {
+	w := &ResourceWebhook{
+		AllowedSnapshotRegistries: []string{"registry.eu-west.example.com:5000", "Registry.Example.com"},
+		AllowedTransformHosts:     []string{"relocator.ocm-system.svc", "http://relocator:8080"},
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(res *Resource)
+		errStr string
+	}{
+		{
+			name:   "valid resource",
+			modify: func(res *Resource) {},
+		},
+		{
+			name: "missing resource ref",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+			},
+			errStr: "spec.sourceRef.resourceRef: Required value",
+		},
+		{
+			name: "multiple resources",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Resources = []ResourceSelector{
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "manifests"}}},
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image"}}},
+				}
+			},
+		},
+		{
+			name: "resources and resource ref",
+			modify: func(res *Resource) {
+				res.Spec.Resources = []ResourceSelector{
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image"}}},
+				}
+			},
+			errStr: "spec.resources: Forbidden: resources and sourceRef.resourceRef are mutually exclusive",
+		},
+		{
+			name: "source",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Source = &ResourceReference{ElementMeta: ElementMeta{Name: "git-archive"}}
+			},
+		},
+		{
+			name: "source and resource ref",
+			modify: func(res *Resource) {
+				res.Spec.Source = &ResourceReference{ElementMeta: ElementMeta{Name: "git-archive"}}
+			},
+			errStr: "spec.source: Forbidden: source and sourceRef.resourceRef are mutually exclusive",
+		},
+		{
+			name: "source and resources",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Source = &ResourceReference{ElementMeta: ElementMeta{Name: "git-archive"}}
+				res.Spec.Resources = []ResourceSelector{
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image"}}},
+				}
+			},
+			errStr: "spec.source: Forbidden: source and resources are mutually exclusive",
+		},
+		{
+			name: "source without name",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Source = &ResourceReference{}
+			},
+			errStr: "spec.source.name: Required value",
+		},
+		{
+			name: "source with referrers",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Source = &ResourceReference{ElementMeta: ElementMeta{Name: "git-archive"}}
+				res.Spec.IncludeReferrers = true
+			},
+			errStr: "spec.includeReferrers: Forbidden: can't be used together with source",
+		},
+		{
+			name: "duplicate resources",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.Resources = []ResourceSelector{
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image"}}},
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image", Version: "v0.2.0"}}},
+				}
+			},
+			errStr: `spec.resources[1].name: Duplicate value: "image"`,
+		},
+		{
+			name: "resources with dry run",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef = nil
+				res.Spec.DryRun = true
+				res.Spec.Resources = []ResourceSelector{
+					{ResourceReference: ResourceReference{ElementMeta: ElementMeta{Name: "image"}}},
+				}
+			},
+			errStr: "spec.dryRun: Forbidden: can't be used together with resources",
+		},
+		{
+			name: "empty resource name",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef.Name = ""
+			},
+			errStr: "spec.sourceRef.resourceRef.name: Required value",
+		},
+		{
+			name: "empty component version name",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.Name = ""
+			},
+			errStr: "spec.sourceRef.name: Required value",
+		},
+		{
+			name: "unsupported source kind",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.Kind = "GitRepository"
+			},
+			errStr: `spec.sourceRef.kind: Unsupported value: "GitRepository"`,
+		},
+		{
+			name: "reference path without name",
+			modify: func(res *Resource) {
+				res.Spec.SourceRef.ResourceRef.ReferencePath = append(res.Spec.SourceRef.ResourceRef.ReferencePath, map[string]string{
+					"version": "v0.1.0",
+				})
+			},
+			errStr: "spec.sourceRef.resourceRef.referencePath[0].name: Required value",
+		},
+		{
+			name: "invalid snapshot name",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Name: "Invalid_Name"}
+			},
+			errStr: `spec.snapshotTemplate.name: Invalid value: "Invalid_Name"`,
+		},
+		{
+			name: "snapshot registry",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Registry: "https://registry.eu-west.example.com:5000"}
+			},
+		},
+		{
+			name: "snapshot registry with a path",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Registry: "registry.example.com/snapshots"}
+			},
+			errStr: `spec.snapshotTemplate.registry: Invalid value: "registry.example.com/snapshots": must be of the form [scheme://]host[:port]`,
+		},
+		{
+			name: "allowed snapshot registry without a scheme",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Registry: "registry.example.com"}
+			},
+		},
+		{
+			name: "snapshot registry that isn't allowed",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Registry: "https://169.254.169.254"}
+			},
+			errStr: `spec.snapshotTemplate.registry: Invalid value: "https://169.254.169.254": registry 169.254.169.254 is not allowed by the controller`,
+		},
+		{
+			name: "snapshot registry with an unsupported scheme",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Registry: "oci://registry.example.com"}
+			},
+			errStr: `spec.snapshotTemplate.registry: Invalid value: "oci://registry.example.com": unsupported scheme "oci"`,
+		},
+		{
+			name: "snapshot config",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Config: &SnapshotConfig{OS: "linux", Architecture: "arm64", Variant: "v8"}}
+			},
+		},
+		{
+			name: "snapshot config without architecture",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Config: &SnapshotConfig{OS: "linux"}}
+			},
+			errStr: `spec.snapshotTemplate.config.architecture: Required value: must be set together with os and variant`,
+		},
+		{
+			name: "snapshot config without os",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Config: &SnapshotConfig{Architecture: "amd64"}}
+			},
+			errStr: `spec.snapshotTemplate.config.os: Required value: must be set together with architecture`,
+		},
+		{
+			name: "snapshot config with labels only",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Config: &SnapshotConfig{Labels: map[string]string{"team": "delivery"}}}
+			},
+		},
+		{
+			name: "tagless snapshot",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Tagless: true}
+			},
+		},
+		{
+			name: "tagless snapshot tagged with digest",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Tagless: true, TagFromDigest: true}
+			},
+			errStr: "spec.snapshotTemplate.tagless: Forbidden: tagless and tagFromDigest are mutually exclusive",
+		},
+		{
+			name: "snapshot artifact type",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{ArtifactType: "application/vnd.ocm.software.snapshot.v1"}
+			},
+		},
+		{
+			name: "invalid snapshot artifact type",
+			modify: func(res *Resource) {
+				res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{ArtifactType: "ocm snapshot"}
+			},
+			errStr: `spec.snapshotTemplate.artifactType: Invalid value: "ocm snapshot": must be a media type of the form type/subtype`,
+		},
+		{
+			name: "maximum size",
+			modify: func(res *Resource) {
+				maxSize := resource.MustParse("500Mi")
+				res.Spec.MaxSize = &maxSize
+			},
+		},
+		{
+			name: "zero maximum size",
+			modify: func(res *Resource) {
+				maxSize := resource.MustParse("0")
+				res.Spec.MaxSize = &maxSize
+			},
+			errStr: `spec.maxSize: Invalid value: "0": must be positive`,
+		},
+		{
+			name: "timeout",
+			modify: func(res *Resource) {
+				res.Spec.Timeout = &metav1.Duration{Duration: 30 * time.Minute}
+			},
+		},
+		{
+			name: "zero timeout",
+			modify: func(res *Resource) {
+				res.Spec.Timeout = &metav1.Duration{}
+			},
+			errStr: `spec.timeout: Invalid value: "0s": must be positive`,
+		},
+		{
+			name: "invalid platform",
+			modify: func(res *Resource) {
+				res.Spec.Platform = "linux"
+			},
+			errStr: `spec.platform: Invalid value: "linux": must be of the form os/arch[/variant]`,
+		},
+		{
+			name: "invalid extract path",
+			modify: func(res *Resource) {
+				res.Spec.Extract = &ExtractSpec{Path: "manifests/["}
+			},
+			errStr: `spec.extract.path: Invalid value: "manifests/["`,
+		},
+		{
+			name: "transform webhook",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{
+					URL:     "https://relocator.ocm-system.svc/transform",
+					Timeout: &metav1.Duration{Duration: 5 * time.Minute},
+				}
+			},
+		},
+		{
+			name: "missing transform URL",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{}
+			},
+			errStr: "spec.transform.url: Required value",
+		},
+		{
+			name: "relative transform URL",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{URL: "relocator/transform"}
+			},
+			errStr: `spec.transform.url: Invalid value: "relocator/transform": unsupported scheme ""`,
+		},
+		{
+			name: "transform URL without host",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{URL: "https:///transform"}
+			},
+			errStr: "must be an absolute URL",
+		},
+		{
+			name: "plain http transform webhook",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{URL: "http://relocator:8080/transform"}
+			},
+		},
+		{
+			name: "transform webhook that isn't allowed",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{URL: "https://169.254.169.254/latest/meta-data"}
+			},
+			errStr: `spec.transform.url: Invalid value: "https://169.254.169.254/latest/meta-data": host 169.254.169.254 is not allowed by the controller`,
+		},
+		{
+			name: "transform webhook over plain http",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{URL: "http://relocator.ocm-system.svc/transform"}
+			},
+			errStr: "host relocator.ocm-system.svc may only be accessed over https",
+		},
+		{
+			name: "negative transform timeout",
+			modify: func(res *Resource) {
+				res.Spec.Transform = &TransformSpec{
+					URL:     "http://relocator:8080",
+					Timeout: &metav1.Duration{Duration: -time.Second},
+				}
+			},
+			errStr: `spec.transform.timeout: Invalid value: "-1s"`,
+		},
+		{
+			name: "default interval",
+			modify: func(res *Resource) {
+				res.Spec.Interval = metav1.Duration{}
+			},
+		},
+		{
+			name: "negative interval",
+			modify: func(res *Resource) {
+				res.Spec.Interval = metav1.Duration{Duration: -time.Minute}
+			},
+			errStr: "spec.interval: Invalid value",
+		},
+		{
+			name: "public key with value and secret ref",
+			modify: func(res *Resource) {
+				res.Spec.Verify = []Signature{
+					{
+						Name: "signature",
+						PublicKey: PublicKey{
+							Value:     "key",
+							SecretRef: &v1.LocalObjectReference{Name: "secret"},
+						},
+					},
+				}
+			},
+			errStr: "spec.verify[0].publicKey: Forbidden: value and secretRef are mutually exclusive",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			res := validResource()
+			tt.modify(res)
+
+			err := w.ValidateCreate(context.Background(), res)
+			if tt.errStr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.errStr)
+		})
+	}
+}
+
+func TestResourceDefault(t *testing.T) {
+	w := &ResourceWebhook{}
+	ctx := context.Background()
+
+	t.Log("defaulting the snapshot name to the name of the resource")
+	res := validResource()
+	require.NoError(t, w.Default(ctx, res))
+	assert.Equal(t, "test-resource", res.GetSnapshotTemplateName())
+	assert.NoError(t, w.ValidateCreate(ctx, res))
+
+	t.Log("keeping the snapshot name of the template")
+	res = validResource()
+	res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Name: "custom-snapshot", TagFromDigest: true}
+	require.NoError(t, w.Default(ctx, res))
+	assert.Equal(t, &SnapshotTemplateSpec{Name: "custom-snapshot", TagFromDigest: true}, res.Spec.SnapshotTemplate)
+
+	t.Log("not defaulting the snapshot name of a resource with a generated name")
+	res = validResource()
+	res.Name, res.GenerateName = "", "test-resource-"
+	require.NoError(t, w.Default(ctx, res))
+	assert.Nil(t, res.Spec.SnapshotTemplate)
+}
+
+func TestResourceValidateUpdate(t *testing.T) {
+	w := &ResourceWebhook{}
+	ctx := context.Background()
+
+	old := validResource()
+	old.Status.SnapshotName = "test-resource-snapshot"
+
+	res := validResource()
+	res.Spec.SnapshotTemplate = &SnapshotTemplateSpec{Name: "test-resource-snapshot"}
+	assert.NoError(t, w.ValidateUpdate(ctx, old, res))
+
+	res.Spec.SnapshotTemplate.Name = "renamed-snapshot"
+	assert.ErrorContains(t, w.ValidateUpdate(ctx, old, res), "spec.snapshotTemplate.name: Forbidden: snapshot test-resource-snapshot has already been created")
+}
+
+func validResource() *Resource {
+	return &Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-resource",
+			Namespace: "default",
+		},
+		Spec: ResourceSpec{
+			Interval: metav1.Duration{Duration: 10 * time.Minute},
+			SourceRef: ObjectReference{
+				NamespacedObjectKindReference: meta.NamespacedObjectKindReference{
+					Kind: ComponentVersionKind,
+					Name: "test-component",
+				},
+				ResourceRef: &ResourceReference{
+					ElementMeta: ElementMeta{
+						Name: "manifests",
+					},
+				},
+			},
+		},
+	}
+}
@@ -4,14 +4,113 @@
 
 package v1alpha1
 
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// Compressions of the snapshot layer.
+const (
+	// CompressionGzip stores the resource data gzip-compressed.
+	CompressionGzip = "gzip"
+	// CompressionNone stores the resource data uncompressed.
+	CompressionNone = "none"
+	// CompressionPassthrough stores the resource data as it is fetched, without decompressing or recompressing it.
+	CompressionPassthrough = "passthrough"
+)
+
+// CompressionAnnotation records the compression of the snapshot layer on the Snapshot.
+const CompressionAnnotation = "delivery.ocm.software/compression"
+
 // SnapshotTemplateSpec defines the template used to create snapshots.
 type SnapshotTemplateSpec struct {
-	// +required
-	Name string `json:"name"`
+	// Name of the snapshot. If not set, a name is generated. The name is only used when the
+	// snapshot is created for the first time.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// TagFromDigest tags the snapshot with the digest of the resource data in the form sha256-<hex> instead of
+	// the resource version. The same content therefore always maps to the same tag.
+	// +optional
+	TagFromDigest bool `json:"tagFromDigest,omitempty"`
+
+	// Tagless pushes the snapshot by the digest of its manifest only, without a tag. The Snapshot then refers to
+	// the manifest digest, so consumers always get exactly the data that was pushed. Helm charts can't be
+	// installed from a snapshot without a tag. Can't be combined with tagFromDigest.
+	// +optional
+	Tagless bool `json:"tagless,omitempty"`
+
+	// Compression controls how the resource data is encoded in the snapshot layer. gzip, the default, stores the
+	// data gzip-compressed, none stores it uncompressed and passthrough stores it as it is fetched. Image resources
+	// are copied with their layers as they are, except that with gzip zstd-compressed layers are recompressed with
+	// gzip, keeping their uncompressed data and the config of the image.
+	// +kubebuilder:validation:Enum=gzip;none;passthrough
+	// +optional
+	Compression string `json:"compression,omitempty"`
+
+	// Registry is the address of the registry the snapshot is pushed to in the form [scheme://]host[:port], for
+	// example https://registry.eu-west.example.com. If not set, the snapshot is pushed to the in-cluster registry.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+
+	// ReuseLayers mounts the layer of the resource data from the repository of the previous version of the
+	// snapshot if that repository already holds a layer with the same digest, instead of uploading the data again.
+	// The data is buffered before it is pushed to compute the digest of its layer. Image resources are always
+	// copied as they are.
+	// +optional
+	ReuseLayers bool `json:"reuseLayers,omitempty"`
+
+	// ArtifactType is the media type of the config of the snapshot manifest, for example
+	// application/vnd.ocm.software.snapshot.v1, so snapshots can be told apart from regular images. If not set, the
+	// snapshot is an image. Helm charts keep the config media type Helm requires and image resources are always
+	// copied as they are.
+	// +optional
+	ArtifactType string `json:"artifactType,omitempty"`
+
+	// Config sets the image config of the snapshot, for example its platform, for tools that expect a valid
+	// config. If not set, the snapshot has an empty config. Image resources are always copied as they are.
+	// +optional
+	Config *SnapshotConfig `json:"config,omitempty"`
+
+	// Retention deletes the data of older snapshots of the Resource from the registry once a new snapshot has
+	// been pushed. If not set, the data of older snapshots is kept until the garbage collection deletes it.
+	// +optional
+	Retention *SnapshotRetention `json:"retention,omitempty"`
 
+	// Labels are added to the labels of the snapshot.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
 
+	// Annotations are added to the annotations of the snapshot.
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
+
+// SnapshotRetention defines how many snapshots of a Resource are kept in the registry.
+type SnapshotRetention struct {
+	// KeepLast is the number of most recently pushed snapshots that are kept, including the current one. The data
+	// of older snapshots is deleted, unless a Snapshot still refers to it.
+	// +kubebuilder:validation:Minimum=1
+	KeepLast int `json:"keepLast"`
+}
+
+// SnapshotConfig defines the image config of a snapshot.
+type SnapshotConfig struct {
+	// OS is the operating system of the snapshot, for example linux. Must be set together with architecture.
+	// +optional
+	OS string `json:"os,omitempty"`
+
+	// Architecture is the CPU architecture of the snapshot, for example amd64. Must be set together with os.
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
+
+	// Variant is the variant of the CPU architecture, for example v8 for arm64.
+	// +optional
+	Variant string `json:"variant,omitempty"`
+
+	// Created is the creation time recorded in the config. If not set, the config has no creation time, so the
+	// same data always results in the same manifest.
+	// +optional
+	Created *metav1.Time `json:"created,omitempty"`
+
+	// Labels are the labels of the image config.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
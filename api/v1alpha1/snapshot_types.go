@@ -6,10 +6,16 @@ package v1alpha1
 
 import (
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// SnapshotKind is the string representation of a Snapshot.
+	SnapshotKind = "Snapshot"
+)
+
 // SnapshotWriter defines any object which produces a snapshot
 // +k8s:deepcopy-gen=false
 type SnapshotWriter interface {
@@ -24,8 +30,27 @@ type SnapshotSpec struct {
 
 	Digest string `json:"digest"`
 
+	// Tag is the tag the snapshot data is stored with. For a snapshot pushed without a tag, it is the digest of
+	// the manifest in the form sha256:<hex>, which is referenced as repository@sha256:<hex>.
 	Tag string `json:"tag"`
 
+	// Repository is the name of the repository the snapshot data is stored in, as rendered when the data was
+	// pushed. The repository of a snapshot doesn't change if the controller is configured with another repository
+	// name template. If not set, the repository is named after the hash of the identity.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Registry is the address of the registry the snapshot data is stored in. If not set, the data is stored in
+	// the in-cluster registry.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+
+	// SecretRef specifies a Secret of type kubernetes.io/dockerconfigjson in the namespace of the snapshot holding
+	// the credentials for Registry. It is the SecretRef of the Resource that pushed the data, the data is accessed
+	// anonymously if it isn't set.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
 	// Suspend stops all operations on this object.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
@@ -44,6 +69,10 @@ type SnapshotStatus struct {
 	// +optional
 	LastReconciledTag string `json:"tag,omitempty"`
 
+	// LastReconcileTime is the time the data of the snapshot was last found in the registry.
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
 	// RepositoryURL has the concrete URL pointing to the local registry including the service name.
 	// +optional
 	RepositoryURL string `json:"repositoryURL,omitempty"`
@@ -16,7 +16,7 @@ import (
 	"k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -78,6 +78,21 @@ func (in *ComponentDescriptorList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentDescriptorRevision) DeepCopyInto(out *ComponentDescriptorRevision) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentDescriptorRevision.
+func (in *ComponentDescriptorRevision) DeepCopy() *ComponentDescriptorRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentDescriptorRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentDescriptorSpec) DeepCopyInto(out *ComponentDescriptorSpec) {
 	*out = *in
@@ -314,6 +329,21 @@ func (in *DeliverySpec) DeepCopy() *DeliverySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunResult) DeepCopyInto(out *DryRunResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunResult.
+func (in *DryRunResult) DeepCopy() *DryRunResult {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ElementMeta) DeepCopyInto(out *ElementMeta) {
 	*out = *in
@@ -343,6 +373,21 @@ func (in *ElementMeta) DeepCopy() *ElementMeta {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtractSpec) DeepCopyInto(out *ExtractSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtractSpec.
+func (in *ExtractSpec) DeepCopy() *ExtractSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtractSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FluxDeployer) DeepCopyInto(out *FluxDeployer) {
 	*out = *in
@@ -972,11 +1017,96 @@ func (in *ResourceReference) DeepCopy() *ResourceReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
+	*out = *in
+	in.ResourceReference.DeepCopyInto(&out.ResourceReference)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSelector.
+func (in *ResourceSelector) DeepCopy() *ResourceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceSnapshotStatus) DeepCopyInto(out *ResourceSnapshotStatus) {
+	*out = *in
+	if in.ComponentDescriptor != nil {
+		in, out := &in.ComponentDescriptor, &out.ComponentDescriptor
+		*out = new(ComponentDescriptorRevision)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSnapshotStatus.
+func (in *ResourceSnapshotStatus) DeepCopy() *ResourceSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSpec) DeepCopyInto(out *ResourceSpec) {
 	*out = *in
 	out.Interval = in.Interval
 	in.SourceRef.DeepCopyInto(&out.SourceRef)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(ResourceReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.SnapshotTemplate != nil {
+		in, out := &in.SnapshotTemplate, &out.SnapshotTemplate
+		*out = new(SnapshotTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Extract != nil {
+		in, out := &in.Extract, &out.Extract
+		*out = new(ExtractSpec)
+		**out = **in
+	}
+	if in.Transform != nil {
+		in, out := &in.Transform, &out.Transform
+		*out = new(TransformSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = make([]Signature, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSpec.
@@ -999,6 +1129,38 @@ func (in *ResourceStatus) DeepCopyInto(out *ResourceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastAppliedComponentDescriptor != nil {
+		in, out := &in.LastAppliedComponentDescriptor, &out.LastAppliedComponentDescriptor
+		*out = new(ComponentDescriptorRevision)
+		**out = **in
+	}
+	if in.ComponentDescriptorWaitStart != nil {
+		in, out := &in.ComponentDescriptorWaitStart, &out.ComponentDescriptorWaitStart
+		*out = (*in).DeepCopy()
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DryRunResult != nil {
+		in, out := &in.DryRunResult, &out.DryRunResult
+		*out = new(DryRunResult)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceSnapshotStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SnapshotHistory != nil {
+		in, out := &in.SnapshotHistory, &out.SnapshotHistory
+		*out = make([]SnapshotHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceStatus.
@@ -1054,6 +1216,53 @@ func (in *Snapshot) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotConfig) DeepCopyInto(out *SnapshotConfig) {
+	*out = *in
+	if in.Created != nil {
+		in, out := &in.Created, &out.Created
+		*out = (*in).DeepCopy()
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotConfig.
+func (in *SnapshotConfig) DeepCopy() *SnapshotConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotHistoryEntry) DeepCopyInto(out *SnapshotHistoryEntry) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.PushedAt.DeepCopyInto(&out.PushedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotHistoryEntry.
+func (in *SnapshotHistoryEntry) DeepCopy() *SnapshotHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SnapshotList) DeepCopyInto(out *SnapshotList) {
 	*out = *in
@@ -1086,6 +1295,21 @@ func (in *SnapshotList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotRetention) DeepCopyInto(out *SnapshotRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotRetention.
+func (in *SnapshotRetention) DeepCopy() *SnapshotRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SnapshotSpec) DeepCopyInto(out *SnapshotSpec) {
 	*out = *in
@@ -1096,6 +1320,11 @@ func (in *SnapshotSpec) DeepCopyInto(out *SnapshotSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotSpec.
@@ -1118,6 +1347,10 @@ func (in *SnapshotStatus) DeepCopyInto(out *SnapshotStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotStatus.
@@ -1133,6 +1366,16 @@ func (in *SnapshotStatus) DeepCopy() *SnapshotStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SnapshotTemplateSpec) DeepCopyInto(out *SnapshotTemplateSpec) {
 	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(SnapshotConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(SnapshotRetention)
+		**out = **in
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -1159,6 +1402,26 @@ func (in *SnapshotTemplateSpec) DeepCopy() *SnapshotTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransformSpec) DeepCopyInto(out *TransformSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransformSpec.
+func (in *TransformSpec) DeepCopy() *TransformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TransformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValuesSource) DeepCopyInto(out *ValuesSource) {
 	*out = *in
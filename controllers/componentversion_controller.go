@@ -131,6 +131,9 @@ func (r *ComponentVersionReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, fmt.Errorf("failed to get component object: %w", err)
 	}
 
+	logger = logger.WithValues(v1alpha1.LogKeyComponent, obj.Spec.Component)
+	ctx = log.IntoContext(ctx, logger)
+
 	if obj.Spec.Suspend {
 		logger.Info("component object suspended")
 
@@ -150,22 +150,22 @@ func (r *FluxDeployerReconciler) reconcile(
 		return ctrl.Result{RequeueAfter: r.RetryInterval}, nil
 	}
 
-	// requeue if snapshot is not ready
-	if conditions.IsFalse(snapshot, meta.ReadyCondition) {
+	// requeue if snapshot is not ready, its data may not be in the registry yet
+	if !conditions.IsReady(snapshot) {
 		logger.Info("snapshot not ready yet", "snapshot", snapshot.Name)
 
 		return ctrl.Result{RequeueAfter: r.RetryInterval}, nil
 	}
 
-	snapshotRepo, err := ocm.ConstructRepositoryName(snapshot.Spec.Identity)
+	snapshotRepo, err := ocm.SnapshotRepositoryName(snapshot)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	// If the type is HelmChart we need to cut off the last part of the snapshot url that will contain
 	// the chart name.
-	if _, ok := snapshot.Spec.Identity[v1alpha1.ResourceHelmChartNameKey]; ok {
-		snapshotRepo = snapshotRepo[0:strings.Index(snapshotRepo, "/")]
+	if chart, ok := snapshot.Spec.Identity[v1alpha1.ResourceHelmChartNameKey]; ok {
+		snapshotRepo = strings.TrimSuffix(snapshotRepo, "/"+chart)
 	}
 	snapshotURL := fmt.Sprintf("oci://%s/%s", r.RegistryServiceName, snapshotRepo)
 
@@ -273,15 +273,19 @@ func (r *FluxDeployerReconciler) reconcileOCIRepo(
 				return fmt.Errorf("failed to set owner reference on oci repository source: %w", err)
 			}
 		}
+		// A snapshot pushed without a tag is referenced by the digest of its manifest.
+		reference := &sourcev1beta2.OCIRepositoryRef{Tag: tag}
+		if strings.HasPrefix(tag, "sha256:") {
+			reference = &sourcev1beta2.OCIRepositoryRef{Digest: tag}
+		}
+
 		ociRepoCR.Spec = sourcev1beta2.OCIRepositorySpec{
 			Interval: obj.Spec.Interval,
 			CertSecretRef: &meta.LocalObjectReference{
 				Name: r.CertSecretName,
 			},
-			URL: url,
-			Reference: &sourcev1beta2.OCIRepositoryRef{
-				Tag: tag,
-			},
+			URL:       url,
+			Reference: reference,
 		}
 
 		return nil
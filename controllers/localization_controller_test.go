@@ -412,9 +412,61 @@ func TestLocalizationReconciler(t *testing.T) {
 				fakeOcm.GetComponentVersionReturnsForName(cmp.GetName(), cmp, nil)
 			},
 		},
+		{
+			name:        "GetImageReference fails for local blob without global access",
+			expectError: "failed to parse access reference: cannot determine image digest",
+			componentVersion: func() *v1alpha1.ComponentVersion {
+				cv := DefaultComponent.DeepCopy()
+				cv.Status.ComponentDescriptor = v1alpha1.Reference{
+					Name:    "test-component",
+					Version: "v0.0.1",
+					ComponentDescriptorRef: meta.NamespacedObjectReference{
+						Name:      cv.Name + "-descriptor",
+						Namespace: cv.Namespace,
+					},
+				}
+
+				return cv
+			},
+			componentDescriptor: func(owner client.Object) *v1alpha1.ComponentDescriptor {
+				cd := DefaultComponentDescriptor.DeepCopy()
+				err := controllerutil.SetOwnerReference(owner, cd, env.scheme)
+				require.NoError(t, err)
+
+				return cd
+			},
+			snapshot: func(cv *v1alpha1.ComponentVersion, resource *v1alpha1.Resource) *v1alpha1.Snapshot {
+				// do nothing
+				return nil
+			},
+			source: func(snapshot *v1alpha1.Snapshot) v1alpha1.ObjectReference {
+				return v1alpha1.ObjectReference{
+					NamespacedObjectKindReference: meta.NamespacedObjectKindReference{
+						APIVersion: v1alpha1.GroupVersion.String(),
+						Kind:       "ComponentVersion",
+						Name:       DefaultComponent.Name,
+						Namespace:  DefaultComponent.Namespace,
+					},
+					ResourceRef: &v1alpha1.ResourceReference{
+						ElementMeta: v1alpha1.ElementMeta{
+							Name:    "some-resource",
+							Version: "1.0.0",
+						},
+					},
+				}
+			},
+			mock: func(fakeCache *cachefakes.FakeCache, fakeOcm *fakes.MockFetcher) {
+				content, err := os.Open(filepath.Join("testdata", "localization-deploy.tar"))
+				require.NoError(t, err)
+				fakeOcm.GetResourceReturnsOnCall(0, content, nil)
+				fakeOcm.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer(localizationConfigData)), nil)
+				cmp := getMockComponent(DefaultComponent, ocmfake.RemoveGlobalAccess())
+				fakeOcm.GetComponentVersionReturnsForName(cmp.GetName(), cmp, nil)
+			},
+		},
 		{
 			name:        "ParseReference fails",
-			expectError: "failed to parse access reference: could not parse reference: invalid:@:1.0.0@sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2",
+			expectError: "failed to parse access reference: invalid reference: failed to parse reference \"invalid:@:1.0.0\"",
 			componentVersion: func() *v1alpha1.ComponentVersion {
 				cv := DefaultComponent.DeepCopy()
 				cv.Status.ComponentDescriptor = v1alpha1.Reference{
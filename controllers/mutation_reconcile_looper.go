@@ -298,7 +298,8 @@ func (m *MutationReconcileLooper) fetchDataFromObjectReference(ctx context.Conte
 			fmt.Errorf("failed to get component object: %w", err)
 	}
 
-	if conditions.IsFalse(snapshot, meta.ReadyCondition) {
+	// The data of a Snapshot is only known to be in the registry once the Snapshot is ready.
+	if !conditions.IsReady(snapshot) {
 		return nil, fmt.Errorf("snapshot not ready: %s", key)
 	}
 
@@ -349,12 +350,22 @@ func (m *MutationReconcileLooper) fetchDataFromComponentVersion(ctx context.Cont
 
 // This might be problematic if the resource is too large in the snapshot. ReadAll will read it into memory.
 func (m *MutationReconcileLooper) getSnapshotBytes(ctx context.Context, snapshot *v1alpha1.Snapshot) ([]byte, error) {
-	name, err := ocm.ConstructRepositoryName(snapshot.Spec.Identity)
+	name, err := ocm.SnapshotRepositoryName(snapshot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct name: %w", err)
 	}
 
-	reader, err := m.Cache.FetchDataByDigest(ctx, name, snapshot.Status.LastReconciledDigest)
+	keychain, err := registryKeychain(ctx, m.Client, snapshot.Spec.Registry, snapshot.GetNamespace(), snapshot.Spec.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := registryCache(m.Cache, snapshot.Spec.Registry, keychain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot registry: %w", err)
+	}
+
+	reader, err := store.FetchDataByDigest(ctx, name, snapshot.Status.LastReconciledDigest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch data: %w", err)
 	}
@@ -445,7 +456,7 @@ func (m *MutationReconcileLooper) performLocalization(
 		case *ociartifact.AccessSpec:
 			ref = x.ImageReference
 		case *ociblob.AccessSpec:
-			ref = fmt.Sprintf("%s@%s", x.Reference, x.Digest)
+			ref, refErr = ocm.BlobReference(x.Reference, x.Digest.String())
 		case *localblob.AccessSpec:
 			if x.GlobalAccess == nil {
 				refErr = errors.New("cannot determine image digest")
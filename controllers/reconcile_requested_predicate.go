@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// ReconcileRequestedPredicate triggers a reconciliation when the value of the reconcile request annotation
+// changes, which doesn't change the generation of the object.
+type ReconcileRequestedPredicate struct {
+	predicate.Funcs
+}
+
+func (ReconcileRequestedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	newValue, ok := e.ObjectNew.GetAnnotations()[v1alpha1.ReconcileRequestAnnotation]
+	if !ok || newValue == "" {
+		return false
+	}
+
+	return newValue != e.ObjectOld.GetAnnotations()[v1alpha1.ReconcileRequestAnnotation]
+}
+
+// reconcileRequested returns true if the Resource carries a reconcile request that hasn't been handled yet.
+func reconcileRequested(obj *v1alpha1.Resource) bool {
+	value := obj.GetAnnotations()[v1alpha1.ReconcileRequestAnnotation]
+
+	return value != "" && value != obj.Status.LastHandledReconcileAt
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+const (
+	// minRegistryBackoff is the delay before reaching out to a snapshot registry again after it first couldn't be
+	// reached.
+	minRegistryBackoff = 15 * time.Second
+	// maxRegistryBackoff is the longest delay between attempts to reach a snapshot registry that is down.
+	maxRegistryBackoff = 5 * time.Minute
+)
+
+// registryAvailability tracks the outages of the snapshot registries, shared by all Resources pushing to them. While
+// a registry is down, Resources wait for its backoff instead of each running into the same failure on their own,
+// and the backoff grows faster than the failure backoff of a single Resource.
+type registryAvailability struct {
+	mu      sync.Mutex
+	outages map[string]*registryOutage
+}
+
+// registryOutage is the state of a snapshot registry that couldn't be reached.
+type registryOutage struct {
+	err      error
+	since    time.Time
+	failures int
+	retryAt  time.Time
+}
+
+// markUnavailable records that the registry couldn't be reached and returns the delay before it is tried again.
+// Failures of Resources that tried the registry during the same attempt don't extend the backoff.
+func (a *registryAvailability) markUnavailable(registry string, err error, now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.outages == nil {
+		a.outages = make(map[string]*registryOutage)
+	}
+
+	outage, ok := a.outages[registry]
+	if !ok {
+		outage = &registryOutage{since: now}
+		a.outages[registry] = outage
+	}
+
+	outage.err = err
+	if !now.Before(outage.retryAt) {
+		outage.failures++
+		outage.retryAt = now.Add(registryBackoff(outage.failures))
+	}
+
+	return outage.retryAt.Sub(now)
+}
+
+// markAvailable records that the registry has been reached.
+func (a *registryAvailability) markAvailable(registry string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.outages, registry)
+}
+
+// unavailable returns the delay until the registry is tried again if it is down, together with the outage. The delay
+// is zero if the registry is available or should be tried again now.
+func (a *registryAvailability) unavailable(registry string, now time.Time) (time.Duration, registryOutage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	outage, ok := a.outages[registry]
+	if !ok || !now.Before(outage.retryAt) {
+		return 0, registryOutage{}
+	}
+
+	return outage.retryAt.Sub(now), *outage
+}
+
+// registryBackoff returns the delay before reaching out to a registry again after the given number of consecutive
+// failed attempts. The delay quadruples with every attempt, starting at minRegistryBackoff.
+func registryBackoff(failures int) time.Duration {
+	if shift := 2 * (failures - 1); shift < 32 {
+		if d := minRegistryBackoff << shift; d < maxRegistryBackoff {
+			return d
+		}
+	}
+
+	return maxRegistryBackoff
+}
+
+// isRegistryUnavailable returns whether the error is caused by the snapshot registry being unreachable.
+func isRegistryUnavailable(err error) bool {
+	var rerr *cache.RegistryUnavailableError
+
+	return errors.As(err, &rerr)
+}
@@ -5,37 +5,98 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
 	"github.com/fluxcd/pkg/runtime/patch"
 	rreconcile "github.com/fluxcd/pkg/runtime/reconcile"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
 	"github.com/open-component-model/ocm-controller/pkg/cache"
 	"github.com/open-component-model/ocm-controller/pkg/component"
+	"github.com/open-component-model/ocm-controller/pkg/metrics"
 	"github.com/open-component-model/ocm-controller/pkg/ocm"
 	"github.com/open-component-model/ocm-controller/pkg/snapshot"
 	"github.com/open-component-model/ocm-controller/pkg/status"
+	"github.com/open-component-model/ocm-controller/pkg/tracing"
+	ocmcore "github.com/open-component-model/ocm/pkg/contexts/ocm"
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kuberecorder "k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+const resourceFinalizer = "finalizers.resource.ocm.software"
+
+// DefaultMaxConcurrentReconciles is the number of Resources reconciled in parallel if nothing else is configured.
+const DefaultMaxConcurrentReconciles = 4
+
+// snapshotFieldManager is the field manager the Snapshots of Resources are applied with.
+const snapshotFieldManager = "resource-controller"
+
+// sourceRefIndexKey indexes Resources by the namespace/name of the ComponentVersion they reference, so the
+// Resources affected by a change of a ComponentVersion or its descriptors are looked up without listing all
+// Resources.
+const sourceRefIndexKey = ".spec.sourceRef"
+
+// indexResourceSourceRef returns the namespace/name of the ComponentVersion the Resource references. The
+// namespace defaults to the namespace of the Resource.
+func indexResourceSourceRef(obj client.Object) []string {
+	res, ok := obj.(*v1alpha1.Resource)
+	if !ok {
+		return nil
+	}
+
+	ns := res.Spec.SourceRef.Namespace
+	if ns == "" {
+		ns = res.GetNamespace()
+	}
+
+	return []string{fmt.Sprintf("%s/%s", ns, res.Spec.SourceRef.Name)}
+}
+
+// secretRefIndexKey indexes Resources by the namespace/name of the Secret holding their registry credentials, so
+// the Resources using rotated credentials are looked up without listing all Resources.
+const secretRefIndexKey = ".spec.secretRef"
+
+// indexResourceSecretRef returns the namespace/name of the Secret the Resource references in Spec.SecretRef. The
+// Secret is in the namespace of the Resource.
+func indexResourceSecretRef(obj client.Object) []string {
+	res, ok := obj.(*v1alpha1.Resource)
+	if !ok || res.Spec.SecretRef == nil {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("%s/%s", res.GetNamespace(), res.Spec.SecretRef.Name)}
+}
+
+// minFailureBackoff is the delay before retrying to fetch a resource after the first transient failure.
+const minFailureBackoff = 5 * time.Second
+
 // ResourceReconciler reconciles a Resource object.
 type ResourceReconciler struct {
 	client.Client
@@ -43,6 +104,42 @@ type ResourceReconciler struct {
 	kuberecorder.EventRecorder
 	OCMClient ocm.Contract
 	Cache     cache.Cache
+
+	// MaxConcurrentReconciles is the maximum number of Resources reconciled in parallel.
+	// Defaults to DefaultMaxConcurrentReconciles.
+	MaxConcurrentReconciles int
+
+	// ComponentRefTimeout is how long a Resource waits for the component descriptor of its component before it is
+	// marked with the ComponentRefUnresolved condition. The Resource is still reconciled at its interval. Zero
+	// waits forever.
+	ComponentRefTimeout time.Duration
+
+	// DefaultInterval is the interval at which Resources that don't set Spec.Interval are reconciled. Defaults to
+	// v1alpha1.DefaultResourceInterval.
+	DefaultInterval time.Duration
+
+	// ReconcileTimeout is how long a reconciliation of a Resource that doesn't set Spec.Timeout may take before it
+	// is aborted and retried. Zero doesn't limit reconciliations.
+	ReconcileTimeout time.Duration
+
+	// RequeueJitter is the fraction of the requeue delay that is randomly added to or subtracted from it, for
+	// example 0.1 for up to 10% in either direction, so Resources created together don't reach the registry at
+	// the same time. Zero requeues at the exact delay.
+	RequeueJitter float64
+
+	// FieldManager is the field manager the Snapshots of Resources are applied with. Defaults to
+	// "resource-controller", changing it hands the fields of existing Snapshots over to the new manager.
+	FieldManager string
+
+	// registries tracks the snapshot registries that can't be reached.
+	registries registryAvailability
+
+	// RepositoryNamer names the repositories the Snapshots of Resources are pushed to. It must name them like the
+	// OCMClient does. If nil, repositories are named after the hash of the identity.
+	RepositoryNamer *ocm.RepositoryNamer
+
+	// snapshotIdentities caches the identities of the Snapshots of the Resources.
+	snapshotIdentities snapshotIdentityCache
 }
 
 // +kubebuilder:rbac:groups=delivery.ocm.software,resources=resources,verbs=get;list;watch;create;update;patch;delete
@@ -53,33 +150,41 @@ type ResourceReconciler struct {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	const (
-		resourceKey = ".metadata.resource"
-	)
-
-	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.Resource{}, resourceKey, func(rawObj client.Object) []string {
-		res, ok := rawObj.(*v1alpha1.Resource)
-		if !ok {
-			return nil
-		}
-
-		ns := res.Spec.SourceRef.Namespace
-		if ns == "" {
-			ns = res.GetNamespace()
-		}
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.Resource{}, sourceRefIndexKey, indexResourceSourceRef); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
 
-		return []string{fmt.Sprintf("%s/%s", ns, res.Spec.SourceRef.Name)}
-	}); err != nil {
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.Resource{}, secretRefIndexKey, indexResourceSecretRef); err != nil {
 		return fmt.Errorf("failed setting index fields: %w", err)
 	}
 
+	concurrency := r.MaxConcurrentReconciles
+	if concurrency <= 0 {
+		concurrency = DefaultMaxConcurrentReconciles
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Resource{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: concurrency}).
+		For(&v1alpha1.Resource{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			ReconcileRequestedPredicate{},
+			SuspendAnnotationChangedPredicate{},
+		))).
 		Watches(
 			&source.Kind{Type: &v1alpha1.ComponentVersion{}},
-			handler.EnqueueRequestsFromMapFunc(r.findObjects(resourceKey)),
+			handler.EnqueueRequestsFromMapFunc(r.findObjects(sourceRefIndexKey)),
 			builder.WithPredicates(ComponentVersionChangedPredicate{}),
 		).
+		Watches(
+			&source.Kind{Type: &v1alpha1.ComponentDescriptor{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForComponentDescriptor(sourceRefIndexKey)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForSecret(secretRefIndexKey)),
+			builder.WithPredicates(SecretDataChangedPredicate{}),
+		).
 		Complete(r)
 }
 
@@ -89,7 +194,29 @@ func (r *ResourceReconciler) Reconcile(
 	ctx context.Context,
 	req ctrl.Request,
 ) (result ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "Resource.Reconcile",
+		tracing.NamespaceKey.String(req.Namespace),
+		tracing.NameKey.String(req.Name),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	// Both the interval and the backoff after failures are spread.
+	defer func() {
+		result.RequeueAfter = jitter(result.RequeueAfter, r.RequeueJitter)
+	}()
+
 	obj := &v1alpha1.Resource{}
+
+	start := time.Now()
+	defer func() {
+		metrics.ResourceReconcileDuration.WithLabelValues(req.Name, req.Namespace).Observe(time.Since(start).Seconds())
+		if err != nil || conditions.IsFalse(obj, meta.ReadyCondition) {
+			metrics.ResourceReconcileErrorsTotal.WithLabelValues(req.Name, req.Namespace).Inc()
+		}
+	}()
+
 	if err = r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
 		if apierrors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -98,19 +225,66 @@ func (r *ResourceReconciler) Reconcile(
 		return ctrl.Result{}, fmt.Errorf("failed to get resource object: %w", err)
 	}
 
-	if obj.Spec.Suspend {
-		return result, nil
+	if obj.GetDeletionTimestamp() != nil {
+		if !controllerutil.ContainsFinalizer(obj, resourceFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if err = r.reconcileDelete(ctx, obj); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+		}
+
+		return ctrl.Result{}, nil
 	}
 
 	patchHelper := patch.NewSerialPatcher(obj, r.Client)
 
+	// A suspended Resource only reports that it is suspended, neither the registry nor its Snapshots are touched.
+	if obj.IsSuspended() {
+		if conditions.IsTrue(obj, v1alpha1.SuspendedCondition) {
+			return result, nil
+		}
+
+		conditions.MarkTrue(obj, v1alpha1.SuspendedCondition, v1alpha1.SuspendedReason, "Reconciliation is suspended")
+		if err := patchHelper.Patch(ctx, obj); err != nil {
+			return result, fmt.Errorf("failed to mark resource as suspended: %w", err)
+		}
+
+		return result, nil
+	}
+
+	conditions.Delete(obj, v1alpha1.SuspendedCondition)
+
+	// Add the finalizer if it isn't present already.
+	controllerutil.AddFinalizer(obj, resourceFinalizer)
+
 	// Always attempt to patch the object and status after each reconciliation.
 	defer func() {
-		if derr := status.UpdateStatus(ctx, patchHelper, obj, r.EventRecorder, obj.GetRequeueAfter()); derr != nil {
+		if derr := status.UpdateStatus(ctx, patchHelper, obj, r.EventRecorder, r.requeueAfter(obj)); derr != nil {
 			err = errors.Join(err, derr)
 		}
 	}()
 
+	// A reconcile request is handled by this reconciliation, whatever its outcome.
+	defer func() {
+		if reconcileRequested(obj) {
+			obj.Status.LastHandledReconcileAt = obj.GetAnnotations()[v1alpha1.ReconcileRequestAnnotation]
+		}
+	}()
+
+	// Failures are kept in the status until a reconciliation succeeds, so flapping Resources can be spotted.
+	defer func() {
+		recordReconcileResult(obj, start, err)
+	}()
+
+	// The wait for the component descriptor ends with any other outcome of the reconciliation.
+	defer func() {
+		if !isWaitingForComponentDescriptor(obj) {
+			obj.Status.ComponentDescriptorWaitStart = nil
+			conditions.Delete(obj, v1alpha1.ComponentRefUnresolvedCondition)
+		}
+	}()
+
 	// Starts the progression by setting ReconcilingCondition.
 	// This will be checked in defer.
 	// Should only be deleted on a success.
@@ -119,6 +293,12 @@ func (r *ResourceReconciler) Reconcile(
 	// if the snapshot name has not been generated then
 	// generate, patch the status and requeue
 	if obj.GetSnapshotName() == "" {
+		if obj.Spec.SnapshotTemplate != nil && obj.Spec.SnapshotTemplate.Name != "" {
+			obj.Status.SnapshotName = obj.Spec.SnapshotTemplate.Name
+
+			return ctrl.Result{Requeue: true}, nil
+		}
+
 		name, err := snapshot.GenerateSnapshotName(obj.GetName())
 		if err != nil {
 			err = fmt.Errorf("failed to generate snapshot name for: %s: %w", obj.GetName(), err)
@@ -132,7 +312,42 @@ func (r *ResourceReconciler) Reconcile(
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	return r.reconcile(ctx, obj)
+	return r.reconcileWithTimeout(ctx, obj)
+}
+
+// reconcileWithTimeout reconciles the Resource within its timeout. Its data is fetched and pushed with a context
+// that is cancelled once the timeout expires, so a stuck copy is aborted instead of holding a worker. Whichever step
+// the reconciliation was aborted in, the Resource is marked with the ReconcileTimeout reason and retried with the
+// backoff of transient failures. The status is still patched with the context of the request.
+func (r *ResourceReconciler) reconcileWithTimeout(ctx context.Context, obj *v1alpha1.Resource) (ctrl.Result, error) {
+	timeout := r.ReconcileTimeout
+	if obj.Spec.Timeout != nil {
+		timeout = obj.Spec.Timeout.Duration
+	}
+
+	if timeout <= 0 {
+		return r.reconcile(ctx, obj)
+	}
+
+	failures := obj.Status.FailureCount
+
+	reconcileCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// A reconciliation that finished just as the timeout expired isn't retried.
+	result, err := r.reconcile(reconcileCtx, obj)
+	if ctx.Err() != nil || !errors.Is(reconcileCtx.Err(), context.DeadlineExceeded) || conditions.IsReady(obj) {
+		return result, err
+	}
+
+	log.FromContext(ctx).Error(err, "reconciliation timed out", "timeout", timeout)
+
+	obj.Status.FailureCount = failures + 1
+	backoff := r.retryDelay(err, obj.Status.FailureCount, r.requeueAfter(obj))
+	status.MarkRetrying(r.EventRecorder, obj, v1alpha1.ReconcileTimeoutReason,
+		fmt.Sprintf("reconciliation didn't finish within %s, retrying in %s", timeout, backoff.Round(time.Second)))
+
+	return ctrl.Result{RequeueAfter: backoff}, nil
 }
 
 func (r *ResourceReconciler) reconcile(
@@ -154,10 +369,29 @@ func (r *ResourceReconciler) reconcile(
 		obj.Spec.SourceRef.Namespace = obj.GetNamespace()
 	}
 
+	// Retrying doesn't help until the address of the registry has been fixed or the registry has been allowed.
+	if _, err := registryCache(r.Cache, obj.GetSnapshotRegistry(), nil); err != nil {
+		err = fmt.Errorf("invalid snapshot registry: %w", err)
+		status.MarkAsStalled(r.EventRecorder, obj, v1alpha1.InvalidSnapshotRegistryReason, err.Error())
+
+		return ctrl.Result{}, nil
+	}
+
+	// All Resources pushing to a registry that is down wait for the same backoff.
+	if wait, outage := r.registries.unavailable(obj.GetSnapshotRegistry(), time.Now()); wait > 0 && !obj.Spec.DryRun {
+		status.MarkRetrying(r.EventRecorder, obj, v1alpha1.RegistryProxyUnavailableReason, fmt.Sprintf("%s since %s, retrying in %s",
+			outage.err, outage.since.UTC().Format(time.RFC3339), wait.Round(time.Second)))
+
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
 	var componentVersion v1alpha1.ComponentVersion
 	if err := r.Get(ctx, obj.Spec.SourceRef.GetObjectKey(), &componentVersion); err != nil {
 		if apierrors.IsNotFound(err) {
-			return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+			msg := fmt.Sprintf("component version %s not found, retrying in %s", obj.Spec.SourceRef.GetNamespacedName(), r.requeueAfter(obj))
+			status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ComponentVersionNotFoundReason, msg)
+
+			return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
 		}
 
 		err = fmt.Errorf("failed to get component version: %w", err)
@@ -169,7 +403,42 @@ func (r *ResourceReconciler) reconcile(
 	if !conditions.IsReady(&componentVersion) {
 		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ComponentVersionNotReadyReason, "component version not ready yet")
 
-		return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+		return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+	}
+
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues(v1alpha1.LogKeyComponent, componentVersion.Spec.Component))
+
+	// A reconcile request snapshots the resource again even if the Snapshot is up-to-date.
+	if !obj.Spec.DryRun && !reconcileRequested(obj) {
+		if obj.GetUpdatePolicy() == v1alpha1.UpdatePolicyOnNewVersion {
+			applied, err := r.isVersionApplied(ctx, obj, &componentVersion)
+			if err != nil {
+				log.FromContext(ctx).Error(err, "failed to check if version is applied, checking the snapshot")
+			}
+
+			if applied {
+				log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("version already applied, skipping snapshot", "version", obj.Status.LastAppliedComponentVersion)
+				status.MarkReady(r.EventRecorder, obj, "Applied version: %s", obj.Status.LastAppliedComponentVersion)
+
+				return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+			}
+		}
+
+		upToDate, err := r.isSnapshotUpToDate(ctx, obj, &componentVersion)
+		if isRegistryUnavailable(err) {
+			return r.markRegistryUnavailable(obj, err), nil
+		}
+
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to check if snapshot is up to date, fetching the resource again")
+		}
+
+		if upToDate {
+			r.registries.markAvailable(obj.GetSnapshotRegistry())
+			status.MarkReady(r.EventRecorder, obj, "Applied version: %s", obj.Status.LastAppliedComponentVersion)
+
+			return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+		}
 	}
 
 	rreconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "component version %s ready, processing ocm resource", componentVersion.Name)
@@ -182,116 +451,1412 @@ func (r *ResourceReconciler) reconcile(
 		return ctrl.Result{}, nil
 	}
 
-	reader, digest, err := r.OCMClient.GetResource(ctx, octx, &componentVersion, obj.Spec.SourceRef.ResourceRef)
-	if err != nil {
-		err = fmt.Errorf("failed to get resource: %w", err)
-		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.GetResourceFailedReason, err.Error())
+	if obj.Spec.SecretRef != nil {
+		if err := ocm.ConfigureDockerConfigCredentials(ctx, octx, r.Client, obj.Spec.SecretRef.Name, obj.GetNamespace()); err != nil {
+			err = fmt.Errorf("failed to configure credentials for resource: %w", err)
+			status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ConfigureCredentialsFailedReason, err.Error())
 
-		return ctrl.Result{}, err
+			return ctrl.Result{}, err
+		}
+	}
+
+	if len(obj.Spec.Verify) > 0 {
+		// The public keys of the Resource are looked up in its own namespace.
+		verifyCV := componentVersion.DeepCopy()
+		verifyCV.Namespace = obj.GetNamespace()
+		verifyCV.Spec.Verify = obj.Spec.Verify
+
+		verified, err := r.OCMClient.VerifyComponent(ctx, octx, verifyCV, componentVersion.Status.ReconciledVersion)
+		if err != nil {
+			err = fmt.Errorf("failed to verify component %s: %w", componentVersion.Spec.Component, err)
+			status.MarkNotReady(r.EventRecorder, obj, v1alpha1.VerificationFailedReason, err.Error())
+
+			return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+		}
+
+		if !verified {
+			status.MarkNotReady(r.EventRecorder, obj, v1alpha1.VerificationFailedReason, "attempted to verify component, but the digest didn't match")
+
+			return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+		}
 	}
-	defer reader.Close()
 
 	version := "latest"
-	if obj.Spec.SourceRef.GetVersion() != "" {
-		version = obj.Spec.SourceRef.GetVersion()
+	if obj.GetElementVersion() != "" {
+		version = obj.GetElementVersion()
+	}
+
+	if obj.Spec.DryRun {
+		return r.reconcileDryRun(ctx, octx, obj, &componentVersion, version)
+	}
+
+	if len(obj.Spec.Resources) > 0 {
+		return r.reconcileResources(ctx, octx, obj, &componentVersion)
+	}
+
+	var (
+		unpinned ocm.UnpinnedReference
+		location ocm.SourceLocation
+		pushed   cache.PushResult
+	)
+	digest, revision, err := r.snapshotResource(ctx, octx, obj, &componentVersion, obj.GetElementRef(), obj.GetSnapshotName(), version,
+		&pushed, ocm.WithUnpinnedReference(&unpinned), ocm.WithSourceLocation(&location))
+	if isRegistryUnavailable(err) {
+		return r.markRegistryUnavailable(obj, err), nil
 	}
 
-	// This is important because THIS is the actual component for our resource. If we used ComponentVersion in the
-	// below identity, that would be the top-level component instead of the component that this resource belongs to.
-	componentDescriptor, err := component.GetComponentDescriptor(ctx, r.Client, obj.GetReferencePath(), componentVersion.Status.ComponentDescriptor)
 	if err != nil {
-		err = fmt.Errorf("failed to get component descriptor for resource: %w", err)
-		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.GetComponentDescriptorFailedReason, err.Error())
+		var serr *snapshotError
+		if !errors.As(err, &serr) {
+			return r.markGetResourceFailed(ctx, obj, err), nil
+		}
+
+		obj.Status.FailureCount = 0
+		status.MarkNotReady(r.EventRecorder, obj, serr.reason, err.Error())
 
 		return ctrl.Result{}, err
 	}
 
-	if componentDescriptor == nil {
-		err := fmt.Errorf(
-			"couldn't find component descriptor for reference '%s' or any root components",
-			obj.GetReferencePath(),
-		)
-		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ComponentDescriptorNotFoundReason, err.Error())
+	r.registries.markAvailable(obj.GetSnapshotRegistry())
 
-		return ctrl.Result{}, err
+	obj.Status.FailureCount = 0
+	obj.Status.LastAppliedResourceVersion = obj.GetElementVersion()
+	obj.Status.LatestSnapshotDigest = digest
+	if obj.GetSnapshotRetention() == 0 {
+		obj.Status.SnapshotHistory = nil
 	}
+	obj.Status.LastAppliedComponentVersion = componentVersion.Status.ReconciledVersion
+	obj.Status.LastAppliedComponentDescriptor = revision
+	obj.Status.SourceRegistry = location.Registry
+	obj.Status.SourceRepository = location.Repository
+	obj.Status.Transformed = obj.Spec.Transform != nil
+	obj.Status.DryRunResult = nil
 
-	if obj.GetSnapshotName() == "" {
-		err := errors.New("snapshot name should not be empty")
-		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.SnapshotNameEmptyReason, err.Error())
+	switch {
+	case unpinned.Reference == "":
+		obj.Status.ResolvedDigest = ""
+	case unpinned.Digest != "":
+		// Otherwise the image has been copied before and the digest it was resolved to is still the same.
+		obj.Status.ResolvedDigest = unpinned.Digest
+	}
 
-		return ctrl.Result{}, err
+	var unpinnedReferences []string
+	if unpinned.Reference != "" {
+		unpinnedReferences = append(unpinnedReferences, unpinned.Reference)
 	}
 
-	rreconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "resource retrieve, constructing snapshot with name %s", obj.GetSnapshotName())
+	markUnpinnedReferences(obj, unpinnedReferences)
+	markSnapshotWritten(obj, pushed)
 
-	identity := ocmmetav1.Identity{
-		v1alpha1.ComponentNameKey:    componentDescriptor.Name,
-		v1alpha1.ComponentVersionKey: componentDescriptor.Spec.Version,
-		v1alpha1.ResourceNameKey:     obj.Spec.SourceRef.ResourceRef.Name,
-		v1alpha1.ResourceVersionKey:  version,
+	status.MarkReady(r.EventRecorder, obj, "Applied version: %s", obj.Status.LastAppliedComponentVersion)
+
+	return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+}
+
+// reconcileResources writes each of the resources selected by Spec.Resources to its own Snapshot. All resources
+// are attempted, the Resource is only ready once every one of them has been written.
+func (r *ResourceReconciler) reconcileResources(
+	ctx context.Context,
+	octx ocmcore.Context,
+	obj *v1alpha1.Resource,
+	cv *v1alpha1.ComponentVersion,
+) (ctrl.Result, error) {
+	if err := r.deleteUnselectedSnapshots(ctx, obj); err != nil {
+		err = fmt.Errorf("failed to delete snapshots of unselected resources: %w", err)
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.CreateOrUpdateSnapshotFailedReason, err.Error())
+
+		return ctrl.Result{}, err
 	}
-	for k, v := range obj.Spec.SourceRef.ResourceRef.ExtraIdentity {
-		identity[k] = v
+
+	var (
+		failures    []string
+		unpinned    []string
+		pushed      []cache.PushResult
+		permanent   = true
+		rateLimited error
+		unavailable error
+		resources   = make([]v1alpha1.ResourceSnapshotStatus, 0, len(obj.Spec.Resources))
+	)
+
+	for _, selector := range obj.Spec.Resources {
+		ref := selector.ResourceReference
+
+		version := "latest"
+		if ref.Version != "" {
+			version = ref.Version
+		}
+
+		resource := v1alpha1.ResourceSnapshotStatus{
+			Name:         ref.Name,
+			SnapshotName: obj.GetResourceSnapshotName(ref.Name),
+		}
+
+		var (
+			reference ocm.UnpinnedReference
+			location  ocm.SourceLocation
+			result    cache.PushResult
+		)
+		digest, revision, err := r.snapshotResource(ctx, octx, obj, cv, &ref, resource.SnapshotName, version,
+			&result, ocm.WithUnpinnedReference(&reference), ocm.WithSourceLocation(&location))
+		if reference.Reference != "" {
+			unpinned = append(unpinned, reference.Reference)
+		}
+
+		if err != nil {
+			var serr *snapshotError
+			if errors.As(err, &serr) || !isPermanentError(err) {
+				permanent = false
+			}
+
+			if isRateLimited(err) && rateLimited == nil {
+				rateLimited = err
+			}
+
+			if isRegistryUnavailable(err) && unavailable == nil {
+				unavailable = err
+			}
+
+			resource.Message = err.Error()
+			failures = append(failures, fmt.Sprintf("%s: %s", ref.Name, err))
+		} else {
+			resource.Digest = digest
+			resource.ComponentDescriptor = revision
+			resource.SourceRegistry = location.Registry
+			resource.SourceRepository = location.Repository
+			resource.Transformed = obj.Spec.Transform != nil
+			resource.Ready = true
+			pushed = append(pushed, result)
+		}
+
+		resources = append(resources, resource)
 	}
 
-	snapshotCR := &v1alpha1.Snapshot{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: obj.GetNamespace(),
-			Name:      obj.GetSnapshotName(),
-		},
+	obj.Status.Resources = resources
+	obj.Status.DryRunResult = nil
+	markUnpinnedReferences(obj, unpinned)
+	markSnapshotWritten(obj, pushed...)
+
+	if len(pushed) > 0 {
+		r.registries.markAvailable(obj.GetSnapshotRegistry())
 	}
 
-	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, snapshotCR, func() error {
-		if snapshotCR.ObjectMeta.CreationTimestamp.IsZero() {
-			if err := controllerutil.SetOwnerReference(obj, snapshotCR, r.Scheme); err != nil {
-				return fmt.Errorf("failed to set owner to snapshot object: %w", err)
-			}
+	if len(failures) > 0 {
+		msg := fmt.Sprintf("%d of %d resources failed: %s", len(failures), len(resources), strings.Join(failures, "; "))
+		if permanent {
+			obj.Status.FailureCount = 0
+			status.MarkAsStalled(r.EventRecorder, obj, v1alpha1.SnapshotResourcesFailedReason, msg)
+
+			return ctrl.Result{}, nil
 		}
-		snapshotCR.Spec = v1alpha1.SnapshotSpec{
-			Identity: identity,
-			Digest:   digest,
-			Tag:      version,
+
+		obj.Status.FailureCount++
+		reason := v1alpha1.SnapshotResourcesFailedReason
+		backoff := failureBackoff(obj.Status.FailureCount, r.requeueAfter(obj))
+		switch {
+		case unavailable != nil && len(pushed) == 0:
+			// The other resources can't be written either until the registry is back.
+			reason = v1alpha1.RegistryProxyUnavailableReason
+			backoff = r.registries.markUnavailable(obj.GetSnapshotRegistry(), unavailable, time.Now())
+		case rateLimited != nil:
+			// Retrying before the registry lifts the rate limit would fail again.
+			reason = v1alpha1.RateLimitedReason
+			backoff = r.retryDelay(rateLimited, obj.Status.FailureCount, r.requeueAfter(obj))
 		}
 
-		return nil
-	})
-	if err != nil {
-		err = fmt.Errorf("failed to create or update snapshot: %w", err)
-		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.CreateOrUpdateSnapshotFailedReason, err.Error())
+		status.MarkNotReady(r.EventRecorder, obj, reason, fmt.Sprintf("%s, retrying in %s", msg, backoff.Round(time.Second)))
 
-		return ctrl.Result{}, err
+		return ctrl.Result{RequeueAfter: backoff}, nil
 	}
 
+	obj.Status.FailureCount = 0
 	obj.Status.LastAppliedResourceVersion = obj.Spec.SourceRef.GetVersion()
-	obj.Status.LastAppliedComponentVersion = componentVersion.Status.ReconciledVersion
+	obj.Status.LastAppliedComponentVersion = cv.Status.ReconciledVersion
 
 	status.MarkReady(r.EventRecorder, obj, "Applied version: %s", obj.Status.LastAppliedComponentVersion)
 
-	return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+	return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
 }
 
-// this function will enqueue a reconciliation for any snapshot which is referenced
-// in the .spec.sourceRef or spec.configRef field of a Localization.
-func (r *ResourceReconciler) findObjects(key string) handler.MapFunc {
-	return func(obj client.Object) []reconcile.Request {
-		resources := &v1alpha1.ResourceList{}
-		if err := r.List(context.TODO(), resources, &client.ListOptions{
-			FieldSelector: fields.OneTermEqualSelector(key, client.ObjectKeyFromObject(obj).String()),
-		}); err != nil {
-			return []reconcile.Request{}
+// markUnpinnedReferences warns with the UnpinnedReference condition that resources are images referenced by tag
+// without a digest. Resolving the same component version again may then result in different data.
+func markUnpinnedReferences(obj *v1alpha1.Resource, references []string) {
+	if len(references) == 0 {
+		conditions.Delete(obj, v1alpha1.UnpinnedReferenceCondition)
+
+		return
+	}
+
+	conditions.MarkTrue(
+		obj,
+		v1alpha1.UnpinnedReferenceCondition,
+		v1alpha1.TagReferenceReason,
+		"image referenced by tag without a digest, the data may change for the same component version: %s",
+		strings.Join(references, ", "),
+	)
+}
+
+// markSnapshotWritten records with the SnapshotWritten condition whether the data of any of the snapshots was
+// written to the registry, or whether all of it was already stored there.
+func markSnapshotWritten(obj *v1alpha1.Resource, results ...cache.PushResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	written := 0
+	for _, result := range results {
+		if !result.Unchanged {
+			written++
 		}
+	}
 
-		requests := make([]reconcile.Request, len(resources.Items))
-		for i, item := range resources.Items {
-			requests[i] = reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      item.GetName(),
-					Namespace: item.GetNamespace(),
-				},
-			}
+	if written == 0 {
+		conditions.MarkFalse(obj, v1alpha1.SnapshotWrittenCondition, v1alpha1.SnapshotUnchangedReason,
+			"snapshot data is already stored in the registry")
+
+		return
+	}
+
+	if len(results) == 1 {
+		conditions.MarkTrue(obj, v1alpha1.SnapshotWrittenCondition, v1alpha1.SnapshotPushedReason, "wrote snapshot data to the registry")
+
+		return
+	}
+
+	conditions.MarkTrue(obj, v1alpha1.SnapshotWrittenCondition, v1alpha1.SnapshotPushedReason,
+		"wrote the data of %d of %d snapshots to the registry", written, len(results))
+}
+
+// deleteUnselectedSnapshots deletes the Snapshots of resources that have been removed from Spec.Resources since
+// the last reconciliation. Otherwise, they would only be removed together with the Resource.
+func (r *ResourceReconciler) deleteUnselectedSnapshots(ctx context.Context, obj *v1alpha1.Resource) error {
+	selected := make(map[string]struct{}, len(obj.Spec.Resources))
+	for _, name := range obj.GetSnapshotNames() {
+		selected[name] = struct{}{}
+	}
+
+	for _, resource := range obj.Status.Resources {
+		if _, ok := selected[resource.SnapshotName]; ok {
+			continue
 		}
 
-		return requests
+		if err := r.deleteSnapshot(ctx, obj, resource.SnapshotName); err != nil {
+			return fmt.Errorf("failed to delete snapshot %s: %w", resource.SnapshotName, err)
+		}
+	}
+
+	return nil
+}
+
+// snapshotError is returned by snapshotResource if the resource has been fetched, but couldn't be written to its
+// Snapshot. The reason is reported in the Ready condition of the Resource.
+type snapshotError struct {
+	reason string
+	err    error
+}
+
+func (e *snapshotError) Error() string {
+	return e.err.Error()
+}
+
+func (e *snapshotError) Unwrap() error {
+	return e.err
+}
+
+// snapshotResource fetches the resource referenced by ref, pushes it to the in-cluster registry and points the
+// Snapshot with the given name at the data. Returns the digest of the resource data and the revision of the
+// component descriptor it was fetched from. The Snapshot isn't written if the component descriptor changed in the
+// meantime. Errors fetching the resource are returned as is, all later errors are returned as a *snapshotError.
+// The outcome of the push is recorded in pushed, a tagless Snapshot refers to the manifest digest recorded in it.
+// The options, like WithUnpinnedReference, are passed to GetResource to record details of the fetched resource.
+func (r *ResourceReconciler) snapshotResource(
+	ctx context.Context,
+	octx ocmcore.Context,
+	obj *v1alpha1.Resource,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+	snapshotName, version string,
+	pushed *cache.PushResult,
+	recordOpts ...ocm.GetResourceOption,
+) (_ string, _ *v1alpha1.ComponentDescriptorRevision, err error) {
+	ctx, span := tracing.Start(ctx, "Resource.snapshotResource",
+		tracing.ResourceNameKey.String(ref.Name),
+		tracing.SnapshotKey.String(snapshotName),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	logger := log.FromContext(ctx).WithValues(v1alpha1.LogKeyResourceName, ref.Name)
+	ctx = log.IntoContext(ctx, logger)
+
+	// Don't fetch the resource if its Snapshot can't be written anyway.
+	existing, err := r.checkSnapshotOwner(ctx, obj, snapshotName)
+	if err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.SnapshotNameConflictReason,
+			err:    err,
+		}
+	}
+
+	keychain, err := registryKeychain(ctx, r.Client, obj.GetSnapshotRegistry(), obj.GetNamespace(), obj.Spec.SecretRef)
+	if err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.ConfigureCredentialsFailedReason,
+			err:    err,
+		}
 	}
+
+	store, err := registryCache(r.Cache, obj.GetSnapshotRegistry(), keychain)
+	if err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.InvalidSnapshotRegistryReason,
+			err:    fmt.Errorf("invalid snapshot registry: %w", err),
+		}
+	}
+
+	// The data is pushed with the version as tag, there is no point in fetching the resource if it isn't valid.
+	versionTag, err := ocm.SnapshotTag(version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The descriptor is captured before the resource is fetched, so the Snapshot is tied to the revision the
+	// resource was fetched from. Fetching the resource reads the same descriptor, errors reading it are returned
+	// like errors fetching the resource, so a descriptor that hasn't been created yet is waited for.
+	revision, err := r.componentDescriptorRevision(ctx, cv, ref)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get component descriptor for resource: %w", err)
+	}
+
+	opts := append(getResourceOptions(obj), ocm.WithPushResult(pushed))
+	opts = append(opts, recordOpts...)
+
+	if registry := obj.GetSnapshotRegistry(); registry != "" {
+		opts = append(opts, ocm.WithSnapshotRegistry(registry, keychain))
+	}
+
+	// An existing Snapshot has already adopted the data at its reference, only the first write of a new Snapshot
+	// must not take over an image pushed by another tool.
+	if existing == nil {
+		opts = append(opts, ocm.WithAdoptionGuard(obj.Spec.ForceOverwrite))
+	}
+
+	if from := previousSnapshotRepository(obj, existing); from != "" {
+		opts = append(opts, ocm.WithMountFrom(from))
+	}
+	reader, digest, err := r.OCMClient.GetResource(ctx, octx, cv, ref, opts...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get resource: %w", err)
+	}
+	defer reader.Close()
+
+	span.SetAttributes(tracing.SourceDigestKey.String(digest))
+	logger.V(v1alpha1.LevelDebug).Info("fetched resource", v1alpha1.LogKeySourceDigest, digest)
+
+	identity, err := r.snapshotIdentity(ctx, obj, cv, ref, snapshotName, version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The repository is recorded on the Snapshot, consumers read it back instead of rendering it again.
+	repository, err := r.RepositoryNamer.RepositoryName(identity)
+	if err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.NameGenerationFailedReason,
+			err:    fmt.Errorf("failed to construct repository name: %w", err),
+		}
+	}
+
+	rreconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "resource retrieve, constructing snapshot with name %s", snapshotName)
+
+	tag := versionTag
+	if obj.IsSnapshotTagless() {
+		if tag = pushed.Manifest; tag == "" {
+			return "", nil, &snapshotError{
+				reason: v1alpha1.SnapshotVerificationFailedReason,
+				err:    errors.New("no manifest digest was recorded for the snapshot data pushed without a tag"),
+			}
+		}
+	} else if obj.Spec.SnapshotTemplate != nil && obj.Spec.SnapshotTemplate.TagFromDigest {
+		tag = digestTag(digest)
+		if err := r.tagSnapshotData(ctx, store, repository, versionTag, tag); err != nil {
+			return "", nil, &snapshotError{
+				reason: v1alpha1.TagSnapshotFailedReason,
+				err:    fmt.Errorf("failed to tag snapshot data with digest: %w", err),
+			}
+		}
+	}
+
+	// Only point the Snapshot at the data once it is known to exist in the registry.
+	if err := r.verifySnapshotData(ctx, store, repository, tag); err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.SnapshotVerificationFailedReason,
+			err:    fmt.Errorf("failed to verify snapshot data: %w", err),
+		}
+	}
+
+	snapshotRef := snapshotReference(repository, tag)
+	span.SetAttributes(tracing.SnapshotRefKey.String(snapshotRef))
+
+	if obj.Spec.IncludeReferrers {
+		if err := r.pushReferrers(ctx, store, octx, cv, ref, repository, tag); err != nil {
+			return "", nil, &snapshotError{
+				reason: v1alpha1.PushReferrersFailedReason,
+				err:    fmt.Errorf("failed to push referrers: %w", err),
+			}
+		}
+	}
+
+	if err := r.checkComponentDescriptorRevision(ctx, cv, ref, revision); err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.ComponentDescriptorChangedReason,
+			err:    err,
+		}
+	}
+
+	created, err := r.applySnapshot(ctx, obj, snapshotName, v1alpha1.SnapshotSpec{
+		Identity:   identity,
+		Digest:     digest,
+		Tag:        tag,
+		Repository: repository,
+		Registry:   obj.GetSnapshotRegistry(),
+		SecretRef:  snapshotSecretRef(obj),
+	})
+	if err != nil {
+		return "", nil, &snapshotError{
+			reason: v1alpha1.CreateOrUpdateSnapshotFailedReason,
+			err:    fmt.Errorf("failed to apply snapshot: %w", err),
+		}
+	}
+
+	if created {
+		r.EventRecorder.AnnotatedEventf(
+			obj,
+			obj.GetVID(),
+			corev1.EventTypeNormal,
+			v1alpha1.SnapshotCreatedReason,
+			"Created snapshot %s with digest %s",
+			snapshotName,
+			digest,
+		)
+	}
+
+	logger.Info("applied snapshot", "snapshot", snapshotName, v1alpha1.LogKeySnapshotRef, snapshotRef, v1alpha1.LogKeySourceDigest, digest)
+
+	if obj.GetSnapshotRetention() > 0 {
+		tags := []string{versionTag}
+		if obj.IsSnapshotTagless() {
+			tags = []string{tag}
+		} else if tag != versionTag {
+			tags = append(tags, tag)
+		}
+
+		// The snapshot has been written, failing to delete older data only delays it until the next push.
+		if err := r.recordSnapshotHistory(ctx, obj, v1alpha1.SnapshotHistoryEntry{
+			SnapshotName: snapshotName,
+			Registry:     obj.GetSnapshotRegistry(),
+			Repository:   repository,
+			Tags:         tags,
+			Digest:       digest,
+			PushedAt:     metav1.Now(),
+		}); err != nil {
+			logger.Error(err, "failed to delete snapshots beyond retention")
+		}
+	}
+
+	return digest, revision, nil
+}
+
+// componentDescriptorRevision returns the revision of the component descriptor the resource referenced by ref
+// belongs to. Returns an error if there is no such descriptor.
+func (r *ResourceReconciler) componentDescriptorRevision(
+	ctx context.Context,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+) (*v1alpha1.ComponentDescriptorRevision, error) {
+	descriptor, err := component.GetComponentDescriptor(ctx, r.Client, ref.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if descriptor == nil {
+		return nil, fmt.Errorf("couldn't find component descriptor for reference '%s' or any root components", ref.ReferencePath)
+	}
+
+	return &v1alpha1.ComponentDescriptorRevision{
+		Name:            descriptor.Name,
+		Namespace:       descriptor.Namespace,
+		ResourceVersion: descriptor.ResourceVersion,
+	}, nil
+}
+
+// checkComponentDescriptorRevision returns an error if the component descriptor of the resource isn't at the
+// given revision anymore. A nil revision is an error, the revision the resource was fetched from is unknown.
+func (r *ResourceReconciler) checkComponentDescriptorRevision(
+	ctx context.Context,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+	revision *v1alpha1.ComponentDescriptorRevision,
+) error {
+	if revision == nil {
+		return errors.New("the revision of the component descriptor the resource was fetched from is unknown")
+	}
+
+	current, err := r.componentDescriptorRevision(ctx, cv, ref)
+	if err != nil {
+		return fmt.Errorf("failed to check component descriptor: %w", err)
+	}
+
+	if *current == *revision {
+		return nil
+	}
+
+	return fmt.Errorf("component descriptor %s/%s changed from resource version %s to %s while the resource was fetched",
+		current.Namespace, current.Name, revision.ResourceVersion, current.ResourceVersion)
+}
+
+// pushReferrers pushes the resources describing the resource as referrers of the snapshot data at tag.
+func (r *ResourceReconciler) pushReferrers(
+	ctx context.Context,
+	store cache.Cache,
+	octx ocmcore.Context,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+	name, tag string,
+) error {
+	referrers, err := r.OCMClient.GetReferrers(ctx, octx, cv, ref)
+	if err != nil {
+		return err
+	}
+
+	if len(referrers) == 0 {
+		return nil
+	}
+
+	for _, referrer := range referrers {
+		digest, err := store.PushReferrer(ctx, bytes.NewReader(referrer.Data), referrer.ArtifactType, name, tag)
+		if err != nil {
+			return fmt.Errorf("failed to push referrer %s: %w", referrer.Name, err)
+		}
+
+		log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("pushed referrer", "referrer", referrer.Name, "subject", snapshotReference(name, tag), "digest", digest)
+	}
+
+	return nil
+}
+
+// applySnapshot writes the Snapshot with server-side apply. Only the owner reference, the labels and annotations
+// of the snapshot template and the spec are owned by the Resource controller, fields set by other field managers
+// are left alone. Returns true if the Snapshot didn't exist before.
+func (r *ResourceReconciler) applySnapshot(
+	ctx context.Context,
+	obj *v1alpha1.Resource,
+	name string,
+	spec v1alpha1.SnapshotSpec,
+) (bool, error) {
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}
+
+	created := false
+	if err := r.Get(ctx, key, &v1alpha1.Snapshot{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get snapshot: %w", err)
+		}
+
+		created = true
+	}
+
+	snapshotCR := &v1alpha1.Snapshot{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       v1alpha1.SnapshotKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+		},
+		Spec: spec,
+	}
+
+	if template := obj.Spec.SnapshotTemplate; template != nil {
+		snapshotCR.Labels = maps.Clone(template.Labels)
+		snapshotCR.Annotations = maps.Clone(template.Annotations)
+	}
+
+	// Consumers reading the layer directly from the registry need to know how it is encoded.
+	if snapshotCR.Annotations == nil {
+		snapshotCR.Annotations = map[string]string{}
+	}
+	snapshotCR.Annotations[v1alpha1.CompressionAnnotation] = obj.GetSnapshotCompression()
+
+	// Owner references are merged by UID, so applying the same reference every time is idempotent.
+	if err := controllerutil.SetControllerReference(obj, snapshotCR, r.Scheme); err != nil {
+		return false, fmt.Errorf("failed to set owner to snapshot object: %w", err)
+	}
+
+	if err := r.Patch(ctx, snapshotCR, client.Apply, client.FieldOwner(r.fieldManager()), client.ForceOwnership); err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// fieldManager returns the field manager Snapshots are applied with.
+func (r *ResourceReconciler) fieldManager() string {
+	if r.FieldManager == "" {
+		return snapshotFieldManager
+	}
+
+	return r.FieldManager
+}
+
+// checkSnapshotOwner returns an error if the Snapshot with the given name exists and is owned by another
+// Resource, for example because both use the same snapshot template name. The Resources would otherwise
+// overwrite each other's Snapshot. Returns the Snapshot, nil if it doesn't exist.
+func (r *ResourceReconciler) checkSnapshotOwner(ctx context.Context, obj *v1alpha1.Resource, name string) (*v1alpha1.Snapshot, error) {
+	snapshotCR := &v1alpha1.Snapshot{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}, snapshotCR); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	if owner := otherResourceOwner(obj, snapshotCR); owner != "" {
+		return snapshotCR, fmt.Errorf("snapshot %s is already owned by Resource %s, use a different snapshot template name", name, owner)
+	}
+
+	return snapshotCR, nil
+}
+
+// previousSnapshotRepository returns the repository the previous version of the Snapshot is stored in if the
+// layer of the resource data should be mounted from it. Layers can only be mounted within a registry, so it is
+// empty if the Snapshot is stored in another registry than the one the resource is pushed to.
+func previousSnapshotRepository(obj *v1alpha1.Resource, snapshotCR *v1alpha1.Snapshot) string {
+	if !obj.GetSnapshotReuseLayers() || snapshotCR == nil || snapshotCR.Spec.Registry != obj.GetSnapshotRegistry() {
+		return ""
+	}
+
+	name, err := ocm.SnapshotRepositoryName(snapshotCR)
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
+
+// otherResourceOwner returns the name of the Resource other than obj that owns the Snapshot, if any.
+func otherResourceOwner(obj *v1alpha1.Resource, snapshotCR *v1alpha1.Snapshot) string {
+	for _, ref := range snapshotCR.GetOwnerReferences() {
+		if ref.Kind == v1alpha1.ResourceKind && ref.APIVersion == v1alpha1.GroupVersion.String() && ref.UID != obj.GetUID() {
+			return ref.Name
+		}
+	}
+
+	return ""
+}
+
+// deleteSnapshot deletes the Snapshot with the given name unless it is owned by another Resource.
+func (r *ResourceReconciler) deleteSnapshot(ctx context.Context, obj *v1alpha1.Resource, name string) error {
+	snapshotCR := &v1alpha1.Snapshot{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}, snapshotCR); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	if owner := otherResourceOwner(obj, snapshotCR); owner != "" {
+		log.FromContext(ctx).Info("not deleting snapshot owned by another resource", "snapshot", name, "owner", owner)
+
+		return nil
+	}
+
+	if err := r.Delete(ctx, snapshotCR); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// reconcileDryRun resolves the resource and records the snapshot that would be created for it in the status,
+// without pushing the resource to the registry or creating the Snapshot.
+func (r *ResourceReconciler) reconcileDryRun(
+	ctx context.Context,
+	octx ocmcore.Context,
+	obj *v1alpha1.Resource,
+	cv *v1alpha1.ComponentVersion,
+	version string,
+) (ctrl.Result, error) {
+	identity, err := r.snapshotIdentity(ctx, obj, cv, obj.GetElementRef(), obj.GetSnapshotName(), version)
+	if err != nil {
+		var serr *snapshotError
+		if errors.As(err, &serr) {
+			status.MarkNotReady(r.EventRecorder, obj, serr.reason, err.Error())
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	digest, err := r.OCMClient.GetResourceDigest(ctx, octx, cv, obj.GetElementRef(), getResourceOptions(obj)...)
+	if err != nil {
+		return r.markGetResourceFailed(ctx, obj, fmt.Errorf("failed to get resource digest: %w", err)), nil
+	}
+
+	obj.Status.FailureCount = 0
+
+	repository, err := r.RepositoryNamer.RepositoryName(identity)
+	if err != nil {
+		err = fmt.Errorf("failed to construct repository name: %w", err)
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.NameGenerationFailedReason, err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	tag, err := ocm.SnapshotTag(version)
+	if err != nil {
+		return r.markGetResourceFailed(ctx, obj, err), nil
+	}
+
+	if obj.IsSnapshotTagless() {
+		tag = ""
+	} else if obj.Spec.SnapshotTemplate != nil && obj.Spec.SnapshotTemplate.TagFromDigest {
+		tag = digestTag(digest)
+	}
+
+	obj.Status.DryRunResult = &v1alpha1.DryRunResult{
+		SnapshotName: obj.GetSnapshotName(),
+		Repository:   repository,
+		Tag:          tag,
+		Digest:       digest,
+	}
+
+	if tag == "" {
+		status.MarkReady(r.EventRecorder, obj, "Dry run: resource would be pushed to %s without a tag with digest %s", repository, digest)
+	} else {
+		status.MarkReady(r.EventRecorder, obj, "Dry run: resource would be pushed to %s:%s with digest %s", repository, tag, digest)
+	}
+
+	return ctrl.Result{RequeueAfter: r.requeueAfter(obj)}, nil
+}
+
+// verifySnapshotData checks that the data of the snapshot has been written to the repository of the registry.
+func (r *ResourceReconciler) verifySnapshotData(ctx context.Context, store cache.Cache, name, version string) error {
+	cached, err := store.IsCached(ctx, name, version)
+	if err != nil {
+		return fmt.Errorf("failed to check registry for %s: %w", snapshotReference(name, version), err)
+	}
+
+	if !cached {
+		return fmt.Errorf("%s not found in registry", snapshotReference(name, version))
+	}
+
+	return nil
+}
+
+// tagSnapshotData adds the tag to the snapshot data the resource has been pushed with.
+func (r *ResourceReconciler) tagSnapshotData(ctx context.Context, store cache.Cache, name, version, tag string) error {
+	return store.TagData(ctx, name, version, tag)
+}
+
+// digestTag turns a digest of the form algorithm:hex into a valid OCI tag of the form algorithm-hex.
+func digestTag(digest string) string {
+	return strings.ReplaceAll(digest, ":", "-")
+}
+
+// snapshotReference returns the reference of the snapshot data stored in the repository with the given tag, which
+// is the manifest digest for data pushed without a tag.
+func snapshotReference(repository, tag string) string {
+	if strings.HasPrefix(tag, "sha256:") {
+		return repository + "@" + tag
+	}
+
+	return repository + ":" + tag
+}
+
+// snapshotIdentity constructs the identity of the snapshot for the resource from the component descriptor the
+// resource belongs to. Errors are returned as a *snapshotError.
+func (r *ResourceReconciler) snapshotIdentity(
+	ctx context.Context,
+	obj *v1alpha1.Resource,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+	snapshotName, version string,
+) (ocmmetav1.Identity, error) {
+	// This is important because THIS is the actual component for our resource. If we used ComponentVersion in the
+	// below identity, that would be the top-level component instead of the component that this resource belongs to.
+	componentDescriptor, err := component.GetComponentDescriptor(ctx, r.Client, ref.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		reason := v1alpha1.GetComponentDescriptorFailedReason
+		if errors.Is(err, component.ErrComponentVersionNotFound) {
+			reason = v1alpha1.ComponentDescriptorNotFoundReason
+		}
+
+		return nil, &snapshotError{
+			reason: reason,
+			err:    fmt.Errorf("failed to get component descriptor for resource: %w", err),
+		}
+	}
+
+	if componentDescriptor == nil {
+		return nil, &snapshotError{
+			reason: v1alpha1.ComponentDescriptorNotFoundReason,
+			err:    fmt.Errorf("couldn't find component descriptor for reference '%s' or any root components", ref.ReferencePath),
+		}
+	}
+
+	if snapshotName == "" {
+		return nil, &snapshotError{
+			reason: v1alpha1.SnapshotNameEmptyReason,
+			err:    errors.New("snapshot name should not be empty"),
+		}
+	}
+
+	identity, err := r.snapshotIdentities.get(obj, componentDescriptor, ref, version)
+	if err != nil {
+		return nil, &snapshotError{
+			reason: v1alpha1.CreateRepositoryNameReason,
+			err:    err,
+		}
+	}
+
+	return identity, nil
+}
+
+// newSnapshotIdentity returns the identity of the Snapshot of the resource of the component descriptor.
+func newSnapshotIdentity(
+	obj *v1alpha1.Resource,
+	componentDescriptor *v1alpha1.ComponentDescriptor,
+	ref *v1alpha1.ResourceReference,
+	version string,
+) (ocmmetav1.Identity, error) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    componentDescriptor.Name,
+		v1alpha1.ComponentVersionKey: componentDescriptor.Spec.Version,
+		v1alpha1.ResourceNameKey:     ref.Name,
+		v1alpha1.ResourceVersionKey:  version,
+	}
+	for k, v := range ref.ExtraIdentity {
+		identity[k] = v
+	}
+
+	if obj.Spec.Platform != "" {
+		identity[v1alpha1.ResourcePlatformKey] = obj.Spec.Platform
+	}
+
+	if obj.Spec.Extract != nil {
+		identity[v1alpha1.ResourceExtractPathKey] = obj.Spec.Extract.Path
+	}
+
+	if obj.Spec.Source != nil {
+		identity[v1alpha1.ResourceKindKey] = v1alpha1.ResourceKindSource
+	}
+
+	if compression := obj.GetSnapshotCompression(); compression != v1alpha1.CompressionGzip {
+		identity[v1alpha1.ResourceCompressionKey] = compression
+	}
+
+	if config := snapshotImageConfig(obj); config != nil {
+		hash, err := config.Hash()
+		if err != nil {
+			return nil, err
+		}
+
+		identity[v1alpha1.ResourceConfigKey] = hash
+	}
+
+	if artifactType := obj.GetSnapshotArtifactType(); artifactType != "" {
+		identity[v1alpha1.ResourceArtifactTypeKey] = artifactType
+	}
+
+	if obj.Spec.Transform != nil {
+		identity[v1alpha1.ResourceTransformKey] = obj.Spec.Transform.URL
+	}
+
+	identity[v1alpha1.SnapshotNamespaceKey] = obj.Namespace
+
+	return identity, nil
+}
+
+// snapshotSecretRef returns the secret with the credentials for the snapshot registry of the Resource, which is
+// its SecretRef if it pushes to another registry than the in-cluster registry.
+func snapshotSecretRef(obj *v1alpha1.Resource) *corev1.LocalObjectReference {
+	if obj.GetSnapshotRegistry() == "" {
+		return nil
+	}
+
+	return obj.Spec.SecretRef
+}
+
+// getResourceOptions returns the options for fetching the resource selected by the Resource.
+func getResourceOptions(obj *v1alpha1.Resource) []ocm.GetResourceOption {
+	opts := []ocm.GetResourceOption{ocm.WithSnapshotNamespace(obj.Namespace)}
+	if obj.Spec.Platform != "" {
+		opts = append(opts, ocm.WithPlatform(obj.Spec.Platform))
+	}
+
+	if obj.Spec.Extract != nil {
+		opts = append(opts, ocm.WithExtractPath(obj.Spec.Extract.Path))
+	}
+
+	if compression := obj.GetSnapshotCompression(); compression != v1alpha1.CompressionGzip {
+		opts = append(opts, ocm.WithCompression(compression))
+	}
+
+	if obj.Spec.MaxSize != nil {
+		opts = append(opts, ocm.WithMaxSize(obj.Spec.MaxSize.Value()))
+	}
+
+	if config := snapshotImageConfig(obj); config != nil {
+		opts = append(opts, ocm.WithImageConfig(config))
+	}
+
+	if artifactType := obj.GetSnapshotArtifactType(); artifactType != "" {
+		opts = append(opts, ocm.WithArtifactType(artifactType))
+	}
+
+	if transform := obj.Spec.Transform; transform != nil {
+		var timeout time.Duration
+		if transform.Timeout != nil {
+			timeout = transform.Timeout.Duration
+		}
+
+		opts = append(opts, ocm.WithTransform(transform.URL, timeout))
+	}
+
+	if obj.IsSnapshotTagless() {
+		opts = append(opts, ocm.WithoutTag())
+	}
+
+	if obj.Spec.Source != nil {
+		opts = append(opts, ocm.WithSource())
+	}
+
+	return opts
+}
+
+// snapshotImageConfig returns the image config the snapshot data is stored in, nil if the snapshot template
+// doesn't set one.
+func snapshotImageConfig(obj *v1alpha1.Resource) *cache.ImageConfig {
+	config := obj.GetSnapshotConfig()
+	if config == nil {
+		return nil
+	}
+
+	imageConfig := &cache.ImageConfig{
+		OS:           config.OS,
+		Architecture: config.Architecture,
+		Variant:      config.Variant,
+		Labels:       config.Labels,
+	}
+	if config.Created != nil {
+		imageConfig.Created = config.Created.UTC()
+	}
+
+	return imageConfig
+}
+
+// recordReconcileResult records the start of the reconciliation and its error in the status of the Resource. The
+// error is the returned error, or the message of the Ready condition if it is false, and is cleared once the
+// Resource is ready. A reconciliation that is still in progress, for example requeued to generate the snapshot
+// name, leaves the error as it is.
+func recordReconcileResult(obj *v1alpha1.Resource, start time.Time, err error) {
+	reconcileTime := metav1.NewTime(start)
+	obj.Status.LastReconcileTime = &reconcileTime
+
+	switch {
+	case err != nil:
+		obj.Status.LastError = err.Error()
+	case conditions.IsReady(obj):
+		obj.Status.LastError = ""
+	case conditions.IsFalse(obj, meta.ReadyCondition):
+		obj.Status.LastError = conditions.GetMessage(obj, meta.ReadyCondition)
+	}
+}
+
+// markGetResourceFailed updates the status of the Resource after the resource couldn't be fetched. Transient errors
+// are retried with an increasing backoff, while permanent errors stall the Resource until it is changed.
+func (r *ResourceReconciler) markGetResourceFailed(ctx context.Context, obj *v1alpha1.Resource, err error) ctrl.Result {
+	reason := v1alpha1.GetResourceFailedReason
+	switch {
+	case errors.Is(err, ocm.ErrDigestMismatch):
+		reason = v1alpha1.DigestMismatchReason
+	case errors.Is(err, ocm.ErrResourceNotFound):
+		reason = v1alpha1.ResourceNotFoundReason
+	case errors.Is(err, ocm.ErrAmbiguousResource):
+		reason = v1alpha1.AmbiguousResourceReason
+	case errors.Is(err, ocm.ErrNoFilesMatched):
+		reason = v1alpha1.ExtractFailedReason
+	case errors.Is(err, ocm.ErrTransformFailed):
+		reason = v1alpha1.TransformFailedReason
+	case errors.Is(err, ocm.ErrTransformNotAllowed):
+		reason = v1alpha1.TransformNotAllowedReason
+	case errors.Is(err, ocm.ErrComponentNotFound):
+		reason = v1alpha1.ComponentNotFoundReason
+	case errors.Is(err, ocm.ErrUnsupportedAccess):
+		reason = v1alpha1.UnsupportedAccessReason
+	case errors.Is(err, ocm.ErrRegistryAuth):
+		reason = v1alpha1.RegistryAuthFailedReason
+	case errors.Is(err, ocm.ErrRegistryNotAllowed):
+		reason = v1alpha1.RegistryNotAllowedReason
+	case errors.Is(err, ocm.ErrResourceTooLarge):
+		reason = v1alpha1.ResourceTooLargeReason
+	case errors.Is(err, ocm.ErrSnapshotConflict):
+		reason = v1alpha1.SnapshotConflictReason
+	case errors.Is(err, ocm.ErrInvalidSnapshotTag):
+		reason = v1alpha1.InvalidSnapshotTagReason
+	case errors.Is(err, ocm.ErrInvalidReference):
+		reason = v1alpha1.InvalidReferenceReason
+	case errors.Is(err, component.ErrComponentDescriptorNotCreated):
+		reason = v1alpha1.ComponentDescriptorNotCreatedReason
+	case isRateLimited(err):
+		reason = v1alpha1.RateLimitedReason
+	}
+
+	if errors.Is(err, component.ErrComponentDescriptorNotCreated) {
+		return r.markComponentDescriptorNotCreated(obj, err)
+	}
+
+	if errors.Is(err, ocm.ErrResourceNotFound) {
+		// The resource may still be added to the component descriptor, check again at the regular interval
+		// instead of reporting success or backing off.
+		obj.Status.FailureCount = 0
+		requeueAfter := r.requeueAfter(obj)
+		status.MarkNotReady(r.EventRecorder, obj, reason, fmt.Sprintf("%s, retrying in %s", err, requeueAfter))
+
+		return ctrl.Result{RequeueAfter: requeueAfter}
+	}
+
+	if isPermanentError(err) {
+		// Retrying won't help, wait for the Resource or its ComponentVersion to change.
+		obj.Status.FailureCount = 0
+		status.MarkAsStalled(r.EventRecorder, obj, reason, err.Error())
+
+		return ctrl.Result{}
+	}
+
+	// Transient errors keep the Resource reconciling, it is retried with an increasing backoff.
+	obj.Status.FailureCount++
+	backoff := r.retryDelay(err, obj.Status.FailureCount, r.requeueAfter(obj))
+	status.MarkRetrying(r.EventRecorder, obj, reason, fmt.Sprintf("%s, retrying in %s", err, backoff.Round(time.Second)))
+	log.FromContext(ctx).Error(err, "failed to get resource", "failures", obj.Status.FailureCount, "retryAfter", backoff)
+
+	return ctrl.Result{RequeueAfter: backoff}
+}
+
+// markRegistryUnavailable marks the Resource as waiting for its snapshot registry to be reachable again. The
+// registry is tried again after the backoff shared by all Resources pushing to it.
+func (r *ResourceReconciler) markRegistryUnavailable(obj *v1alpha1.Resource, err error) ctrl.Result {
+	retry := r.registries.markUnavailable(obj.GetSnapshotRegistry(), err, time.Now())
+	status.MarkRetrying(r.EventRecorder, obj, v1alpha1.RegistryProxyUnavailableReason, fmt.Sprintf("%s, retrying in %s", err, retry.Round(time.Second)))
+
+	return ctrl.Result{RequeueAfter: retry}
+}
+
+// markComponentDescriptorNotCreated marks the Resource as waiting for the component descriptor of a component,
+// for example of a referenced component, to be created by the ComponentVersion. The creation of the descriptor
+// triggers a reconciliation, it is checked again at the regular interval as well. Once the Resource has been
+// waiting for longer than the ComponentRefTimeout, the ComponentRefUnresolved condition reports that the component
+// reference is likely misconfigured.
+func (r *ResourceReconciler) markComponentDescriptorNotCreated(obj *v1alpha1.Resource, err error) ctrl.Result {
+	obj.Status.FailureCount = 0
+	requeueAfter := r.requeueAfter(obj)
+
+	now := time.Now()
+	if obj.Status.ComponentDescriptorWaitStart == nil {
+		waitStart := metav1.NewTime(now)
+		obj.Status.ComponentDescriptorWaitStart = &waitStart
+	}
+
+	waited := now.Sub(obj.Status.ComponentDescriptorWaitStart.Time)
+	if r.ComponentRefTimeout <= 0 || waited < r.ComponentRefTimeout {
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ComponentDescriptorNotCreatedReason, fmt.Sprintf("%s, retrying in %s", err, requeueAfter))
+
+		return ctrl.Result{RequeueAfter: requeueAfter}
+	}
+
+	msg := fmt.Sprintf("component descriptor hasn't been created within %s, check the component reference of the resource: %s", r.ComponentRefTimeout, err)
+	conditions.MarkTrue(obj, v1alpha1.ComponentRefUnresolvedCondition, v1alpha1.ComponentRefUnresolvedReason, msg)
+	status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ComponentRefUnresolvedReason, fmt.Sprintf("%s, retrying in %s", msg, requeueAfter))
+
+	return ctrl.Result{RequeueAfter: requeueAfter}
+}
+
+// isWaitingForComponentDescriptor returns whether the Resource is not ready because the component descriptor of
+// its component hasn't been created.
+func isWaitingForComponentDescriptor(obj *v1alpha1.Resource) bool {
+	if !conditions.IsFalse(obj, meta.ReadyCondition) {
+		return false
+	}
+
+	reason := conditions.GetReason(obj, meta.ReadyCondition)
+
+	return reason == v1alpha1.ComponentDescriptorNotCreatedReason || reason == v1alpha1.ComponentRefUnresolvedReason
+}
+
+// isPermanentError returns true for errors which won't be resolved by retrying, like client errors returned by
+// a registry, rejected credentials, registries or transformation webhooks that aren't allowed, unsupported access
+// types or an extract path that doesn't match any of the files of the resource.
+func isPermanentError(err error) bool {
+	for _, target := range []error{
+		ocm.ErrNoFilesMatched,
+		ocm.ErrUnsupportedAccess,
+		ocm.ErrRegistryAuth,
+		ocm.ErrRegistryNotAllowed,
+		ocm.ErrTransformNotAllowed,
+		ocm.ErrResourceTooLarge,
+		ocm.ErrSnapshotConflict,
+		ocm.ErrInvalidSnapshotTag,
+		ocm.ErrAmbiguousResource,
+		ocm.ErrInvalidReference,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	if ocmerrors.IsErrNotSupported(err) || ocmerrors.IsErrInvalid(err) {
+		return true
+	}
+
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode >= http.StatusBadRequest &&
+			terr.StatusCode < http.StatusInternalServerError &&
+			terr.StatusCode != http.StatusRequestTimeout &&
+			terr.StatusCode != http.StatusTooManyRequests
+	}
+
+	return false
+}
+
+// isRateLimited returns true if a registry rejected a request because too many have been sent.
+func isRateLimited(err error) bool {
+	var rerr *cache.RateLimitError
+
+	return errors.As(err, &rerr)
+}
+
+// requeueAfter returns the interval at which the Resource is reconciled, the default interval of the reconciler if
+// the Resource doesn't set one.
+func (r *ResourceReconciler) requeueAfter(obj *v1alpha1.Resource) time.Duration {
+	defaultInterval := r.DefaultInterval
+	if defaultInterval <= 0 {
+		defaultInterval = v1alpha1.DefaultResourceInterval
+	}
+
+	return obj.GetRequeueAfter(defaultInterval)
+}
+
+// retryDelay returns the delay before retrying after the error. If a registry rate limited the request and
+// suggested when to retry, that delay is used, padded by the jitter so the request isn't retried before the
+// registry allows it. Otherwise, the delay is the backoff for the number of failures.
+func (r *ResourceReconciler) retryDelay(err error, failures int, interval time.Duration) time.Duration {
+	var rerr *cache.RateLimitError
+	if errors.As(err, &rerr) && rerr.RetryAfter > 0 {
+		return time.Duration(math.Ceil(float64(rerr.RetryAfter) / (1 - r.RequeueJitter)))
+	}
+
+	return failureBackoff(failures, interval)
+}
+
+// failureBackoff returns the delay before the next attempt after the given number of consecutive failures. The
+// delay doubles with every failure starting at minFailureBackoff and is capped at the interval of the object. The
+// shift is bounded by the interval first, shifting minFailureBackoff by itself would overflow after 31 failures.
+func failureBackoff(failures int, interval time.Duration) time.Duration {
+	if shift := failures - 1; shift >= 0 && shift < 32 && minFailureBackoff <= interval>>shift {
+		if d := minFailureBackoff << shift; d < interval {
+			return d
+		}
+	}
+
+	return interval
+}
+
+// jitter returns the delay randomly shifted by up to the fraction of it in either direction. A zero delay is
+// returned as it is, a negative one is raised to minFailureBackoff.
+func jitter(delay time.Duration, fraction float64) time.Duration {
+	// A negative delay would stop the retries altogether.
+	if delay < 0 {
+		return minFailureBackoff
+	}
+
+	spread := int64(float64(delay) * fraction)
+	if delay == 0 || spread <= 0 {
+		return delay
+	}
+
+	return delay - time.Duration(spread) + time.Duration(rand.Int63n(2*spread+1)) //nolint:gosec // jitter doesn't need crypto
+}
+
+// isVersionApplied returns true if the Snapshots of the Resource have been written for the requested version of the
+// resource and the version the ComponentVersion reconciled. Unlike isSnapshotUpToDate it doesn't check that the data
+// is still present in the registry, only that the Snapshots exist.
+func (r *ResourceReconciler) isVersionApplied(ctx context.Context, obj *v1alpha1.Resource, cv *v1alpha1.ComponentVersion) (bool, error) {
+	if obj.Generation != obj.Status.ObservedGeneration ||
+		obj.Status.LastAppliedComponentVersion == "" ||
+		obj.Status.LastAppliedResourceVersion != obj.GetElementVersion() ||
+		obj.Status.LastAppliedComponentVersion != cv.Status.ReconciledVersion {
+		return false, nil
+	}
+
+	for _, snapshotName := range obj.GetSnapshotNames() {
+		snapshotCR := &v1alpha1.Snapshot{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: snapshotName}, snapshotCR); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("failed to get snapshot: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// isSnapshotUpToDate returns true if the Snapshots of the Resource already contain the requested version of the
+// resource and the data is still present in the registry. In that case fetching and pushing the resources can be
+// skipped.
+func (r *ResourceReconciler) isSnapshotUpToDate(ctx context.Context, obj *v1alpha1.Resource, cv *v1alpha1.ComponentVersion) (bool, error) {
+	if obj.Generation != obj.Status.ObservedGeneration ||
+		obj.Status.LastAppliedResourceVersion != obj.GetElementVersion() ||
+		obj.Status.LastAppliedComponentVersion != cv.Status.ReconciledVersion {
+		return false, nil
+	}
+
+	for _, snapshotName := range obj.GetSnapshotNames() {
+		snapshotCR := &v1alpha1.Snapshot{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: snapshotName}, snapshotCR); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("failed to get snapshot: %w", err)
+		}
+
+		if !conditions.IsReady(snapshotCR) || snapshotCR.Spec.Digest == "" {
+			return false, nil
+		}
+
+		name, err := ocm.SnapshotRepositoryName(snapshotCR)
+		if err != nil {
+			return false, fmt.Errorf("failed to construct repository name: %w", err)
+		}
+
+		keychain, err := registryKeychain(ctx, r.Client, snapshotCR.Spec.Registry, snapshotCR.GetNamespace(), snapshotCR.Spec.SecretRef)
+		if err != nil {
+			return false, err
+		}
+
+		store, err := registryCache(r.Cache, snapshotCR.Spec.Registry, keychain)
+		if err != nil {
+			return false, fmt.Errorf("invalid snapshot registry: %w", err)
+		}
+
+		cached, err := store.IsCached(ctx, name, snapshotCR.Spec.Tag)
+		if err != nil {
+			return false, fmt.Errorf("failed to check cache: %w", err)
+		}
+
+		if !cached {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// reconcileDelete removes the Snapshots that belong to the Resource. The Snapshots are owned by the Resource,
+// but deleting them explicitly makes sure that their finalizers clean up the data in the registry even if the
+// Resource is removed without cascading the deletion to its dependents.
+func (r *ResourceReconciler) reconcileDelete(ctx context.Context, obj *v1alpha1.Resource) error {
+	patchHelper, err := patch.NewHelper(obj, r.Client)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile delete: %w", err)
+	}
+
+	if obj.GetSnapshotName() != "" {
+		names := obj.GetSnapshotNames()
+		for _, resource := range obj.Status.Resources {
+			names = append(names, resource.SnapshotName)
+		}
+
+		for _, name := range names {
+			if err := r.deleteSnapshot(ctx, obj, name); err != nil {
+				return fmt.Errorf("failed to delete snapshot: %w", err)
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(obj, resourceFinalizer)
+
+	return patchHelper.Patch(ctx, obj)
+}
+
+// findObjects maps a changed ComponentVersion to all Resources that reference it.
+func (r *ResourceReconciler) findObjects(key string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		return r.requestsForIndexedObject(key, client.ObjectKeyFromObject(obj), nil)
+	}
+}
+
+// findObjectsForSecret maps a changed Secret to all Resources that take their registry credentials from it, so
+// rotated credentials are used right away instead of at the next interval or retry.
+func (r *ResourceReconciler) findObjectsForSecret(key string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		return r.requestsForIndexedObject(key, client.ObjectKeyFromObject(obj), nil)
+	}
+}
+
+// findObjectsForComponentDescriptor maps a changed ComponentDescriptor to all Resources that reference
+// one of the ComponentVersions owning the descriptor.
+func (r *ResourceReconciler) findObjectsForComponentDescriptor(key string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		var (
+			requests []reconcile.Request
+			seen     = map[types.NamespacedName]struct{}{}
+		)
+
+		for _, owner := range obj.GetOwnerReferences() {
+			if owner.Kind != v1alpha1.ComponentVersionKind {
+				continue
+			}
+
+			cv := types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}
+			requests = append(requests, r.requestsForIndexedObject(key, cv, seen)...)
+		}
+
+		return requests
+	}
+}
+
+// requestsForIndexedObject returns requests for the Resources referencing the object, a ComponentVersion or Secret,
+// in the index with the given key. Only the matching Resources are looked up in the index, and as only their names
+// are read they aren't deep copied. Resources already in seen are skipped, seen may be nil.
+func (r *ResourceReconciler) requestsForIndexedObject(key string, obj types.NamespacedName, seen map[types.NamespacedName]struct{}) []reconcile.Request {
+	resources := &v1alpha1.ResourceList{}
+	if err := r.List(context.TODO(), resources, client.MatchingFields{key: obj.String()}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(resources.Items))
+	for _, item := range resources.Items {
+		name := types.NamespacedName{
+			Name:      item.GetName(),
+			Namespace: item.GetNamespace(),
+		}
+
+		if seen != nil {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+
+			seen[name] = struct{}{}
+		}
+
+		requests = append(requests, reconcile.Request{NamespacedName: name})
+	}
+
+	return requests
 }
@@ -10,23 +10,35 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache"
 	cachefakes "github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	"github.com/open-component-model/ocm-controller/pkg/component"
 	"github.com/open-component-model/ocm-controller/pkg/ocm"
 	"github.com/open-component-model/ocm-controller/pkg/ocm/fakes"
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
 )
 
 func TestResourceReconciler(t *testing.T) {
@@ -56,6 +68,7 @@ func TestResourceReconciler(t *testing.T) {
 	t.Log("priming fake cache")
 	cache := &cachefakes.FakeCache{}
 	cache.PushDataReturns("digest", nil)
+	cache.IsCachedReturns(true, nil)
 
 	t.Log("priming fake ocm client")
 	ocmClient := &fakes.MockFetcher{}
@@ -98,61 +111,2427 @@ func TestResourceReconciler(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, "1.0.0", resource.Status.LastAppliedResourceVersion)
+	assert.Equal(t, "digest", resource.Status.LatestSnapshotDigest)
+	assert.Equal(t, resource.Status.LatestSnapshotDigest, resource.GetSnapshotDigest())
 
 	hash, err := ocm.HashIdentity(snapshot.Spec.Identity)
 	require.NoError(t, err)
 	assert.Equal(t, "sha-18322151501422808564", hash)
 	assert.True(t, conditions.IsTrue(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.SnapshotPushedReason, conditions.GetReason(resource, v1alpha1.SnapshotWrittenCondition))
 
 	close(recorder.Events)
-	event := ""
+	var events []string
 	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.Contains(t, events, "Normal SnapshotCreated Created snapshot test-resource-lmt3orf with digest digest")
+
+	event := ""
+	for _, e := range events {
 		if strings.Contains(e, "Reconciliation finished, next run in") {
 			event = e
 
-			break
+			break
+		}
+	}
+	assert.Contains(t, event, "Reconciliation finished, next run in")
+}
+
+func TestResourceReconcilerSnapshotVerificationFailed(t *testing.T) {
+	t.Log("setting up resource object")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+
+	t.Log("priming fake cache without the pushed data")
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	assert.ErrorContains(t, err, "failed to verify snapshot data")
+
+	t.Log("verifying no snapshot has been created")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.SnapshotVerificationFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Empty(t, resource.Status.LastAppliedResourceVersion)
+}
+
+func TestResourceReconcilerPlatform(t *testing.T) {
+	t.Log("setting up resource object selecting a single platform")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.Platform = "linux/arm64"
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying the platform is part of the snapshot identity")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "linux/arm64", snapshot.Spec.Identity[v1alpha1.ResourcePlatformKey])
+}
+
+func TestResourceReconcilerSource(t *testing.T) {
+	t.Log("setting up resource object selecting a source of the component")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef = nil
+	resource.Spec.Source = &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "git-archive",
+			Version: "1.0.0",
+		},
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	args := ocmClient.GetResourceCallingArgumentsOnCall(0)
+	assert.Equal(t, resource.Spec.Source, args[1])
+
+	t.Log("verifying the snapshot identity marks the data as a source")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "git-archive", snapshot.Spec.Identity[v1alpha1.ResourceNameKey])
+	assert.Equal(t, "1.0.0", snapshot.Spec.Identity[v1alpha1.ResourceVersionKey])
+	assert.Equal(t, v1alpha1.ResourceKindSource, snapshot.Spec.Identity[v1alpha1.ResourceKindKey])
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsReady(resource))
+	assert.Equal(t, "1.0.0", resource.Status.LastAppliedResourceVersion)
+}
+
+func TestResourceReconcilerTagFromDigest(t *testing.T) {
+	t.Log("setting up resource object with a snapshot tag derived from the digest")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		TagFromDigest: true,
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "sha256:abcdef", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying the data has been tagged with the digest")
+	args := cache.TagDataCallingArgumentsOnCall(0)
+	assert.Equal(t, resource.Spec.SourceRef.GetVersion(), args[1])
+	assert.Equal(t, "sha256-abcdef", args[2])
+	assert.Equal(t, "sha256-abcdef", cache.IsCachedCallingArgumentsOnCall(0)[1])
+
+	t.Log("verifying the snapshot points at the digest tag")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256-abcdef", snapshot.Spec.Tag)
+	assert.Equal(t, "sha256:abcdef", snapshot.Spec.Digest)
+}
+
+func TestResourceReconcilerTagless(t *testing.T) {
+	t.Log("setting up resource object with a snapshot pushed without a tag")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		Tagless: true,
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	store := &cachefakes.FakeCache{}
+	store.IsCachedReturns(true, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "sha256:abcdef", nil)
+	ocmClient.GetResourceRecordsPushResult(cache.PushResult{Manifest: "sha256:123456"})
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         store,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying the data has been looked up by the manifest digest without tagging it")
+	assert.True(t, store.TagDataWasNotCalled())
+	assert.Equal(t, "sha256:123456", store.IsCachedCallingArgumentsOnCall(0)[1])
+
+	t.Log("verifying the snapshot points at the manifest digest")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:123456", snapshot.Spec.Tag)
+	assert.Equal(t, "sha256:abcdef", snapshot.Spec.Digest)
+
+	t.Log("failing if the push didn't record the manifest digest")
+	ocmClient.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+	ocmClient.GetResourceRecordsPushResult(cache.PushResult{})
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	assert.ErrorContains(t, err, "no manifest digest was recorded")
+}
+
+func TestResourceReconcilerSnapshotRetention(t *testing.T) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    "github.com/open-component-model/test-component",
+		v1alpha1.ComponentVersionKey: "v0.0.9",
+		v1alpha1.ResourceNameKey:     "introspect-image",
+		v1alpha1.ResourceVersionKey:  "0.9.0",
+	}
+	repository, err := ocm.ConstructRepositoryName(identity)
+	require.NoError(t, err)
+
+	t.Log("setting up resource object with a snapshot history beyond its retention")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		TagFromDigest: true,
+		Retention:     &v1alpha1.SnapshotRetention{KeepLast: 2},
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	now := time.Now()
+	resource.Status.SnapshotHistory = []v1alpha1.SnapshotHistoryEntry{
+		{
+			SnapshotName: resource.Status.SnapshotName,
+			Repository:   repository,
+			Tags:         []string{"0.9.5"},
+			Digest:       "sha256:0950",
+			PushedAt:     metav1.NewTime(now.Add(-time.Hour)),
+		},
+		{
+			SnapshotName: resource.Status.SnapshotName,
+			Repository:   repository,
+			Tags:         []string{"0.9.0", "sha256-0900"},
+			Digest:       "sha256:0900",
+			PushedAt:     metav1.NewTime(now.Add(-2 * time.Hour)),
+		},
+		{
+			SnapshotName: resource.Status.SnapshotName,
+			Repository:   repository,
+			Tags:         []string{"0.8.0"},
+			Digest:       "sha256:0800",
+			PushedAt:     metav1.NewTime(now.Add(-3 * time.Hour)),
+		},
+	}
+
+	t.Log("setting up a snapshot of another resource that still refers to the oldest data")
+	other := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-snapshot",
+			Namespace: resource.Namespace,
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Identity: identity,
+			Digest:   "sha256:0800",
+			Tag:      "0.8.0",
+		},
+	}
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd, other))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "sha256:abcdef", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying only the data beyond the retention that no snapshot refers to has been deleted")
+	assert.Equal(t, []any{repository, "0.9.0"}, cache.DeleteDataCallingArgumentsOnCall(0))
+	assert.Equal(t, []any{repository, "sha256-0900"}, cache.DeleteDataCallingArgumentsOnCall(1))
+
+	err = client.Get(context.Background(), types.NamespacedName{Namespace: resource.Namespace, Name: resource.Name}, resource)
+	require.NoError(t, err)
+
+	history := resource.Status.SnapshotHistory
+	require.Len(t, history, 3)
+	assert.Equal(t, []string{resource.Spec.SourceRef.GetVersion(), "sha256-abcdef"}, history[0].Tags)
+	assert.Equal(t, "sha256:abcdef", history[0].Digest)
+	assert.Equal(t, "sha256:0950", history[1].Digest)
+	assert.Equal(t, "sha256:0800", history[2].Digest, "data a snapshot refers to is deleted once it doesn't anymore")
+}
+
+func TestResourceReconcilerIncludeReferrers(t *testing.T) {
+	t.Log("setting up resource object including referrers")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.IncludeReferrers = true
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+	cache.PushReferrerReturns("sha256:referrer", nil)
+
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+	ocmClient.GetReferrersReturns([]ocm.Referrer{
+		{Name: "sbom", ArtifactType: "application/spdx+json", Data: []byte("sbom")},
+	}, nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying the referrer has been pushed for the snapshot")
+	assert.Equal(t, []any{"sbom", "application/spdx+json", "sha-18322151501422808564", "1.0.0"}, cache.PushReferrerCallingArgumentsOnCall(0))
+
+	t.Log("verifying a failing referrer keeps the resource from becoming ready")
+	cache.PushReferrerReturns("", errors.New("manifest invalid"))
+	ocmClient.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	assert.ErrorContains(t, err, "failed to push referrers: failed to push referrer sbom: manifest invalid")
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.PushReferrersFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestResourceReconcilerDryRun(t *testing.T) {
+	t.Log("setting up resource object in dry run mode")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.DryRun = true
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+
+	t.Log("priming fake ocm client")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceDigestReturns("sha256:digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+	assert.True(t, cache.PushDataWasNotCalled())
+
+	t.Log("verifying no snapshot has been created")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("verifying the dry run result")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsTrue(resource, meta.ReadyCondition))
+	require.NotNil(t, resource.Status.DryRunResult)
+	assert.Equal(t, "test-resource-lmt3orf", resource.Status.DryRunResult.SnapshotName)
+	assert.Equal(t, "sha-18322151501422808564", resource.Status.DryRunResult.Repository)
+	assert.Equal(t, "1.0.0", resource.Status.DryRunResult.Tag)
+	assert.Equal(t, "sha256:digest", resource.Status.DryRunResult.Digest)
+	assert.Empty(t, resource.Status.LastAppliedComponentVersion)
+}
+
+func TestResourceReconcilerResources(t *testing.T) {
+	t.Log("setting up resource object selecting multiple resources")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef = nil
+	resource.Spec.Resources = []v1alpha1.ResourceSelector{
+		{ResourceReference: v1alpha1.ResourceReference{ElementMeta: v1alpha1.ElementMeta{Name: "manifests"}}},
+		{ResourceReference: v1alpha1.ResourceReference{ElementMeta: v1alpha1.ElementMeta{Name: "image", Version: "1.0.0"}}},
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Status.Resources = []v1alpha1.ResourceSnapshotStatus{
+		{Name: "removed", SnapshotName: "test-resource-lmt3orf-removed", Ready: true},
+	}
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	removed := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-resource-lmt3orf-removed",
+			Namespace: resource.Namespace,
+		},
+	}
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd, removed))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	t.Log("priming fake ocm client to fail fetching the second resource")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+	ocmClient.GetResourceReturnsOnCall(1, nil, errors.New("connection reset by peer"))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, result.RequeueAfter)
+	assert.Equal(t, "image", ocmClient.GetResourceCallingArgumentsOnCall(1)[1].(*v1alpha1.ResourceReference).Name)
+
+	t.Log("verifying the snapshot of the fetched resource")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      "test-resource-lmt3orf-manifests",
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "digest", snapshot.Spec.Digest)
+	assert.Equal(t, "latest", snapshot.Spec.Tag)
+	assert.Equal(t, "manifests", snapshot.Spec.Identity[v1alpha1.ResourceNameKey])
+
+	t.Log("verifying no snapshot has been created for the failed resource")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      "test-resource-lmt3orf-image",
+		Namespace: resource.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("verifying the snapshot of the unselected resource has been deleted")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      removed.Name,
+		Namespace: removed.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("verifying the aggregated status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.SnapshotResourcesFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), "1 of 2 resources failed: image: failed to get resource: connection reset by peer")
+	assert.Equal(t, 1, resource.Status.FailureCount)
+	require.Len(t, resource.Status.Resources, 2)
+	assert.Equal(t, v1alpha1.ResourceSnapshotStatus{
+		Name:         "manifests",
+		SnapshotName: "test-resource-lmt3orf-manifests",
+		Digest:       "digest",
+		ComponentDescriptor: &v1alpha1.ComponentDescriptorRevision{
+			Name:            cd.Name,
+			Namespace:       cd.Namespace,
+			ResourceVersion: "999",
+		},
+		Ready: true,
+	}, resource.Status.Resources[0])
+	assert.False(t, resource.Status.Resources[1].Ready)
+	assert.Equal(t, "test-resource-lmt3orf-image", resource.Status.Resources[1].SnapshotName)
+	assert.Contains(t, resource.Status.Resources[1].Message, "connection reset by peer")
+}
+
+func TestResourceReconcilerSnapshotTemplate(t *testing.T) {
+	t.Log("setting up resource object with a snapshot template")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		Labels: map[string]string{
+			"app": "podinfo",
+		},
+		Annotations: map[string]string{
+			"delivery.ocm.software/note": "test",
+		},
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	t.Log("setting up a pre-existing snapshot without an owner")
+	existing := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resource.Status.SnapshotName,
+			Namespace: resource.Namespace,
+			Labels: map[string]string{
+				"existing": "label",
+			},
+		},
+	}
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd, existing))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+	recorder := record.NewFakeRecorder(32)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		EventRecorder: recorder,
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller twice")
+	for i := 0; i < 2; i++ {
+		ocmClient := &fakes.MockFetcher{}
+		ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+		rr.OCMClient = ocmClient
+
+		_, err := rr.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	close(recorder.Events)
+	for e := range recorder.Events {
+		assert.NotContains(t, e, v1alpha1.SnapshotCreatedReason)
+	}
+
+	t.Log("verifying the snapshot metadata")
+	snapshot := &v1alpha1.Snapshot{}
+	err := client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"existing": "label", "app": "podinfo"}, snapshot.Labels)
+	assert.Equal(t, map[string]string{
+		"delivery.ocm.software/note":        "test",
+		"delivery.ocm.software/compression": "gzip",
+	}, snapshot.Annotations)
+	require.Len(t, snapshot.OwnerReferences, 1)
+	assert.Equal(t, resource.Name, snapshot.OwnerReferences[0].Name)
+	assert.Equal(t, "digest", snapshot.Spec.Digest)
+}
+
+func TestResourceReconcilerSnapshotCompression(t *testing.T) {
+	t.Log("setting up resource object storing the snapshot uncompressed")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		Compression: v1alpha1.CompressionNone,
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+		OCMClient:     ocmClient,
+	}
+
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("recording the compression on the snapshot")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+
+	assert.Equal(t, v1alpha1.CompressionNone, snapshot.Annotations[v1alpha1.CompressionAnnotation])
+	assert.Equal(t, v1alpha1.CompressionNone, snapshot.Spec.Identity[v1alpha1.ResourceCompressionKey])
+}
+
+func TestResourceReconcilerSnapshotConfig(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		Config: &v1alpha1.SnapshotConfig{
+			OS:           "linux",
+			Architecture: "amd64",
+		},
+		ArtifactType: "application/vnd.ocm.software.snapshot.v1",
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	fakeCache := &cachefakes.FakeCache{}
+	fakeCache.IsCachedReturns(true, nil)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         fakeCache,
+		OCMClient:     ocmClient,
+	}
+
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("storing data with an image config apart from the data without one")
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+
+	expected, err := (&cache.ImageConfig{OS: "linux", Architecture: "amd64"}).Hash()
+	require.NoError(t, err)
+	assert.Equal(t, expected, snapshot.Spec.Identity[v1alpha1.ResourceConfigKey])
+
+	t.Log("storing data with an artifact type apart from images")
+	assert.Equal(t, "application/vnd.ocm.software.snapshot.v1", snapshot.Spec.Identity[v1alpha1.ResourceArtifactTypeKey])
+}
+
+func TestResourceReconcilerTransform(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.Transform = &v1alpha1.TransformSpec{URL: "https://relocator.ocm-system.svc/transform"}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	fakeClient := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	fakeCache := &cachefakes.FakeCache{}
+	fakeCache.IsCachedReturns(true, nil)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", fmt.Errorf("failed to transform resource: %w", ocm.ErrTransformFailed))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        fakeClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         fakeCache,
+		OCMClient:     ocmClient,
+	}
+
+	t.Log("marking the Resource with the reason of a failed transformation")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(resource)})
+	require.NoError(t, err)
+
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource))
+	assert.Equal(t, v1alpha1.TransformFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.False(t, resource.Status.Transformed)
+
+	t.Log("recording the transformation of the written resource")
+	ocmClient.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(resource)})
+	require.NoError(t, err)
+
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource))
+	assert.True(t, conditions.IsReady(resource), conditions.GetMessage(resource, meta.ReadyCondition))
+	assert.True(t, resource.Status.Transformed)
+
+	snapshot := &v1alpha1.Snapshot{}
+	require.NoError(t, fakeClient.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot))
+	assert.Equal(t, "https://relocator.ocm-system.svc/transform", snapshot.Spec.Identity[v1alpha1.ResourceTransformKey],
+		"transformed data should be stored apart from the data as it is")
+}
+
+// changingDescriptorClient returns a new resource version of ComponentDescriptors on every read, as if they were
+// updated concurrently.
+type changingDescriptorClient struct {
+	client.Client
+	reads int
+}
+
+func (c *changingDescriptorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+
+	if _, ok := obj.(*v1alpha1.ComponentDescriptor); ok {
+		c.reads++
+		obj.SetResourceVersion(fmt.Sprintf("%d", 1000+c.reads))
+	}
+
+	return nil
+}
+
+func TestResourceReconcilerComponentDescriptorRevision(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	fakeClient := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	fakeCache := &cachefakes.FakeCache{}
+	fakeCache.IsCachedReturns(true, nil)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        &changingDescriptorClient{Client: fakeClient},
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         fakeCache,
+		OCMClient:     ocmClient,
+	}
+
+	t.Log("not writing the snapshot if the descriptor changes while the resource is fetched")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	assert.ErrorContains(t, err, "changed from resource version 1001 to 1003 while the resource was fetched")
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource)
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.ComponentDescriptorChangedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Nil(t, resource.Status.LastAppliedComponentDescriptor)
+
+	err = fakeClient.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("recording the revision of the descriptor the snapshot was written from")
+	ocmClient.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+	rr.Client = fakeClient
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsReady(resource))
+	assert.Equal(t, &v1alpha1.ComponentDescriptorRevision{
+		Name:            cd.Name,
+		Namespace:       cd.Namespace,
+		ResourceVersion: "999",
+	}, resource.Status.LastAppliedComponentDescriptor)
+}
+
+func TestResourceReconcilerComponentDescriptorMissing(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	// The descriptor isn't created, the revision the resource would be fetched from can't be captured.
+	withComponentDescriptor(cv)
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	fakeClient := env.FakeKubeClient(WithObjects(cv, resource))
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        fakeClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+		OCMClient:     ocmClient,
+	}
+
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval), result.RequeueAfter)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource)
+	require.NoError(t, err)
+	assert.False(t, conditions.IsReady(resource))
+	assert.Equal(t, v1alpha1.ComponentDescriptorNotCreatedReason, conditions.GetReason(resource, meta.ReadyCondition))
+
+	err = fakeClient.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, &v1alpha1.Snapshot{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestCheckComponentDescriptorRevisionUnknown(t *testing.T) {
+	cv := DefaultComponent.DeepCopy()
+	cd := withComponentDescriptor(cv)
+
+	rr := ResourceReconciler{
+		Scheme: env.scheme,
+		Client: env.FakeKubeClient(WithObjects(cv, cd)),
+	}
+
+	err := rr.checkComponentDescriptorRevision(context.Background(), cv, &v1alpha1.ResourceReference{}, nil)
+	assert.ErrorContains(t, err, "is unknown", "a snapshot must not be written without the revision it was fetched from")
+}
+
+func TestResourceReconcilerSnapshotRegistry(t *testing.T) {
+	t.Log("setting up resource object pushing the snapshot to its own registry")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{
+		Registry: "https://registry.example.com",
+	}
+	resource.Spec.SecretRef = &corev1.LocalObjectReference{Name: "registry-credentials"}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry-credentials",
+			Namespace: resource.Namespace,
+		},
+		Data: map[string][]byte{
+			".dockerconfigjson": []byte(`{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`),
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+	}
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd, secret))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(io.NopCloser(bytes.NewBuffer([]byte("content"))), "digest", nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+		OCMClient:     ocmClient,
+	}
+
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("verifying the snapshot data is checked in the registry of the resource")
+	assert.Equal(t, "https://registry.example.com", cache.ForRegistryCallingArgumentsOnCall(0))
+
+	t.Log("verifying the snapshot is pushed with the credentials of the resource")
+	keychain := cache.ForRegistryKeychainOnCall(1)
+	require.NotNil(t, keychain)
+	ref, err := ociname.NewRepository("registry.example.com/snapshot")
+	require.NoError(t, err)
+	auth, err := keychain.Resolve(ref)
+	require.NoError(t, err)
+	credentials, err := auth.Authorization()
+	require.NoError(t, err)
+	assert.Equal(t, "user", credentials.Username)
+
+	snapshot := &v1alpha1.Snapshot{}
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Status.SnapshotName,
+		Namespace: resource.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com", snapshot.Spec.Registry)
+	assert.Equal(t, resource.Spec.SecretRef, snapshot.Spec.SecretRef)
+
+	t.Log("stalling the resource if the registry is invalid")
+	cache.ForRegistryReturns(errors.New("registry address must be of the form host[:port]"))
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsStalled(resource))
+	assert.Equal(t, v1alpha1.InvalidSnapshotRegistryReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestPreviousSnapshotRepository(t *testing.T) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    "github.com/open-component-model/test-component",
+		v1alpha1.ComponentVersionKey: "v0.1.0",
+		v1alpha1.ResourceNameKey:     "introspect-image",
+		v1alpha1.ResourceVersionKey:  "1.0.0",
+	}
+	repository, err := ocm.ConstructRepositoryName(identity)
+	require.NoError(t, err)
+
+	snapshotCR := &v1alpha1.Snapshot{
+		Spec: v1alpha1.SnapshotSpec{
+			Identity: identity,
+		},
+	}
+
+	resource := DefaultResource.DeepCopy()
+	assert.Empty(t, previousSnapshotRepository(resource, snapshotCR), "layers are only reused if the template asks for it")
+
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{ReuseLayers: true}
+	assert.Equal(t, repository, previousSnapshotRepository(resource, snapshotCR))
+	assert.Empty(t, previousSnapshotRepository(resource, nil), "a new snapshot has no previous version")
+
+	resource.Spec.SnapshotTemplate.Registry = "https://registry.example.com"
+	assert.Empty(t, previousSnapshotRepository(resource, snapshotCR), "layers can't be mounted across registries")
+}
+
+func TestResourceReconcilerRegistryUnavailable(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	other := resource.DeepCopy()
+	other.Name = "other-resource"
+	other.Status.SnapshotName = "other-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	fakeClient := env.FakeKubeClient(WithObjects(cv, resource, other, cd))
+	unavailable := &cache.RegistryUnavailableError{Host: "registry.ocm-system.svc.cluster.local:5000", Err: errors.New("connection refused")}
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", fmt.Errorf("failed to push data: %w", unavailable))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        fakeClient,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	t.Log("backing off when the snapshot registry can't be reached")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(resource)})
+	require.NoError(t, err)
+	assert.Equal(t, minRegistryBackoff, result.RequeueAfter)
+
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(resource), resource))
+	assert.Equal(t, v1alpha1.RegistryProxyUnavailableReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), "snapshot registry registry.ocm-system.svc.cluster.local:5000 is unavailable")
+
+	t.Log("waiting for the registry with all other resources without fetching them")
+	result, err = rr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(other)})
+	require.NoError(t, err)
+	assert.Greater(t, result.RequeueAfter, time.Duration(0))
+	assert.LessOrEqual(t, result.RequeueAfter, minRegistryBackoff)
+
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(other), other))
+	assert.Equal(t, v1alpha1.RegistryProxyUnavailableReason, conditions.GetReason(other, meta.ReadyCondition))
+}
+
+func TestRegistryAvailability(t *testing.T) {
+	var registries registryAvailability
+	now := time.Now()
+	err := errors.New("connection refused")
+
+	wait, _ := registries.unavailable("", now)
+	assert.Zero(t, wait)
+
+	t.Log("backing off faster than a single resource")
+	assert.Equal(t, minRegistryBackoff, registries.markUnavailable("", err, now))
+	assert.Equal(t, minRegistryBackoff-time.Second, registries.markUnavailable("", err, now.Add(time.Second)),
+		"resources failing during the same attempt don't extend the backoff")
+
+	now = now.Add(minRegistryBackoff)
+	assert.Equal(t, 4*minRegistryBackoff, registries.markUnavailable("", err, now))
+	wait, outage := registries.unavailable("", now.Add(30*time.Second))
+	assert.Equal(t, 4*minRegistryBackoff-30*time.Second, wait)
+	assert.Equal(t, err, outage.err)
+
+	t.Log("keeping the outages of registries apart")
+	wait, _ = registries.unavailable("https://registry.example.com", now)
+	assert.Zero(t, wait)
+
+	t.Log("trying the registry again right away once it has been reached")
+	registries.markAvailable("")
+	wait, _ = registries.unavailable("", now)
+	assert.Zero(t, wait)
+
+	assert.Equal(t, maxRegistryBackoff, registryBackoff(4))
+	assert.Equal(t, maxRegistryBackoff, registryBackoff(100))
+}
+
+func TestResourceReconcilerFailed(t *testing.T) {
+	t.Log("setting up resource object")
+	resource := DefaultResource.DeepCopy()
+	// Tests that the component descriptor exists for root items.
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	t.Log("priming fake cache")
+	cache := &cachefakes.FakeCache{}
+	cache.PushDataReturns("", errors.New("nope"))
+
+	t.Log("priming fake ocm client")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "nil", errors.New("nope"))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	t.Log("calling reconcile on resource controller")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.RequeueAfter, minFailureBackoff)
+	assert.Less(t, result.RequeueAfter, 2*minFailureBackoff)
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+
+	require.NoError(t, err)
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.GetResourceFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), "failed to get resource: nope")
+	assert.Equal(t, 1, resource.Status.FailureCount)
+	assert.Contains(t, resource.Status.LastError, "failed to get resource: nope")
+	require.NotNil(t, resource.Status.LastReconcileTime)
+	lastReconcileTime := resource.Status.LastReconcileTime
+
+	t.Log("clearing the error once the resource has been fetched")
+	ocmClient.GetResourceReturnsOnCall(1, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+	cache.IsCachedReturns(true, nil)
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsReady(resource))
+	assert.Empty(t, resource.Status.LastError)
+	assert.Equal(t, 0, resource.Status.FailureCount)
+	assert.False(t, resource.Status.LastReconcileTime.Before(lastReconcileTime))
+}
+
+func TestResourceReconcilerPermanentFailure(t *testing.T) {
+	t.Log("setting up resource object")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Status.FailureCount = 3
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cd := withComponentDescriptor(cv)
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, cd, resource))
+
+	t.Log("priming fake ocm client with a client error of the registry")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", fmt.Errorf("failed to cache blob: %w", &transport.Error{StatusCode: http.StatusUnauthorized}))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	t.Log("calling reconcile on resource controller")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+
+	require.NoError(t, err)
+	assert.True(t, conditions.IsStalled(resource))
+	assert.Equal(t, v1alpha1.GetResourceFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Equal(t, 0, resource.Status.FailureCount)
+}
+
+func TestResourceReconcilerFailureCategories(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		reason    string
+		permanent bool
+		// retrying is set for failures that keep the Resource reconciling while it backs off.
+		retrying bool
+	}{
+		{
+			name:      "rejected registry credentials",
+			err:       fmt.Errorf("failed to cache image: %w", ocm.ErrRegistryAuth),
+			reason:    v1alpha1.RegistryAuthFailedReason,
+			permanent: true,
+		},
+		{
+			name:      "unsupported access",
+			err:       fmt.Errorf("failed to fetch access spec: %w", ocm.ErrUnsupportedAccess),
+			reason:    v1alpha1.UnsupportedAccessReason,
+			permanent: true,
+		},
+		{
+			name:     "component not found",
+			err:      fmt.Errorf("failed to get component Version: %w", ocm.ErrComponentNotFound),
+			reason:   v1alpha1.ComponentNotFoundReason,
+			retrying: true,
+		},
+		{
+			name:      "registry not allowed",
+			err:       fmt.Errorf("failed to get resource: %w", ocm.ErrRegistryNotAllowed),
+			reason:    v1alpha1.RegistryNotAllowedReason,
+			permanent: true,
+		},
+		{
+			name:      "transformation webhook not allowed",
+			err:       fmt.Errorf("failed to transform resource: %w", ocm.ErrTransformNotAllowed),
+			reason:    v1alpha1.TransformNotAllowedReason,
+			permanent: true,
+		},
+		{
+			name:   "component descriptor of a referenced component not created yet",
+			err:    fmt.Errorf("failed to find component descriptor for reference: %w", component.ErrComponentDescriptorNotCreated),
+			reason: v1alpha1.ComponentDescriptorNotCreatedReason,
+		},
+		{
+			name:      "resource too large",
+			err:       fmt.Errorf("failed to cache blob: %w", ocm.ErrResourceTooLarge),
+			reason:    v1alpha1.ResourceTooLargeReason,
+			permanent: true,
+		},
+		{
+			name:      "snapshot reference used by another image",
+			err:       fmt.Errorf("failed to get resource: %w", ocm.ErrSnapshotConflict),
+			reason:    v1alpha1.SnapshotConflictReason,
+			permanent: true,
+		},
+		{
+			name:      "several variants of the resource match",
+			err:       fmt.Errorf("failed to get resource: %w", ocm.ErrAmbiguousResource),
+			reason:    v1alpha1.AmbiguousResourceReason,
+			permanent: true,
+		},
+		{
+			name:     "rate limited",
+			err:      fmt.Errorf("failed to cache image: %w", &cache.RateLimitError{Host: "ghcr.io"}),
+			reason:   v1alpha1.RateLimitedReason,
+			retrying: true,
+		},
+		{
+			name:      "malformed image reference",
+			err:       fmt.Errorf("failed to get resource: %w", ocm.ErrInvalidReference),
+			reason:    v1alpha1.InvalidReferenceReason,
+			permanent: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := DefaultResource.DeepCopy()
+			resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+			resource.Status.SnapshotName = "test-resource-lmt3orf"
+			resource.Generation = 2
+
+			cv := DefaultComponent.DeepCopy()
+			cd := withComponentDescriptor(cv)
+			conditions.MarkTrue(cv,
+				meta.ReadyCondition,
+				meta.SucceededReason,
+				"Applied version: 1.0.0")
+
+			client := env.FakeKubeClient(WithObjects(cv, cd, resource))
+			ocmClient := &fakes.MockFetcher{}
+			ocmClient.GetResourceReturns(nil, "", tt.err)
+
+			rr := ResourceReconciler{
+				Scheme:        env.scheme,
+				Client:        client,
+				OCMClient:     ocmClient,
+				EventRecorder: record.NewFakeRecorder(32),
+				Cache:         &cachefakes.FakeCache{},
+			}
+
+			result, err := rr.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: resource.Namespace,
+					Name:      resource.Name,
+				},
+			})
+			require.NoError(t, err)
+
+			err = client.Get(context.Background(), types.NamespacedName{
+				Name:      resource.Name,
+				Namespace: resource.Namespace,
+			}, resource)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.reason, conditions.GetReason(resource, meta.ReadyCondition))
+			assert.Equal(t, tt.permanent, conditions.IsStalled(resource))
+			assert.Equal(t, tt.retrying, conditions.IsReconciling(resource))
+			if tt.retrying {
+				assert.Equal(t, meta.ProgressingWithRetryReason, conditions.GetReason(resource, meta.ReconcilingCondition))
+			}
+
+			if tt.permanent {
+				assert.Equal(t, ctrl.Result{}, result)
+				assert.Equal(t, resource.Generation, resource.Status.ObservedGeneration, "the stall applies to this generation")
+			} else {
+				assert.NotZero(t, result.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestResourceReconcilerInvalidSnapshotTag(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SourceRef.ResourceRef.Version = "1.0.0/rc"
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource))
+	ocmClient := &fakes.MockFetcher{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+
+	t.Log("stalling the resource without fetching it")
+	assert.True(t, conditions.IsStalled(resource))
+	assert.Equal(t, v1alpha1.InvalidSnapshotTagReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), `version "1.0.0/rc" can't be used as tag`)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+}
+
+func TestResourceReconcilerRateLimited(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := withComponentDescriptor(cv)
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, cd, resource))
+
+	t.Log("priming fake ocm client with a rate limited registry suggesting a delay")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", fmt.Errorf("failed to cache image: %w", &cache.RateLimitError{
+		Host:       "ghcr.io",
+		RetryAfter: 3 * time.Minute,
+	}))
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: 3 * time.Minute}, result)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.RateLimitedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), "registry ghcr.io is rate limiting requests, retry after 3m0s")
+	assert.Equal(t, 1, resource.Status.FailureCount)
+}
+
+func TestResourceReconcilerTimeout(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.Timeout = &metav1.Duration{Duration: 10 * time.Millisecond}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+
+	t.Log("priming fake ocm client with a resource that doesn't finish copying")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", nil)
+	ocmClient.GetResourceWaitsForCancellation()
+
+	rr := ResourceReconciler{
+		Scheme:           env.scheme,
+		Client:           client,
+		OCMClient:        ocmClient,
+		EventRecorder:    record.NewFakeRecorder(32),
+		Cache:            &cachefakes.FakeCache{},
+		ReconcileTimeout: time.Hour,
+	}
+
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: minFailureBackoff}, result)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+
+	t.Log("marking the resource as timed out and retrying")
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.True(t, conditions.IsTrue(resource, meta.ReconcilingCondition))
+	assert.Equal(t, v1alpha1.ReconcileTimeoutReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Equal(t, "reconciliation didn't finish within 10ms, retrying in 5s", conditions.GetMessage(resource, meta.ReadyCondition))
+	assert.Equal(t, 1, resource.Status.FailureCount)
+	assert.Empty(t, resource.Status.LatestSnapshotDigest)
+}
+
+func TestResourceReconcilerRequeueAfter(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.Interval = metav1.Duration{}
+
+	t.Log("falling back to the default interval of the API without one on the reconciler")
+	rr := ResourceReconciler{}
+	assert.Equal(t, v1alpha1.DefaultResourceInterval, rr.requeueAfter(resource))
+
+	t.Log("using the default interval of the reconciler for Resources without an interval")
+	rr.DefaultInterval = 5 * time.Minute
+	assert.Equal(t, 5*time.Minute, rr.requeueAfter(resource))
+
+	t.Log("using the interval of the Resource")
+	resource.Spec.Interval = metav1.Duration{Duration: time.Hour}
+	assert.Equal(t, time.Hour, rr.requeueAfter(resource))
+}
+
+func TestFailureBackoff(t *testing.T) {
+	interval := 10 * time.Minute
+
+	for failures, expected := range map[int]time.Duration{
+		1:   minFailureBackoff,
+		2:   2 * minFailureBackoff,
+		4:   8 * minFailureBackoff,
+		31:  interval,
+		32:  interval,
+		33:  interval,
+		100: interval,
+	} {
+		assert.Equal(t, expected, failureBackoff(failures, interval))
+	}
+
+	longest := time.Duration(math.MaxInt64)
+	assert.Equal(t, minFailureBackoff<<30, failureBackoff(31, longest))
+	assert.Equal(t, longest, failureBackoff(32, longest), "the backoff doesn't overflow")
+}
+
+func TestJitter(t *testing.T) {
+	interval := 10 * time.Minute
+
+	for i := 0; i < 100; i++ {
+		delay := jitter(interval, 0.1)
+		assert.GreaterOrEqual(t, delay, 9*time.Minute)
+		assert.LessOrEqual(t, delay, 11*time.Minute)
+	}
+
+	assert.Equal(t, interval, jitter(interval, 0), "no jitter without a fraction")
+	assert.Zero(t, jitter(0, 0.1), "results without a requeue aren't requeued")
+	assert.Equal(t, minFailureBackoff, jitter(-time.Second, 0.1), "a negative delay doesn't stop the retries")
+
+	r := &ResourceReconciler{RequeueJitter: 0.5}
+	retryAfter := &cache.RateLimitError{RetryAfter: 3 * time.Minute}
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, jitter(r.retryDelay(retryAfter, 1, interval), r.RequeueJitter), 3*time.Minute,
+			"a rate limited request isn't retried before the registry allows it")
+	}
+}
+
+func TestResourceReconcilerRequeueJitter(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	conditions.MarkFalse(cv, meta.ReadyCondition, meta.FailedReason, "not ready")
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        env.FakeKubeClient(WithObjects(cv, resource)),
+		OCMClient:     &fakes.MockFetcher{},
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+		RequeueJitter: 0.5,
+	}
+
+	interval := resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)
+	delays := make(map[time.Duration]struct{})
+	for i := 0; i < 10; i++ {
+		result, err := rr.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+			},
+		})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, result.RequeueAfter, interval/2)
+		assert.LessOrEqual(t, result.RequeueAfter, interval+interval/2)
+
+		delays[result.RequeueAfter] = struct{}{}
+	}
+
+	assert.Greater(t, len(delays), 1, "requeues of the interval are spread")
+}
+
+func TestResourceReconcilerResourceNotFound(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Status.FailureCount = 2
+
+	cv := DefaultComponent.DeepCopy()
+	cd := withComponentDescriptor(cv)
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, cd, resource))
+
+	t.Log("priming fake ocm client with a component descriptor that doesn't list the resource")
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", fmt.Errorf("%w: no resource with name introspect-image in component descriptor test", ocm.ErrResourceNotFound))
+
+	recorder := &record.FakeRecorder{
+		Events: make(chan string, 32),
+	}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: recorder,
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.False(t, conditions.IsStalled(resource))
+	assert.Equal(t, v1alpha1.ResourceNotFoundReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.Equal(t, 0, resource.Status.FailureCount)
+	assert.Empty(t, resource.Status.LastAppliedResourceVersion)
+
+	close(recorder.Events)
+	var warnings []string
+	for e := range recorder.Events {
+		if strings.HasPrefix(e, corev1.EventTypeWarning) {
+			warnings = append(warnings, e)
+		}
+	}
+	require.NotEmpty(t, warnings)
+	assert.Contains(t, warnings[0], "no resource with name introspect-image")
+}
+
+func TestResourceReconcilerComponentRefUnresolved(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
+	cache := &cachefakes.FakeCache{}
+	cache.IsCachedReturns(true, nil)
+
+	notCreated := fmt.Errorf("failed to find component descriptor for reference: %w", component.ErrComponentDescriptorNotCreated)
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceReturns(nil, "", notCreated)
+	ocmClient.GetResourceReturnsOnCall(1, nil, notCreated)
+	ocmClient.GetResourceReturnsOnCall(2, io.NopCloser(bytes.NewBuffer([]byte("content"))), nil)
+
+	recorder := &record.FakeRecorder{
+		Events: make(chan string, 32),
+	}
+
+	rr := ResourceReconciler{
+		Scheme:              env.scheme,
+		Client:              client,
+		OCMClient:           ocmClient,
+		EventRecorder:       recorder,
+		Cache:               cache,
+		ComponentRefTimeout: time.Hour,
+	}
+
+	reconcile := func() ctrl.Result {
+		t.Helper()
+
+		result, err := rr.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+			},
+		})
+		require.NoError(t, err)
+
+		err = client.Get(context.Background(), types.NamespacedName{
+			Name:      resource.Name,
+			Namespace: resource.Namespace,
+		}, resource)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	t.Log("waiting for the component descriptor to be created")
+	result := reconcile()
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
+	assert.Equal(t, v1alpha1.ComponentDescriptorNotCreatedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.NotNil(t, resource.Status.ComponentDescriptorWaitStart)
+	assert.False(t, conditions.Has(resource, v1alpha1.ComponentRefUnresolvedCondition))
+
+	t.Log("marking the component reference as unresolved once the timeout has passed")
+	waitStart := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+	resource.Status.ComponentDescriptorWaitStart = &waitStart
+	require.NoError(t, client.Update(context.Background(), resource))
+
+	result = reconcile()
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result, "the resource is still reconciled at its interval")
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.False(t, conditions.IsStalled(resource))
+	assert.Equal(t, v1alpha1.ComponentRefUnresolvedReason, conditions.GetReason(resource, meta.ReadyCondition))
+	assert.True(t, conditions.IsTrue(resource, v1alpha1.ComponentRefUnresolvedCondition))
+	assert.Contains(t, conditions.GetMessage(resource, v1alpha1.ComponentRefUnresolvedCondition), "component descriptor hasn't been created within 1h0m0s")
+	assert.True(t, waitStart.Equal(resource.Status.ComponentDescriptorWaitStart), "the start of the wait is kept")
+
+	var warnings []string
+	for len(recorder.Events) > 0 {
+		if e := <-recorder.Events; strings.HasPrefix(e, corev1.EventTypeWarning+" "+v1alpha1.ComponentRefUnresolvedReason) {
+			warnings = append(warnings, e)
+		}
+	}
+	assert.Len(t, warnings, 1)
+
+	t.Log("clearing the wait once the component descriptor has been found")
+	reconcile()
+	assert.True(t, conditions.IsReady(resource))
+	assert.Nil(t, resource.Status.ComponentDescriptorWaitStart)
+	assert.False(t, conditions.Has(resource, v1alpha1.ComponentRefUnresolvedCondition))
+}
+
+func TestResourceReconcilerSnapshotNameConflict(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SnapshotTemplate = &v1alpha1.SnapshotTemplateSpec{Name: "shared-snapshot"}
+	resource.Status.SnapshotName = "shared-snapshot"
+	resource.UID = "resource-uid"
+
+	cv := DefaultComponent.DeepCopy()
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	t.Log("setting up a snapshot with the same name owned by another resource")
+	snapshot := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared-snapshot",
+			Namespace: resource.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1alpha1.GroupVersion.String(),
+					Kind:       v1alpha1.ResourceKind,
+					Name:       "other-resource",
+					UID:        "other-uid",
+				},
+			},
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Digest: "sha256:other",
+		},
+	}
+
+	client := env.FakeKubeClient(WithObjects(cv, resource, snapshot))
+	ocmClient := &fakes.MockFetcher{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	assert.ErrorContains(t, err, "snapshot shared-snapshot is already owned by Resource other-resource")
+	assert.True(t, ocmClient.GetResourceWasNotCalled(), "the resource shouldn't be fetched if its snapshot can't be written")
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+	require.NoError(t, err)
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.SnapshotNameConflictReason, conditions.GetReason(resource, meta.ReadyCondition))
+
+	t.Log("deleting the resource keeps the snapshot of the other resource")
+	resource.Finalizers = []string{resourceFinalizer}
+	require.NoError(t, client.Update(context.Background(), resource))
+	require.NoError(t, client.Delete(context.Background(), resource))
+
+	_, err = rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      snapshot.Name,
+		Namespace: snapshot.Namespace,
+	}, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:other", snapshot.Spec.Digest)
+}
+
+func TestResourceReconcilerComponentVersionNotFound(t *testing.T) {
+	t.Log("setting up resource object without a component version")
+	resource := DefaultResource.DeepCopy()
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	client := env.FakeKubeClient(WithObjects(resource))
+	ocmClient := &fakes.MockFetcher{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	t.Log("calling reconcile on resource controller")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+
+	require.NoError(t, err)
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.ComponentVersionNotFoundReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestResourceReconcilerSecretRefNotFound(t *testing.T) {
+	t.Log("setting up resource object with a missing secret")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.SecretRef = &corev1.LocalObjectReference{
+		Name: "missing-secret",
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource))
+	ocmClient := &fakes.MockFetcher{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	t.Log("calling reconcile on resource controller")
+	_, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get docker config secret")
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+
+	require.NoError(t, err)
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.ConfigureCredentialsFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestResourceReconcilerVerificationFailed(t *testing.T) {
+	t.Log("setting up resource object with signatures to verify")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.Verify = []v1alpha1.Signature{
+		{
+			Name: "test-signature",
+			PublicKey: v1alpha1.PublicKey{
+				SecretRef: &corev1.LocalObjectReference{
+					Name: "test-public-key",
+				},
+			},
+		},
+	}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+
+	t.Log("setting up component version")
+	cv := DefaultComponent.DeepCopy()
+	cv.Status.ReconciledVersion = "v0.0.1"
+	conditions.MarkTrue(cv,
+		meta.ReadyCondition,
+		meta.SucceededReason,
+		"Applied version: 1.0.0")
+
+	client := env.FakeKubeClient(WithObjects(cv, resource))
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.VerifyComponentReturns(false, nil)
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+
+	t.Log("calling reconcile on resource controller")
+	result, err := rr.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	args := ocmClient.VerifyComponentCallingArgumentsOnCall(0)
+	verifiedCV, ok := args[0].(*v1alpha1.ComponentVersion)
+	require.True(t, ok)
+	assert.Equal(t, resource.Spec.Verify, verifiedCV.Spec.Verify)
+	assert.Equal(t, resource.Namespace, verifiedCV.Namespace)
+	assert.Equal(t, "v0.0.1", args[1])
+
+	t.Log("verifying updated resource object status")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      resource.Name,
+		Namespace: resource.Namespace,
+	}, resource)
+
+	require.NoError(t, err)
+	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.VerificationFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestReconcileRequestedPredicate(t *testing.T) {
+	withRequest := func(value string) *v1alpha1.Resource {
+		resource := DefaultResource.DeepCopy()
+		if value != "" {
+			resource.Annotations = map[string]string{v1alpha1.ReconcileRequestAnnotation: value}
 		}
+
+		return resource
+	}
+
+	testCases := []struct {
+		name     string
+		old, new string
+		expected bool
+	}{
+		{name: "new request", new: "1", expected: true},
+		{name: "changed request", old: "1", new: "2", expected: true},
+		{name: "unchanged request", old: "1", new: "1"},
+		{name: "removed request", old: "1"},
+		{name: "no request"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ReconcileRequestedPredicate{}.Update(event.UpdateEvent{
+				ObjectOld: withRequest(tt.old),
+				ObjectNew: withRequest(tt.new),
+			}))
+		})
 	}
-	assert.Contains(t, event, "Reconciliation finished, next run in")
 }
 
-func XTestResourceReconcilerFailed(t *testing.T) {
-	t.Log("setting up resource object")
+func TestResourceReconcilerSuspended(t *testing.T) {
+	t.Log("setting up a resource suspended by an annotation")
 	resource := DefaultResource.DeepCopy()
-	// Tests that the component descriptor exists for root items.
 	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Annotations = map[string]string{v1alpha1.SuspendAnnotation: "true"}
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
 
-	t.Log("setting up component version")
 	cv := DefaultComponent.DeepCopy()
-	cd := DefaultComponentDescriptor.DeepCopy()
-	cv.Status.ComponentDescriptor = v1alpha1.Reference{
-		Name:    resource.Spec.SourceRef.Name,
-		Version: resource.Spec.SourceRef.GetVersion(),
-		ComponentDescriptorRef: meta.NamespacedObjectReference{
-			Name:      cd.Name,
-			Namespace: cd.Namespace,
-		},
-	}
-	conditions.MarkTrue(cv,
-		meta.ReadyCondition,
-		meta.SucceededReason,
-		"Applied version: 1.0.0")
+	cd := withComponentDescriptor(cv)
+	conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: 1.0.0")
 
-	client := env.FakeKubeClient(WithObjects(cv, resource, cd))
-	t.Log("priming fake cache")
+	client := env.FakeKubeClient(WithObjects(cv, cd, resource))
 	cache := &cachefakes.FakeCache{}
-	cache.PushDataReturns("", errors.New("nope"))
+	ocmClient := &fakes.MockFetcher{}
 
-	t.Log("priming fake ocm client")
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+		OCMClient:     ocmClient,
+	}
+
+	reconcile := func() {
+		_, err := rr.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+			},
+		})
+		require.NoError(t, err)
+
+		err = client.Get(context.Background(), types.NamespacedName{
+			Name:      resource.Name,
+			Namespace: resource.Namespace,
+		}, resource)
+		require.NoError(t, err)
+	}
+
+	reconcile()
+
+	t.Log("marking the resource as suspended without touching the registry")
+	assert.True(t, conditions.IsTrue(resource, v1alpha1.SuspendedCondition))
+	assert.Equal(t, v1alpha1.SuspendedReason, conditions.GetReason(resource, v1alpha1.SuspendedCondition))
+	assert.True(t, cache.IsCachedWasNotCalled())
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	t.Log("resuming the resource once the annotation is removed")
+	resource.Annotations = nil
+	require.NoError(t, client.Update(context.Background(), resource))
+
+	reconcile()
+
+	assert.False(t, conditions.Has(resource, v1alpha1.SuspendedCondition))
+	// The fetcher isn't set up to return the resource, failing to get it shows that it was requested.
+	assert.Equal(t, v1alpha1.GetResourceFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+func TestSuspendAnnotationChangedPredicate(t *testing.T) {
+	suspended := DefaultResource.DeepCopy()
+	suspended.Annotations = map[string]string{v1alpha1.SuspendAnnotation: "true"}
+	resumed := DefaultResource.DeepCopy()
+
+	assert.True(t, SuspendAnnotationChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: resumed, ObjectNew: suspended}))
+	assert.True(t, SuspendAnnotationChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: suspended, ObjectNew: resumed}))
+	assert.False(t, SuspendAnnotationChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: suspended, ObjectNew: suspended}))
+}
+
+func TestResourceReconcilerDelete(t *testing.T) {
+	t.Log("setting up a deleted resource object with an existing snapshot")
+	resource := DefaultResource.DeepCopy()
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Finalizers = []string{resourceFinalizer}
+	resource.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	snapshot := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resource.Status.SnapshotName,
+			Namespace: resource.Namespace,
+		},
+	}
+
+	client := env.FakeKubeClient(WithObjects(resource, snapshot))
 	ocmClient := &fakes.MockFetcher{}
-	ocmClient.GetResourceReturns(nil, "nil", errors.New("nope"))
 
 	rr := ResourceReconciler{
 		Scheme:        env.scheme,
 		Client:        client,
 		OCMClient:     ocmClient,
 		EventRecorder: record.NewFakeRecorder(32),
-		Cache:         cache,
+		Cache:         &cachefakes.FakeCache{},
 	}
 
 	t.Log("calling reconcile on resource controller")
@@ -162,27 +2541,37 @@ func XTestResourceReconcilerFailed(t *testing.T) {
 			Name:      resource.Name,
 		},
 	})
-	assert.EqualError(t, err, "failed to get resource: nope")
-	t.Log("verifying updated resource object status")
+	require.NoError(t, err)
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	t.Log("verifying that the snapshot has been deleted")
+	err = client.Get(context.Background(), types.NamespacedName{
+		Name:      snapshot.Name,
+		Namespace: snapshot.Namespace,
+	}, snapshot)
+	assert.True(t, apierrors.IsNotFound(err))
+
+	t.Log("verifying that the resource is gone once the finalizer has been removed")
 	err = client.Get(context.Background(), types.NamespacedName{
 		Name:      resource.Name,
 		Namespace: resource.Namespace,
 	}, resource)
-
-	require.NoError(t, err)
-	assert.True(t, conditions.IsFalse(resource, meta.ReadyCondition))
+	assert.True(t, apierrors.IsNotFound(err))
 }
 
-// TODO: rewrite these so that they test the predicate functions.
-func XTestResourceShouldReconcile(t *testing.T) {
+func TestResourceShouldReconcile(t *testing.T) {
 	testcase := []struct {
 		name             string
 		errStr           string
+		cached           bool
+		requestedAt      string
+		handledAt        string
 		snapshot         func(resource v1alpha1.Resource) *v1alpha1.Snapshot
 		componentVersion func() *v1alpha1.ComponentVersion
 	}{
 		{
-			name: "should not reconcile in case of matching generation and existing snapshot with ready state",
+			name:   "should not reconcile in case of matching generation and existing snapshot with ready state",
+			cached: true,
 			componentVersion: func() *v1alpha1.ComponentVersion {
 				cv := DefaultComponent.DeepCopy()
 				cv.Status.ReconciledVersion = "v0.0.1"
@@ -195,7 +2584,14 @@ func XTestResourceShouldReconcile(t *testing.T) {
 						Name:      resource.Status.SnapshotName,
 						Namespace: resource.Namespace,
 					},
-					Spec:   v1alpha1.SnapshotSpec{},
+					Spec: v1alpha1.SnapshotSpec{
+						Identity: ocmmetav1.Identity{
+							v1alpha1.ResourceNameKey:    resource.Spec.SourceRef.ResourceRef.Name,
+							v1alpha1.ResourceVersionKey: resource.Spec.SourceRef.GetVersion(),
+						},
+						Digest: "digest",
+						Tag:    resource.Spec.SourceRef.GetVersion(),
+					},
 					Status: v1alpha1.SnapshotStatus{},
 				}
 				conditions.MarkTrue(snapshot, meta.ReadyCondition, meta.SucceededReason, "Snapshot with name '%s' is ready", snapshot.Name)
@@ -206,6 +2602,7 @@ func XTestResourceShouldReconcile(t *testing.T) {
 		{
 			name:   "should reconcile if snapshot is not ready",
 			errStr: "failed to get resource: unexpected number of calls; not enough return values have been configured; call count 0",
+			cached: true,
 			componentVersion: func() *v1alpha1.ComponentVersion {
 				cv := DefaultComponent.DeepCopy()
 				cv.Status.ReconciledVersion = "v0.0.1"
@@ -218,7 +2615,9 @@ func XTestResourceShouldReconcile(t *testing.T) {
 						Name:      resource.Status.SnapshotName,
 						Namespace: resource.Namespace,
 					},
-					Spec:   v1alpha1.SnapshotSpec{},
+					Spec: v1alpha1.SnapshotSpec{
+						Digest: "digest",
+					},
 					Status: v1alpha1.SnapshotStatus{},
 				}
 				conditions.MarkFalse(snapshot, meta.ReadyCondition, meta.SucceededReason, "Snapshot with name '%s' is ready", snapshot.Name)
@@ -226,9 +2625,101 @@ func XTestResourceShouldReconcile(t *testing.T) {
 				return snapshot
 			},
 		},
+		{
+			name:   "should reconcile if the snapshot data is missing from the registry",
+			errStr: "failed to get resource: unexpected number of calls; not enough return values have been configured; call count 0",
+			componentVersion: func() *v1alpha1.ComponentVersion {
+				cv := DefaultComponent.DeepCopy()
+				cv.Status.ReconciledVersion = "v0.0.1"
+
+				return cv
+			},
+			snapshot: func(resource v1alpha1.Resource) *v1alpha1.Snapshot {
+				snapshot := &v1alpha1.Snapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resource.Status.SnapshotName,
+						Namespace: resource.Namespace,
+					},
+					Spec: v1alpha1.SnapshotSpec{
+						Digest: "digest",
+						Tag:    resource.Spec.SourceRef.GetVersion(),
+					},
+					Status: v1alpha1.SnapshotStatus{},
+				}
+				conditions.MarkTrue(snapshot, meta.ReadyCondition, meta.SucceededReason, "Snapshot with name '%s' is ready", snapshot.Name)
+
+				return snapshot
+			},
+		},
+		{
+			name:        "should reconcile if a reconcile was requested",
+			errStr:      "failed to get resource: unexpected number of calls; not enough return values have been configured; call count 0",
+			cached:      true,
+			requestedAt: "2023-06-01T10:00:00Z",
+			handledAt:   "2023-05-01T10:00:00Z",
+			componentVersion: func() *v1alpha1.ComponentVersion {
+				cv := DefaultComponent.DeepCopy()
+				cv.Status.ReconciledVersion = "v0.0.1"
+
+				return cv
+			},
+			snapshot: func(resource v1alpha1.Resource) *v1alpha1.Snapshot {
+				snapshot := &v1alpha1.Snapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resource.Status.SnapshotName,
+						Namespace: resource.Namespace,
+					},
+					Spec: v1alpha1.SnapshotSpec{
+						Identity: ocmmetav1.Identity{
+							v1alpha1.ResourceNameKey:    resource.Spec.SourceRef.ResourceRef.Name,
+							v1alpha1.ResourceVersionKey: resource.Spec.SourceRef.GetVersion(),
+						},
+						Digest: "digest",
+						Tag:    resource.Spec.SourceRef.GetVersion(),
+					},
+					Status: v1alpha1.SnapshotStatus{},
+				}
+				conditions.MarkTrue(snapshot, meta.ReadyCondition, meta.SucceededReason, "Snapshot with name '%s' is ready", snapshot.Name)
+
+				return snapshot
+			},
+		},
+		{
+			name:        "should not reconcile if the reconcile request has been handled",
+			cached:      true,
+			requestedAt: "2023-06-01T10:00:00Z",
+			handledAt:   "2023-06-01T10:00:00Z",
+			componentVersion: func() *v1alpha1.ComponentVersion {
+				cv := DefaultComponent.DeepCopy()
+				cv.Status.ReconciledVersion = "v0.0.1"
+
+				return cv
+			},
+			snapshot: func(resource v1alpha1.Resource) *v1alpha1.Snapshot {
+				snapshot := &v1alpha1.Snapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resource.Status.SnapshotName,
+						Namespace: resource.Namespace,
+					},
+					Spec: v1alpha1.SnapshotSpec{
+						Identity: ocmmetav1.Identity{
+							v1alpha1.ResourceNameKey:    resource.Spec.SourceRef.ResourceRef.Name,
+							v1alpha1.ResourceVersionKey: resource.Spec.SourceRef.GetVersion(),
+						},
+						Digest: "digest",
+						Tag:    resource.Spec.SourceRef.GetVersion(),
+					},
+					Status: v1alpha1.SnapshotStatus{},
+				}
+				conditions.MarkTrue(snapshot, meta.ReadyCondition, meta.SucceededReason, "Snapshot with name '%s' is ready", snapshot.Name)
+
+				return snapshot
+			},
+		},
 		{
 			name:   "should reconcile if component version doesn't match",
 			errStr: "failed to get resource: unexpected number of calls; not enough return values have been configured; call count 0",
+			cached: true,
 			componentVersion: func() *v1alpha1.ComponentVersion {
 				cv := DefaultComponent.DeepCopy()
 				cv.Status.ReconciledVersion = "v0.0.2"
@@ -243,18 +2734,27 @@ func XTestResourceShouldReconcile(t *testing.T) {
 
 	for i, tt := range testcase {
 		t.Run(fmt.Sprintf("%d: %s", i, tt.name), func(t *testing.T) {
-			// We don't set a source because it shouldn't get that far.
 			resource := DefaultResource.DeepCopy()
+			resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+			resource.Status.SnapshotName = "test-resource-lmt3orf"
 			resource.Status.LastAppliedComponentVersion = "v0.0.1"
+			resource.Status.LastAppliedResourceVersion = resource.Spec.SourceRef.GetVersion()
+			resource.Status.LastHandledReconcileAt = tt.handledAt
+			if tt.requestedAt != "" {
+				resource.Annotations = map[string]string{v1alpha1.ReconcileRequestAnnotation: tt.requestedAt}
+			}
 			snapshot := tt.snapshot(*resource)
 			cv := tt.componentVersion()
+			cd := withComponentDescriptor(cv)
+			conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: %s", cv.Status.ReconciledVersion)
 
-			objs := []client.Object{cv, resource}
+			objs := []client.Object{cv, cd, resource}
 			if snapshot != nil {
 				objs = append(objs, snapshot)
 			}
 			client := env.FakeKubeClient(WithObjects(objs...))
 			cache := &cachefakes.FakeCache{}
+			cache.IsCachedReturns(tt.cached, nil)
 			fakeOcm := &fakes.MockFetcher{}
 
 			rr := ResourceReconciler{
@@ -272,15 +2772,296 @@ func XTestResourceShouldReconcile(t *testing.T) {
 				},
 			})
 
+			require.NoError(t, err)
 			if tt.errStr == "" {
-				require.NoError(t, err)
-				assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter()}, result)
+				assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
 				assert.True(t, cache.FetchDataByDigestWasNotCalled())
 				assert.True(t, cache.PushDataWasNotCalled())
 				assert.True(t, fakeOcm.GetResourceWasNotCalled())
 			} else {
-				assert.EqualError(t, err, tt.errStr)
+				err = client.Get(context.Background(), types.NamespacedName{
+					Name:      resource.Name,
+					Namespace: resource.Namespace,
+				}, resource)
+				require.NoError(t, err)
+				assert.Equal(t, v1alpha1.GetResourceFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+				assert.Contains(t, conditions.GetMessage(resource, meta.ReadyCondition), tt.errStr)
+			}
+
+			if tt.requestedAt != "" {
+				err = client.Get(context.Background(), types.NamespacedName{
+					Name:      resource.Name,
+					Namespace: resource.Namespace,
+				}, resource)
+				require.NoError(t, err)
+				assert.Equal(t, tt.requestedAt, resource.Status.LastHandledReconcileAt)
 			}
 		})
 	}
 }
+
+func TestResourceReconcilerUpdatePolicyOnNewVersion(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Spec.UpdatePolicy = v1alpha1.UpdatePolicyOnNewVersion
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Status.LastAppliedComponentVersion = "v0.0.1"
+	resource.Status.LastAppliedResourceVersion = resource.Spec.SourceRef.GetVersion()
+
+	snapshot := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resource.Status.SnapshotName,
+			Namespace: resource.Namespace,
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Digest: "digest",
+			Tag:    resource.Spec.SourceRef.GetVersion(),
+		},
+	}
+
+	cv := DefaultComponent.DeepCopy()
+	cd := withComponentDescriptor(cv)
+	cv.Status.ReconciledVersion = "v0.0.1"
+	conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: %s", cv.Status.ReconciledVersion)
+
+	client := env.FakeKubeClient(WithObjects(cv, cd, resource, snapshot))
+	cache := &cachefakes.FakeCache{}
+	ocmClient := &fakes.MockFetcher{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         cache,
+	}
+
+	reconcile := func() ctrl.Result {
+		result, err := rr.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: resource.Namespace,
+				Name:      resource.Name,
+			},
+		})
+		require.NoError(t, err)
+
+		err = client.Get(context.Background(), types.NamespacedName{
+			Name:      resource.Name,
+			Namespace: resource.Namespace,
+		}, resource)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	t.Log("skipping the snapshot without accessing the registry while the version is unchanged")
+	result := reconcile()
+	assert.Equal(t, ctrl.Result{RequeueAfter: resource.GetRequeueAfter(v1alpha1.DefaultResourceInterval)}, result)
+	assert.True(t, conditions.IsReady(resource))
+	assert.True(t, cache.IsCachedWasNotCalled())
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+
+	t.Log("fetching the resource once a new component version is reconciled")
+	cv.Status.ReconciledVersion = "v0.0.2"
+	require.NoError(t, client.Status().Update(context.Background(), cv))
+
+	reconcile()
+
+	// The fetcher isn't set up to return the resource, failing to get it shows that it was requested.
+	assert.Equal(t, v1alpha1.GetResourceFailedReason, conditions.GetReason(resource, meta.ReadyCondition))
+}
+
+// indexedResources returns a client with Resources referencing the ComponentVersion cv, as well as additional
+// Resources referencing other ComponentVersions, indexed by their source reference.
+// withComponentDescriptor points the ComponentVersion at a copy of the default component descriptor and returns it.
+func withComponentDescriptor(cv *v1alpha1.ComponentVersion) *v1alpha1.ComponentDescriptor {
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    cv.Spec.Component,
+		Version: cv.Status.ReconciledVersion,
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+
+	return cd
+}
+
+func indexedResources(tb testing.TB, matching, other int) client.Client {
+	tb.Helper()
+
+	var objs []client.Object
+	for i := 0; i < matching+other; i++ {
+		res := DefaultResource.DeepCopy()
+		res.Name = fmt.Sprintf("resource-%d", i)
+		if i >= matching {
+			res.Namespace = fmt.Sprintf("namespace-%d", i%50)
+			res.Spec.SourceRef.Name = fmt.Sprintf("component-%d", i%100)
+		}
+
+		objs = append(objs, res)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(env.scheme).
+		WithObjects(objs...).
+		WithIndex(&v1alpha1.Resource{}, sourceRefIndexKey, indexResourceSourceRef).
+		Build()
+}
+
+func TestResourceReconcilerFindObjects(t *testing.T) {
+	cv := DefaultComponent.DeepCopy()
+
+	crossNamespace := DefaultResource.DeepCopy()
+	crossNamespace.Name = "cross-namespace"
+	crossNamespace.Namespace = "other"
+	crossNamespace.Spec.SourceRef.Namespace = cv.Namespace
+
+	// without a namespace the source reference is in the namespace of the Resource.
+	defaultNamespace := DefaultResource.DeepCopy()
+	defaultNamespace.Name = "default-namespace"
+	defaultNamespace.Spec.SourceRef.Namespace = ""
+
+	otherComponent := DefaultResource.DeepCopy()
+	otherComponent.Name = "other-component"
+	otherComponent.Spec.SourceRef.Name = "other-component"
+
+	sameNameOtherNamespace := DefaultResource.DeepCopy()
+	sameNameOtherNamespace.Name = "same-name"
+	sameNameOtherNamespace.Namespace = "other"
+	sameNameOtherNamespace.Spec.SourceRef.Namespace = ""
+
+	rr := ResourceReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(env.scheme).
+			WithObjects(DefaultResource.DeepCopy(), crossNamespace, defaultNamespace, otherComponent, sameNameOtherNamespace).
+			WithIndex(&v1alpha1.Resource{}, sourceRefIndexKey, indexResourceSourceRef).
+			Build(),
+	}
+
+	expected := []ctrl.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: DefaultResource.Name}},
+		{NamespacedName: types.NamespacedName{Namespace: "other", Name: "cross-namespace"}},
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "default-namespace"}},
+	}
+
+	t.Log("mapping a ComponentVersion to the Resources referencing it")
+	assert.ElementsMatch(t, expected, rr.findObjects(sourceRefIndexKey)(cv))
+
+	t.Log("mapping a ComponentDescriptor to the Resources referencing its owners once")
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cd.OwnerReferences = []metav1.OwnerReference{
+		{Kind: v1alpha1.ComponentVersionKind, Name: cv.Name},
+		{Kind: v1alpha1.ComponentVersionKind, Name: cv.Name},
+		{Kind: "ConfigMap", Name: "other-component"},
+	}
+	assert.ElementsMatch(t, expected, rr.findObjectsForComponentDescriptor(sourceRefIndexKey)(cd))
+}
+
+func TestResourceReconcilerFindObjectsForSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}
+
+	withSecret := DefaultResource.DeepCopy()
+	withSecret.Name = "with-secret"
+	withSecret.Spec.SecretRef = &corev1.LocalObjectReference{Name: secret.Name}
+
+	// the Secret is looked up in the namespace of the Resource, not in the namespace of its source reference.
+	otherNamespace := withSecret.DeepCopy()
+	otherNamespace.Namespace = "other"
+	otherNamespace.Spec.SourceRef.Namespace = "default"
+
+	otherSecret := DefaultResource.DeepCopy()
+	otherSecret.Name = "other-secret"
+	otherSecret.Spec.SecretRef = &corev1.LocalObjectReference{Name: "other-credentials"}
+
+	rr := ResourceReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(env.scheme).
+			WithObjects(DefaultResource.DeepCopy(), withSecret, otherNamespace, otherSecret).
+			WithIndex(&v1alpha1.Resource{}, secretRefIndexKey, indexResourceSecretRef).
+			Build(),
+	}
+
+	t.Log("mapping a Secret to the Resources taking their credentials from it")
+	assert.ElementsMatch(t, []ctrl.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "with-secret"}},
+	}, rr.findObjectsForSecret(secretRefIndexKey)(secret))
+
+	t.Log("only triggering on changes of the credentials")
+	rotated := secret.DeepCopy()
+	rotated.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"ghcr.io":{}}}`)
+	relabeled := secret.DeepCopy()
+	relabeled.Labels = map[string]string{"app": "ocm"}
+
+	p := SecretDataChangedPredicate{}
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: rotated}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: relabeled}))
+	assert.True(t, p.Create(event.CreateEvent{Object: secret}))
+}
+
+func BenchmarkResourceReconcilerFindObjects(b *testing.B) {
+	rr := ResourceReconciler{
+		Client: indexedResources(b, 10, 5000),
+	}
+	mapFunc := rr.findObjectsForComponentDescriptor(sourceRefIndexKey)
+
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cd.OwnerReferences = []metav1.OwnerReference{{Kind: v1alpha1.ComponentVersionKind, Name: DefaultComponent.Name}}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if requests := mapFunc(cd); len(requests) != 10 {
+			b.Fatalf("expected 10 requests, got %d", len(requests))
+		}
+	}
+}
+
+func TestCachedSnapshotIdentity(t *testing.T) {
+	obj := DefaultResource.DeepCopy()
+	obj.UID, obj.Generation = "resource-uid", 1
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cd.UID, cd.Generation = "descriptor-uid", 1
+	ref := obj.GetElementRef()
+
+	var identities snapshotIdentityCache
+	identity, err := identities.get(obj, cd, ref, "v0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, cd.Spec.Version, identity[v1alpha1.ComponentVersionKey])
+
+	t.Log("returning a copy of the cached identity")
+	identity["modified"] = "true"
+	cached, err := identities.get(obj, cd, ref, "v0.0.1")
+	require.NoError(t, err)
+	assert.NotContains(t, cached, "modified")
+
+	t.Log("computing the identity again for a new generation of the component descriptor")
+	cd.Generation++
+	cd.Spec.Version = "v0.0.2"
+	identity, err = identities.get(obj, cd, ref, "v0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.2", identity[v1alpha1.ComponentVersionKey])
+
+	t.Log("computing the identity again for a new generation of the Resource")
+	obj.Generation++
+	obj.Spec.Platform = "linux/arm64"
+	identity, err = identities.get(obj, cd, ref, "v0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "linux/arm64", identity[v1alpha1.ResourcePlatformKey])
+
+	t.Log("keeping the identities of different resources apart")
+	other := ref.DeepCopy()
+	other.Name = "other-resource"
+	identity, err = identities.get(obj, cd, other, "v0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "other-resource", identity[v1alpha1.ResourceNameKey])
+
+	t.Log("not caching objects without generation")
+	assert.Empty(t, snapshotIdentityKey(DefaultResource.DeepCopy(), cd, ref, "v0.0.1"))
+}
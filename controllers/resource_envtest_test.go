@@ -0,0 +1,264 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ocmcore "github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+	"github.com/open-component-model/ocm-controller/pkg/oci"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+)
+
+// startEnvtest starts an API server with the CRDs of the controller and returns a client of it. The test is
+// skipped unless the envtest binaries are installed, make test downloads them and sets KUBEBUILDER_ASSETS.
+func startEnvtest(t *testing.T) client.Client {
+	t.Helper()
+
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS isn't set, run make test to run the tests against an API server")
+	}
+
+	testEnvironment := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+
+	cfg, err := testEnvironment.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testEnvironment.Stop())
+	})
+
+	kubeClient, err := client.New(cfg, client.Options{Scheme: env.scheme})
+	require.NoError(t, err)
+
+	return kubeClient
+}
+
+// fakeContextClient fetches components from a fake OCM context instead of the repository of the ComponentVersion.
+// Everything else, reading the component descriptors and pushing the resources to the registry, is done by the
+// real client.
+type fakeContextClient struct {
+	*ocm.Client
+	octx ocmcore.Context
+}
+
+func (c *fakeContextClient) CreateAuthenticatedOCMContext(context.Context, *v1alpha1.ComponentVersion) (ocmcore.Context, error) {
+	return c.octx, nil
+}
+
+// reconcileResource reconciles the Resource until it isn't requeued immediately anymore, for example after its
+// snapshot name has been generated.
+func reconcileResource(t *testing.T, rr *ResourceReconciler, resource *v1alpha1.Resource) ctrl.Result {
+	t.Helper()
+
+	for i := 0; i < 3; i++ {
+		result, err := rr.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(resource)})
+		require.NoError(t, err)
+
+		if !result.Requeue {
+			return result
+		}
+	}
+
+	t.Fatalf("resource %s is still requeued after 3 reconciliations", resource.Name)
+
+	return ctrl.Result{}
+}
+
+func TestResourceReconcilerEnvtest(t *testing.T) {
+	kubeClient := startEnvtest(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+
+	snapshotCache := oci.NewClient(strings.TrimPrefix(server.URL, "http://"), oci.WithInsecureSkipVerify(true))
+
+	octx := fakeocm.NewFakeOCMContext()
+	comp := &fakeocm.Component{
+		Name:    DefaultComponent.Spec.Component,
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:      "manifests",
+		Version:   "1.0.0",
+		Data:      []byte("kind: ConfigMap"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+	})
+	require.NoError(t, octx.AddComponent(comp))
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-component-descriptor",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    "manifests",
+							Version: "1.0.0",
+						},
+						Type:     "ociBlob",
+						Relation: "local",
+						// The API server requires an access, the data is fetched from the fake OCM context.
+						Access: &ocmruntime.UnstructuredTypedObject{
+							Object: map[string]interface{}{
+								"localReference": "manifests",
+								"mediaType":      "application/x-yaml",
+								"type":           "localBlob",
+							},
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+	require.NoError(t, kubeClient.Create(ctx, cd))
+
+	cv := DefaultComponent.DeepCopy()
+	require.NoError(t, kubeClient.Create(ctx, cv))
+	cv.Status.ReconciledVersion = "v0.0.1"
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    cv.Spec.Component,
+		Version: "v0.0.1",
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: v0.0.1")
+	require.NoError(t, kubeClient.Status().Update(ctx, cv))
+
+	rr := &ResourceReconciler{
+		Client:        kubeClient,
+		Scheme:        env.scheme,
+		EventRecorder: record.NewFakeRecorder(32),
+		OCMClient:     &fakeContextClient{Client: ocm.NewClient(kubeClient, snapshotCache), octx: octx},
+		Cache:         snapshotCache,
+	}
+
+	t.Log("writing the resource to a Snapshot and the registry")
+	resource := &v1alpha1.Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "manifests",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ResourceSpec{
+			Interval: metav1.Duration{Duration: v1alpha1.DefaultResourceInterval},
+			SourceRef: v1alpha1.ObjectReference{
+				NamespacedObjectKindReference: meta.NamespacedObjectKindReference{
+					Kind:      v1alpha1.ComponentVersionKind,
+					Name:      cv.Name,
+					Namespace: cv.Namespace,
+				},
+				ResourceRef: &v1alpha1.ResourceReference{
+					ElementMeta: v1alpha1.ElementMeta{
+						Name:    "manifests",
+						Version: "1.0.0",
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, kubeClient.Create(ctx, resource))
+
+	result := reconcileResource(t, rr, resource)
+	assert.Equal(t, v1alpha1.DefaultResourceInterval, result.RequeueAfter)
+
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(resource), resource))
+	require.True(t, conditions.IsReady(resource), conditions.GetMessage(resource, meta.ReadyCondition))
+	assert.Equal(t, resource.Generation, resource.Status.ObservedGeneration)
+	assert.Equal(t, "1.0.0", resource.Status.LastAppliedResourceVersion)
+	assert.Equal(t, "v0.0.1", resource.Status.LastAppliedComponentVersion)
+	assert.Equal(t, cd.Name, resource.Status.LastAppliedComponentDescriptor.Name)
+	assert.NotEmpty(t, resource.Status.LatestSnapshotDigest)
+	require.NotEmpty(t, resource.Status.SnapshotName)
+
+	snapshot := &v1alpha1.Snapshot{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKey{Namespace: resource.Namespace, Name: resource.Status.SnapshotName}, snapshot))
+	assert.Equal(t, resource.Status.LatestSnapshotDigest, snapshot.Spec.Digest)
+	assert.Equal(t, "1.0.0", snapshot.Spec.Tag)
+	assert.Equal(t, "manifests", snapshot.Spec.Identity[v1alpha1.ResourceNameKey])
+	assert.Equal(t, "default", snapshot.Spec.Identity[v1alpha1.SnapshotNamespaceKey])
+	require.Len(t, snapshot.OwnerReferences, 1)
+	assert.Equal(t, resource.UID, snapshot.OwnerReferences[0].UID)
+
+	name, err := ocm.ConstructRepositoryName(snapshot.Spec.Identity)
+	require.NoError(t, err)
+	cached, err := snapshotCache.IsCached(ctx, name, snapshot.Spec.Tag)
+	require.NoError(t, err)
+	assert.True(t, cached, "the snapshot should have been pushed to the registry")
+
+	reader, err := snapshotCache.FetchDataByDigest(ctx, name, snapshot.Spec.Digest)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "kind: ConfigMap", string(content))
+
+	t.Log("requeueing a Resource whose component descriptor hasn't been created")
+	missing := DefaultComponent.DeepCopy()
+	missing.Name = "missing-descriptor"
+	require.NoError(t, kubeClient.Create(ctx, missing))
+	missing.Status.ReconciledVersion = "v0.0.1"
+	missing.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    missing.Spec.Component,
+		Version: "v0.0.1",
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      "not-created-yet",
+			Namespace: missing.Namespace,
+		},
+	}
+	conditions.MarkTrue(missing, meta.ReadyCondition, meta.SucceededReason, "Applied version: v0.0.1")
+	require.NoError(t, kubeClient.Status().Update(ctx, missing))
+
+	waiting := resource.DeepCopy()
+	waiting.ObjectMeta = metav1.ObjectMeta{Name: "waiting", Namespace: "default"}
+	waiting.Spec.SourceRef.Name = missing.Name
+	waiting.Status = v1alpha1.ResourceStatus{}
+	require.NoError(t, kubeClient.Create(ctx, waiting))
+
+	result = reconcileResource(t, rr, waiting)
+	assert.Equal(t, v1alpha1.DefaultResourceInterval, result.RequeueAfter)
+
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(waiting), waiting))
+	assert.True(t, conditions.IsFalse(waiting, meta.ReadyCondition))
+	assert.Equal(t, v1alpha1.ComponentDescriptorNotCreatedReason, conditions.GetReason(waiting, meta.ReadyCondition))
+	assert.NotNil(t, waiting.Status.ComponentDescriptorWaitStart)
+	assert.Empty(t, waiting.Status.LatestSnapshotDigest)
+
+	snapshots := &v1alpha1.SnapshotList{}
+	require.NoError(t, kubeClient.List(ctx, snapshots, client.InNamespace("default")))
+	assert.Len(t, snapshots.Items, 1, "no Snapshot should have been written for the waiting Resource")
+}
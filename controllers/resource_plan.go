@@ -0,0 +1,319 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	ocmcore "github.com/open-component-model/ocm/pkg/contexts/ocm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/component"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+)
+
+// ResourcePlanPath is the path the plan endpoint of the Resource controller is served at, followed by the
+// namespace and name of the Resource.
+const ResourcePlanPath = "/debug/resources/"
+
+// DefaultResourcePlanConcurrency is the number of plans the plan endpoint resolves in parallel by default.
+const DefaultResourcePlanConcurrency = 2
+
+// ResourcePlan is the plan of a Resource as the controller resolves it, returned by the plan endpoint.
+type ResourcePlan struct {
+	// Namespace and Name identify the Resource.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// ComponentVersion is the ComponentVersion the resources are fetched from, in the form namespace/name.
+	ComponentVersion string `json:"componentVersion"`
+	// Resources are the plans of the resources of the Resource, one for each resource selected by Spec.Resources.
+	Resources []ResourcePlanEntry `json:"resources,omitempty"`
+	// Ready is the status of the Ready condition of the Resource.
+	Ready string `json:"ready,omitempty"`
+	// LastError is the message of the Ready condition if the last reconciliation failed.
+	LastError string `json:"lastError,omitempty"`
+	// Error is the reason the plan couldn't be resolved for any resource, for example a ComponentVersion that
+	// isn't ready.
+	Error string `json:"error,omitempty"`
+}
+
+// ResourcePlanEntry is the plan of a single resource of a Resource.
+type ResourcePlanEntry struct {
+	// Resource is the name of the resource.
+	Resource string `json:"resource"`
+	// ComponentDescriptor is the ComponentDescriptor the resource matched, in the form namespace/name.
+	ComponentDescriptor string `json:"componentDescriptor,omitempty"`
+	// ComponentVersion is the version of the component the resource belongs to.
+	ComponentVersion string `json:"componentVersion,omitempty"`
+	// SourceDigest is the digest the resource data would be pushed with, the same as the Snapshot digest once it is
+	// pushed. Unless the plan is resolved, it is the digest of the data last pushed to the Snapshot.
+	SourceDigest string `json:"sourceDigest,omitempty"`
+	// SnapshotName is the name of the Snapshot the data is written to.
+	SnapshotName string `json:"snapshotName,omitempty"`
+	// SnapshotRef is the reference the data would be pushed to. A tagless snapshot only has a repository, its
+	// manifest digest is only known once it has been pushed.
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+	// Error is the reason the plan of the resource couldn't be resolved completely.
+	Error string `json:"error,omitempty"`
+}
+
+// ResolvePlan resolves the plan of the Resource with the given key the way a reconciliation would, without writing
+// anything: the resources are matched against the component descriptors and, if resolve is set, fetched to compute
+// their digest, but neither pushed nor verified, and the Resource and its Snapshots are left as they are. Without
+// resolve, the digests are taken from the status of the Resource. A plan is returned for every Resource that
+// exists, failures to resolve it are recorded in the plan. The returned error is not found if the Resource doesn't
+// exist.
+func (r *ResourceReconciler) ResolvePlan(ctx context.Context, key types.NamespacedName, resolve bool) (*ResourcePlan, error) {
+	obj := &v1alpha1.Resource{}
+	if err := r.Get(ctx, key, obj); err != nil {
+		return nil, err
+	}
+
+	if obj.Spec.SourceRef.Namespace == "" {
+		obj.Spec.SourceRef.Namespace = obj.GetNamespace()
+	}
+
+	plan := &ResourcePlan{
+		Namespace:        obj.Namespace,
+		Name:             obj.Name,
+		ComponentVersion: obj.Spec.SourceRef.GetNamespacedName(),
+	}
+
+	if ready := conditions.Get(obj, meta.ReadyCondition); ready != nil {
+		plan.Ready = string(ready.Status)
+		if !conditions.IsReady(obj) {
+			plan.LastError = ready.Message
+		}
+	}
+
+	cv := &v1alpha1.ComponentVersion{}
+	if err := r.Get(ctx, obj.Spec.SourceRef.GetObjectKey(), cv); err != nil {
+		plan.Error = fmt.Sprintf("failed to get component version: %s", err)
+
+		return plan, nil
+	}
+
+	if !conditions.IsReady(cv) {
+		plan.Error = "component version not ready yet"
+
+		return plan, nil
+	}
+
+	// The OCM context is only needed to fetch the resources.
+	var octx ocmcore.Context
+	if resolve {
+		var err error
+		if octx, err = r.OCMClient.CreateAuthenticatedOCMContext(ctx, cv); err != nil {
+			plan.Error = fmt.Sprintf("failed to create authenticated client: %s", err)
+
+			return plan, nil
+		}
+
+		if obj.Spec.SecretRef != nil {
+			if err := ocm.ConfigureDockerConfigCredentials(ctx, octx, r.Client, obj.Spec.SecretRef.Name, obj.GetNamespace()); err != nil {
+				plan.Error = fmt.Sprintf("failed to configure credentials for resource: %s", err)
+
+				return plan, nil
+			}
+		}
+	}
+
+	// The Snapshot name is only generated by the first reconciliation, the name of the template is used until then.
+	snapshotName := obj.GetSnapshotName()
+	if snapshotName == "" {
+		snapshotName = obj.GetSnapshotTemplateName()
+	}
+
+	if ref := obj.GetElementRef(); ref != nil && len(obj.Spec.Resources) == 0 {
+		plan.Resources = append(plan.Resources, r.resolvePlanEntry(ctx, octx, obj, cv, ref, snapshotName))
+
+		return plan, nil
+	}
+
+	for _, selector := range obj.Spec.Resources {
+		ref := selector.ResourceReference
+		name := ""
+		if snapshotName != "" {
+			name = snapshotName + "-" + ref.Name
+		}
+
+		plan.Resources = append(plan.Resources, r.resolvePlanEntry(ctx, octx, obj, cv, &ref, name))
+	}
+
+	return plan, nil
+}
+
+// resolvePlanEntry resolves the plan of the resource referenced by ref, which is written to the Snapshot with the
+// given name. The digest of the resource is only computed if an OCM context is given, it is taken from the status of
+// the Resource otherwise.
+func (r *ResourceReconciler) resolvePlanEntry(
+	ctx context.Context,
+	octx ocmcore.Context,
+	obj *v1alpha1.Resource,
+	cv *v1alpha1.ComponentVersion,
+	ref *v1alpha1.ResourceReference,
+	snapshotName string,
+) ResourcePlanEntry {
+	entry := ResourcePlanEntry{
+		Resource:     ref.Name,
+		SnapshotName: snapshotName,
+	}
+
+	descriptor, err := component.GetComponentDescriptor(ctx, r.Client, ref.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		entry.Error = fmt.Sprintf("failed to get component descriptor for resource: %s", err)
+
+		return entry
+	}
+
+	if descriptor == nil {
+		entry.Error = fmt.Sprintf("couldn't find component descriptor for reference '%s' or any root components", ref.ReferencePath)
+
+		return entry
+	}
+
+	entry.ComponentDescriptor = descriptor.Namespace + "/" + descriptor.Name
+	entry.ComponentVersion = descriptor.Spec.Version
+
+	digest := pushedDigest(obj, ref.Name)
+	if octx != nil {
+		var err error
+		if digest, err = r.OCMClient.GetResourceDigest(ctx, octx, cv, ref, getResourceOptions(obj)...); err != nil {
+			entry.Error = fmt.Sprintf("failed to get resource digest: %s", err)
+
+			return entry
+		}
+	}
+
+	entry.SourceDigest = digest
+
+	version := "latest"
+	if ref.Version != "" {
+		version = ref.Version
+	}
+
+	identity, err := r.snapshotIdentity(ctx, obj, cv, ref, snapshotName, version)
+	if err != nil {
+		entry.Error = err.Error()
+
+		return entry
+	}
+
+	repository, err := r.RepositoryNamer.RepositoryName(identity)
+	if err != nil {
+		entry.Error = fmt.Sprintf("failed to construct repository name: %s", err)
+
+		return entry
+	}
+
+	tag, err := ocm.SnapshotTag(version)
+	if err != nil {
+		entry.Error = err.Error()
+
+		return entry
+	}
+
+	switch {
+	case obj.IsSnapshotTagless():
+		entry.SnapshotRef = repository
+	case obj.Spec.SnapshotTemplate != nil && obj.Spec.SnapshotTemplate.TagFromDigest:
+		// The tag is only known once the digest is.
+		if digest != "" {
+			entry.SnapshotRef = snapshotReference(repository, digestTag(digest))
+		}
+	default:
+		entry.SnapshotRef = snapshotReference(repository, tag)
+	}
+
+	return entry
+}
+
+// pushedDigest returns the digest of the data last pushed for the resource with the given name, as recorded in the
+// status of the Resource.
+func pushedDigest(obj *v1alpha1.Resource, name string) string {
+	if len(obj.Spec.Resources) == 0 {
+		return obj.Status.LatestSnapshotDigest
+	}
+
+	for _, resource := range obj.Status.Resources {
+		if resource.Name == name {
+			return resource.Digest
+		}
+	}
+
+	return ""
+}
+
+// PlanHandler returns the handler of the plan endpoint. A GET request for ResourcePlanPath followed by
+// <namespace>/<name> returns the plan of that Resource as JSON, resolved with ResolvePlan. The resources are only
+// fetched to compute their digests with the query ?resolve=true, at most maxConcurrent of those requests are served
+// at a time and further ones are answered with 429 Too Many Requests. maxConcurrent defaults to
+// DefaultResourcePlanConcurrency. The endpoint isn't authenticated.
+func (r *ResourceReconciler) PlanHandler(maxConcurrent int) http.Handler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultResourcePlanConcurrency
+	}
+
+	resolving := make(chan struct{}, maxConcurrent)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		namespace, name, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, ResourcePlanPath), "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			http.Error(w, fmt.Sprintf("expected a path of the form %s<namespace>/<name>", ResourcePlanPath), http.StatusNotFound)
+
+			return
+		}
+
+		resolve := req.URL.Query().Get("resolve") == "true"
+		if resolve {
+			select {
+			case resolving <- struct{}{}:
+				defer func() { <-resolving }()
+			default:
+				http.Error(w, "too many plans are being resolved, try again later", http.StatusTooManyRequests)
+
+				return
+			}
+		}
+
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		plan, err := r.ResolvePlan(req.Context(), key, resolve)
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("resource %s not found", key), http.StatusNotFound)
+
+			return
+		}
+
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to get resource %s: %s", key, err), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil && !errors.Is(err, req.Context().Err()) {
+			log.FromContext(req.Context()).Error(err, "failed to write resource plan", "resource", key)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	ocmcore "github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	cachefakes "github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+	"github.com/open-component-model/ocm-controller/pkg/ocm/fakes"
+)
+
+func TestResourceReconcilerPlanHandler(t *testing.T) {
+	t.Log("setting up a resource that failed its last reconciliation")
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+	resource.Status.SnapshotName = "test-resource-lmt3orf"
+	resource.Status.LatestSnapshotDigest = "sha256:pushed"
+	conditions.MarkFalse(resource, meta.ReadyCondition, v1alpha1.GetResourceFailedReason, "failed to get resource: rate limited")
+
+	unready := DefaultResource.DeepCopy()
+	unready.Name = "unready-resource"
+	unready.Spec.SourceRef.Name = "unready-component"
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cd.Spec.Version = "v0.0.1"
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: 1.0.0")
+
+	unreadyCV := DefaultComponent.DeepCopy()
+	unreadyCV.Name = "unready-component"
+
+	client := env.FakeKubeClient(WithObjects(cv, unreadyCV, resource, unready, cd))
+	ocmClient := &fakes.MockFetcher{}
+	ocmClient.GetResourceDigestReturns("sha256:abcdef", nil)
+	store := &cachefakes.FakeCache{}
+
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        client,
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         store,
+	}
+	handler := rr.PlanHandler(0)
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+
+		return recorder
+	}
+
+	identity, err := newSnapshotIdentity(resource, cd, resource.Spec.SourceRef.ResourceRef, "1.0.0")
+	require.NoError(t, err)
+	repository, err := ocm.ConstructRepositoryName(identity)
+	require.NoError(t, err)
+
+	t.Log("taking the digest from the status of the resource unless the plan is resolved")
+	response := get(http.MethodGet, ResourcePlanPath+"default/test-resource")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+	var plan ResourcePlan
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &plan))
+	require.Len(t, plan.Resources, 1)
+	assert.Equal(t, "sha256:pushed", plan.Resources[0].SourceDigest)
+	assert.Equal(t, repository+":1.0.0", plan.Resources[0].SnapshotRef)
+	assert.True(t, ocmClient.GetResourceDigestWasNotCalled(), "the resource shouldn't have been fetched")
+
+	t.Log("resolving the plan of the resource")
+	response = get(http.MethodGet, ResourcePlanPath+"default/test-resource?resolve=true")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	plan = ResourcePlan{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &plan))
+	assert.Equal(t, "default/test-component", plan.ComponentVersion)
+	assert.Equal(t, "False", plan.Ready)
+	assert.Equal(t, "failed to get resource: rate limited", plan.LastError)
+	assert.Empty(t, plan.Error)
+	require.Len(t, plan.Resources, 1)
+	assert.Equal(t, ResourcePlanEntry{
+		Resource:            "introspect-image",
+		ComponentDescriptor: cd.Namespace + "/" + cd.Name,
+		ComponentVersion:    "v0.0.1",
+		SourceDigest:        "sha256:abcdef",
+		SnapshotName:        "test-resource-lmt3orf",
+		SnapshotRef:         repository + ":1.0.0",
+	}, plan.Resources[0])
+
+	t.Log("verifying nothing has been written")
+	assert.True(t, ocmClient.GetResourceWasNotCalled())
+	assert.True(t, store.PushDataWasNotCalled())
+	assert.True(t, store.IsCachedWasNotCalled())
+	stored := &v1alpha1.Resource{}
+	require.NoError(t, client.Get(context.Background(), types.NamespacedName{Namespace: resource.Namespace, Name: resource.Name}, stored))
+	assert.Equal(t, "failed to get resource: rate limited", conditions.GetMessage(stored, meta.ReadyCondition))
+	assert.Equal(t, "sha256:pushed", stored.Status.LatestSnapshotDigest)
+
+	t.Log("recording why the plan can't be resolved")
+	response = get(http.MethodGet, ResourcePlanPath+"default/unready-resource")
+	require.Equal(t, http.StatusOK, response.Code)
+	plan = ResourcePlan{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &plan))
+	assert.Equal(t, "component version not ready yet", plan.Error)
+	assert.Empty(t, plan.Resources)
+
+	t.Log("recording the failure to fetch the resource")
+	ocmClient.GetResourceDigestReturns("", errors.New("registry unavailable"))
+	response = get(http.MethodGet, ResourcePlanPath+"default/test-resource?resolve=true")
+	plan = ResourcePlan{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &plan))
+	require.Len(t, plan.Resources, 1)
+	assert.Equal(t, "failed to get resource digest: registry unavailable", plan.Resources[0].Error)
+	assert.Empty(t, plan.Resources[0].SnapshotRef)
+
+	t.Log("rejecting missing resources, invalid paths and other methods")
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, ResourcePlanPath+"default/missing").Code)
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, ResourcePlanPath+"default").Code)
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, ResourcePlanPath+"default/test-resource/extra").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, get(http.MethodPost, ResourcePlanPath+"default/test-resource").Code)
+}
+
+// blockingFetcher blocks GetResourceDigest until it is released.
+type blockingFetcher struct {
+	*fakes.MockFetcher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingFetcher) GetResourceDigest(
+	ctx context.Context,
+	octx ocmcore.Context,
+	cv *v1alpha1.ComponentVersion,
+	resource *v1alpha1.ResourceReference,
+	opts ...ocm.GetResourceOption,
+) (string, error) {
+	f.started <- struct{}{}
+	<-f.release
+
+	return f.MockFetcher.GetResourceDigest(ctx, octx, cv, resource, opts...)
+}
+
+func TestResourceReconcilerPlanHandlerConcurrency(t *testing.T) {
+	resource := DefaultResource.DeepCopy()
+	resource.Spec.SourceRef.ResourceRef.ReferencePath = nil
+
+	cv := DefaultComponent.DeepCopy()
+	cd := DefaultComponentDescriptor.DeepCopy()
+	cv.Status.ComponentDescriptor = v1alpha1.Reference{
+		Name:    resource.Spec.SourceRef.Name,
+		Version: resource.Spec.SourceRef.GetVersion(),
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+		},
+	}
+	conditions.MarkTrue(cv, meta.ReadyCondition, meta.SucceededReason, "Applied version: 1.0.0")
+
+	ocmClient := &blockingFetcher{
+		MockFetcher: &fakes.MockFetcher{},
+		started:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	rr := ResourceReconciler{
+		Scheme:        env.scheme,
+		Client:        env.FakeKubeClient(WithObjects(cv, resource, cd)),
+		OCMClient:     ocmClient,
+		EventRecorder: record.NewFakeRecorder(32),
+		Cache:         &cachefakes.FakeCache{},
+	}
+	handler := rr.PlanHandler(1)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return recorder
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- get(ResourcePlanPath + "default/test-resource?resolve=true")
+	}()
+	<-ocmClient.started
+
+	t.Log("rejecting plans to resolve while the limit is reached")
+	assert.Equal(t, http.StatusTooManyRequests, get(ResourcePlanPath+"default/test-resource?resolve=true").Code)
+	assert.Equal(t, http.StatusOK, get(ResourcePlanPath+"default/test-resource").Code, "plans from the status aren't limited")
+
+	close(ocmClient.release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+
+	t.Log("resolving plans again once the limit is no longer reached")
+	go func() {
+		<-ocmClient.started
+	}()
+	assert.Equal(t, http.StatusOK, get(ResourcePlanPath+"default/test-resource?resolve=true").Code)
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// SecretDataChangedPredicate filters updates of Secrets that don't change their type or data, like changes of their
+// labels or annotations, so rotating the credentials in a Secret is what triggers the objects referencing it.
+type SecretDataChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (SecretDataChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	oldSecret, ok := e.ObjectOld.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+
+	newSecret, ok := e.ObjectNew.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+
+	// StringData is write-only, the API server merges it into Data and never returns it.
+	return oldSecret.Type != newSecret.Type || !maps.EqualFunc(oldSecret.Data, newSecret.Data, slices.Equal[[]byte])
+}
@@ -8,15 +8,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/patch"
 	rreconcile "github.com/fluxcd/pkg/runtime/reconcile"
-	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/open-component-model/ocm-controller/pkg/cache"
+	"github.com/open-component-model/ocm-controller/pkg/oci"
 	"github.com/open-component-model/ocm-controller/pkg/status"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kuberecorder "k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -31,7 +34,11 @@ import (
 
 const (
 	snapshotFinalizer = "finalizers.snapshot.ocm.software"
-	scheme            = "https"
+	defaultScheme     = "https"
+
+	// snapshotDataRetryInterval is the interval at which a Snapshot whose data isn't in the registry is checked
+	// again.
+	snapshotDataRetryInterval = 10 * time.Second
 )
 
 // SnapshotReconciler reconciles a Snapshot object.
@@ -40,6 +47,8 @@ type SnapshotReconciler struct {
 	Scheme *runtime.Scheme
 	kuberecorder.EventRecorder
 	RegistryServiceName string
+	// RegistryScheme is the scheme of the repository URL in the status. Defaults to https.
+	RegistryScheme string
 
 	Cache cache.Cache
 }
@@ -87,7 +96,7 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 
 	patchHelper := patch.NewSerialPatcher(obj, r.Client)
 
-	// AddFinalizer is not present already.
+	// Add the finalizer if it isn't present already.
 	controllerutil.AddFinalizer(obj, snapshotFinalizer)
 
 	// Always attempt to patch the object and status after each reconciliation.
@@ -102,7 +111,7 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 	// Should only be deleted on a success.
 	rreconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress for snapshot: %s", obj.Name)
 
-	name, err := ocm.ConstructRepositoryName(obj.Spec.Identity)
+	name, err := ocm.SnapshotRepositoryName(obj)
 	if err != nil {
 		err = fmt.Errorf("failed to construct name: %w", err)
 		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.CreateRepositoryNameReason, err.Error())
@@ -110,9 +119,56 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return ctrl.Result{}, err
 	}
 
+	host, scheme := r.RegistryServiceName, r.RegistryScheme
+	if obj.Spec.Registry != "" {
+		if host, scheme, err = oci.ParseRegistryAddress(obj.Spec.Registry); err != nil {
+			err = fmt.Errorf("invalid snapshot registry: %w", err)
+			status.MarkNotReady(r.EventRecorder, obj, v1alpha1.InvalidSnapshotRegistryReason, err.Error())
+
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if scheme == "" {
+		scheme = defaultScheme
+	}
+
+	keychain, err := registryKeychain(ctx, r.Client, obj.Spec.Registry, obj.GetNamespace(), obj.Spec.SecretRef)
+	if err != nil {
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.ConfigureCredentialsFailedReason, err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	// Only report the Snapshot as ready once its data can be fetched, consumers read the data as soon as it is.
+	store, err := registryCache(r.Cache, obj.Spec.Registry, keychain)
+	if err != nil {
+		err = fmt.Errorf("invalid snapshot registry: %w", err)
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.InvalidSnapshotRegistryReason, err.Error())
+
+		return ctrl.Result{}, nil
+	}
+
+	cached, err := store.IsCached(ctx, name, obj.Spec.Tag)
+	if err != nil {
+		err = fmt.Errorf("failed to check snapshot data: %w", err)
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.SnapshotVerificationFailedReason, err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	if !cached {
+		status.MarkNotReady(r.EventRecorder, obj, v1alpha1.SnapshotDataNotFoundReason,
+			fmt.Sprintf("data of snapshot %s with tag %s not found in the registry, retrying in %s", name, obj.Spec.Tag, snapshotDataRetryInterval))
+
+		return ctrl.Result{RequeueAfter: snapshotDataRetryInterval}, nil
+	}
+
+	now := metav1.Now()
+	obj.Status.LastReconcileTime = &now
 	obj.Status.LastReconciledDigest = obj.Spec.Digest
 	obj.Status.LastReconciledTag = obj.Spec.Tag
-	obj.Status.RepositoryURL = fmt.Sprintf("%s://%s/%s", scheme, r.RegistryServiceName, name)
+	obj.Status.RepositoryURL = fmt.Sprintf("%s://%s/%s", scheme, host, name)
 
 	msg := fmt.Sprintf("Snapshot with name '%s' is ready", obj.Name)
 	status.MarkReady(r.EventRecorder, obj, msg)
@@ -127,20 +183,24 @@ func (r *SnapshotReconciler) reconcileDeleteSnapshot(ctx context.Context, obj *v
 		return fmt.Errorf("failed to reconcile delete: %w", err)
 	}
 
-	name, err := ocm.ConstructRepositoryName(obj.Spec.Identity)
+	name, err := ocm.SnapshotRepositoryName(obj)
 	if err != nil {
 		return fmt.Errorf("failed to construct name: %w", err)
 	}
 
-	if err := r.Cache.DeleteData(ctx, name, obj.Spec.Tag); err != nil {
-		var terr *transport.Error
-		if !errors.As(err, &terr) {
-			return fmt.Errorf("failure was not a transport error during data deletion: %w", err)
-		}
+	keychain, err := registryKeychain(ctx, r.Client, obj.Spec.Registry, obj.GetNamespace(), obj.Spec.SecretRef)
+	if err != nil {
+		return err
+	}
 
-		if terr.StatusCode != http.StatusNotFound && !isUnknownManifestError(terr.Errors) {
-			return fmt.Errorf("failed to delete data: %w", err)
-		}
+	store, err := registryCache(r.Cache, obj.Spec.Registry, keychain)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot registry: %w", err)
+	}
+
+	// The data may already be gone, for example if the registry was reset.
+	if err := store.DeleteData(ctx, name, obj.Spec.Tag); err != nil && !cache.IsNotFound(err) {
+		return fmt.Errorf("failed to delete data: %w", err)
 	}
 
 	controllerutil.RemoveFinalizer(obj, snapshotFinalizer)
@@ -148,12 +208,33 @@ func (r *SnapshotReconciler) reconcileDeleteSnapshot(ctx context.Context, obj *v
 	return patchHelper.Patch(ctx, obj)
 }
 
-func isUnknownManifestError(errors []transport.Diagnostic) bool {
-	for _, e := range errors {
-		if e.Code == transport.ManifestUnknownErrorCode {
-			return true
-		}
+// registryCache returns the cache of the registry at the address authenticated with the keychain, or the in-cluster
+// registry if the address is empty.
+func registryCache(c cache.Cache, address string, keychain authn.Keychain) (cache.Cache, error) {
+	if address == "" {
+		return c, nil
+	}
+
+	return c.ForRegistry(address, keychain)
+}
+
+// registryKeychain returns the keychain with the credentials in the .dockerconfigjson of the secret in the
+// namespace for the registry at the address. The keychain is nil for the in-cluster registry and without a secret,
+// the registry is accessed anonymously then. The credentials of the controller are never used for it.
+func registryKeychain(
+	ctx context.Context,
+	kube client.Client,
+	address, namespace string,
+	secretRef *corev1.LocalObjectReference,
+) (authn.Keychain, error) {
+	if address == "" || secretRef == nil {
+		return nil, nil
+	}
+
+	keychain, err := ocm.DockerConfigKeychain(ctx, kube, secretRef.Name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for snapshot registry: %w", err)
 	}
 
-	return false
+	return keychain, nil
 }
@@ -49,6 +49,7 @@ func TestSnapshotReconciler(t *testing.T) {
 	}
 	client := env.FakeKubeClient(WithObjects(snapshot))
 	fakeCache := &fakes.FakeCache{}
+	fakeCache.IsCachedReturns(true, nil)
 	recorder := record.NewFakeRecorder(32)
 
 	sr := SnapshotReconciler{
@@ -69,9 +70,11 @@ func TestSnapshotReconciler(t *testing.T) {
 	err = client.Get(context.Background(), types.NamespacedName{Name: snapshot.Name, Namespace: snapshot.Namespace}, snapshot)
 	require.NoError(t, err)
 	assert.True(t, conditions.IsTrue(snapshot, meta.ReadyCondition))
+	assert.Equal(t, []any{"sha-16038726184537443379", "1234"}, fakeCache.IsCachedCallingArgumentsOnCall(0))
 	assert.Equal(t, "digest-1", snapshot.Status.LastReconciledDigest)
 	assert.Equal(t, "1234", snapshot.Status.LastReconciledTag)
 	assert.Equal(t, "https://127.0.0.1:5000/sha-16038726184537443379", snapshot.Status.RepositoryURL)
+	assert.NotNil(t, snapshot.Status.LastReconcileTime)
 
 	close(recorder.Events)
 	event := ""
@@ -85,6 +88,79 @@ func TestSnapshotReconciler(t *testing.T) {
 	assert.Contains(t, event, "Reconciliation finished")
 }
 
+func TestSnapshotReconcilerDataNotFound(t *testing.T) {
+	snapshot := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-snapshot",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Identity: ocmmetav1.Identity{
+				v1alpha1.ComponentNameKey:    "component-name",
+				v1alpha1.ComponentVersionKey: "v0.0.1",
+				v1alpha1.ResourceNameKey:     "resource-name",
+				v1alpha1.ResourceVersionKey:  "v0.0.5",
+			},
+			Digest: "digest-1",
+			Tag:    "1234",
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectedResult ctrl.Result
+	}{
+		{
+			name:           "data not pushed yet",
+			expectedReason: v1alpha1.SnapshotDataNotFoundReason,
+			expectedResult: ctrl.Result{RequeueAfter: snapshotDataRetryInterval},
+		},
+		{
+			name:           "registry not reachable",
+			err:            errors.New("connection refused"),
+			expectedReason: v1alpha1.SnapshotVerificationFailedReason,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := snapshot.DeepCopy()
+			client := env.FakeKubeClient(WithObjects(obj))
+			fakeCache := &fakes.FakeCache{}
+			fakeCache.IsCachedReturns(false, tt.err)
+
+			sr := SnapshotReconciler{
+				Client:              client,
+				Scheme:              env.scheme,
+				RegistryServiceName: "127.0.0.1:5000",
+				EventRecorder:       record.NewFakeRecorder(32),
+				Cache:               fakeCache,
+			}
+			result, err := sr.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      obj.Name,
+					Namespace: obj.Namespace,
+				},
+			})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResult, result)
+
+			err = client.Get(context.Background(), types.NamespacedName{Name: obj.Name, Namespace: obj.Namespace}, obj)
+			require.NoError(t, err)
+			assert.True(t, conditions.IsFalse(obj, meta.ReadyCondition))
+			assert.Equal(t, tt.expectedReason, conditions.GetReason(obj, meta.ReadyCondition))
+			assert.Empty(t, obj.Status.LastReconciledDigest, "the digest is only reported once the data is in the registry")
+			assert.Nil(t, obj.Status.LastReconcileTime)
+		})
+	}
+}
+
 func TestSnapshotReconcilerDelete(t *testing.T) {
 	snapshot := &v1alpha1.Snapshot{
 		ObjectMeta: metav1.ObjectMeta{
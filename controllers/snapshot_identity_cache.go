@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"k8s.io/utils/lru"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// snapshotIdentityCacheSize is the number of snapshot identities kept in memory.
+const snapshotIdentityCacheSize = 1024
+
+// snapshotIdentityCache holds the identities of recently written Snapshots. An identity only depends on the spec of
+// the Resource, the selected resource and its version, and the component descriptor the resource belongs to, so it
+// is valid as long as neither the Resource nor the descriptor changes its generation. The zero value is ready to use.
+type snapshotIdentityCache struct {
+	once    sync.Once
+	entries *lru.Cache
+}
+
+// get returns the identity of the Snapshot of the resource, computing it only once per generation of the Resource
+// and of the component descriptor. Objects without UID or generation, which aren't read from the cluster, aren't
+// cached. The identity is returned as a copy the caller may modify.
+func (c *snapshotIdentityCache) get(
+	obj *v1alpha1.Resource,
+	componentDescriptor *v1alpha1.ComponentDescriptor,
+	ref *v1alpha1.ResourceReference,
+	version string,
+) (ocmmetav1.Identity, error) {
+	key := snapshotIdentityKey(obj, componentDescriptor, ref, version)
+	if key == "" {
+		return newSnapshotIdentity(obj, componentDescriptor, ref, version)
+	}
+
+	c.once.Do(func() {
+		c.entries = lru.New(snapshotIdentityCacheSize)
+	})
+
+	if value, ok := c.entries.Get(key); ok {
+		return value.(ocmmetav1.Identity).Copy(), nil
+	}
+
+	identity, err := newSnapshotIdentity(obj, componentDescriptor, ref, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries.Add(key, identity.Copy())
+
+	return identity, nil
+}
+
+// snapshotIdentityKey returns the key of the snapshot identity in the cache, empty if it can't be cached.
+func snapshotIdentityKey(
+	obj *v1alpha1.Resource,
+	componentDescriptor *v1alpha1.ComponentDescriptor,
+	ref *v1alpha1.ResourceReference,
+	version string,
+) string {
+	if obj.UID == "" || obj.Generation == 0 || componentDescriptor.UID == "" || componentDescriptor.Generation == 0 {
+		return ""
+	}
+
+	// Maps are formatted with sorted keys, so the same reference always results in the same key.
+	return fmt.Sprintf("%s/%d/%s/%d/%s/%v", obj.UID, obj.Generation, componentDescriptor.UID,
+		componentDescriptor.Generation, version, *ref)
+}
@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+)
+
+// recordSnapshotHistory adds the pushed snapshot data to the history of the Resource and deletes the data of the
+// snapshots of the same Snapshot beyond the retention of the Resource from the registry. The snapshots are ordered
+// by the time they were pushed, data that is pushed again moves to the front. Deleting a tag deletes the manifest
+// it points at, so data is dropped from the history without being deleted if a retained snapshot has the same data
+// or one of its tags, which then holds newer data. Data another Snapshot still refers to is kept in the registry,
+// like data that couldn't be deleted it stays in the history and its deletion is tried again after the next push.
+func (r *ResourceReconciler) recordSnapshotHistory(ctx context.Context, obj *v1alpha1.Resource, pushed v1alpha1.SnapshotHistoryEntry) error {
+	keep := obj.GetSnapshotRetention()
+
+	var (
+		history  []v1alpha1.SnapshotHistoryEntry
+		expired  []v1alpha1.SnapshotHistoryEntry
+		retained int
+	)
+
+	for _, entry := range obj.Status.SnapshotHistory {
+		if sameSnapshotData(entry, pushed) {
+			for _, tag := range entry.Tags {
+				if !slices.Contains(pushed.Tags, tag) {
+					pushed.Tags = append(pushed.Tags, tag)
+				}
+			}
+
+			continue
+		}
+
+		history = append(history, entry)
+	}
+
+	history = append([]v1alpha1.SnapshotHistoryEntry{pushed}, history...)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[j].PushedAt.Before(&history[i].PushedAt)
+	})
+
+	var result []v1alpha1.SnapshotHistoryEntry
+	for _, entry := range history {
+		if entry.SnapshotName != pushed.SnapshotName {
+			result = append(result, entry)
+
+			continue
+		}
+
+		if retained < keep {
+			retained++
+			result = append(result, entry)
+
+			continue
+		}
+
+		expired = append(expired, entry)
+	}
+
+	if len(expired) == 0 {
+		obj.Status.SnapshotHistory = result
+
+		return nil
+	}
+
+	live, err := r.liveSnapshotData(ctx, obj.Namespace, pushed.SnapshotName)
+	if err != nil {
+		return err
+	}
+
+	logger := log.FromContext(ctx)
+
+	var (
+		errs     []error
+		deferred []v1alpha1.SnapshotHistoryEntry
+	)
+
+	for _, entry := range expired {
+		if sharesSnapshotData(entry, result) {
+			continue
+		}
+
+		if sharesSnapshotData(entry, live) {
+			deferred = append(deferred, entry)
+
+			continue
+		}
+
+		if err := r.deleteSnapshotData(ctx, obj, entry); err != nil {
+			errs = append(errs, err)
+			deferred = append(deferred, entry)
+
+			continue
+		}
+
+		logger.Info("deleted snapshot beyond retention", v1alpha1.LogKeySnapshotRef, snapshotReference(entry.Repository, entry.Tags[0]),
+			v1alpha1.LogKeySourceDigest, entry.Digest)
+	}
+
+	obj.Status.SnapshotHistory = append(result, deferred...)
+
+	return errors.Join(errs...)
+}
+
+// deleteSnapshotData deletes the tags of the entry from its registry with the credentials of the Resource. Tags that
+// don't exist anymore are skipped, deleting the first tag usually deletes the others too.
+func (r *ResourceReconciler) deleteSnapshotData(ctx context.Context, obj *v1alpha1.Resource, entry v1alpha1.SnapshotHistoryEntry) error {
+	keychain, err := registryKeychain(ctx, r.Client, entry.Registry, obj.GetNamespace(), obj.Spec.SecretRef)
+	if err != nil {
+		return err
+	}
+
+	store, err := registryCache(r.Cache, entry.Registry, keychain)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot registry: %w", err)
+	}
+
+	for _, tag := range entry.Tags {
+		cached, err := store.IsCached(ctx, entry.Repository, tag)
+		if err != nil {
+			return fmt.Errorf("failed to check registry for %s: %w", snapshotReference(entry.Repository, tag), err)
+		}
+
+		if !cached {
+			continue
+		}
+
+		if err := store.DeleteData(ctx, entry.Repository, tag); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", snapshotReference(entry.Repository, tag), err)
+		}
+	}
+
+	return nil
+}
+
+// liveSnapshotData returns the snapshot data Snapshots refer to as entries with the tags of the Snapshot. The last
+// reconciled tag of the Snapshot that has just been pushed is left out, that is the data being replaced.
+func (r *ResourceReconciler) liveSnapshotData(ctx context.Context, namespace, pushed string) ([]v1alpha1.SnapshotHistoryEntry, error) {
+	snapshots := &v1alpha1.SnapshotList{}
+	if err := r.List(ctx, snapshots); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	live := make([]v1alpha1.SnapshotHistoryEntry, 0, len(snapshots.Items))
+	for i := range snapshots.Items {
+		snapshot := &snapshots.Items[i]
+		name, err := ocm.SnapshotRepositoryName(snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct repository name of snapshot %s/%s: %w", snapshot.Namespace, snapshot.Name, err)
+		}
+
+		entry := v1alpha1.SnapshotHistoryEntry{
+			Registry:   snapshot.Spec.Registry,
+			Repository: name,
+			Digest:     snapshot.Spec.Digest,
+		}
+		entry.Tags = append(entry.Tags, snapshot.Spec.Tag)
+		if tag := snapshot.Status.LastReconciledTag; tag != "" && (snapshot.Namespace != namespace || snapshot.Name != pushed) {
+			entry.Tags = append(entry.Tags, tag)
+		}
+
+		live = append(live, entry)
+	}
+
+	return live, nil
+}
+
+// sharesSnapshotData returns whether one of the other entries has the same data as the entry or one of its tags in
+// the same repository.
+func sharesSnapshotData(entry v1alpha1.SnapshotHistoryEntry, others []v1alpha1.SnapshotHistoryEntry) bool {
+	for _, other := range others {
+		if other.Registry != entry.Registry || other.Repository != entry.Repository {
+			continue
+		}
+
+		if other.Digest == entry.Digest {
+			return true
+		}
+
+		for _, tag := range entry.Tags {
+			if slices.Contains(other.Tags, tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sameSnapshotData returns whether the entries describe the same data of the same Snapshot.
+func sameSnapshotData(a, b v1alpha1.SnapshotHistoryEntry) bool {
+	return a.SnapshotName == b.SnapshotName && a.Registry == b.Registry && a.Repository == b.Repository && a.Digest == b.Digest
+}
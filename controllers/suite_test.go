@@ -5,6 +5,7 @@
 package controllers
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -12,8 +13,10 @@ import (
 	"github.com/open-component-model/ocm/pkg/contexts/ocm"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -57,10 +60,27 @@ func (t *testEnv) FakeKubeClient(opts ...FakeKubeClientOption) client.Client {
 	for _, o := range opts {
 		o(t)
 	}
-	return fake.NewClientBuilder().
-		WithScheme(t.scheme).
-		WithObjects(t.obj...).
-		Build()
+	return &applyClient{
+		Client: fake.NewClientBuilder().
+			WithScheme(t.scheme).
+			WithObjects(t.obj...).
+			Build(),
+	}
+}
+
+// applyClient creates objects that are applied with server-side apply but don't exist yet. The fake client can
+// only apply patches to existing objects.
+type applyClient struct {
+	client.Client
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	if patch.Type() == types.ApplyPatchType && apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	}
+
+	return err
 }
 
 // FakeKubeClient creates a fake kube client with some defaults and optional arguments.
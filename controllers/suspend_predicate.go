@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// SuspendAnnotationChangedPredicate triggers a reconciliation when the suspend annotation is set or removed, so
+// an object suspended by the annotation is resumed once it is removed.
+type SuspendAnnotationChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (SuspendAnnotationChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	return e.ObjectOld.GetAnnotations()[v1alpha1.SuspendAnnotation] != e.ObjectNew.GetAnnotations()[v1alpha1.SuspendAnnotation]
+}
@@ -3,10 +3,10 @@ package resource
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/open-component-model/ocm-controller/internal/wasm/hostfuncs/types"
 	wasmio "github.com/open-component-model/ocm-controller/internal/wasm/io"
+	ocmctrl "github.com/open-component-model/ocm-controller/pkg/ocm"
 	wasmerr "github.com/open-component-model/ocm-controller/pkg/wasm/errors"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/localblob"
@@ -57,7 +57,7 @@ func getReference(octx ocm.Context, res ocm.ResourceAccess) (string, error) {
 		case *ociartifact.AccessSpec:
 			ref = x.ImageReference
 		case *ociblob.AccessSpec:
-			ref = fmt.Sprintf("%s@%s", x.Reference, x.Digest)
+			ref, err = ocmctrl.BlobReference(x.Reference, x.Digest.String())
 		case *localblob.AccessSpec:
 			if x.GlobalAccess == nil {
 				err = errors.New("cannot determine image digest")
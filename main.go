@@ -5,8 +5,14 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	helmv1 "github.com/fluxcd/helm-controller/api/v2beta1"
@@ -14,13 +20,16 @@ import (
 	"github.com/fluxcd/pkg/runtime/events"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -29,12 +38,31 @@ import (
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
 	"github.com/open-component-model/ocm-controller/controllers"
+	"github.com/open-component-model/ocm-controller/pkg/config"
 	"github.com/open-component-model/ocm-controller/pkg/oci"
 	"github.com/open-component-model/ocm-controller/pkg/ocm"
 	"github.com/open-component-model/ocm-controller/pkg/snapshot"
+	"github.com/open-component-model/ocm-controller/pkg/tracing"
 )
 
-const controllerName = "ocm-controller"
+const (
+	controllerName = "ocm-controller"
+
+	// defaultRegistryTimeout is generous because a push streams the whole resource from the upstream repository.
+	defaultRegistryTimeout = 10 * time.Minute
+	// defaultRegistryUnreachableThreshold tolerates short registry restarts before the controller reports unready.
+	defaultRegistryUnreachableThreshold = time.Minute
+	// defaultSnapshotGCGracePeriod leaves enough time to create the Snapshot for data that has just been pushed.
+	defaultSnapshotGCGracePeriod = time.Hour
+	// defaultResourceCacheSize bounds the memory used to remember pushed resources by their digest.
+	defaultResourceCacheSize = 1000
+	// defaultRequeueInterval is used for Resources that don't set an interval.
+	defaultRequeueInterval = 10 * time.Minute
+	// defaultRequeueJitter spreads the requeues of Resources by up to 10% in either direction.
+	defaultRequeueJitter = 0.1
+	// defaultComponentRefTimeout is how long Resources wait for the component descriptor of their component.
+	defaultComponentRefTimeout = time.Hour
+)
 
 var (
 	scheme   = runtime.NewScheme()
@@ -52,55 +80,275 @@ func init() {
 }
 
 func main() {
-	var (
-		metricsAddr                   string
-		eventsAddr                    string
-		enableLeaderElection          bool
-		probeAddr                     string
-		ociRegistryAddr               string
-		ociRegistryCertSecretName     string
-		ociRegistryInsecureSkipVerify bool
-		ociRegistryNamespace          string
-	)
+	var configFile string
+	settings := &controllerSettings{Settings: &config.Settings{}}
 
 	flag.StringVar(
-		&metricsAddr,
+		&settings.MetricsAddr,
 		"metrics-bind-address",
 		":8080",
 		"The address the metric endpoint binds to.",
 	)
-	flag.StringVar(&eventsAddr, "events-addr", "", "The address of the events receiver.")
+	flag.StringVar(&settings.EventsAddr, "events-addr", "", "The address of the events receiver.")
 	flag.StringVar(
-		&probeAddr,
+		&settings.ProbeAddr,
 		"health-probe-bind-address",
 		":8081",
 		"The address the probe endpoint binds to.",
 	)
 	flag.StringVar(
-		&ociRegistryAddr,
+		&settings.OCIRegistryAddr,
 		"oci-registry-addr",
 		":5000",
-		"The address of the OCI registry.",
+		"The address of the OCI registry in the form host[:port]. A http or https scheme is used as the "+
+			"--oci-registry-scheme if that isn't set.",
 	)
 	flag.StringVar(
-		&ociRegistryCertSecretName,
+		&settings.CertificateSecretName,
 		"certificate-secret-name",
 		v1alpha1.DefaultRegistryCertificateSecretName,
 		"",
 	)
 	flag.StringVar(
-		&ociRegistryNamespace,
+		&settings.OCIRegistryNamespace,
 		"oci-registry-namespace",
 		"ocm-system",
 		"The namespace in which the registry is running in.",
 	)
+	flag.StringVar(
+		&settings.OCIRegistryScheme,
+		"oci-registry-scheme",
+		"",
+		"The scheme used to talk to the registry, either http or https. If not set, https is used unless "+
+			"--oci-registry-insecure is set.",
+	)
 	flag.BoolVar(
-		&ociRegistryInsecureSkipVerify,
+		&settings.OCIRegistryInsecureSkipVerify,
 		"oci-registry-insecure-skip-verify",
 		false,
 		"Skip verification of the certificate that the registry is using.",
 	)
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	flag.BoolVar(
+		&settings.OCIRegistryInsecure,
+		"oci-registry-insecure",
+		false,
+		"Allow plain http to the registry and skip the verification of its certificate, for development setups "+
+			"only. Without it, the registry is accessed over https with a verified certificate unless "+
+			"--oci-registry-scheme or --oci-registry-insecure-skip-verify say otherwise. The OCI_REGISTRY_INSECURE "+
+			"environment variable is used if neither this flag nor the config file are set.",
+	)
+	flag.DurationVar(
+		&settings.RegistryTimeout,
+		"registry-timeout",
+		defaultRegistryTimeout,
+		"The timeout for requests to the in-cluster registry and for downloads of resources with an http access. "+
+			"It applies to the whole push of a resource, including reading it from the upstream repository. Zero "+
+			"disables the timeout.",
+	)
+	flag.DurationVar(
+		&settings.RegistryUnreachableThreshold,
+		"registry-unreachable-threshold",
+		defaultRegistryUnreachableThreshold,
+		"The duration the in-cluster registry may be unreachable before the readiness check of the controller fails.",
+	)
+	flag.IntVar(
+		&settings.ResourceConcurrency,
+		"resource-concurrency",
+		controllers.DefaultMaxConcurrentReconciles,
+		"The number of Resources that are reconciled concurrently.",
+	)
+	flag.IntVar(
+		&settings.ResourceCacheSize,
+		"resource-cache-size",
+		defaultResourceCacheSize,
+		"The number of pushed resources remembered by their digest. Resources with a known digest are copied "+
+			"within the in-cluster registry instead of being fetched from upstream again. Zero disables the cache.",
+	)
+	flag.DurationVar(
+		&settings.DefaultRequeueInterval,
+		"default-requeue-interval",
+		defaultRequeueInterval,
+		"The interval at which Resources that don't set an interval are reconciled.",
+	)
+	flag.Float64Var(
+		&settings.RequeueJitter,
+		"requeue-jitter",
+		defaultRequeueJitter,
+		"The fraction of the requeue delay of Resources, both at their interval and after failures, that is "+
+			"randomly added to or subtracted from it, so Resources created together don't reach the registry at "+
+			"the same time. Must be at least 0 and less than 1, zero disables the jitter.",
+	)
+	flag.StringVar(
+		&settings.SnapshotRepositoryTemplate,
+		"snapshot-repository-template",
+		"",
+		"A Go template for the names of snapshot repositories in the in-cluster registry, for example "+
+			"'{{ .Namespace }}/{{ .ComponentName }}/{{ .ResourceName }}'. Available fields are Hash, Namespace, "+
+			"ComponentName, ComponentVersion, ResourceName, ResourceVersion and Identity. If not set, repositories "+
+			"are named after the hash of the snapshot identity.",
+	)
+	flag.DurationVar(
+		&settings.SnapshotGCInterval,
+		"snapshot-gc-interval",
+		0,
+		"The interval at which tags that no Snapshot refers to are deleted from the in-cluster registry. "+
+			"Zero disables the garbage collection.",
+	)
+	flag.DurationVar(
+		&settings.SnapshotGCGracePeriod,
+		"snapshot-gc-grace-period",
+		defaultSnapshotGCGracePeriod,
+		"The duration a tag has to be without a Snapshot before the garbage collection deletes it.",
+	)
+	flag.StringVar(
+		&settings.MaxResourceSize,
+		"max-resource-size",
+		"",
+		"The maximum size of a resource that is fetched and pushed to the registry, for example 1Gi. Larger "+
+			"resources are rejected, before they are fetched if their size is known. If not set, the size isn't limited.",
+	)
+	listVar(
+		&settings.AllowedRegistries,
+		"allowed-registries",
+		"",
+		"A comma separated list of registry hosts resources may be fetched from, for example "+
+			"'ghcr.io,registry.local:5000'. If not set, resources are fetched from any registry.",
+	)
+	listVar(
+		&settings.AllowedTransformHosts,
+		"allowed-transform-hosts",
+		"",
+		"A comma separated list of hosts the transformation webhooks of Resources may be served from over https, "+
+			"for example 'relocator.ocm-system.svc'. A host prefixed with http:// may be accessed over plain http too. "+
+			"If not set, Resources can't be transformed.",
+	)
+	listVar(
+		&settings.RegistryMirrors,
+		"registry-mirror-map",
+		"",
+		"A comma separated list of host=mirror pairs of registries whose images are fetched from a mirror, for "+
+			"example 'docker.io=mirror.corp/docker.io'. The allowed registries and the recorded source of resources "+
+			"still refer to the original registry.",
+	)
+	listVar(
+		&settings.WatchNamespaces,
+		"watch-namespaces",
+		"",
+		"A comma separated list of namespaces the controller watches and reconciles objects in, for example "+
+			"'team-a,team-b'. The namespace of the registry is always watched. If not set, all namespaces are watched.",
+	)
+	flag.DurationVar(
+		&settings.ComponentRefTimeout,
+		"component-ref-timeout",
+		defaultComponentRefTimeout,
+		"How long a Resource waits for the component descriptor of its component before it is marked with the "+
+			"ComponentRefUnresolved condition. Zero waits forever.",
+	)
+	flag.DurationVar(
+		&settings.ResourceReconcileTimeout,
+		"resource-reconcile-timeout",
+		0,
+		"How long a reconciliation of a Resource may take before it is aborted and retried, for Resources that "+
+			"don't set spec.timeout. Zero doesn't limit reconciliations.",
+	)
+	listVar(
+		&settings.InsecureRegistries,
+		"insecure-registries",
+		"",
+		"A comma separated list of registry hosts whose certificates aren't verified and that may be accessed "+
+			"over http, for example 'registry.dev:5000'. The same as listing the hosts in both --allow-http and "+
+			"--skip-tls-verify.",
+	)
+	listVar(
+		&settings.HTTPRegistries,
+		"allow-http",
+		"",
+		"A comma separated list of registry hosts that may be accessed over plain http, for example "+
+			"'registry.plain:5000'. Their certificates are still verified if they are accessed over https.",
+	)
+	listVar(
+		&settings.SnapshotRegistries,
+		"snapshot-registries",
+		"",
+		"A comma separated list of registry hosts Resources may push their snapshots to with "+
+			"spec.snapshotTemplate.registry instead of the in-cluster registry, for example 'registry.example.com:5000'. "+
+			"Snapshots are pushed with the credentials of the Resource's spec.secretRef. If empty, Resources can "+
+			"only push to the in-cluster registry.",
+	)
+	listVar(
+		&settings.SkipTLSVerifyRegistries,
+		"skip-tls-verify",
+		"",
+		"A comma separated list of registry hosts whose certificates aren't verified, for example "+
+			"'registry.dev:5000'. They are still accessed over https only.",
+	)
+	flag.StringVar(
+		&settings.CABundleConfigMap,
+		"ca-bundle-configmap",
+		"",
+		"The [namespace/]name of a ConfigMap with PEM encoded CA certificates the certificates of registries are "+
+			"verified against in addition to the system certificates, for registries with certificates of an internal "+
+			"CA. The namespace defaults to --oci-registry-namespace. Changes to the ConfigMap are picked up without a restart.",
+	)
+	listVar(
+		&settings.SnapshotAnnotations,
+		"snapshot-annotations",
+		ocm.AnnotationKeyType,
+		"A comma separated list of the resource metadata added to the manifests of snapshots as 'software.ocm/' "+
+			"annotations, any of 'type', 'version', 'extraIdentity', 'labels' or 'label:<name>' for a single label. "+
+			"The component and resource name and version are always added. Images are copied as they are and aren't annotated.",
+	)
+	flag.BoolVar(
+		&settings.EnableWebhooks,
+		"enable-webhooks",
+		false,
+		"Serve the admission webhooks defaulting and validating the objects of the controller. "+
+			"Requires a serving certificate in the webhook server's certificate directory.",
+	)
+	flag.StringVar(
+		&settings.OTLPEndpoint,
+		"otlp-endpoint",
+		"",
+		"The host:port of an OTLP http endpoint, for example an OpenTelemetry collector, the traces of the "+
+			"reconciliations are exported to. If not set, tracing is disabled.",
+	)
+	flag.BoolVar(
+		&settings.OTLPInsecure,
+		"otlp-insecure",
+		false,
+		"Export traces to the OTLP endpoint over plain http instead of https.",
+	)
+	flag.StringVar(
+		&settings.FieldManager,
+		"field-manager",
+		"",
+		"The field manager Snapshots are created and updated with, for example to tell several installations of "+
+			"the controller apart in the managed fields. Defaults to 'resource-controller' for the Snapshots of "+
+			"Resources and to the user agent of the controller for the others.",
+	)
+	flag.StringVar(
+		&configFile,
+		"config",
+		"",
+		"The path of a ComponentControllerConfig file with the settings of the registry, concurrency and requeueing. "+
+			"Flags given on the command line override the settings of the file.",
+	)
+	flag.BoolVar(
+		&settings.EnableResourcePlan,
+		"enable-resource-plan-endpoint",
+		false,
+		"Serve the plan of a Resource as JSON at /debug/resources/<namespace>/<name> on the metrics server, for "+
+			"debugging. The endpoint is not authenticated, anyone who can reach the metrics server can read the plans. "+
+			"The digests are taken from the status of the Resource, unless ?resolve=true is given, which fetches the resources.",
+	)
+	flag.IntVar(
+		&settings.ResourcePlanConcurrency,
+		"resource-plan-concurrency",
+		controllers.DefaultResourcePlanConcurrency,
+		"The number of plans the resource plan endpoint resolves with ?resolve=true at a time, further requests are "+
+			"answered with 429 Too Many Requests.",
+	)
+	flag.BoolVar(&settings.EnableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 
@@ -112,27 +360,172 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var controllerConfig *config.ComponentControllerConfig
+	if configFile != "" {
+		var err error
+		if controllerConfig, err = config.Load(configFile); err != nil {
+			setupLog.Error(err, "invalid value for --config")
+			os.Exit(1)
+		}
+		controllerConfig.ApplyTo(settings.Settings, flag.CommandLine)
+	}
+
+	if settings.OCIRegistryScheme != "" && settings.OCIRegistryScheme != "http" && settings.OCIRegistryScheme != "https" {
+		setupLog.Error(fmt.Errorf("unsupported scheme %q", settings.OCIRegistryScheme), "invalid value for --oci-registry-scheme, must be http or https")
+		os.Exit(1)
+	}
+
+	if v, found := os.LookupEnv("OCI_REGISTRY_LOCALHOST"); found {
+		settings.OCIRegistryAddr = v
+	}
+
+	// The address is used as is in references, fail now instead of producing broken references later.
+	registryAddr, registryScheme, err := oci.ParseRegistryAddress(settings.OCIRegistryAddr)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --oci-registry-addr")
+		os.Exit(1)
+	}
+	if settings.OCIRegistryScheme != "" && registryScheme != "" && settings.OCIRegistryScheme != registryScheme {
+		setupLog.Error(
+			fmt.Errorf("scheme %q of the registry address doesn't match --oci-registry-scheme %q", registryScheme, settings.OCIRegistryScheme),
+			"invalid value for --oci-registry-addr",
+		)
+		os.Exit(1)
+	}
+	settings.OCIRegistryAddr = registryAddr
+	if settings.OCIRegistryScheme == "" {
+		settings.OCIRegistryScheme = registryScheme
+	}
+
+	// The environment variable only applies if neither the command line nor the config file set the flag.
+	insecureSource := "--oci-registry-insecure"
+	switch {
+	case visitedFlags(flag.CommandLine)["oci-registry-insecure"]:
+		// The command line overrides the config file and the environment.
+	case controllerConfig != nil && controllerConfig.OCIRegistryInsecure != nil:
+		insecureSource = "config file"
+	default:
+		if v, found := os.LookupEnv("OCI_REGISTRY_INSECURE"); found {
+			insecure, err := strconv.ParseBool(v)
+			if err != nil {
+				setupLog.Error(err, "invalid value for OCI_REGISTRY_INSECURE")
+				os.Exit(1)
+			}
+			settings.OCIRegistryInsecure = insecure
+			insecureSource = "OCI_REGISTRY_INSECURE"
+		}
+	}
+	if settings.OCIRegistryInsecure {
+		setupLog.Info("insecure access to the registry is enabled", "source", insecureSource)
+	}
+
+	// Fail closed, the registry is only accessed over plain http or with an unverified certificate if asked to.
+	if settings.OCIRegistryInsecure {
+		settings.OCIRegistryInsecureSkipVerify = true
+	} else if settings.OCIRegistryScheme == "" {
+		settings.OCIRegistryScheme = "https"
+	}
+
+	if settings.OCIRegistryInsecureSkipVerify || settings.OCIRegistryScheme != "https" {
+		setupLog.Info("WARNING: the connection to the registry is insecure, this is meant for development setups only",
+			"address", settings.OCIRegistryAddr, "allowHTTP", settings.OCIRegistryScheme != "https", "skipTLSVerify", settings.OCIRegistryInsecureSkipVerify)
+	}
+
+	if settings.DefaultRequeueInterval <= 0 {
+		setupLog.Error(fmt.Errorf("interval %s is not positive", settings.DefaultRequeueInterval), "invalid value for --default-requeue-interval")
+		os.Exit(1)
+	}
+
+	if settings.RequeueJitter < 0 || settings.RequeueJitter >= 1 {
+		setupLog.Error(fmt.Errorf("jitter %v is not in [0, 1)", settings.RequeueJitter), "invalid value for --requeue-jitter")
+		os.Exit(1)
+	}
+
+	if settings.MaxResourceSize != "" {
+		quantity, err := apiresource.ParseQuantity(settings.MaxResourceSize)
+		if err != nil {
+			setupLog.Error(err, "invalid value for --max-resource-size")
+			os.Exit(1)
+		}
+		if quantity.Sign() <= 0 {
+			setupLog.Error(fmt.Errorf("size %s is not positive", settings.MaxResourceSize), "invalid value for --max-resource-size")
+			os.Exit(1)
+		}
+		settings.maxResourceBytes = quantity.Value()
+	}
+
+	if err := ocm.ValidateAnnotationKeys(settings.SnapshotAnnotations...); err != nil {
+		setupLog.Error(err, "invalid value for --snapshot-annotations")
+		os.Exit(1)
+	}
+
+	settings.registryMirrors, err = ocm.ParseRegistryMirrors(settings.RegistryMirrors...)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --registry-mirror-map")
+		os.Exit(1)
+	}
+
+	settings.repositoryNamer, err = ocm.NewRepositoryNamer(settings.SnapshotRepositoryTemplate)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --snapshot-repository-template")
+		os.Exit(1)
+	}
+
+	var found bool
+	settings.caBundleNamespace, settings.caBundleName, found = strings.Cut(settings.CABundleConfigMap, "/")
+	if !found {
+		settings.caBundleNamespace, settings.caBundleName = settings.OCIRegistryNamespace, settings.CABundleConfigMap
+	}
+
+	namespaces, err := parseWatchNamespaces(settings.WatchNamespaces, settings.OCIRegistryNamespace, settings.caBundleNamespace)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --watch-namespaces")
+		os.Exit(1)
+	}
+
 	restConfig := ctrl.GetConfigOrDie()
 
 	const metricsServerPort = 9443
-	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+	options := ctrl.Options{
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
+		MetricsBindAddress:     settings.MetricsAddr,
 		Port:                   metricsServerPort,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
+		HealthProbeBindAddress: settings.ProbeAddr,
+		LeaderElection:         settings.EnableLeaderElection,
 		LeaderElectionID:       "f8b21459.ocm.software",
-	})
+	}
+	if len(namespaces) > 0 {
+		setupLog.Info("watching namespaces", "namespaces", namespaces)
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if v, found := os.LookupEnv("OCI_REGISTRY_LOCALHOST"); found {
-		ociRegistryAddr = v
+	cache := setupManagers(mgr, restConfig, settings)
+
+	if settings.EnableWebhooks {
+		resourceWebhook := &v1alpha1.ResourceWebhook{
+			AllowedSnapshotRegistries: settings.SnapshotRegistries,
+			AllowedTransformHosts:     settings.AllowedTransformHosts,
+		}
+		if err := resourceWebhook.SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Resource")
+			os.Exit(1)
+		}
 	}
 
-	setupManagers(ociRegistryAddr, mgr, ociRegistryNamespace, ociRegistryCertSecretName, ociRegistryInsecureSkipVerify, restConfig, eventsAddr)
+	if settings.SnapshotGCInterval > 0 {
+		// The cache only holds the watched namespaces, the Snapshots of every namespace are listed from the API server
+		// so that the data of Snapshots in namespaces that aren't watched isn't collected.
+		if err := mgr.Add(snapshot.NewGarbageCollector(mgr.GetAPIReader(), cache, settings.SnapshotGCInterval, settings.SnapshotGCGracePeriod)); err != nil {
+			setupLog.Error(err, "unable to set up snapshot garbage collection")
+			os.Exit(1)
+		}
+	}
 
 	//+kubebuilder:scaffold:builder
 
@@ -144,33 +537,163 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("registry", oci.NewRegistryChecker(cache, settings.RegistryUnreachableThreshold).Check); err != nil {
+		setupLog.Error(err, "unable to set up registry ready check")
+		os.Exit(1)
+	}
 
 	ctx := ctrl.SetupSignalHandler()
 
+	shutdownTracing, err := tracing.Setup(ctx, settings.OTLPEndpoint, settings.OTLPInsecure)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctx); err != nil {
+	err = mgr.Start(ctx)
+
+	// Export the spans of the last reconciliations, the signal handler context is already done at this point.
+	if serr := shutdownTracing(context.Background()); serr != nil {
+		setupLog.Error(serr, "failed to shut down tracing")
+	}
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
-func setupManagers(
-	ociRegistryAddr string,
-	mgr manager.Manager,
-	ociRegistryNamespace, ociRegistryCertSecretName string,
-	ociRegistryInsecureSkipVerify bool,
-	restConfig *rest.Config,
-	eventsAddr string,
-) {
-	cache := oci.NewClient(
-		ociRegistryAddr,
+// controllerSettings are the settings of the controller together with the values parsed from them while they are
+// validated.
+type controllerSettings struct {
+	*config.Settings
+
+	maxResourceBytes  int64
+	registryMirrors   map[string]string
+	repositoryNamer   *ocm.RepositoryNamer
+	caBundleNamespace string
+	caBundleName      string
+}
+
+// listValue is a flag.Value of a comma separated list.
+type listValue []string
+
+func (v *listValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *listValue) Set(value string) error {
+	*v = strings.Split(value, ",")
+
+	return nil
+}
+
+// listVar defines a flag of a comma separated list with the given default, stored in p.
+func listVar(p *[]string, name, value, usage string) {
+	if value != "" {
+		*p = strings.Split(value, ",")
+	}
+
+	flag.Var((*listValue)(p), name, usage)
+}
+
+// visitedFlags returns the names of the flags of the set that have been set.
+func visitedFlags(flags *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+// parseWatchNamespaces returns the namespaces listed in values, nil if they don't list any.
+// The required namespaces are added to a non-empty list, so the objects the controller needs, like the
+// certificate secret of the registry and the CA bundle, can still be read from the cache.
+func parseWatchNamespaces(values []string, required ...string) ([]string, error) {
+	var namespaces []string
+	seen := map[string]struct{}{}
+	for _, namespace := range values {
+		if namespace = strings.TrimSpace(namespace); namespace == "" {
+			continue
+		}
+
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+
+		if _, ok := seen[namespace]; !ok {
+			seen[namespace] = struct{}{}
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+
+	for _, namespace := range required {
+		if _, ok := seen[namespace]; !ok && namespace != "" {
+			seen[namespace] = struct{}{}
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces, nil
+}
+
+// downloadClient returns the http client resources with an http access are downloaded with. Like for the requests
+// to the registry, the timeout applies to connecting to the server and waiting for its response, reading the data
+// is limited by the timeout of the push it is read for. Zero returns http.DefaultClient.
+func downloadClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // the default transport is always an *http.Transport
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+
+	return &http.Client{Transport: transport}
+}
+
+// setupManagers sets up the registry and OCM clients and the reconcilers with the settings and returns the client of
+// the in-cluster registry.
+func setupManagers(mgr manager.Manager, restConfig *rest.Config, settings *controllerSettings) *oci.Client {
+	cacheOpts := []oci.ClientOptsFunc{
 		oci.WithClient(mgr.GetClient()),
-		oci.WithNamespace(ociRegistryNamespace),
-		oci.WithCertificateSecret(ociRegistryCertSecretName),
-		oci.WithInsecureSkipVerify(ociRegistryInsecureSkipVerify),
+		oci.WithNamespace(settings.OCIRegistryNamespace),
+		oci.WithCertificateSecret(settings.CertificateSecretName),
+		oci.WithInsecureSkipVerify(settings.OCIRegistryInsecureSkipVerify),
+		oci.WithScheme(settings.OCIRegistryScheme),
+		oci.WithTimeout(settings.RegistryTimeout),
+		// The registries resources are fetched from and snapshots are pushed to, other than the in-cluster registry.
+		oci.WithInsecureRegistries(settings.InsecureRegistries...),
+		oci.WithHTTPRegistries(settings.HTTPRegistries...),
+		oci.WithSkipTLSVerifyRegistries(settings.SkipTLSVerifyRegistries...),
+		oci.WithSnapshotRegistries(settings.SnapshotRegistries...),
+	}
+	if settings.caBundleName != "" {
+		cacheOpts = append(cacheOpts, oci.WithCABundle(settings.caBundleNamespace, settings.caBundleName))
+	}
+	cache := oci.NewClient(settings.OCIRegistryAddr, cacheOpts...)
+	ocmClient := ocm.NewClient(
+		mgr.GetClient(),
+		cache,
+		ocm.WithHTTPClient(downloadClient(settings.RegistryTimeout)),
+		ocm.WithResourceCacheSize(settings.ResourceCacheSize),
+		ocm.WithAllowedRegistries(settings.AllowedRegistries...),
+		ocm.WithAllowedTransformHosts(settings.AllowedTransformHosts...),
+		ocm.WithRegistryMirrors(settings.registryMirrors),
+		ocm.WithMaxResourceSize(settings.maxResourceBytes),
+		ocm.WithSnapshotAnnotations(settings.SnapshotAnnotations...),
+		ocm.WithRepositoryNamer(settings.repositoryNamer),
 	)
-	ocmClient := ocm.NewClient(mgr.GetClient(), cache)
 	snapshotWriter := snapshot.NewOCIWriter(mgr.GetClient(), cache, mgr.GetScheme())
+	snapshotWriter.FieldManager = settings.FieldManager
+	snapshotWriter.RepositoryNamer = settings.repositoryNamer
 	dynClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to get dynamic config client", "controller", "ocm-controller")
@@ -178,7 +701,7 @@ func setupManagers(
 	}
 
 	var eventsRecorder *events.Recorder
-	if eventsRecorder, err = events.NewRecorder(mgr, ctrl.Log, eventsAddr, controllerName); err != nil {
+	if eventsRecorder, err = events.NewRecorder(mgr, ctrl.Log, settings.EventsAddr, controllerName); err != nil {
 		setupLog.Error(err, "unable to create event recorder")
 		os.Exit(1)
 	}
@@ -197,24 +720,40 @@ func setupManagers(
 		Client:              mgr.GetClient(),
 		Scheme:              mgr.GetScheme(),
 		EventRecorder:       eventsRecorder,
-		RegistryServiceName: ociRegistryAddr,
+		RegistryServiceName: settings.OCIRegistryAddr,
+		RegistryScheme:      settings.OCIRegistryScheme,
 		Cache:               cache,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Snapshot")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.ResourceReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		EventRecorder: eventsRecorder,
-		OCMClient:     ocmClient,
-		Cache:         cache,
-	}).SetupWithManager(mgr); err != nil {
+	resourceReconciler := &controllers.ResourceReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		EventRecorder:           eventsRecorder,
+		OCMClient:               ocmClient,
+		Cache:                   cache,
+		MaxConcurrentReconciles: settings.ResourceConcurrency,
+		DefaultInterval:         settings.DefaultRequeueInterval,
+		RepositoryNamer:         settings.repositoryNamer,
+		ComponentRefTimeout:     settings.ComponentRefTimeout,
+		ReconcileTimeout:        settings.ResourceReconcileTimeout,
+		RequeueJitter:           settings.RequeueJitter,
+		FieldManager:            settings.FieldManager,
+	}
+	if err = resourceReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Resource")
 		os.Exit(1)
 	}
 
+	if settings.EnableResourcePlan {
+		if err := mgr.AddMetricsExtraHandler(controllers.ResourcePlanPath, resourceReconciler.PlanHandler(settings.ResourcePlanConcurrency)); err != nil {
+			setupLog.Error(err, "unable to set up resource plan endpoint")
+			os.Exit(1)
+		}
+	}
+
 	mutationReconciler := controllers.MutationReconcileLooper{
 		Client:         mgr.GetClient(),
 		Scheme:         mgr.GetScheme(),
@@ -259,8 +798,8 @@ func setupManagers(
 		ReconcileInterval:   time.Hour,
 		RetryInterval:       time.Minute,
 		DynamicClient:       dynClient,
-		RegistryServiceName: ociRegistryAddr,
-		CertSecretName:      ociRegistryCertSecretName,
+		RegistryServiceName: settings.OCIRegistryAddr,
+		CertSecretName:      settings.CertificateSecretName,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FluxDeployer")
 		os.Exit(1)
@@ -275,4 +814,6 @@ func setupManagers(
 		setupLog.Error(err, "unable to create controller", "controller", "ResourcePipeline")
 		os.Exit(1)
 	}
+
+	return cache
 }
@@ -6,14 +6,240 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/mitchellh/hashstructure/v2"
 )
 
 // Cache defines capabilities for a cache whatever the backing medium might be.
 type Cache interface {
 	IsCached(ctx context.Context, name, tag string) (bool, error)
-	PushData(ctx context.Context, data io.ReadCloser, mediaType, name, tag string) (string, error)
+	PushData(ctx context.Context, data io.ReadCloser, mediaType, name, tag string, opts ...PushOption) (string, error)
+	LayerDigest(data io.Reader, opts ...PushOption) (string, error)
+	CopyArtifact(
+		ctx context.Context,
+		source, name, tag string,
+		auth authn.Authenticator,
+		platform *v1.Platform,
+		opts ...PushOption,
+	) (string, error)
+	ResolveArtifact(ctx context.Context, source string, auth authn.Authenticator) (string, error)
+	ArtifactSize(ctx context.Context, source string, auth authn.Authenticator, platform *v1.Platform) (int64, error)
+	ArtifactLayerDigest(
+		ctx context.Context,
+		source string,
+		auth authn.Authenticator,
+		platform *v1.Platform,
+		opts ...PushOption,
+	) (string, error)
 	FetchDataByIdentity(ctx context.Context, name, tag string) (io.ReadCloser, string, error)
 	FetchDataByDigest(ctx context.Context, name, digest string) (io.ReadCloser, error)
 	DeleteData(ctx context.Context, name, tag string) error
+	TagData(ctx context.Context, name, tag, newTag string) error
+	PushReferrer(ctx context.Context, data io.Reader, artifactType, name, tag string) (string, error)
+	ManifestAnnotations(ctx context.Context, name, tag string) (map[string]string, error)
+	DataSize(ctx context.Context, name, tag string) (int64, error)
+	ForRegistry(address string, keychain authn.Keychain) (Cache, error)
+}
+
+// ErrRegistryNotAllowed is returned by ForRegistry for registries snapshots may not be pushed to. Only the
+// registries the operator of the controller allowed can be used instead of the in-cluster registry.
+var ErrRegistryNotAllowed = errors.New("snapshot registry not allowed")
+
+// ManagedByAnnotation is added to the manifests of all data pushed by PushData with the value ManagedByValue, so
+// data pushed by the controller can be told apart from images pushed to the same repository by other tools.
+// Artifacts copied by CopyArtifact are stored as they are and don't carry it.
+const (
+	ManagedByAnnotation = "software.ocm/managed-by"
+	ManagedByValue      = "ocm-controller"
+)
+
+// PushOption configures how data is pushed by PushData. Only WithPushResult, WithGzipLayers and WithoutTag apply
+// to CopyArtifact.
+type PushOption func(o *PushOptions)
+
+// PushOptions are the options of PushData.
+type PushOptions struct {
+	// Uncompressed stores the data as it is instead of gzip-compressing it.
+	Uncompressed bool
+	// Annotations are added to the manifest the data is stored in.
+	Annotations map[string]string
+	// Config is the config of the image the data is stored in. The image has an empty config if it is nil.
+	Config *ImageConfig
+	// ArtifactType is the media type of the config of the manifest the data is stored in, the media type of an
+	// image config if it is empty.
+	ArtifactType string
+	// MountFrom are the repositories of the registry the layer of the data is mounted from if one of them already
+	// holds it.
+	MountFrom []string
+	// Result records the outcome of the push if it is set.
+	Result *PushResult
+	// GzipLayers recompresses the zstd-compressed layers of a copied image with gzip.
+	GzipLayers bool
+	// Untagged pushes the manifest by its digest and leaves the tag alone.
+	Untagged bool
+}
+
+// PushResult is the outcome of a push.
+type PushResult struct {
+	// Unchanged is true if the tag already pointed at the same manifest and nothing was written.
+	Unchanged bool
+	// Transcoded is true if layers of a copied image were recompressed, the digest of the copy then differs from
+	// the digest of the source.
+	Transcoded bool
+	// Manifest is the digest of the manifest that was pushed, or that was already stored if nothing was written.
+	Manifest string
+}
+
+// ImageConfig is the config of the image data is stored in.
+type ImageConfig struct {
+	OS           string
+	Architecture string
+	Variant      string
+	// Created is the creation time of the image, the image has no creation time if it is zero.
+	Created time.Time
+	Labels  map[string]string
+}
+
+// Hash returns a hash of the config, so data stored with different configs can be told apart.
+func (c *ImageConfig) Hash() (string, error) {
+	hash, err := hashstructure.Hash(c, hashstructure.FormatV2, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash image config: %w", err)
+	}
+
+	return fmt.Sprintf("sha-%d", hash), nil
+}
+
+// WithoutCompression stores the data as it is instead of gzip-compressing it. Data that is already compressed
+// stays compressed.
+func WithoutCompression() PushOption {
+	return func(o *PushOptions) {
+		o.Uncompressed = true
+	}
+}
+
+// WithAnnotations adds the annotations to the manifest the data is stored in. The digest of the data isn't
+// affected by them.
+func WithAnnotations(annotations map[string]string) PushOption {
+	return func(o *PushOptions) {
+		o.Annotations = annotations
+	}
+}
+
+// WithImageConfig stores the data in an image with the given config instead of an empty config.
+func WithImageConfig(config *ImageConfig) PushOption {
+	return func(o *PushOptions) {
+		o.Config = config
+	}
+}
+
+// WithArtifactType stores the data in an OCI manifest with the artifact type as the media type of its config, so
+// the data can be told apart from regular images. Helm charts keep the config media type Helm requires.
+func WithArtifactType(artifactType string) PushOption {
+	return func(o *PushOptions) {
+		o.ArtifactType = artifactType
+	}
+}
+
+// WithMountFrom mounts the layer of the data from one of the repositories of the same registry if it already holds
+// a layer with the same digest, instead of uploading the data again. That is the case if the data didn't change
+// since it was pushed to one of them. The data is buffered to compute the digest of its layer before it is pushed.
+func WithMountFrom(names ...string) PushOption {
+	return func(o *PushOptions) {
+		o.MountFrom = append(o.MountFrom, names...)
+	}
+}
+
+// WithGzipLayers recompresses the zstd-compressed layers of an image copied with CopyArtifact with gzip, for
+// consumers that don't support zstd. The uncompressed data of the layers, and so the config of the image, doesn't
+// change, but the digest of the copy does.
+func WithGzipLayers() PushOption {
+	return func(o *PushOptions) {
+		o.GzipLayers = true
+	}
+}
+
+// WithoutTag pushes the manifest only by its digest, the tag passed to the push is ignored. Nothing refers to the
+// manifest by a tag, so consumers have to use the digest recorded in the push result, which never changes, to
+// fetch it. The tag is still used for the data of PushData, which is returned instead of the manifest digest.
+func WithoutTag() PushOption {
+	return func(o *PushOptions) {
+		o.Untagged = true
+	}
+}
+
+// WithPushResult records the outcome of the push in result. The manifest is only written if the tag doesn't
+// already point at the same manifest, which is the case if the same data has been pushed to it before.
+func WithPushResult(result *PushResult) PushOption {
+	return func(o *PushOptions) {
+		o.Result = result
+	}
+}
+
+// RateLimitError is returned when a registry rejects a request with 429 Too Many Requests.
+type RateLimitError struct {
+	// Host is the registry that rate limited the request.
+	Host string
+	// RetryAfter is the delay suggested by the Retry-After header of the response. It is zero if the registry
+	// didn't suggest one.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("registry %s is rate limiting requests, retry after %s", e.Host, e.RetryAfter)
+	}
+
+	return fmt.Sprintf("registry %s is rate limiting requests", e.Host)
+}
+
+// RegistryUnavailableError is returned when the registry snapshots are stored in can't be reached, for example
+// because the in-cluster registry or the proxy in front of it is down. Errors of upstream registries are returned as
+// they are.
+type RegistryUnavailableError struct {
+	// Host is the registry that couldn't be reached.
+	Host string
+	// Err is the error the connection failed with.
+	Err error
+}
+
+// Error implements error.
+func (e *RegistryUnavailableError) Error() string {
+	return fmt.Sprintf("snapshot registry %s is unavailable: %s", e.Host, e.Err)
+}
+
+// Unwrap returns the error the connection failed with.
+func (e *RegistryUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound returns true if the registry rejected a request because the data doesn't exist, with 404 Not Found or
+// a MANIFEST_UNKNOWN error. Callers can tell missing data apart without depending on the errors of the registry
+// client the cache is implemented with.
+func IsNotFound(err error) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return false
+	}
+
+	if terr.StatusCode == http.StatusNotFound {
+		return true
+	}
+
+	for _, e := range terr.Errors {
+		if e.Code == transport.ManifestUnknownErrorCode {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNotFound(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "not found status",
+			err:      &transport.Error{StatusCode: http.StatusNotFound},
+			expected: true,
+		},
+		{
+			name: "unknown manifest",
+			err: &transport.Error{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}},
+			},
+			expected: true,
+		},
+		{
+			name:     "wrapped not found status",
+			err:      fmt.Errorf("failed to delete data: %w", &transport.Error{StatusCode: http.StatusNotFound}),
+			expected: true,
+		},
+		{
+			name: "other registry error",
+			err: &transport.Error{
+				StatusCode: http.StatusUnauthorized,
+				Errors:     []transport.Diagnostic{{Code: transport.UnauthorizedErrorCode}},
+			},
+		},
+		{
+			name: "not a transport error",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "no error",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsNotFound(tt.err))
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
 	"github.com/open-component-model/ocm-controller/pkg/cache"
 )
 
@@ -24,7 +27,29 @@ type FakeCache struct {
 	isCachedCalledWith            [][]any
 	pushDataString                string
 	pushDataErr                   error
+	pushDataManifest              string
 	pushDataCalledWith            []PushDataArguments
+	layerDigestString             string
+	layerDigestErr                error
+	layerDigestCalledWith         []PushDataArguments
+	copyArtifactString            string
+	copyArtifactErr               error
+	copyArtifactCalledWith        [][]any
+	copyArtifactTranscoded        bool
+	copyArtifactGzipLayers        []bool
+	copyArtifactUntagged          []bool
+	resolveArtifactDigest         string
+	resolveArtifactErr            error
+	resolveArtifactCalledWith     [][]any
+	artifactSize                  int64
+	artifactSizeErr               error
+	artifactSizeCalledWith        [][]any
+	artifactLayerDigest           string
+	artifactLayerDigestErr        error
+	artifactLayerDigestCalledWith [][]any
+	dataSize                      int64
+	dataSizeErr                   error
+	dataSizeCalledWith            [][]any
 	fetchDataByIdentityReader     io.ReadCloser
 	fetchDataByIdentityDigest     string
 	fetchDataByIdentityErr        error
@@ -34,6 +59,17 @@ type FakeCache struct {
 	fetchDataByDigestCalledWith   [][]any
 	deleteDataErr                 error
 	deleteDataCalledWith          [][]any
+	tagDataErr                    error
+	tagDataCalledWith             [][]any
+	pushReferrerDigest            string
+	pushReferrerErr               error
+	pushReferrerCalledWith        [][]any
+	manifestAnnotations           map[string]string
+	manifestAnnotationsErr        error
+	manifestAnnotationsCalledWith [][]any
+	forRegistryErr                error
+	forRegistryCalledWith         []string
+	forRegistryKeychains          []authn.Keychain
 }
 
 func (f *FakeCache) IsCached(ctx context.Context, name, tag string) (bool, error) {
@@ -54,13 +90,30 @@ func (f *FakeCache) IsCachedWasNotCalled() bool {
 	return len(f.isCachedCalledWith) == 0
 }
 
-func (f *FakeCache) PushData(ctx context.Context, data io.ReadCloser, mediaType, name, tag string) (string, error) {
+func (f *FakeCache) PushData(ctx context.Context, data io.ReadCloser, mediaType, name, tag string, opts ...cache.PushOption) (string, error) {
 	content, err := io.ReadAll(data)
 	if err != nil {
 		return "", fmt.Errorf("failed to read read closer: %w", err)
 	}
 
-	f.pushDataCalledWith = append(f.pushDataCalledWith, PushDataArguments{Content: string(content), Name: name, Version: tag})
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	f.pushDataCalledWith = append(f.pushDataCalledWith, PushDataArguments{
+		Content:      string(content),
+		Name:         name,
+		Version:      tag,
+		Uncompressed: options.Uncompressed,
+		Annotations:  options.Annotations,
+		Untagged:     options.Untagged,
+	})
+
+	if options.Result != nil {
+		options.Result.Manifest = f.pushDataManifest
+	}
+
 	return f.pushDataString, f.pushDataErr
 }
 
@@ -69,10 +122,18 @@ func (f *FakeCache) PushDataReturns(digest string, err error) {
 	f.pushDataErr = err
 }
 
+// PushDataReturnsManifest records the manifest digest in the push result of PushData.
+func (f *FakeCache) PushDataReturnsManifest(digest string) {
+	f.pushDataManifest = digest
+}
+
 type PushDataArguments struct {
-	Name    string
-	Version string
-	Content string
+	Name         string
+	Version      string
+	Content      string
+	Uncompressed bool
+	Annotations  map[string]string
+	Untagged     bool
 }
 
 func (f *FakeCache) PushDataCallingArgumentsOnCall(i int) PushDataArguments {
@@ -83,6 +144,151 @@ func (f *FakeCache) PushDataWasNotCalled() bool {
 	return len(f.pushDataCalledWith) == 0
 }
 
+func (f *FakeCache) LayerDigest(data io.Reader, opts ...cache.PushOption) (string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read reader: %w", err)
+	}
+
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	f.layerDigestCalledWith = append(f.layerDigestCalledWith, PushDataArguments{
+		Content:      string(content),
+		Uncompressed: options.Uncompressed,
+	})
+
+	return f.layerDigestString, f.layerDigestErr
+}
+
+func (f *FakeCache) LayerDigestReturns(digest string, err error) {
+	f.layerDigestString = digest
+	f.layerDigestErr = err
+}
+
+func (f *FakeCache) LayerDigestCallingArgumentsOnCall(i int) PushDataArguments {
+	return f.layerDigestCalledWith[i]
+}
+
+func (f *FakeCache) LayerDigestWasNotCalled() bool {
+	return len(f.layerDigestCalledWith) == 0
+}
+
+func (f *FakeCache) CopyArtifact(
+	ctx context.Context,
+	source, name, tag string,
+	auth authn.Authenticator,
+	platform *v1.Platform,
+	opts ...cache.PushOption,
+) (string, error) {
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Result != nil {
+		options.Result.Transcoded = options.GzipLayers && f.copyArtifactTranscoded
+		options.Result.Manifest = f.copyArtifactString
+	}
+
+	f.copyArtifactCalledWith = append(f.copyArtifactCalledWith, []any{source, name, tag, platform})
+	f.copyArtifactGzipLayers = append(f.copyArtifactGzipLayers, options.GzipLayers)
+	f.copyArtifactUntagged = append(f.copyArtifactUntagged, options.Untagged)
+	return f.copyArtifactString, f.copyArtifactErr
+}
+
+// CopyArtifactTranscodes reports copies with WithGzipLayers as transcoded in their push result.
+func (f *FakeCache) CopyArtifactTranscodes(transcoded bool) {
+	f.copyArtifactTranscoded = transcoded
+}
+
+func (f *FakeCache) CopyArtifactGzipLayersOnCall(i int) bool {
+	return f.copyArtifactGzipLayers[i]
+}
+
+func (f *FakeCache) CopyArtifactUntaggedOnCall(i int) bool {
+	return f.copyArtifactUntagged[i]
+}
+
+func (f *FakeCache) CopyArtifactReturns(digest string, err error) {
+	f.copyArtifactString = digest
+	f.copyArtifactErr = err
+}
+
+func (f *FakeCache) CopyArtifactCallingArgumentsOnCall(i int) []any {
+	return f.copyArtifactCalledWith[i]
+}
+
+func (f *FakeCache) CopyArtifactWasNotCalled() bool {
+	return len(f.copyArtifactCalledWith) == 0
+}
+
+func (f *FakeCache) ResolveArtifact(ctx context.Context, source string, auth authn.Authenticator) (string, error) {
+	f.resolveArtifactCalledWith = append(f.resolveArtifactCalledWith, []any{source})
+	return f.resolveArtifactDigest, f.resolveArtifactErr
+}
+
+func (f *FakeCache) ResolveArtifactReturns(digest string, err error) {
+	f.resolveArtifactDigest = digest
+	f.resolveArtifactErr = err
+}
+
+func (f *FakeCache) ResolveArtifactCallingArgumentsOnCall(i int) []any {
+	return f.resolveArtifactCalledWith[i]
+}
+
+func (f *FakeCache) ResolveArtifactWasNotCalled() bool {
+	return len(f.resolveArtifactCalledWith) == 0
+}
+
+func (f *FakeCache) ArtifactSize(ctx context.Context, source string, auth authn.Authenticator, platform *v1.Platform) (int64, error) {
+	f.artifactSizeCalledWith = append(f.artifactSizeCalledWith, []any{source, platform})
+	return f.artifactSize, f.artifactSizeErr
+}
+
+func (f *FakeCache) ArtifactSizeReturns(size int64, err error) {
+	f.artifactSize = size
+	f.artifactSizeErr = err
+}
+
+func (f *FakeCache) ArtifactSizeCallingArgumentsOnCall(i int) []any {
+	return f.artifactSizeCalledWith[i]
+}
+
+func (f *FakeCache) ArtifactSizeWasNotCalled() bool {
+	return len(f.artifactSizeCalledWith) == 0
+}
+
+func (f *FakeCache) ArtifactLayerDigest(
+	ctx context.Context,
+	source string,
+	auth authn.Authenticator,
+	platform *v1.Platform,
+	opts ...cache.PushOption,
+) (string, error) {
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+	f.artifactLayerDigestCalledWith = append(f.artifactLayerDigestCalledWith, []any{source, platform, options.GzipLayers})
+	return f.artifactLayerDigest, f.artifactLayerDigestErr
+}
+
+func (f *FakeCache) ArtifactLayerDigestReturns(digest string, err error) {
+	f.artifactLayerDigest = digest
+	f.artifactLayerDigestErr = err
+}
+
+func (f *FakeCache) ArtifactLayerDigestCallingArgumentsOnCall(i int) []any {
+	return f.artifactLayerDigestCalledWith[i]
+}
+
+func (f *FakeCache) ArtifactLayerDigestWasNotCalled() bool {
+	return len(f.artifactLayerDigestCalledWith) == 0
+}
+
 func (f *FakeCache) FetchDataByIdentity(ctx context.Context, name, tag string) (io.ReadCloser, string, error) {
 	f.fetchDataByIdentityCalledWith = append(f.fetchDataByIdentityCalledWith, []any{name, tag})
 	return f.fetchDataByIdentityReader, f.fetchDataByIdentityDigest, f.fetchDataByIdentityErr
@@ -156,4 +362,107 @@ func (f *FakeCache) DeleteDataWasNotCalled() bool {
 	return len(f.deleteDataCalledWith) == 0
 }
 
+func (f *FakeCache) TagData(ctx context.Context, name, tag, newTag string) error {
+	f.tagDataCalledWith = append(f.tagDataCalledWith, []any{name, tag, newTag})
+	return f.tagDataErr
+}
+
+func (f *FakeCache) TagDataReturns(err error) {
+	f.tagDataErr = err
+}
+
+func (f *FakeCache) TagDataCallingArgumentsOnCall(i int) []any {
+	return f.tagDataCalledWith[i]
+}
+
+func (f *FakeCache) TagDataWasNotCalled() bool {
+	return len(f.tagDataCalledWith) == 0
+}
+
+func (f *FakeCache) PushReferrer(ctx context.Context, data io.Reader, artifactType, name, tag string) (string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read referrer data: %w", err)
+	}
+	f.pushReferrerCalledWith = append(f.pushReferrerCalledWith, []any{string(content), artifactType, name, tag})
+	return f.pushReferrerDigest, f.pushReferrerErr
+}
+
+func (f *FakeCache) PushReferrerReturns(digest string, err error) {
+	f.pushReferrerDigest = digest
+	f.pushReferrerErr = err
+}
+
+func (f *FakeCache) PushReferrerCallingArgumentsOnCall(i int) []any {
+	return f.pushReferrerCalledWith[i]
+}
+
+func (f *FakeCache) PushReferrerWasNotCalled() bool {
+	return len(f.pushReferrerCalledWith) == 0
+}
+
+// ManifestAnnotations returns the annotations configured with ManifestAnnotationsReturns.
+func (f *FakeCache) ManifestAnnotations(ctx context.Context, name, tag string) (map[string]string, error) {
+	f.manifestAnnotationsCalledWith = append(f.manifestAnnotationsCalledWith, []any{name, tag})
+	return f.manifestAnnotations, f.manifestAnnotationsErr
+}
+
+func (f *FakeCache) ManifestAnnotationsReturns(annotations map[string]string, err error) {
+	f.manifestAnnotations = annotations
+	f.manifestAnnotationsErr = err
+}
+
+func (f *FakeCache) ManifestAnnotationsCallingArgumentsOnCall(i int) []any {
+	return f.manifestAnnotationsCalledWith[i]
+}
+
+func (f *FakeCache) ManifestAnnotationsWasNotCalled() bool {
+	return len(f.manifestAnnotationsCalledWith) == 0
+}
+
+func (f *FakeCache) DataSize(ctx context.Context, name, tag string) (int64, error) {
+	f.dataSizeCalledWith = append(f.dataSizeCalledWith, []any{name, tag})
+	return f.dataSize, f.dataSizeErr
+}
+
+func (f *FakeCache) DataSizeReturns(size int64, err error) {
+	f.dataSize = size
+	f.dataSizeErr = err
+}
+
+func (f *FakeCache) DataSizeCallingArgumentsOnCall(i int) []any {
+	return f.dataSizeCalledWith[i]
+}
+
+func (f *FakeCache) DataSizeWasNotCalled() bool {
+	return len(f.dataSizeCalledWith) == 0
+}
+
+// ForRegistry returns the fake itself, so the calls for all registries are recorded together.
+func (f *FakeCache) ForRegistry(address string, keychain authn.Keychain) (cache.Cache, error) {
+	f.forRegistryCalledWith = append(f.forRegistryCalledWith, address)
+	f.forRegistryKeychains = append(f.forRegistryKeychains, keychain)
+	if f.forRegistryErr != nil {
+		return nil, f.forRegistryErr
+	}
+
+	return f, nil
+}
+
+func (f *FakeCache) ForRegistryReturns(err error) {
+	f.forRegistryErr = err
+}
+
+func (f *FakeCache) ForRegistryCallingArgumentsOnCall(i int) string {
+	return f.forRegistryCalledWith[i]
+}
+
+func (f *FakeCache) ForRegistryKeychainOnCall(i int) authn.Keychain {
+	return f.forRegistryKeychains[i]
+}
+
+func (f *FakeCache) ForRegistryWasNotCalled() bool {
+	return len(f.forRegistryCalledWith) == 0
+}
+
 var _ cache.Cache = &FakeCache{}
@@ -6,10 +6,12 @@ package component
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -17,18 +19,38 @@ import (
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
 )
 
+// ErrComponentVersionNotFound is returned when the reference path selects a version of a component
+// for which no component descriptor exists.
+var ErrComponentVersionNotFound = errors.New("component descriptor for requested version not found")
+
+// ErrComponentDescriptorNotCreated is returned when the component descriptor of a component, for example of a
+// referenced component, hasn't been created yet by the reconciliation of the ComponentVersion.
+var ErrComponentDescriptorNotCreated = errors.New("component descriptor not created yet")
+
 func getComponentDescriptorObject(ctx context.Context, c client.Client, ref meta.NamespacedObjectReference) (*v1alpha1.ComponentDescriptor, error) {
 	componentDescriptor := &v1alpha1.ComponentDescriptor{}
 	if err := c.Get(ctx, types.NamespacedName{
 		Name:      ref.Name,
 		Namespace: ref.Namespace,
 	}, componentDescriptor); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: component descriptor %s/%s: %w", ErrComponentDescriptorNotCreated, ref.Namespace, ref.Name, err)
+		}
+
 		return nil, fmt.Errorf("failed to find component descriptor: %w", err)
 	}
 
 	return componentDescriptor, nil
 }
 
+// GetComponentDescriptor returns the component descriptor selected by the reference path. The reference path
+// lists the names of the component references leading from the component to the referenced component, for
+// example the component references of the component and of the component it references. Intermediate
+// references may be left out, a path consisting only of the name of the referenced component matches it at any
+// depth. If an identity in the reference path contains a version, only references with that version are
+// matched. In case the component is referenced, but not with the requested version, ErrComponentVersionNotFound
+// is returned. If the component descriptor of the referenced component doesn't exist yet,
+// ErrComponentDescriptorNotCreated is returned.
 func GetComponentDescriptor(
 	ctx context.Context,
 	c client.Client,
@@ -40,38 +62,126 @@ func GetComponentDescriptor(
 		return getComponentDescriptorObject(ctx, c, obj.ComponentDescriptorRef)
 	}
 
-	// Handle the nested loop. If we get to this part, we check if the reference that we found
-	// is the one we were looking for.
-	if referencePathContainsName(obj.Name, refPath) {
-		return getComponentDescriptorObject(ctx, c, obj.ComponentDescriptorRef)
+	desc, otherVersion, err := findComponentDescriptor(ctx, c, refPath, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if desc == nil && otherVersion {
+		return nil, fmt.Errorf("%w: %+v", ErrComponentVersionNotFound, refPath)
+	}
+
+	return desc, nil
+}
+
+// findComponentDescriptor walks the reference tree looking for the reference selected by refPath. A reference
+// matching the first identity of refPath consumes it, the last identity selects the component descriptor.
+// The returned bool reports whether a reference with a matching name but a different version was seen.
+func findComponentDescriptor(
+	ctx context.Context,
+	c client.Client,
+	refPath []ocmmetav1.Identity,
+	obj v1alpha1.Reference,
+) (*v1alpha1.ComponentDescriptor, bool, error) {
+	nameMatch, versionMatch := referenceMatches(obj, refPath[0])
+	if nameMatch && versionMatch && len(refPath) == 1 {
+		desc, err := getComponentDescriptorObject(ctx, c, obj.ComponentDescriptorRef)
+
+		return desc, false, err
+	}
+
+	otherVersion := nameMatch && !versionMatch && len(refPath) == 1
+
+	// The references of this reference are looked up with the rest of the path if it matched.
+	remaining := refPath
+	if nameMatch && versionMatch {
+		remaining = refPath[1:]
 	}
 
-	// This is not the reference object we are looking for, let's dig deeper.
 	for _, ref := range obj.References {
-		desc, err := GetComponentDescriptor(ctx, c, refPath, ref)
+		desc, other, err := findComponentDescriptor(ctx, c, remaining, ref)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		// recursive call for ref did not result in a reference
 		// get the next ref, do the same lookup again
 		if desc == nil {
+			otherVersion = otherVersion || other
 			continue
 		}
 
-		return desc, nil
+		return desc, false, nil
 	}
 
-	return nil, nil
+	return nil, otherVersion, nil
+}
+
+// referenceMatches reports whether the reference has the name of the identity, and if so, whether its version
+// matches the version requested for it. An identity without a version matches any version.
+func referenceMatches(obj v1alpha1.Reference, identity ocmmetav1.Identity) (bool, bool) {
+	if name, ok := identity[compdesc.SystemIdentityName]; !ok || name != obj.Name {
+		return false, false
+	}
+
+	version, ok := identity[compdesc.SystemIdentityVersion]
+
+	return true, !ok || version == obj.Version
 }
 
-func referencePathContainsName(name string, refPath []ocmmetav1.Identity) bool {
-	for _, ref := range refPath {
-		for k, v := range ref {
-			if k == compdesc.SystemIdentityName && name == v {
-				return true
-			}
+// GetComponentName returns the name of the component selected by the reference path. Only the root of the
+// reference tree carries the name of its component, references are named by the component references, so the name
+// of a referenced component is looked up in the component descriptor of the component referencing it.
+func GetComponentName(
+	ctx context.Context,
+	c client.Client,
+	refPath []ocmmetav1.Identity,
+	obj v1alpha1.Reference,
+) (string, error) {
+	if len(refPath) == 0 {
+		return obj.Name, nil
+	}
+
+	ref, parent := findReference(refPath, obj, nil)
+	if ref == nil {
+		return "", fmt.Errorf("%w: %+v", ErrComponentVersionNotFound, refPath)
+	}
+
+	if parent == nil {
+		return ref.Name, nil
+	}
+
+	desc, err := getComponentDescriptorObject(ctx, c, parent.ComponentDescriptorRef)
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range desc.Spec.References {
+		if r.Name == ref.Name && r.Version == ref.Version {
+			return r.ComponentName, nil
+		}
+	}
+
+	return "", fmt.Errorf("component reference %s:%s not found in component descriptor %s", ref.Name, ref.Version, desc.Name)
+}
+
+// findReference returns the reference selected by refPath the same way findComponentDescriptor does, together with
+// the reference it is referenced by. The parent is nil if the root of the tree is selected.
+func findReference(refPath []ocmmetav1.Identity, obj v1alpha1.Reference, parent *v1alpha1.Reference) (*v1alpha1.Reference, *v1alpha1.Reference) {
+	nameMatch, versionMatch := referenceMatches(obj, refPath[0])
+	if nameMatch && versionMatch && len(refPath) == 1 {
+		return &obj, parent
+	}
+
+	remaining := refPath
+	if nameMatch && versionMatch {
+		remaining = refPath[1:]
+	}
+
+	for _, ref := range obj.References {
+		if found, foundParent := findReference(remaining, ref, &obj); found != nil {
+			return found, foundParent
 		}
 	}
 
-	return false
+	return nil, nil
 }
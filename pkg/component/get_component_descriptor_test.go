@@ -16,6 +16,7 @@ import (
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
 )
 
 func TestGetNestedComponentDescriptor(t *testing.T) {
@@ -45,7 +46,8 @@ func TestGetNestedComponentDescriptor(t *testing.T) {
 								},
 							},
 							{
-								Name: "nested-twice-second",
+								Name:    "nested-twice-second",
+								Version: "v0.1.0",
 								ComponentDescriptorRef: meta.NamespacedObjectReference{
 									Name:      componentName,
 									Namespace: namespace,
@@ -95,6 +97,71 @@ func TestGetNestedComponentDescriptor(t *testing.T) {
 		assert.Equal(t, componentName, comp.Name)
 	})
 
+	t.Run("with reference path and version", func(t *testing.T) {
+		refPath := []ocmmetav1.Identity{
+			{
+				"name":    "nested-twice-second",
+				"version": "v0.1.0",
+			},
+		}
+		comp, err := GetComponentDescriptor(context.Background(), client, refPath, obj.Status.ComponentDescriptor)
+		assert.NoError(t, err)
+		assert.Equal(t, componentName, comp.Name)
+	})
+
+	t.Run("with reference path and missing version", func(t *testing.T) {
+		refPath := []ocmmetav1.Identity{
+			{
+				"name":    "nested-twice-second",
+				"version": "v0.2.0",
+			},
+		}
+		_, err := GetComponentDescriptor(context.Background(), client, refPath, obj.Status.ComponentDescriptor)
+		assert.ErrorIs(t, err, ErrComponentVersionNotFound)
+	})
+
+	t.Run("with reference path through the referencing component", func(t *testing.T) {
+		refPath := []ocmmetav1.Identity{
+			{
+				"name": "nested-once",
+			},
+			{
+				"name": "nested-twice-second",
+			},
+		}
+		comp, err := GetComponentDescriptor(context.Background(), client, refPath, obj.Status.ComponentDescriptor)
+		assert.NoError(t, err)
+		assert.Equal(t, componentName, comp.Name)
+	})
+
+	t.Run("with reference path through a component not referencing it", func(t *testing.T) {
+		refPath := []ocmmetav1.Identity{
+			{
+				"name": "nested-twice",
+			},
+			{
+				"name": "nested-twice-second",
+			},
+		}
+		comp, err := GetComponentDescriptor(context.Background(), client, refPath, obj.Status.ComponentDescriptor)
+		assert.NoError(t, err)
+		assert.Nil(t, comp)
+	})
+
+	t.Run("with reference path to a component descriptor not created yet", func(t *testing.T) {
+		refPath := []ocmmetav1.Identity{
+			{
+				"name": "nested-once",
+			},
+			{
+				"name": "nested-twice",
+			},
+		}
+		_, err := GetComponentDescriptor(context.Background(), client, refPath, obj.Status.ComponentDescriptor)
+		assert.ErrorIs(t, err, ErrComponentDescriptorNotCreated)
+		assert.ErrorContains(t, err, "default/not-component")
+	})
+
 	t.Run("without reference path", func(t *testing.T) {
 		loc := &v1alpha1.Localization{
 			Spec: v1alpha1.MutationSpec{
@@ -108,3 +175,60 @@ func TestGetNestedComponentDescriptor(t *testing.T) {
 		assert.Equal(t, notNestedName, comp.Name)
 	})
 }
+
+func TestGetComponentName(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	root := v1alpha1.Reference{
+		Name:    "github.com/skarlso/ocm-demo-index",
+		Version: "v0.0.1",
+		ComponentDescriptorRef: meta.NamespacedObjectReference{
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+			Namespace: "default",
+		},
+		References: []v1alpha1.Reference{
+			{
+				Name:    "podinfo",
+				Version: "v6.3.5",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-podinfo-v6.3.5-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+	rootDesc := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      root.ComponentDescriptorRef.Name,
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				References: []v3alpha1.Reference{
+					{
+						ElementMeta:   v3alpha1.ElementMeta{Name: "podinfo", Version: "v6.3.5"},
+						ComponentName: "github.com/skarlso/podinfo",
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+	client := fake.NewClientBuilder().WithObjects(rootDesc).WithScheme(scheme).Build()
+
+	name, err := GetComponentName(context.Background(), client, nil, root)
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/skarlso/ocm-demo-index", name)
+
+	name, err = GetComponentName(context.Background(), client, []ocmmetav1.Identity{{"name": "github.com/skarlso/ocm-demo-index"}}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/skarlso/ocm-demo-index", name)
+
+	name, err = GetComponentName(context.Background(), client, []ocmmetav1.Identity{{"name": "podinfo"}}, root)
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/skarlso/podinfo", name, "a reference is named by the component reference")
+
+	_, err = GetComponentName(context.Background(), client, []ocmmetav1.Identity{{"name": "podinfo", "version": "v6.4.0"}}, root)
+	assert.ErrorIs(t, err, ErrComponentVersionNotFound)
+}
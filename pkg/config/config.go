@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ComponentControllerConfigKind is the kind of the configuration file of the controller.
+const ComponentControllerConfigKind = "ComponentControllerConfig"
+
+// ComponentControllerConfig is the configuration of the controller, loaded from the file given with --config. Each
+// field sets the setting of the flag it is documented with, unless that flag is given on the command line, which
+// overrides the file. Fields that aren't set keep the default of their flag.
+type ComponentControllerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// OCIRegistryAddr sets --oci-registry-addr.
+	OCIRegistryAddr string `json:"ociRegistryAddr,omitempty"`
+
+	// OCIRegistryNamespace sets --oci-registry-namespace.
+	OCIRegistryNamespace string `json:"ociRegistryNamespace,omitempty"`
+
+	// OCIRegistryScheme sets --oci-registry-scheme.
+	OCIRegistryScheme string `json:"ociRegistryScheme,omitempty"`
+
+	// OCIRegistryInsecure sets --oci-registry-insecure.
+	OCIRegistryInsecure *bool `json:"ociRegistryInsecure,omitempty"`
+
+	// OCIRegistryInsecureSkipVerify sets --oci-registry-insecure-skip-verify.
+	OCIRegistryInsecureSkipVerify *bool `json:"ociRegistryInsecureSkipVerify,omitempty"`
+
+	// CertificateSecretName sets --certificate-secret-name.
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+
+	// InsecureRegistries sets --insecure-registries.
+	InsecureRegistries []string `json:"insecureRegistries,omitempty"`
+
+	// HTTPRegistries sets --allow-http.
+	HTTPRegistries []string `json:"httpRegistries,omitempty"`
+
+	// SkipTLSVerifyRegistries sets --skip-tls-verify.
+	SkipTLSVerifyRegistries []string `json:"skipTLSVerifyRegistries,omitempty"`
+
+	// RegistryTimeout sets --registry-timeout.
+	RegistryTimeout *metav1.Duration `json:"registryTimeout,omitempty"`
+
+	// RegistryUnreachableThreshold sets --registry-unreachable-threshold.
+	RegistryUnreachableThreshold *metav1.Duration `json:"registryUnreachableThreshold,omitempty"`
+
+	// ResourceConcurrency sets --resource-concurrency.
+	ResourceConcurrency *int `json:"resourceConcurrency,omitempty"`
+
+	// DefaultRequeueInterval sets --default-requeue-interval.
+	DefaultRequeueInterval *metav1.Duration `json:"defaultRequeueInterval,omitempty"`
+
+	// RequeueJitter sets --requeue-jitter.
+	RequeueJitter *float64 `json:"requeueJitter,omitempty"`
+
+	// ComponentRefTimeout sets --component-ref-timeout.
+	ComponentRefTimeout *metav1.Duration `json:"componentRefTimeout,omitempty"`
+}
+
+// Load reads the configuration from the YAML or JSON file at path. Unknown fields are rejected, so a misspelled
+// setting doesn't go unnoticed.
+func Load(path string) (*ComponentControllerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config := &ComponentControllerConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if config.Kind != "" && config.Kind != ComponentControllerConfigKind {
+		return nil, fmt.Errorf("config file %s is of kind %s, must be %s", path, config.Kind, ComponentControllerConfigKind)
+	}
+
+	return config, nil
+}
+
+// ApplyTo sets the settings of the configuration, except for the settings whose flags have been set on the command
+// line, which override the file.
+func (c *ComponentControllerConfig) ApplyTo(settings *Settings, flags *flag.FlagSet) {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	setString := func(name string, dst *string, value string) {
+		if value != "" && !set[name] {
+			*dst = value
+		}
+	}
+	setList := func(name string, dst *[]string, value []string) {
+		if len(value) > 0 && !set[name] {
+			*dst = value
+		}
+	}
+	setBool := func(name string, dst *bool, value *bool) {
+		if value != nil && !set[name] {
+			*dst = *value
+		}
+	}
+	setDuration := func(name string, dst *time.Duration, value *metav1.Duration) {
+		if value != nil && !set[name] {
+			*dst = value.Duration
+		}
+	}
+
+	setString("oci-registry-addr", &settings.OCIRegistryAddr, c.OCIRegistryAddr)
+	setString("oci-registry-namespace", &settings.OCIRegistryNamespace, c.OCIRegistryNamespace)
+	setString("oci-registry-scheme", &settings.OCIRegistryScheme, c.OCIRegistryScheme)
+	setBool("oci-registry-insecure", &settings.OCIRegistryInsecure, c.OCIRegistryInsecure)
+	setBool("oci-registry-insecure-skip-verify", &settings.OCIRegistryInsecureSkipVerify, c.OCIRegistryInsecureSkipVerify)
+	setString("certificate-secret-name", &settings.CertificateSecretName, c.CertificateSecretName)
+	setList("insecure-registries", &settings.InsecureRegistries, c.InsecureRegistries)
+	setList("allow-http", &settings.HTTPRegistries, c.HTTPRegistries)
+	setList("skip-tls-verify", &settings.SkipTLSVerifyRegistries, c.SkipTLSVerifyRegistries)
+	setDuration("registry-timeout", &settings.RegistryTimeout, c.RegistryTimeout)
+	setDuration("registry-unreachable-threshold", &settings.RegistryUnreachableThreshold, c.RegistryUnreachableThreshold)
+	setDuration("default-requeue-interval", &settings.DefaultRequeueInterval, c.DefaultRequeueInterval)
+	setDuration("component-ref-timeout", &settings.ComponentRefTimeout, c.ComponentRefTimeout)
+
+	if c.ResourceConcurrency != nil && !set["resource-concurrency"] {
+		settings.ResourceConcurrency = *c.ResourceConcurrency
+	}
+
+	if c.RequeueJitter != nil && !set["requeue-jitter"] {
+		settings.RequeueJitter = *c.RequeueJitter
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadAndApply(t *testing.T) {
+	path := writeConfig(t, `apiVersion: config.ocm.software/v1alpha1
+kind: ComponentControllerConfig
+ociRegistryAddr: registry.ocm-system.svc:5000
+ociRegistryInsecure: true
+insecureRegistries:
+- ghcr.io
+- registry.local
+registryTimeout: 30s
+resourceConcurrency: 8
+defaultRequeueInterval: 5m
+requeueJitter: 0.2
+`)
+
+	settings := &Settings{
+		ResourceConcurrency:    4,
+		RegistryTimeout:        time.Minute,
+		ComponentRefTimeout:    time.Hour,
+		SnapshotAnnotations:    []string{"type"},
+		DefaultRequeueInterval: 10 * time.Minute,
+	}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.IntVar(&settings.ResourceConcurrency, "resource-concurrency", settings.ResourceConcurrency, "")
+	require.NoError(t, flags.Parse([]string{"--resource-concurrency=2"}))
+
+	config, err := Load(path)
+	require.NoError(t, err)
+	config.ApplyTo(settings, flags)
+
+	assert.Equal(t, &Settings{
+		OCIRegistryAddr:        "registry.ocm-system.svc:5000",
+		OCIRegistryInsecure:    true,
+		InsecureRegistries:     []string{"ghcr.io", "registry.local"},
+		RegistryTimeout:        30 * time.Second,
+		ResourceConcurrency:    2,
+		DefaultRequeueInterval: 5 * time.Minute,
+		RequeueJitter:          0.2,
+		// Settings the file doesn't set keep the defaults of their flags.
+		ComponentRefTimeout: time.Hour,
+		SnapshotAnnotations: []string{"type"},
+	}, settings, "the flag given on the command line should override the file")
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		errStr  string
+	}{
+		{
+			name:    "unknown setting",
+			content: "ociRegistryAddres: registry.local:5000\n",
+			errStr:  `unknown field "ociRegistryAddres"`,
+		},
+		{
+			name:    "wrong kind",
+			content: "kind: ResourceControllerConfig\n",
+			errStr:  "is of kind ResourceControllerConfig, must be ComponentControllerConfig",
+		},
+		{
+			name:    "invalid duration",
+			content: "registryTimeout: soon\n",
+			errStr:  "failed to parse config file",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			assert.ErrorContains(t, err, tt.errStr)
+		})
+	}
+}
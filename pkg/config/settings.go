@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import "time"
+
+// Settings are the settings of the controller. Each field holds the value of the flag it is documented with, the
+// flags are parsed into the settings and ComponentControllerConfig.ApplyTo sets those of the config file. Lists are
+// given as comma separated flags.
+type Settings struct {
+	// MetricsAddr is set by --metrics-bind-address.
+	MetricsAddr string
+	// EventsAddr is set by --events-addr.
+	EventsAddr string
+	// ProbeAddr is set by --health-probe-bind-address.
+	ProbeAddr string
+	// EnableLeaderElection is set by --leader-elect.
+	EnableLeaderElection bool
+
+	// OCIRegistryAddr is set by --oci-registry-addr.
+	OCIRegistryAddr string
+	// OCIRegistryNamespace is set by --oci-registry-namespace.
+	OCIRegistryNamespace string
+	// OCIRegistryScheme is set by --oci-registry-scheme.
+	OCIRegistryScheme string
+	// OCIRegistryInsecure is set by --oci-registry-insecure.
+	OCIRegistryInsecure bool
+	// OCIRegistryInsecureSkipVerify is set by --oci-registry-insecure-skip-verify.
+	OCIRegistryInsecureSkipVerify bool
+	// CertificateSecretName is set by --certificate-secret-name.
+	CertificateSecretName string
+	// RegistryTimeout is set by --registry-timeout.
+	RegistryTimeout time.Duration
+	// RegistryUnreachableThreshold is set by --registry-unreachable-threshold.
+	RegistryUnreachableThreshold time.Duration
+
+	// InsecureRegistries is set by --insecure-registries.
+	InsecureRegistries []string
+	// HTTPRegistries is set by --allow-http.
+	HTTPRegistries []string
+	// SkipTLSVerifyRegistries is set by --skip-tls-verify.
+	SkipTLSVerifyRegistries []string
+	// CABundleConfigMap is set by --ca-bundle-configmap.
+	CABundleConfigMap string
+	// AllowedRegistries is set by --allowed-registries.
+	AllowedRegistries []string
+	// RegistryMirrors is set by --registry-mirror-map.
+	RegistryMirrors []string
+	// SnapshotRegistries is set by --snapshot-registries.
+	SnapshotRegistries []string
+	// AllowedTransformHosts is set by --allowed-transform-hosts.
+	AllowedTransformHosts []string
+
+	// ResourceConcurrency is set by --resource-concurrency.
+	ResourceConcurrency int
+	// ResourceCacheSize is set by --resource-cache-size.
+	ResourceCacheSize int
+	// MaxResourceSize is set by --max-resource-size.
+	MaxResourceSize string
+	// DefaultRequeueInterval is set by --default-requeue-interval.
+	DefaultRequeueInterval time.Duration
+	// RequeueJitter is set by --requeue-jitter.
+	RequeueJitter float64
+	// ComponentRefTimeout is set by --component-ref-timeout.
+	ComponentRefTimeout time.Duration
+	// ResourceReconcileTimeout is set by --resource-reconcile-timeout.
+	ResourceReconcileTimeout time.Duration
+	// WatchNamespaces is set by --watch-namespaces.
+	WatchNamespaces []string
+
+	// SnapshotRepositoryTemplate is set by --snapshot-repository-template.
+	SnapshotRepositoryTemplate string
+	// SnapshotAnnotations is set by --snapshot-annotations.
+	SnapshotAnnotations []string
+	// SnapshotGCInterval is set by --snapshot-gc-interval.
+	SnapshotGCInterval time.Duration
+	// SnapshotGCGracePeriod is set by --snapshot-gc-grace-period.
+	SnapshotGCGracePeriod time.Duration
+	// FieldManager is set by --field-manager.
+	FieldManager string
+
+	// EnableWebhooks is set by --enable-webhooks.
+	EnableWebhooks bool
+	// EnableResourcePlan is set by --enable-resource-plan-endpoint.
+	EnableResourcePlan bool
+	// ResourcePlanConcurrency is set by --resource-plan-concurrency.
+	ResourcePlanConcurrency int
+	// OTLPEndpoint is set by --otlp-endpoint.
+	OTLPEndpoint string
+	// OTLPInsecure is set by --otlp-insecure.
+	OTLPInsecure bool
+}
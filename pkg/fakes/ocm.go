@@ -35,6 +35,38 @@ func SetAccessRef(t string) AccessOptionFunc {
 	}
 }
 
+// RemoveGlobalAccess removes the globalAccess field of the resource.
+func RemoveGlobalAccess() AccessOptionFunc {
+	return func(m map[string]any) {
+		delete(m, "globalAccess")
+	}
+}
+
+// SetImageReference replaces the access of the resource with an ociArtifact access for the given image reference.
+func SetImageReference(ref string) AccessOptionFunc {
+	return func(m map[string]any) {
+		for k := range m {
+			delete(m, k)
+		}
+		m["type"] = "ociArtifact"
+		m["imageReference"] = ref
+	}
+}
+
+// SetDownloadAccess replaces the access of the resource with an http access for the given URL and optional checksum.
+func SetDownloadAccess(url, checksum string) AccessOptionFunc {
+	return func(m map[string]any) {
+		for k := range m {
+			delete(m, k)
+		}
+		m["type"] = "http"
+		m["url"] = url
+		if checksum != "" {
+			m["checksum"] = checksum
+		}
+	}
+}
+
 // Resource presents a simple layout for a resource that AddComponentVersionToRepository will use.
 type Resource struct {
 	Name     string
@@ -44,6 +76,7 @@ type Resource struct {
 	Kind     string
 	Type     string
 	Relation ocmmetav1.ResourceRelation
+	Digest   *ocmmetav1.DigestSpec
 
 	// The component that contains this resource. This is a backlink in OCM.
 	Component *Component
@@ -52,6 +85,11 @@ type Resource struct {
 	AccessOptions []AccessOptionFunc
 }
 
+// Source presents a simple layout for a source of a component. Its data is accessed like the data of a resource.
+type Source struct {
+	Resource
+}
+
 // Sign defines the two needed values to perform a component signing.
 type Sign struct {
 	Name    string
@@ -73,6 +111,7 @@ type Component struct {
 	Version             string
 	Sign                *Sign
 	Resources           []*Resource
+	Sources             []*Source
 	ComponentDescriptor *compdesc.ComponentDescriptor
 }
 
@@ -214,6 +253,22 @@ func (c *Context) constructComponentDescriptor(
 		})
 	}
 
+	var sources compdesc.Sources
+
+	for _, src := range component.Sources {
+		sources = append(sources, compdesc.Source{
+			SourceMeta: compdesc.SourceMeta{
+				ElementMeta: compdesc.ElementMeta{
+					Name:    src.Name,
+					Version: src.Version,
+					Labels:  src.Labels,
+				},
+				Type: src.Type,
+			},
+			Access: src.AccessSpec(),
+		})
+	}
+
 	compd := &compdesc.ComponentDescriptor{
 		Metadata: compdesc.Metadata{
 			ConfiguredVersion: "v2",
@@ -227,6 +282,7 @@ func (c *Context) constructComponentDescriptor(
 				},
 			},
 			Resources: resources,
+			Sources:   sources,
 		},
 	}
 
@@ -360,15 +416,36 @@ func (c *Component) GetResources() []ocm.ResourceAccess {
 
 func (c *Component) GetResource(meta ocmmetav1.Identity) (ocm.ResourceAccess, error) {
 	for _, r := range c.Resources {
-		//  && r.Version == meta["version"] --> add this at some point
-		if r.Name == meta["name"] {
-			return r, nil
+		if r.Name != meta["name"] {
+			continue
 		}
+
+		if v, ok := meta["version"]; ok && r.Version != v {
+			continue
+		}
+
+		return r, nil
 	}
 
 	return nil, fmt.Errorf("failed to find resource on component with identity: %v", meta)
 }
 
+func (c *Component) GetSource(meta ocmmetav1.Identity) (ocm.SourceAccess, error) {
+	for _, s := range c.Sources {
+		if s.Name != meta["name"] {
+			continue
+		}
+
+		if v, ok := meta["version"]; ok && s.Version != v {
+			continue
+		}
+
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("failed to find source on component with identity: %v", meta)
+}
+
 func (c *Component) GetName() string {
 	return c.Name
 }
@@ -390,6 +467,7 @@ func (r *Resource) Meta() *ocm.ResourceMeta {
 		},
 		Type:     r.Type,
 		Relation: r.Relation,
+		Digest:   r.Digest,
 	}
 }
 
@@ -435,6 +513,21 @@ func (r *Resource) AccessMethod() (ocm.AccessMethod, error) {
 	return r, nil
 }
 
+// ************** Mock Source Access Value and Functions **************
+
+var _ ocm.SourceAccess = &Source{}
+
+func (s *Source) Meta() *ocm.SourceMeta {
+	return &ocm.SourceMeta{
+		ElementMeta: compdesc.ElementMeta{
+			Name:    s.Name,
+			Version: s.Version,
+			Labels:  s.Labels,
+		},
+		Type: s.Type,
+	}
+}
+
 // ************** Mock Access Method **************
 
 var _ ocm.AccessMethod = &Resource{}
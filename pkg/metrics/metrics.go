@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "ocm_controller"
+
+var (
+	// SnapshotPushTotal counts the number of snapshots that have been pushed to the in-cluster registry. The result
+	// is success, failure, or unchanged if the tag already pointed at the same manifest and nothing was written.
+	SnapshotPushTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_push_total",
+		Help:      "Number of snapshots pushed to the in-cluster registry.",
+	}, []string{"repository", "result"})
+
+	// SnapshotPushBytesTotal counts the number of bytes that have been pushed to the in-cluster registry.
+	SnapshotPushBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_push_bytes_total",
+		Help:      "Number of bytes pushed to the in-cluster registry.",
+	}, []string{"repository"})
+
+	// SnapshotLayerMountsTotal counts the snapshot layers that have been mounted from the previous version of a
+	// snapshot instead of being uploaded.
+	SnapshotLayerMountsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_layer_mounts_total",
+		Help:      "Number of snapshot layers mounted from the previous version of the snapshot instead of being uploaded.",
+	}, []string{"repository"})
+
+	// SnapshotPushDuration records the time it took to push a snapshot to the in-cluster registry.
+	SnapshotPushDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "snapshot_push_duration_seconds",
+		Help:      "Duration of pushing a snapshot to the in-cluster registry.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"repository"})
+
+	// SnapshotGarbageCollectedTotal counts the tags in the in-cluster registry that the snapshot garbage collector
+	// deleted, or failed to delete, because no Snapshot refers to them.
+	SnapshotGarbageCollectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_garbage_collected_total",
+		Help:      "Number of orphaned snapshot tags deleted from the in-cluster registry.",
+	}, []string{"result"})
+
+	// ResourceCacheHitsTotal counts the resources that have been copied from previously pushed data with the same digest.
+	ResourceCacheHitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resource_cache_hits_total",
+		Help:      "Number of resources copied from previously pushed data instead of being fetched from upstream.",
+	})
+
+	// ResourceCacheMissesTotal counts the resources with a digest that had to be fetched from upstream.
+	ResourceCacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resource_cache_misses_total",
+		Help:      "Number of resources with a digest that had to be fetched from upstream.",
+	})
+
+	// ResourceReconcileDuration records the time it took to reconcile a Resource.
+	ResourceReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "resource_reconcile_duration_seconds",
+		Help:      "Duration of a Resource reconciliation.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"name", "namespace"})
+
+	// ResourceReconcileErrorsTotal counts the number of failed Resource reconciliations.
+	ResourceReconcileErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resource_reconcile_errors_total",
+		Help:      "Number of failed Resource reconciliations.",
+	}, []string{"name", "namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		SnapshotPushTotal,
+		SnapshotPushBytesTotal,
+		SnapshotLayerMountsTotal,
+		SnapshotPushDuration,
+		SnapshotGarbageCollectedTotal,
+		ResourceCacheHitsTotal,
+		ResourceCacheMissesTotal,
+		ResourceReconcileDuration,
+		ResourceReconcileErrorsTotal,
+	)
+}
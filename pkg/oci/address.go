@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// ParseRegistryAddress validates the address of the in-cluster registry and normalizes it to host[:port], the form
+// the address is used in references. A http or https scheme and a trailing slash are stripped, the scheme is
+// returned separately. An address without a host, like the default :5000, refers to localhost.
+func ParseRegistryAddress(address string) (host, scheme string, err error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", "", errors.New("registry address must not be empty")
+	}
+
+	if before, after, ok := strings.Cut(address, "://"); ok {
+		scheme = strings.ToLower(before)
+		if scheme != "http" && scheme != "https" {
+			return "", "", fmt.Errorf("registry address %q has unsupported scheme %q, must be http or https", address, before)
+		}
+
+		address = after
+	}
+
+	host = strings.TrimRight(address, "/")
+	if strings.Contains(host, "/") {
+		return "", "", fmt.Errorf("registry address %q must not contain a path", address)
+	}
+
+	if u, err := url.Parse("//" + host); err != nil || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", fmt.Errorf("registry address %q must be of the form host[:port]", address)
+	}
+
+	if strings.Contains(host, ":") {
+		hostname, port, err := net.SplitHostPort(host)
+		if err != nil {
+			return "", "", fmt.Errorf("registry address %q must be of the form host[:port]: %w", address, err)
+		}
+
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("registry address %q has invalid port %q", address, port)
+		}
+
+		if hostname == "" {
+			hostname = "localhost"
+		}
+
+		host = net.JoinHostPort(hostname, port)
+	}
+
+	if _, err := ociname.NewRegistry(host, ociname.StrictValidation); err != nil {
+		return "", "", fmt.Errorf("registry address %q is not a valid registry: %w", address, err)
+	}
+
+	return host, scheme, nil
+}
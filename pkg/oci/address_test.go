@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistryAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		host    string
+		scheme  string
+		errStr  string
+	}{
+		{address: "registry.ocm-system.svc.cluster.local:5000", host: "registry.ocm-system.svc.cluster.local:5000"},
+		{address: "registry.local", host: "registry.local"},
+		{address: " registry.local:5000/ ", host: "registry.local:5000"},
+		{address: ":5000", host: "localhost:5000"},
+		{address: "[::1]:5000", host: "[::1]:5000"},
+		{address: "https://registry.local:5000", host: "registry.local:5000", scheme: "https"},
+		{address: "HTTP://registry.local:5000/", host: "registry.local:5000", scheme: "http"},
+		{address: "", errStr: "must not be empty"},
+		{address: "oci://registry.local:5000", errStr: "unsupported scheme"},
+		{address: "registry.local:5000/ocm", errStr: "must not contain a path"},
+		{address: "user@registry.local:5000", errStr: "must be of the form host[:port]"},
+		{address: "registry.local:http", errStr: "must be of the form host[:port]"},
+		{address: "registry.local:70000", errStr: "invalid port"},
+		{address: "registry.local:", errStr: "invalid port"},
+		{address: "::1", errStr: "must be of the form host[:port]"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.address, func(t *testing.T) {
+			host, scheme, err := ParseRegistryAddress(tt.address)
+			if tt.errStr != "" {
+				assert.ErrorContains(t, err, tt.errStr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.scheme, scheme)
+		})
+	}
+}
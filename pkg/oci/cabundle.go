@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WithCABundle trusts the PEM encoded certificates in the values of the ConfigMap, in addition to the system
+// certificates, for the registries resources are fetched from and snapshot registries other than the in-cluster
+// registry. This allows registries with certificates of an internal CA to be verified. The ConfigMap is read
+// with the client set by WithClient and changes to it are picked up by the next request.
+func WithCABundle(namespace, name string) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.caBundle = &apitypes.NamespacedName{Namespace: namespace, Name: name}
+	}
+}
+
+// caBundleTransport verifies the certificates of registries against the system certificates and the certificates
+// of a CA bundle ConfigMap. The transport is rebuilt when the ConfigMap changes.
+type caBundleTransport struct {
+	reader client.Reader
+	key    apitypes.NamespacedName
+	base   *http.Transport
+
+	mu              sync.Mutex
+	resourceVersion string
+	transport       *http.Transport
+}
+
+// newCABundleTransport returns a transport verifying certificates against the CA bundle, the TLS configuration of
+// base is replaced.
+func (c *Client) newCABundleTransport(base *http.Transport) (*caBundleTransport, error) {
+	if c.Client == nil {
+		return nil, fmt.Errorf("client must not be nil if a CA bundle is used, please set WithClient when creating the oci cache")
+	}
+
+	return &caBundleTransport{reader: c.Client, key: *c.caBundle, base: base}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *caBundleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.current(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.RoundTrip(req)
+}
+
+// current returns the transport for the current version of the CA bundle.
+func (t *caBundleTransport) current(req *http.Request) (*http.Transport, error) {
+	bundle := &corev1.ConfigMap{}
+	if err := t.reader.Get(req.Context(), t.key, bundle); err != nil {
+		return nil, fmt.Errorf("failed to get CA bundle %s: %w", t.key, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transport != nil && t.resourceVersion == bundle.ResourceVersion {
+		return t.transport, nil
+	}
+
+	pool, err := caBundlePool(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA bundle %s: %w", t.key, err)
+	}
+
+	// Connections of the previous transport were verified against the old certificates.
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+
+	t.transport = t.base.Clone()
+	t.transport.TLSClientConfig = &tls.Config{RootCAs: pool} //nolint:gosec // the default minimum version of the client applies
+	t.resourceVersion = bundle.ResourceVersion
+
+	return t.transport, nil
+}
+
+// caBundlePool returns the system certificates together with the certificates in the values of the ConfigMap.
+func caBundlePool(bundle *corev1.ConfigMap) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	keys := make([]string, 0, len(bundle.Data))
+	for key := range bundle.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !pool.AppendCertsFromPEM([]byte(bundle.Data[key])) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in %s", key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClient_CABundle(t *testing.T) {
+	upstream := httptest.NewTLSServer(registry.New())
+	defer upstream.Close()
+
+	host := strings.TrimPrefix(upstream.URL, "https://")
+	source := host + "/internal-ca/app:v1"
+
+	image, err := random.Image(64, 1)
+	require.NoError(t, err)
+	ref, err := ociname.ParseReference(source)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, image, remote.WithTransport(upstream.Client().Transport)))
+	expected, err := image.Digest()
+	require.NoError(t, err)
+
+	bundle := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry-ca",
+			Namespace: "ocm-system",
+		},
+		Data: map[string]string{
+			"ca.crt": "not a certificate",
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(bundle).Build()
+
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000", WithClient(fakeClient), WithCABundle("ocm-system", "registry-ca"),
+		WithSnapshotRegistries(host))
+
+	t.Log("rejecting a CA bundle without certificates")
+	_, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	assert.ErrorContains(t, err, "invalid CA bundle ocm-system/registry-ca: no PEM encoded certificates found in ca.crt")
+
+	t.Log("verifying the certificate of the registry against the updated CA bundle")
+	bundle.Data["ca.crt"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw}))
+	require.NoError(t, fakeClient.Update(context.Background(), bundle))
+
+	digest, err := c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), digest)
+
+	t.Log("verifying the certificate of a snapshot registry against the CA bundle")
+	store, err := c.ForRegistry("https://"+host, nil)
+	require.NoError(t, err)
+	_, err = store.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", "internal-ca-snapshot", "v0.0.1")
+	require.NoError(t, err)
+
+	t.Log("failing requests if the CA bundle doesn't exist")
+	require.NoError(t, fakeClient.Delete(context.Background(), bundle))
+	_, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	assert.ErrorContains(t, err, "failed to get CA bundle ocm-system/registry-ca")
+
+	t.Log("verifying the certificate against the system certificates only without a CA bundle")
+	c = NewClient("registry.ocm-system.svc.cluster.local:5000")
+	_, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pinger checks that a registry is reachable.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// RegistryChecker is a health check that fails once the registry has been unreachable for longer than the
+// failure threshold. Short outages therefore don't flip the readiness of the controller.
+type RegistryChecker struct {
+	pinger           pinger
+	failureThreshold time.Duration
+	now              func() time.Time
+
+	mu           sync.Mutex
+	failingSince time.Time
+}
+
+// NewRegistryChecker returns a RegistryChecker pinging the registry of the client.
+func NewRegistryChecker(c *Client, failureThreshold time.Duration) *RegistryChecker {
+	return &RegistryChecker{
+		pinger:           c,
+		failureThreshold: failureThreshold,
+		now:              time.Now,
+	}
+}
+
+// Check implements healthz.Checker.
+func (r *RegistryChecker) Check(req *http.Request) error {
+	err := r.pinger.Ping(req.Context())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.failingSince = time.Time{}
+
+		return nil
+	}
+
+	now := r.now()
+	if r.failingSince.IsZero() {
+		r.failingSince = now
+	}
+
+	if unreachable := now.Sub(r.failingSince); unreachable >= r.failureThreshold {
+		return fmt.Errorf("registry unreachable for %s: %w", unreachable.Round(time.Second), err)
+	}
+
+	return nil
+}
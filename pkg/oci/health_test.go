@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (f *fakePinger) Ping(ctx context.Context) error {
+	return f.err
+}
+
+func TestClient_Ping(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	assert.NoError(t, c.Ping(context.Background()))
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachableAddr := strings.TrimPrefix(unreachable.URL, "http://")
+	unreachable.Close()
+
+	c = NewClient(unreachableAddr, WithInsecureSkipVerify(true), WithTimeout(time.Second))
+	assert.ErrorContains(t, c.Ping(context.Background()), "failed to reach registry")
+}
+
+func TestRegistryChecker(t *testing.T) {
+	now := time.Now()
+	pinger := &fakePinger{}
+	checker := &RegistryChecker{
+		pinger:           pinger,
+		failureThreshold: time.Minute,
+		now: func() time.Time {
+			return now
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	require.NoError(t, checker.Check(req))
+
+	t.Log("tolerating failures shorter than the threshold")
+	pinger.err = errors.New("connection refused")
+	require.NoError(t, checker.Check(req))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, checker.Check(req))
+
+	t.Log("failing once the registry has been unreachable for the threshold")
+	now = now.Add(30 * time.Second)
+	assert.ErrorContains(t, checker.Check(req), "registry unreachable for 1m0s: connection refused")
+
+	t.Log("recovering as soon as the registry is reachable")
+	pinger.err = nil
+	require.NoError(t, checker.Check(req))
+	pinger.err = errors.New("connection refused")
+	assert.NoError(t, checker.Check(req))
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// WithInsecureRegistries marks the given registries as insecure, for example a development registry with a
+// self-signed certificate. It is the same as passing the hosts to both WithHTTPRegistries and
+// WithSkipTLSVerifyRegistries. Hosts are of the form host[:port].
+func WithInsecureRegistries(hosts ...string) ClientOptsFunc {
+	return func(opts *Client) {
+		WithHTTPRegistries(hosts...)(opts)
+		WithSkipTLSVerifyRegistries(hosts...)(opts)
+	}
+}
+
+// WithHTTPRegistries allows the given registries to be accessed over plain http, for registries that don't serve
+// TLS at all. The certificates of these registries are still verified if they are accessed over https. Hosts are
+// of the form host[:port].
+func WithHTTPRegistries(hosts ...string) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.httpRegistries = opts.httpRegistries.add(hosts...)
+	}
+}
+
+// WithSkipTLSVerifyRegistries skips the verification of the certificates of the given registries, for example a
+// development registry with a self-signed certificate. These registries are still accessed over https only.
+// Hosts are of the form host[:port].
+func WithSkipTLSVerifyRegistries(hosts ...string) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.skipVerifyRegistries = opts.skipVerifyRegistries.add(hosts...)
+	}
+}
+
+// registrySet is a set of registry hosts. Hosts are compared case-insensitively.
+type registrySet map[string]struct{}
+
+// add returns the set with the given hosts added, empty hosts are ignored.
+func (s registrySet) add(hosts ...string) registrySet {
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			if s == nil {
+				s = make(registrySet, len(hosts))
+			}
+
+			s[strings.ToLower(host)] = struct{}{}
+		}
+	}
+
+	return s
+}
+
+// has returns whether the host is part of the set.
+func (s registrySet) has(host string) bool {
+	_, ok := s[strings.ToLower(host)]
+
+	return ok
+}
+
+// parseSourceReference parses the reference of an artifact in an upstream registry. Artifacts of registries that
+// allow http may be fetched over plain http.
+func (c *Client) parseSourceReference(source string) (ociname.Reference, error) {
+	ref, err := ociname.ParseReference(source)
+	if err != nil || !c.httpRegistries.has(ref.Context().RegistryStr()) {
+		return ref, err
+	}
+
+	return ociname.ParseReference(source, ociname.Insecure)
+}
+
+// sourceTransport returns the round tripper for requests to upstream registries. It skips the TLS verification
+// of the registries marked to skip it only, verifies the others against the CA bundle if one is set, and like the
+// transport of the snapshot registry it is built once and reused.
+func (c *Client) sourceTransport() http.RoundTripper {
+	if c.shared != nil {
+		return c.shared.sourceTransport()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.source != nil {
+		return c.source
+	}
+
+	var next http.RoundTripper = remote.DefaultTransport
+	if transport, ok := remote.DefaultTransport.(*http.Transport); ok {
+		if c.caBundle != nil {
+			if bundle, err := c.newCABundleTransport(transport); err == nil {
+				next = bundle
+			} else {
+				next = &errorTransport{err: err}
+			}
+		}
+
+		if len(c.skipVerifyRegistries) > 0 {
+			insecure := transport.Clone()
+			insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // only for registries marked to skip the verification
+
+			next = &insecureRegistryTransport{registries: c.skipVerifyRegistries, secure: next, insecure: insecure}
+		}
+	}
+
+	c.source = &rateLimitTransport{next: next}
+
+	return c.source
+}
+
+// insecureRegistryTransport sends requests to the registries without verifying their certificates.
+type insecureRegistryTransport struct {
+	registries registrySet
+	secure     http.RoundTripper
+	insecure   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *insecureRegistryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.registries.has(req.URL.Host) {
+		return t.insecure.RoundTrip(req)
+	}
+
+	return t.secure.RoundTrip(req)
+}
+
+// errorTransport fails all requests with the error the transport couldn't be set up with.
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
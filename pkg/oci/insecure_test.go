@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_InsecureRegistries(t *testing.T) {
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000", WithInsecureRegistries(" Registry.Dev:5000", "", "localhost:5001"))
+
+	for _, registries := range []registrySet{c.httpRegistries, c.skipVerifyRegistries} {
+		assert.True(t, registries.has("registry.dev:5000"))
+		assert.True(t, registries.has("localhost:5001"))
+		assert.False(t, registries.has("registry.dev"), "the port is part of the host")
+		assert.False(t, registries.has("ghcr.io"))
+	}
+
+	ref, err := c.parseSourceReference("registry.dev:5000/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "http", ref.Context().Scheme(), "an insecure registry may be accessed over http")
+
+	ref, err = c.parseSourceReference("ghcr.io/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "https", ref.Context().Scheme())
+
+	_, err = c.parseSourceReference("ghcr.io/App:v1")
+	assert.Error(t, err)
+}
+
+func TestClient_HTTPAndSkipTLSVerifyRegistries(t *testing.T) {
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000",
+		WithHTTPRegistries("registry.plain:5000"),
+		WithSkipTLSVerifyRegistries("registry.self-signed:5000"),
+	)
+
+	assert.True(t, c.httpRegistries.has("registry.plain:5000"))
+	assert.False(t, c.skipVerifyRegistries.has("registry.plain:5000"), "allowing http doesn't skip the verification")
+	assert.True(t, c.skipVerifyRegistries.has("registry.self-signed:5000"))
+	assert.False(t, c.httpRegistries.has("registry.self-signed:5000"), "skipping the verification doesn't allow http")
+
+	ref, err := c.parseSourceReference("registry.plain:5000/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "http", ref.Context().Scheme())
+
+	ref, err = c.parseSourceReference("registry.self-signed:5000/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "https", ref.Context().Scheme())
+}
+
+func TestClient_InsecureRegistriesSkipVerify(t *testing.T) {
+	upstream := httptest.NewTLSServer(registry.New())
+	defer upstream.Close()
+
+	host := strings.TrimPrefix(upstream.URL, "https://")
+	source := host + "/insecure/app:v1"
+
+	image, err := random.Image(64, 1)
+	require.NoError(t, err)
+	ref, err := ociname.ParseReference(source)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, image, remote.WithTransport(upstream.Client().Transport)))
+	expected, err := image.Digest()
+	require.NoError(t, err)
+
+	t.Log("verifying the certificate of registries that aren't marked as insecure")
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000")
+	_, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	assert.Error(t, err)
+
+	t.Log("verifying the certificate of registries that are only allowed to be accessed over http")
+	c = NewClient("registry.ocm-system.svc.cluster.local:5000", WithHTTPRegistries(host))
+	_, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	assert.Error(t, err)
+
+	t.Log("skipping the verification of a registry with a self-signed certificate")
+	c = NewClient("registry.ocm-system.svc.cluster.local:5000", WithSkipTLSVerifyRegistries(host))
+	digest, err := c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), digest)
+
+	t.Log("skipping the verification for an insecure registry")
+	c = NewClient("registry.ocm-system.svc.cluster.local:5000", WithInsecureRegistries(host), WithSnapshotRegistries(host))
+	digest, err = c.ResolveArtifact(context.Background(), source, authn.Anonymous)
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), digest)
+
+	t.Log("pushing snapshots to an insecure registry")
+	store, err := c.ForRegistry("https://"+host, nil)
+	require.NoError(t, err)
+	_, err = store.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", "insecure-snapshot", "v0.0.1")
+	require.NoError(t, err)
+	cached, err := store.IsCached(context.Background(), "insecure-snapshot", "v0.0.1")
+	require.NoError(t, err)
+	assert.True(t, cached)
+}
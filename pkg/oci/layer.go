@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// LayerDigest returns the digest of the layer PushData pushes the data in with the given options, without pushing
+// it. The data is compressed like PushData compresses it, unless it is pushed uncompressed, in which case the
+// digest is the digest of the data as it is.
+func (c *Client) LayerDigest(data io.Reader, opts ...cache.PushOption) (string, error) {
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Uncompressed {
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, data); err != nil {
+			return "", fmt.Errorf("failed to read layer data: %w", err)
+		}
+
+		return v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(hasher.Sum(nil))}.String(), nil
+	}
+
+	layer := computeStreamBlob(io.NopCloser(data), "")
+	compressed, err := layer.Compressed()
+	if err != nil {
+		return "", fmt.Errorf("failed to compress layer: %w", err)
+	}
+
+	_, err = io.Copy(io.Discard, compressed)
+	if cerr := compressed.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to compress layer: %w", err)
+	}
+
+	digest, err := layer.Digest()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute layer digest: %w", err)
+	}
+
+	return digest.String(), nil
+}
+
+// fileLayer is a layer whose blob is the data as it is, buffered in a temporary file. Unlike a stream.Layer, the
+// data isn't gzip-compressed when it is pushed and the digest is known before the upload starts.
+type fileLayer struct {
+	path      string
+	digest    v1.Hash
+	size      int64
+	mediaType types.MediaType
+}
+
+// newFileLayer copies the data to a temporary file, computing its digest along the way, so the data doesn't have
+// to be held in memory. If no media type is given, it is derived from whether the data is gzip-compressed. The
+// caller has to remove the file with cleanup.
+func newFileLayer(data io.Reader, mediaType string) (layer *fileLayer, err error) {
+	reader := bufio.NewReader(data)
+
+	t := types.MediaType(mediaType)
+	if t == "" {
+		t = types.OCIUncompressedLayer
+		if magic, _ := reader.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+			t = types.OCILayer
+		}
+	}
+
+	file, err := os.CreateTemp("", "snapshot-layer-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create layer file: %w", err)
+	}
+
+	layer = &fileLayer{path: file.Name(), mediaType: t}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+
+		if err != nil {
+			err = errors.Join(err, layer.cleanup())
+			layer = nil
+		}
+	}()
+
+	hasher := sha256.New()
+	if layer.size, err = io.Copy(io.MultiWriter(file, hasher), reader); err != nil {
+		return layer, fmt.Errorf("failed to write layer file: %w", err)
+	}
+
+	layer.digest = v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(hasher.Sum(nil))}
+
+	return layer, nil
+}
+
+// toLayer returns the layer as a v1.Layer. The diff ID of the layer is computed from the decompressed data if the
+// data is compressed.
+func (l *fileLayer) toLayer() (v1.Layer, error) {
+	return partial.CompressedToLayer(l)
+}
+
+func (l *fileLayer) cleanup() error {
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove layer file: %w", err)
+	}
+
+	return nil
+}
+
+// Digest implements partial.CompressedLayer.
+func (l *fileLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+// Compressed implements partial.CompressedLayer. It returns the data as it was written.
+func (l *fileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Size implements partial.CompressedLayer.
+func (l *fileLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+// MediaType implements partial.CompressedLayer.
+func (l *fileLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+// rateLimitTransport turns 429 Too Many Requests responses into a cache.RateLimitError, which records the delay
+// requested by the registry. go-containerregistry only keeps the status code of failed responses.
+type rateLimitTransport struct {
+	next http.RoundTripper
+	now  func() time.Time
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+
+	return nil, &cache.RateLimitError{
+		Host:       req.URL.Host,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), now()),
+	}
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, which is either a number of seconds or an HTTP date.
+// Returns zero if the header is missing, invalid or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+
+	if d := date.Sub(now); d > 0 {
+		return d
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, expected := range map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 May 2023 12:02:00 GMT": 2 * time.Minute,
+		"Mon, 01 May 2023 11:00:00 GMT": 0,
+	} {
+		assert.Equal(t, expected, parseRetryAfter(value, now), "Retry-After: %q", value)
+	}
+}
+
+func TestClient_CopyArtifactRateLimited(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer upstream.Close()
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	source := strings.TrimPrefix(upstream.URL, "http://") + "/podinfo:v6.3.5"
+	_, err := c.CopyArtifact(context.Background(), source, "rate-limited", "v6.3.5", authn.Anonymous, nil)
+	require.Error(t, err)
+
+	var rerr *cache.RateLimitError
+	require.True(t, errors.As(err, &rerr), "expected a rate limit error, got %v", err)
+	assert.Equal(t, 30*time.Second, rerr.RetryAfter)
+	assert.Equal(t, strings.TrimPrefix(upstream.URL, "http://"), rerr.Host)
+}
@@ -12,15 +12,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	ociname "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
 	"github.com/google/go-containerregistry/pkg/v1/stream"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/opencontainers/go-digest"
@@ -29,18 +36,91 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/lru"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+	"github.com/open-component-model/ocm-controller/pkg/metrics"
+	"github.com/open-component-model/ocm-controller/pkg/tracing"
 )
 
+// registryClientCacheSize is the number of clients of other registries ForRegistry keeps for reuse.
+const registryClientCacheSize = 32
+
 // Option is a functional option for Repository.
 type Option func(o *options) error
 
 type options struct {
 	// remoteOpts are the options to use when fetching and pushing blobs.
 	remoteOpts []remote.Option
+	// nameOpts are the options to use when parsing the repository name.
+	nameOpts []ociname.Option
+	// mountFrom are the repositories layers are mounted from if they already hold them.
+	mountFrom []string
+	// result records whether a push wrote the artifact.
+	result *cache.PushResult
+	// artifactType is the media type of the config of pushed data, empty for an image config.
+	artifactType string
+	// gzipLayers recompresses the zstd-compressed layers of copied images with gzip.
+	gzipLayers bool
+	// untagged pushes manifests by their digest instead of the given reference.
+	untagged bool
+}
+
+// WithContext sets the context that is used for the requests to the registry.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) error {
+		o.remoteOpts = append(o.remoteOpts, remote.WithContext(ctx))
+
+		return nil
+	}
+}
+
+// withMountFrom mounts pushed layers from the repositories if one of them already holds the layer.
+func withMountFrom(repositories ...string) Option {
+	return func(o *options) error {
+		o.mountFrom = append(o.mountFrom, repositories...)
+
+		return nil
+	}
+}
+
+// withPushResult records in result whether the manifest of a pushed artifact was written.
+func withPushResult(result *cache.PushResult) Option {
+	return func(o *options) error {
+		o.result = result
+
+		return nil
+	}
+}
+
+// withArtifactType sets the media type of the config of pushed data to the artifact type.
+func withArtifactType(artifactType string) Option {
+	return func(o *options) error {
+		o.artifactType = artifactType
+
+		return nil
+	}
+}
+
+// withGzipLayers recompresses the zstd-compressed layers of copied images with gzip.
+func withGzipLayers(enabled bool) Option {
+	return func(o *options) error {
+		o.gzipLayers = enabled
+
+		return nil
+	}
+}
+
+// withoutTag pushes manifests by their digest, the references they are pushed to are ignored.
+func withoutTag(enabled bool) Option {
+	return func(o *options) error {
+		o.untagged = enabled
+
+		return nil
+	}
 }
 
 // ResourceOptions contains all parameters necessary to fetch / push resources.
@@ -76,6 +156,21 @@ func WithInsecureSkipVerify(value bool) ClientOptsFunc {
 	}
 }
 
+// WithScheme sets the scheme used to talk to the registry, either http or https. If it isn't set, the scheme is
+// inferred from the address of the registry.
+func WithScheme(scheme string) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.Scheme = scheme
+	}
+}
+
+// WithTimeout sets the timeout for requests to the registry.
+func WithTimeout(timeout time.Duration) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.Timeout = timeout
+	}
+}
+
 // WithClient sets up certificates for the client.
 func WithClient(client client.Client) ClientOptsFunc {
 	return func(opts *Client) {
@@ -83,6 +178,15 @@ func WithClient(client client.Client) ClientOptsFunc {
 	}
 }
 
+// WithSnapshotRegistries allows snapshots to be pushed to the given registries instead of the in-cluster registry.
+// ForRegistry rejects all other registries, so without this option snapshots can only be pushed to the in-cluster
+// registry. Hosts are of the form host[:port].
+func WithSnapshotRegistries(hosts ...string) ClientOptsFunc {
+	return func(opts *Client) {
+		opts.snapshotRegistries = opts.snapshotRegistries.add(hosts...)
+	}
+}
+
 // Client implements the caching layer and the OCI layer.
 type Client struct {
 	Client             client.Client
@@ -90,31 +194,170 @@ type Client struct {
 	InsecureSkipVerify bool
 	Namespace          string
 	CertSecretName     string
+	// Scheme is the scheme used to talk to the registry. By default, https is used unless the registry has a
+	// local or private address. Private addresses include cluster IPs, so https has to be set explicitly for them.
+	Scheme string
+	// Timeout is applied to connecting to the registry and waiting for its responses. It also limits the total
+	// duration of a push, which includes reading the data from the upstream source. Zero means no timeout.
+	Timeout time.Duration
+
+	// external is set for registries other than the in-cluster registry. Their certificates are verified against
+	// the system certificates instead of the certificate secret of the in-cluster registry, and requests are
+	// authenticated with keychain, or anonymous if it is nil.
+	external bool
+	keychain authn.Keychain
+	// shared is the client of the same registry whose transports are reused by the clients returned by
+	// ForRegistry for the credentials of each Resource.
+	shared *Client
+
+	// httpRegistries are the hosts of the registries that may be accessed over plain http.
+	httpRegistries registrySet
+	// skipVerifyRegistries are the hosts of the registries whose certificates aren't verified.
+	skipVerifyRegistries registrySet
+	// snapshotRegistries are the hosts of the registries ForRegistry returns clients for.
+	snapshotRegistries registrySet
+	// caBundle is the ConfigMap with the additional CA certificates the certificates of registries other than the
+	// in-cluster registry are verified against.
+	caBundle *apitypes.NamespacedName
 
 	certPem []byte
 	keyPem  []byte
 	ca      []byte
+
+	// mu guards the transport and the clients of other registries, which are built once and reused by all
+	// requests so connections to the registries are kept alive between them. Only the most recently used clients
+	// of other registries are kept.
+	mu         sync.Mutex
+	base       http.RoundTripper
+	source     http.RoundTripper
+	registries *lru.Cache
 }
 
 // WithTransport sets up insecure TLS so the library is forced to use HTTPS.
 func (c *Client) WithTransport(ctx context.Context) Option {
 	return func(o *options) error {
-		if c.InsecureSkipVerify {
-			return nil
+		rt, err := c.transport(ctx)
+		if err != nil {
+			return err
 		}
 
-		if c.certPem == nil && c.keyPem == nil {
-			if err := c.setupCertificates(ctx); err != nil {
-				return fmt.Errorf("failed to set up certificates for transport: %w", err)
-			}
-		}
+		o.remoteOpts = append(o.remoteOpts, remote.WithTransport(rt))
+		o.nameOpts = append(o.nameOpts, c.nameOptions()...)
 
-		o.remoteOpts = append(o.remoteOpts, remote.WithTransport(c.constructTLSRoundTripper()))
+		// Other registries than the in-cluster registry usually require authentication. The credentials are those
+		// of the Resource pushing to them, never the ones of the controller.
+		if c.external && c.keychain != nil {
+			o.remoteOpts = append(o.remoteOpts, remote.WithAuthFromKeychain(c.keychain))
+		}
 
 		return nil
 	}
 }
 
+// nameOptions returns the options for parsing references to the registry.
+func (c *Client) nameOptions() []ociname.Option {
+	if c.Scheme == "http" || (c.external && c.httpRegistries.has(c.OCIRepositoryAddr)) {
+		return []ociname.Option{ociname.Insecure}
+	}
+
+	return nil
+}
+
+// transport returns the round tripper used for requests to the registry. If https is configured, requests that
+// go-containerregistry sends over plain http for local and private addresses are upgraded to https. Requests that
+// can't reach the registry fail with a cache.RegistryUnavailableError.
+func (c *Client) transport(ctx context.Context) (http.RoundTripper, error) {
+	rt, err := c.baseTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Scheme == "https" {
+		rt = &httpsRoundTripper{host: c.OCIRepositoryAddr, next: rt}
+	}
+
+	rt = &unavailableTransport{host: c.OCIRepositoryAddr, next: rt}
+
+	return tracing.Transport(rt), nil
+}
+
+// httpsRoundTripper sends the requests to the host over https.
+type httpsRoundTripper struct {
+	host string
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (h *httpsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" && req.URL.Host == h.host {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = "https"
+	}
+
+	return h.next.RoundTrip(req)
+}
+
+// baseTransport returns the round tripper configured with the certificates and timeouts of the client. It is
+// built on first use and shared by all requests of the client, so its connection pool is reused.
+func (c *Client) baseTransport(ctx context.Context) (http.RoundTripper, error) {
+	if c.shared != nil {
+		return c.shared.baseTransport(ctx)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.base != nil {
+		return c.base, nil
+	}
+
+	rt, err := c.newBaseTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.base = rt
+
+	return rt, nil
+}
+
+// newBaseTransport builds the round tripper returned by baseTransport.
+func (c *Client) newBaseTransport(ctx context.Context) (http.RoundTripper, error) {
+	if c.InsecureSkipVerify || c.external {
+		insecure := c.external && c.skipVerifyRegistries.has(c.OCIRepositoryAddr)
+		if c.Timeout <= 0 && !insecure && (!c.external || c.caBundle == nil) {
+			return remote.DefaultTransport, nil
+		}
+
+		transport, ok := remote.DefaultTransport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unexpected default transport type %T", remote.DefaultTransport)
+		}
+
+		transport = c.applyTimeout(transport.Clone())
+		if insecure {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // only for registries marked to skip the verification
+
+			return transport, nil
+		}
+
+		// The certificate of the in-cluster registry is only skipped if InsecureSkipVerify is set.
+		if c.external && c.caBundle != nil {
+			return c.newCABundleTransport(transport)
+		}
+
+		return transport, nil
+	}
+
+	if c.certPem == nil && c.keyPem == nil {
+		if err := c.setupCertificates(ctx); err != nil {
+			return nil, fmt.Errorf("failed to set up certificates for transport: %w", err)
+		}
+	}
+
+	return c.constructTLSRoundTripper(), nil
+}
+
 func (c *Client) setupCertificates(ctx context.Context) error {
 	if c.Client == nil {
 		return fmt.Errorf("client must not be nil if certificate is requested, please set WithClient when creating the oci cache")
@@ -162,9 +405,31 @@ func (c *Client) constructTLSRoundTripper() http.RoundTripper {
 	tlsConfig.InsecureSkipVerify = c.InsecureSkipVerify
 
 	// Create a new HTTP transport with the TLS configuration
-	return &http.Transport{
+	return c.applyTimeout(&http.Transport{
 		TLSClientConfig: tlsConfig,
+	})
+}
+
+// applyTimeout sets the dial, TLS handshake and response header timeouts of the transport to the configured timeout.
+func (c *Client) applyTimeout(t *http.Transport) *http.Transport {
+	if c.Timeout <= 0 {
+		return t
+	}
+
+	t.DialContext = (&net.Dialer{Timeout: c.Timeout}).DialContext
+	t.TLSHandshakeTimeout = c.Timeout
+	t.ResponseHeaderTimeout = c.Timeout
+
+	return t
+}
+
+// withTimeout returns a context that expires after the configured timeout.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+
+	return context.WithTimeout(ctx, c.Timeout)
 }
 
 // NewClient creates a new OCI Client.
@@ -187,6 +452,68 @@ type Repository struct {
 	options
 }
 
+// ForRegistry returns a client for another registry than the in-cluster registry, configured like this client. The
+// address is validated and normalized like the address of the in-cluster registry. It may start with the http or
+// https scheme to use, otherwise https is used unless the registry has a local or private address. Only registries
+// allowed by WithSnapshotRegistries are accepted, others fail with cache.ErrRegistryNotAllowed. Requests are
+// authenticated with the keychain, or sent anonymously if it is nil.
+func (c *Client) ForRegistry(address string, keychain authn.Keychain) (cache.Cache, error) {
+	host, scheme, err := ParseRegistryAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.snapshotRegistries.has(host) {
+		return nil, fmt.Errorf("%w: %s", cache.ErrRegistryNotAllowed, host)
+	}
+
+	// The client of a registry is kept, together with its transport, for the next reconciliation pushing to it.
+	// Only the keychain differs between the clients returned for it.
+	key := scheme + "://" + host
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.registries == nil {
+		c.registries = lru.New(registryClientCacheSize)
+	}
+
+	var registry *Client
+	if value, ok := c.registries.Get(key); ok {
+		registry = value.(*Client)
+	} else {
+		registry = &Client{
+			Client:               c.Client,
+			OCIRepositoryAddr:    host,
+			InsecureSkipVerify:   c.InsecureSkipVerify,
+			Namespace:            c.Namespace,
+			Scheme:               scheme,
+			Timeout:              c.Timeout,
+			external:             true,
+			httpRegistries:       c.httpRegistries,
+			skipVerifyRegistries: c.skipVerifyRegistries,
+			caBundle:             c.caBundle,
+		}
+
+		c.registries.Add(key, registry)
+	}
+
+	return &Client{
+		Client:               registry.Client,
+		OCIRepositoryAddr:    registry.OCIRepositoryAddr,
+		InsecureSkipVerify:   registry.InsecureSkipVerify,
+		Namespace:            registry.Namespace,
+		Scheme:               registry.Scheme,
+		Timeout:              registry.Timeout,
+		external:             true,
+		keychain:             keychain,
+		shared:               registry,
+		httpRegistries:       registry.httpRegistries,
+		skipVerifyRegistries: registry.skipVerifyRegistries,
+		caBundle:             registry.caBundle,
+	}, nil
+}
+
 // NewRepository returns a new Repository. It points to the given remote repository.
 // It accepts a list of options to configure the repository and the underlying remote client.
 func NewRepository(repositoryName string, opts ...Option) (*Repository, error) {
@@ -194,9 +521,7 @@ func NewRepository(repositoryName string, opts ...Option) (*Repository, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to make options: %w", err)
 	}
-	repoOpts := make([]ociname.Option, 0)
-
-	repo, err := ociname.NewRepository(repositoryName, repoOpts...)
+	repo, err := ociname.NewRepository(repositoryName, opt.nameOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Repository name %q: %w", repositoryName, err)
 	}
@@ -205,42 +530,491 @@ func NewRepository(repositoryName string, opts ...Option) (*Repository, error) {
 }
 
 // PushData takes a blob of data and caches it using OCI as a background.
-func (c *Client) PushData(ctx context.Context, data io.ReadCloser, mediaType, name, tag string) (string, error) {
+func (c *Client) PushData(
+	ctx context.Context,
+	data io.ReadCloser,
+	mediaType, name, tag string,
+	opts ...cache.PushOption,
+) (_ string, err error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
-	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
+
+	ctx, span := tracing.Start(ctx, "oci.PushData", tracing.SnapshotRefKey.String(repositoryName+":"+tag))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	mountFrom := make([]string, 0, len(options.MountFrom))
+	for _, from := range options.MountFrom {
+		mountFrom = append(mountFrom, fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, from))
+	}
+
+	result := pushResult(options.Result)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx), withMountFrom(mountFrom...), withPushResult(result),
+		withArtifactType(options.ArtifactType), withoutTag(options.Untagged))
 	if err != nil {
 		return "", fmt.Errorf("failed create new repository: %w", err)
 	}
 
-	manifest, err := repo.PushStreamingImage(tag, data, mediaType, nil)
+	// The upload is aborted by the context, but reading the data from the upstream repository isn't.
+	data = &contextReader{ctx: ctx, ReadCloser: data}
+
+	start := time.Now()
+	var manifest *v1.Manifest
+	if options.Uncompressed {
+		manifest, err = repo.PushUncompressedImage(tag, data, mediaType, options.Annotations, options.Config)
+	} else {
+		manifest, err = repo.PushStreamingImage(tag, data, mediaType, options.Annotations, options.Config)
+	}
+	metrics.SnapshotPushDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
 	if err != nil {
+		metrics.SnapshotPushTotal.WithLabelValues(name, "failure").Inc()
+
+		if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = errors.Join(ctxErr, err)
+		}
+
 		return "", fmt.Errorf("failed to push image: %w", err)
 	}
 
+	metrics.SnapshotPushTotal.WithLabelValues(name, pushResultLabel(result)).Inc()
+
 	layers := manifest.Layers
 	if len(layers) == 0 {
 		return "", fmt.Errorf("no layers returned by manifest: %w", err)
 	}
 
+	if !result.Unchanged {
+		metrics.SnapshotPushBytesTotal.WithLabelValues(name).Add(float64(layers[0].Size))
+	}
+
 	return layers[0].Digest.String(), nil
 }
 
+// pushResult returns the result a push is recorded in, a new one if the caller doesn't record it.
+func pushResult(result *cache.PushResult) *cache.PushResult {
+	if result == nil {
+		return &cache.PushResult{}
+	}
+
+	*result = cache.PushResult{}
+
+	return result
+}
+
+// pushResultLabel returns the result label of the snapshot push metric for a successful push.
+func pushResultLabel(result *cache.PushResult) string {
+	if result.Unchanged {
+		return "unchanged"
+	}
+
+	return "success"
+}
+
+// contextReader stops reading once its context is done, so a push of a large resource can be cancelled even if
+// the data is read from a source that doesn't observe the context.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // the reader is only used for the duration of a push
+	io.ReadCloser
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.ReadCloser.Read(p)
+}
+
+// CopyArtifact copies the image or image index at the source reference to the cache. Unlike PushData, the
+// manifests, config, layers and media types of the source are preserved, unless WithGzipLayers is passed and zstd
+// layers are recompressed. If a platform is given, only the image for that platform is copied from an image index.
+// Returns the digest of the copied manifest. The outcome of the copy is recorded if WithPushResult is passed and the
+// tag is left alone if WithoutTag is passed, other push options don't apply.
+func (c *Client) CopyArtifact(
+	ctx context.Context,
+	source, name, tag string,
+	auth authn.Authenticator,
+	platform *v1.Platform,
+	opts ...cache.PushOption,
+) (_ string, err error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+
+	ctx, span := tracing.Start(ctx, "oci.CopyArtifact",
+		tracing.SourceRefKey.String(source),
+		tracing.SnapshotRefKey.String(repositoryName+":"+tag),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	sourceRef, err := c.parseSourceReference(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse source reference %q: %w", source, err)
+	}
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	result := pushResult(options.Result)
+	repo, err := NewRepository(
+		repositoryName,
+		c.WithTransport(ctx),
+		WithContext(ctx),
+		withPushResult(result),
+		withGzipLayers(options.GzipLayers),
+		withoutTag(options.Untagged),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	copyArtifact := func() (v1.Hash, error) {
+		return repo.CopyArtifact(
+			sourceRef,
+			tag,
+			platform,
+			remote.WithAuth(auth),
+			remote.WithContext(ctx),
+			remote.WithTransport(c.sourceTransport()),
+		)
+	}
+
+	start := time.Now()
+	digest, err := copyArtifact()
+	if isSourceUnauthorized(err, sourceRef) {
+		// The token of the source registry expired during the copy and the registry rejected it without a
+		// challenge, or rejected the refreshed token too. The copy is started again with a new token, the blobs
+		// that have already been copied are skipped.
+		log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("source registry rejected token, authenticating again",
+			v1alpha1.LogKeySourceRef, source)
+
+		digest, err = copyArtifact()
+	}
+	metrics.SnapshotPushDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.SnapshotPushTotal.WithLabelValues(name, "failure").Inc()
+
+		return "", fmt.Errorf("failed to copy artifact: %w", err)
+	}
+
+	metrics.SnapshotPushTotal.WithLabelValues(name, pushResultLabel(result)).Inc()
+	span.SetAttributes(tracing.SourceDigestKey.String(digest.String()))
+
+	return digest.String(), nil
+}
+
+// isSourceUnauthorized returns whether err is a 401 Unauthorized response of the registry of the source. Responses
+// of the token endpoint of the registry aren't included, they mean that the credentials have been rejected.
+func isSourceUnauthorized(err error, source ociname.Reference) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) || terr.StatusCode != http.StatusUnauthorized || terr.Request == nil {
+		return false
+	}
+
+	return terr.Request.URL.Host == source.Context().RegistryStr()
+}
+
+// ResolveArtifact returns the digest of the image or image index at the source reference, so an artifact that is
+// referenced by tag can be copied by its digest.
+func (c *Client) ResolveArtifact(ctx context.Context, source string, auth authn.Authenticator) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	ref, err := c.parseSourceReference(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse source reference %q: %w", source, err)
+	}
+
+	desc, err := remote.Get(
+		ref,
+		remote.WithAuth(auth),
+		remote.WithContext(ctx),
+		remote.WithTransport(c.sourceTransport()),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", source, err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// ArtifactSize returns the size of the image or image index at the source reference as it would be copied by
+// CopyArtifact, that is the size of the manifests, configs and layers, counting blobs shared by several images
+// once. If a platform is given, only the image for that platform is counted.
+func (c *Client) ArtifactSize(
+	ctx context.Context,
+	source string,
+	auth authn.Authenticator,
+	platform *v1.Platform,
+) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	ref, err := c.parseSourceReference(source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse source reference %q: %w", source, err)
+	}
+
+	opts := []remote.Option{
+		remote.WithAuth(auth),
+		remote.WithContext(ctx),
+		remote.WithTransport(c.sourceTransport()),
+	}
+	if platform != nil {
+		opts = append(opts, remote.WithPlatform(*platform))
+	}
+
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+
+	return descriptorSize(desc, platform)
+}
+
+// DataSize returns the size of the data a tag points at as it is stored in the repository, counted like
+// ArtifactSize counts the size of an artifact. The layers of data pushed by PushData are counted compressed.
+func (c *Client) DataSize(ctx context.Context, name, tag string) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	ref, err := parseReference(tag, repo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	desc, err := remote.Get(ref, repo.remoteOpts...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch manifest of %s:%s: %w", name, tag, err)
+	}
+
+	return descriptorSize(desc, nil)
+}
+
+// descriptorSize returns the size of the manifests, configs and layers of the artifact of the descriptor, counting
+// blobs shared by several images once. If a platform is given, only the image for that platform is counted.
+func descriptorSize(desc *remote.Descriptor, platform *v1.Platform) (int64, error) {
+	blobs := map[v1.Hash]int64{}
+	if desc.MediaType.IsIndex() && platform == nil {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get image index: %w", err)
+		}
+
+		blobs[desc.Digest] = desc.Size
+		if err := indexBlobs(index, blobs); err != nil {
+			return 0, err
+		}
+	} else {
+		// For an image index, this resolves the image matching the platform.
+		image, err := desc.Image()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get image: %w", err)
+		}
+
+		if err := imageBlobs(image, blobs); err != nil {
+			return 0, err
+		}
+	}
+
+	var size int64
+	for _, s := range blobs {
+		size += s
+	}
+
+	return size, nil
+}
+
+// ArtifactLayerDigest returns the digest of the first layer of the image at the source reference as CopyArtifact
+// would copy it with the given options, which is the digest FetchDataByIdentity returns for the copy. For an image
+// index, this is the image for the platform or, without a platform, the first image of the index. Only the
+// manifests are fetched, unless the layer is zstd-compressed and WithGzipLayers is passed, in which case the layer
+// is recompressed to compute the digest of the gzip layer.
+func (c *Client) ArtifactLayerDigest(
+	ctx context.Context,
+	source string,
+	auth authn.Authenticator,
+	platform *v1.Platform,
+	opts ...cache.PushOption,
+) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	ref, err := c.parseSourceReference(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse source reference %q: %w", source, err)
+	}
+
+	options := cache.PushOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	remoteOpts := []remote.Option{
+		remote.WithAuth(auth),
+		remote.WithContext(ctx),
+		remote.WithTransport(c.sourceTransport()),
+	}
+	if platform != nil {
+		remoteOpts = append(remoteOpts, remote.WithPlatform(*platform))
+	}
+
+	desc, err := remote.Get(ref, remoteOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+
+	var image v1.Image
+	if desc.MediaType.IsIndex() && platform == nil {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return "", fmt.Errorf("failed to get image index: %w", err)
+		}
+
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return "", fmt.Errorf("failed to get index manifest: %w", err)
+		}
+
+		if len(manifest.Manifests) == 0 || !manifest.Manifests[0].MediaType.IsImage() {
+			return "", fmt.Errorf("image index %s doesn't start with an image", source)
+		}
+
+		if image, err = index.Image(manifest.Manifests[0].Digest); err != nil {
+			return "", fmt.Errorf("failed to get image %s: %w", manifest.Manifests[0].Digest, err)
+		}
+	} else if image, err = desc.Image(); err != nil {
+		return "", fmt.Errorf("failed to get image: %w", err)
+	}
+
+	manifest, err := image.Manifest()
+	if err != nil {
+		return "", fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	if len(manifest.Layers) == 0 {
+		return "", fmt.Errorf("layers for image %s are empty", source)
+	}
+
+	first := manifest.Layers[0]
+	if !options.GzipLayers || first.MediaType != types.OCILayerZStd {
+		return first.Digest.String(), nil
+	}
+
+	layer, err := image.LayerByDigest(first.Digest)
+	if err != nil {
+		return "", fmt.Errorf("failed to get layer %s: %w", first.Digest, err)
+	}
+
+	uncompressed, err := layer.Uncompressed()
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress layer %s: %w", first.Digest, err)
+	}
+	defer uncompressed.Close()
+
+	return c.LayerDigest(uncompressed)
+}
+
+// indexBlobs records the sizes of the manifests and blobs of the images of the index, including nested indexes.
+func indexBlobs(index v1.ImageIndex, blobs map[v1.Hash]int64) error {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("failed to get index manifest: %w", err)
+	}
+
+	for _, child := range manifest.Manifests {
+		blobs[child.Digest] = child.Size
+
+		switch {
+		case child.MediaType.IsIndex():
+			nested, err := index.ImageIndex(child.Digest)
+			if err != nil {
+				return fmt.Errorf("failed to get image index %s: %w", child.Digest, err)
+			}
+
+			if err := indexBlobs(nested, blobs); err != nil {
+				return err
+			}
+		case child.MediaType.IsImage():
+			image, err := index.Image(child.Digest)
+			if err != nil {
+				return fmt.Errorf("failed to get image %s: %w", child.Digest, err)
+			}
+
+			if err := imageBlobs(image, blobs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// imageBlobs records the sizes of the manifest, config and layers of the image.
+func imageBlobs(image v1.Image, blobs map[v1.Hash]int64) error {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return fmt.Errorf("failed to get image manifest: %w", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to get image digest: %w", err)
+	}
+
+	size, err := image.Size()
+	if err != nil {
+		return fmt.Errorf("failed to get image manifest size: %w", err)
+	}
+
+	blobs[digest] = size
+	blobs[manifest.Config.Digest] = manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		blobs[layer.Digest] = layer.Size
+	}
+
+	return nil
+}
+
 // FetchDataByIdentity fetches an existing resource. Errors if there is no resource available. It's advised to call IsCached
 // before fetching. Returns the digest of the resource alongside the data for further processing.
 func (c *Client) FetchDataByIdentity(ctx context.Context, name, tag string) (io.ReadCloser, string, error) {
 	logger := log.FromContext(ctx).WithName("cache")
 	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
-	logger.V(v1alpha1.LevelDebug).Info("cache hit for data", "name", name, "tag", tag, "repository", repositoryName)
+	logger.V(v1alpha1.LevelDebug).Info("cache hit for data", v1alpha1.LogKeySnapshotRef, repositoryName+":"+tag)
 	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get repository: %w", err)
 	}
 
-	manifest, _, err := repo.FetchManifest(tag, nil)
+	// For an image index, the data of the first image in the index is returned.
+	reference, err := repo.resolveIndex(tag)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve reference: %w", err)
+	}
+
+	manifest, _, err := repo.FetchManifest(reference, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch manifest to obtain layers: %w", err)
 	}
-	logger.V(v1alpha1.LevelDebug).Info("got the manifest", "manifest", manifest)
+	logger.V(v1alpha1.LevelTrace).Info("got the manifest", "manifest", manifest)
 	layers := manifest.Layers
 	if len(layers) == 0 {
 		return nil, "", fmt.Errorf("layers for repository is empty")
@@ -248,85 +1022,375 @@ func (c *Client) FetchDataByIdentity(ctx context.Context, name, tag string) (io.
 
 	digest := layers[0].Digest
 
-	reader, err := repo.FetchBlob(digest.String())
+	reader, err := repo.FetchBlob(digest.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch reader for digest of the 0th layer: %w", err)
+	}
+
+	// decompresses the data coming from the cache. Because a streaming layer doesn't support decompression
+	// and a static layer returns the data AS IS, we have to decompress it ourselves.
+	return reader, digest.String(), nil
+}
+
+// FetchDataByDigest returns a reader for a given digest.
+func (c *Client) FetchDataByDigest(ctx context.Context, name, digest string) (io.ReadCloser, error) {
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	reader, err := repo.FetchBlob(digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blob: %w", err)
+	}
+
+	// decompresses the data coming from the cache. Because a streaming layer doesn't support decompression
+	// and a static layer returns the data AS IS, we have to decompress it ourselves.
+	return reader, nil
+}
+
+// IsCached returns whether a certain tag with a given name exists in cache.
+func (c *Client) IsCached(ctx context.Context, name, tag string) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	return repo.head(tag)
+}
+
+// ManifestAnnotations returns the annotations of the manifest, or image index, a tag points at.
+func (c *Client) ManifestAnnotations(ctx context.Context, name, tag string) (map[string]string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	ref, err := parseReference(tag, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	desc, err := remote.Get(ref, repo.remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s:%s: %w", name, tag, err)
+	}
+
+	// Image manifests and image indexes both keep their annotations in the same field.
+	var manifest struct {
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+	if err := json.Unmarshal(desc.Manifest, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s:%s: %w", name, tag, err)
+	}
+
+	return manifest.Annotations, nil
+}
+
+// Ping checks that the registry is reachable by requesting its API version endpoint. Any response of the
+// registry, including an authentication challenge, counts as reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	registry, err := ociname.NewRegistry(c.OCIRepositoryAddr, c.nameOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to parse registry address: %w", err)
+	}
+
+	rt, err := c.transport(ctx)
+	if err != nil {
+		return err
+	}
+
+	scheme := registry.Scheme()
+	if c.Scheme != "" {
+		scheme = c.Scheme
+	}
+
+	url := fmt.Sprintf("%s://%s/v2/", scheme, registry.RegistryStr())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach registry at %s: %w", c.OCIRepositoryAddr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("registry at %s responded with status %s", c.OCIRepositoryAddr, resp.Status)
+	}
+
+	return nil
+}
+
+// DeleteData removes a specific tag from the cache.
+func (c *Client) DeleteData(ctx context.Context, name, tag string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	return repo.deleteTag(tag)
+}
+
+// TagData adds another tag to the manifest referenced by an existing tag.
+func (c *Client) TagData(ctx context.Context, name, tag, newTag string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	return repo.tag(tag, newTag)
+}
+
+// ListRepositories returns the names of all repositories in the registry, without the address of the registry.
+func (c *Client) ListRepositories(ctx context.Context) ([]string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	registry, err := ociname.NewRegistry(c.OCIRepositoryAddr, c.nameOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry address: %w", err)
+	}
+
+	rt, err := c.transport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := remote.Catalog(ctx, registry, remote.WithTransport(rt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	return repositories, nil
+}
+
+// ListTags returns the tags of a repository.
+func (c *Client) ListTags(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	tags, err := remote.List(repo.Repository, repo.remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", name, err)
+	}
+
+	return tags, nil
+}
+
+// ResolveTag returns the digest of the manifest a tag points at.
+func (c *Client) ResolveTag(ctx context.Context, name, tag string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	ref, err := parseReference(tag, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	desc, err := remote.Head(ref, repo.remoteOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch head for reference: %w", err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// PushReferrer pushes an artifact that refers to the manifest at name:tag, for example an SBOM of the snapshot,
+// and returns the digest of the artifact's manifest. Registries that don't support the referrers API are detected
+// when the artifact is written, the artifact is then listed in the index of the referrers tag schema instead.
+func (c *Client) PushReferrer(ctx context.Context, data io.Reader, artifactType, name, tag string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	repo, err := NewRepository(repositoryName, c.WithTransport(ctx), WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed create new repository: %w", err)
+	}
+
+	digest, err := repo.pushReferrer(tag, data, artifactType)
+	if err != nil {
+		return "", err
+	}
+
+	return digest.String(), nil
+}
+
+// head does an authenticated call with the repo context to see if a tag, or a manifest digest, in a repository
+// already exists or not.
+func (r *Repository) head(tag string) (bool, error) {
+	reference, err := parseReference(tag, r)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse repository and tag name: %w", err)
+	}
+
+	if _, err := remote.Head(reference, r.remoteOpts...); err != nil {
+		terr := &transport.Error{}
+		if ok := errors.As(err, &terr); ok {
+			if terr.StatusCode == http.StatusNotFound {
+				return false, nil
+			}
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// resolveIndex returns the digest of the first manifest if the reference points to an image index.
+// Otherwise, the reference is returned as is.
+func (r *Repository) resolveIndex(reference string) (string, error) {
+	ref, err := parseReference(reference, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	desc, err := remote.Head(ref, r.remoteOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch head for reference: %w", err)
+	}
+
+	if !desc.MediaType.IsIndex() {
+		return reference, nil
+	}
+
+	index, err := remote.Index(ref, r.remoteOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image index: %w", err)
+	}
+
+	manifest, err := index.IndexManifest()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch reader for digest of the 0th layer: %w", err)
+		return "", fmt.Errorf("failed to get index manifest: %w", err)
 	}
 
-	// decompresses the data coming from the cache. Because a streaming layer doesn't support decompression
-	// and a static layer returns the data AS IS, we have to decompress it ourselves.
-	return reader, digest.String(), nil
+	if len(manifest.Manifests) == 0 {
+		return "", fmt.Errorf("image index %s contains no manifests", ref)
+	}
+
+	return manifest.Manifests[0].Digest.String(), nil
 }
 
-// FetchDataByDigest returns a reader for a given digest.
-func (c *Client) FetchDataByDigest(ctx context.Context, name, digest string) (io.ReadCloser, error) {
-	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+// tag points newTag at the manifest referenced by tag. Image indexes are tagged as they are.
+func (r *Repository) tag(tag, newTag string) error {
+	ref, err := parseReference(tag, r)
+	if err != nil {
+		return fmt.Errorf("failed to parse reference: %w", err)
+	}
 
-	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
+	desc, err := remote.Get(ref, r.remoteOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get repository: %w", err)
+		return fmt.Errorf("failed to fetch manifest for reference: %w", err)
 	}
 
-	reader, err := repo.FetchBlob(digest)
+	newRef, err := ociname.NewTag(fmt.Sprintf("%s:%s", r.Repository, newTag), r.nameOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch blob: %w", err)
+		return fmt.Errorf("failed to parse tag: %w", err)
 	}
 
-	// decompresses the data coming from the cache. Because a streaming layer doesn't support decompression
-	// and a static layer returns the data AS IS, we have to decompress it ourselves.
-	return reader, nil
-}
+	if err := remote.Tag(newRef, desc, r.remoteOpts...); err != nil {
+		return fmt.Errorf("failed to tag '%s' with '%s': %w", ref, newTag, err)
+	}
 
-// IsCached returns whether a certain tag with a given name exists in cache.
-func (c *Client) IsCached(ctx context.Context, name, tag string) (bool, error) {
-	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
+	return nil
+}
 
-	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
+// pushReferrer pushes the data as a single layer artifact with the manifest at tag as its subject. The artifact
+// type is set as the media type of its config and layer, so clients can filter the referrers by it.
+func (r *Repository) pushReferrer(tag string, data io.Reader, artifactType string) (v1.Hash, error) {
+	ref, err := parseReference(tag, r)
 	if err != nil {
-		return false, fmt.Errorf("failed to get repository: %w", err)
+		return v1.Hash{}, fmt.Errorf("failed to parse reference: %w", err)
 	}
 
-	return repo.head(tag)
-}
+	subject, err := remote.Head(ref, r.remoteOpts...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to fetch subject manifest: %w", err)
+	}
 
-// DeleteData removes a specific tag from the cache.
-func (c *Client) DeleteData(ctx context.Context, name, tag string) error {
-	repositoryName := fmt.Sprintf("%s/%s", c.OCIRepositoryAddr, name)
-	repo, err := NewRepository(repositoryName, c.WithTransport(ctx))
+	// The digest of the artifact has to be known before it is written, so the data can't be streamed.
+	content, err := io.ReadAll(data)
 	if err != nil {
-		return fmt.Errorf("failed create new repository: %w", err)
+		return v1.Hash{}, fmt.Errorf("failed to read referrer data: %w", err)
 	}
 
-	return repo.deleteTag(tag)
-}
+	if artifactType == "" {
+		artifactType = string(types.OCILayer)
+	}
 
-// head does an authenticated call with the repo context to see if a tag in a repository already exists or not.
-func (r *Repository) head(tag string) (bool, error) {
-	reference, err := ociname.ParseReference(fmt.Sprintf("%s:%s", r.Repository, tag))
+	image, err := mutate.AppendLayers(empty.Image, static.NewLayer(content, types.MediaType(artifactType)))
 	if err != nil {
-		return false, fmt.Errorf("failed to parse repository and tag name: %w", err)
+		return v1.Hash{}, fmt.Errorf("failed to compute referrer: %w", err)
 	}
 
-	if _, err := remote.Head(reference, r.remoteOpts...); err != nil {
-		terr := &transport.Error{}
-		if ok := errors.As(err, &terr); ok {
-			if terr.StatusCode == http.StatusNotFound {
-				return false, nil
-			}
-		}
+	image = mutate.MediaType(image, types.OCIManifestSchema1)
+	image = mutate.ConfigMediaType(image, types.MediaType(artifactType))
 
-		return false, err
+	image, ok := mutate.Subject(image, v1.Descriptor{
+		MediaType: subject.MediaType,
+		Size:      subject.Size,
+		Digest:    subject.Digest,
+	}).(v1.Image)
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("returned object was not an Image")
 	}
 
-	return true, nil
+	digest, err := image.Digest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to compute referrer digest: %w", err)
+	}
+
+	if err := r.pushImage(image, r.Digest(digest.String())); err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to push referrer: %w", err)
+	}
+
+	return digest, nil
 }
 
 // deleteTag fetches the latest digest for a tag. This will delete the whole Manifest.
 // This is done because docker registry doesn't technically support deleting a single Tag.
 // But since we have a 1:1 relationship between a tag and a manifest, it's safe to delete
-// the complete manifest.
+// the complete manifest. A manifest pushed without a tag is deleted by its digest.
 func (r *Repository) deleteTag(tag string) error {
-	ref, err := ociname.NewTag(fmt.Sprintf("%s:%s", r.Repository, tag))
+	ref, err := parseReference(tag, r)
 	if err != nil {
 		return fmt.Errorf("failed to parse reference: %w", err)
 	}
@@ -349,7 +1413,7 @@ func (r *Repository) deleteTag(tag string) error {
 
 // fetchBlob fetches a blob from the repository.
 func (r *Repository) fetchBlob(digest string) (v1.Layer, error) {
-	ref, err := ociname.NewDigest(fmt.Sprintf("%s@%s", r.Repository, digest))
+	ref, err := ociname.NewDigest(fmt.Sprintf("%s@%s", r.Repository, digest), r.nameOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse digest %q: %w", digest, err)
 	}
@@ -396,34 +1460,173 @@ func (r *Repository) pushBlob(layer v1.Layer) error {
 // PushStreamingImage pushes a reader to the repository as a streaming OCI image.
 // It accepts a media type and a byte slice as the blob.
 // Default media type is "application/vnd.oci.image.layer.v1.tar+gzip".
-// Annotations can be passed to the image manifest.
+// Annotations can be passed to the image manifest, the image has an empty config unless one is passed.
 func (r *Repository) PushStreamingImage(
 	reference string,
 	reader io.ReadCloser,
 	mediaType string,
 	annotations map[string]string,
-) (*v1.Manifest, error) {
+	config *cache.ImageConfig,
+) (_ *v1.Manifest, err error) {
+	ref, err := parseReference(reference, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reference: %w", err)
+	}
+	base, err := baseImage(config)
+	if err != nil {
+		return nil, err
+	}
+
+	layer := computeStreamBlob(reader, mediaType)
+
+	// The digest of a streamed layer is only known once it has been uploaded, the compressed data is buffered to
+	// find out whether a layer can be mounted instead.
+	if len(r.mountFrom) > 0 {
+		compressed, err := layer.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress layer: %w", err)
+		}
+
+		layerMediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get layer media type: %w", err)
+		}
+
+		file, err := newFileLayer(compressed, string(layerMediaType))
+		if cerr := compressed.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("failed to compress layer: %w", cerr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer layer: %w", err)
+		}
+
+		defer func() {
+			err = errors.Join(err, file.cleanup())
+		}()
+
+		if layer, err = file.toLayer(); err != nil {
+			return nil, fmt.Errorf("failed to compute layer: %w", err)
+		}
+
+		r.mountLayer(layer)
+	}
+
+	image, err := mutate.AppendLayers(base, layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute image: %w", err)
+	}
+
+	return r.pushLayerImage(image, ref, mediaType, annotations)
+}
+
+// PushUncompressedImage pushes a reader to the repository as an OCI image with a single layer containing the data
+// as it is. The data is buffered in a temporary file to compute its digest. If no media type is given, it is
+// "application/vnd.oci.image.layer.v1.tar" or, if the data is gzip-compressed, "application/vnd.oci.image.layer.v1.tar+gzip".
+// Annotations can be passed to the image manifest, the image has an empty config unless one is passed.
+func (r *Repository) PushUncompressedImage(
+	reference string,
+	reader io.Reader,
+	mediaType string,
+	annotations map[string]string,
+	config *cache.ImageConfig,
+) (_ *v1.Manifest, err error) {
 	ref, err := parseReference(reference, r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse reference: %w", err)
 	}
-	image, err := computeStreamImage(reader, mediaType)
+
+	file, err := newFileLayer(reader, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer layer: %w", err)
+	}
+
+	defer func() {
+		err = errors.Join(err, file.cleanup())
+	}()
+
+	layer, err := file.toLayer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute layer: %w", err)
+	}
+
+	r.mountLayer(layer)
+
+	base, err := baseImage(config)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := mutate.AppendLayers(base, layer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute image: %w", err)
 	}
-	if len(annotations) > 0 {
-		i, ok := mutate.Annotations(image, annotations).(v1.Image)
-		if !ok {
-			return nil, fmt.Errorf("returned object was not an Image")
+
+	return r.pushLayerImage(image, ref, mediaType, annotations)
+}
+
+// mountLayer mounts the layer from the first repository it should be mounted from that already holds a layer with
+// the same digest, checked with a HEAD request for the blob. The layer is then already in the repository when the
+// image is pushed and isn't uploaded again. The layer is uploaded with the image if it can't be mounted.
+func (r *Repository) mountLayer(layer v1.Layer) {
+	hash, err := layer.Digest()
+	if err != nil {
+		return
+	}
+
+	for _, from := range r.mountFrom {
+		source, err := ociname.NewRepository(from, r.nameOpts...)
+		if err != nil || source.String() == r.Repository.String() {
+			continue
+		}
+
+		existing, err := remote.Layer(source.Digest(hash.String()), r.remoteOpts...)
+		if err != nil {
+			continue
+		}
+
+		if exists, err := partial.Exists(existing); err != nil || !exists {
+			continue
 		}
 
-		image = i
+		if err := r.pushBlob(&remote.MountableLayer{Layer: layer, Reference: source.Digest(hash.String())}); err == nil {
+			metrics.SnapshotLayerMountsTotal.WithLabelValues(r.RepositoryStr()).Inc()
+
+			return
+		}
+	}
+}
+
+// pushLayerImage annotates the single layer image and pushes it to the reference. Besides the given annotations,
+// the manifest is marked with cache.ManagedByAnnotation. Helm charts keep the config media type Helm requires,
+// other data is typed with the artifact type of the repository if it has one.
+func (r *Repository) pushLayerImage(
+	image v1.Image,
+	ref ociname.Reference,
+	mediaType string,
+	annotations map[string]string,
+) (*v1.Manifest, error) {
+	// The data is always marked as pushed by the controller, the annotations can't override the mark.
+	manifestAnnotations := maps.Clone(annotations)
+	if manifestAnnotations == nil {
+		manifestAnnotations = map[string]string{}
+	}
+	manifestAnnotations[cache.ManagedByAnnotation] = cache.ManagedByValue
+
+	i, ok := mutate.Annotations(image, manifestAnnotations).(v1.Image)
+	if !ok {
+		return nil, fmt.Errorf("returned object was not an Image")
 	}
 
+	image = i
+
 	// These MediaTypes are required to create a Helm compliant OCI repository.
 	if mediaType == registry.ChartLayerMediaType {
 		image = mutate.ConfigMediaType(image, registry.ConfigMediaType)
 		image = mutate.MediaType(image, ocispec.MediaTypeImageManifest)
+	} else if r.artifactType != "" {
+		// Like referrers, the artifact type is the media type of the config of an OCI manifest.
+		image = mutate.MediaType(image, types.OCIManifestSchema1)
+		image = mutate.ConfigMediaType(image, types.MediaType(r.artifactType))
 	}
 
 	if err := r.pushImage(image, ref); err != nil {
@@ -433,9 +1636,261 @@ func (r *Repository) PushStreamingImage(
 	return image.Manifest()
 }
 
-// pushImage pushes an OCI image to the repository. It accepts a v1.RepositoryURL interface.
+// CopyArtifact copies the image or image index at the source reference to the given tag of the repository.
+// If a platform is given, the image for that platform is selected from an image index. The source options
+// are used to fetch the artifact from the source registry.
+func (r *Repository) CopyArtifact(
+	source ociname.Reference,
+	tag string,
+	platform *v1.Platform,
+	sourceOpts ...remote.Option,
+) (v1.Hash, error) {
+	ref, err := parseReference(tag, r)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	if platform != nil {
+		sourceOpts = append(sourceOpts, remote.WithPlatform(*platform))
+	}
+
+	desc, err := remote.Get(source, sourceOpts...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to fetch source artifact: %w", err)
+	}
+
+	if desc.MediaType.IsIndex() && platform == nil {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return v1.Hash{}, fmt.Errorf("failed to get image index: %w", err)
+		}
+
+		if r.gzipLayers {
+			var transcoded bool
+			if index, transcoded, err = r.gzipIndex(index); err != nil {
+				return v1.Hash{}, fmt.Errorf("failed to recompress image index: %w", err)
+			}
+
+			r.recordTranscoded(transcoded)
+		}
+
+		if err := r.pushIndex(index, ref); err != nil {
+			return v1.Hash{}, fmt.Errorf("failed to push image index: %w", err)
+		}
+
+		return index.Digest()
+	}
+
+	// For an image index, this resolves the image matching the platform.
+	image, err := desc.Image()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to get image: %w", err)
+	}
+
+	if r.gzipLayers {
+		var transcoded bool
+		if image, transcoded, err = r.gzipImage(image); err != nil {
+			return v1.Hash{}, fmt.Errorf("failed to recompress image: %w", err)
+		}
+
+		r.recordTranscoded(transcoded)
+	}
+
+	if err := r.pushImage(image, ref); err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to push image: %w", err)
+	}
+
+	return image.Digest()
+}
+
+// recordTranscoded records in the push result whether the layers of a copied image were recompressed.
+func (r *Repository) recordTranscoded(transcoded bool) {
+	if r.result != nil {
+		r.result.Transcoded = transcoded
+	}
+}
+
+// pushImage pushes an OCI image to the repository. It accepts a v1.RepositoryURL interface. Nothing is written if
+// the reference already points at the image. If the repository pushes without tags, the image is pushed by its
+// digest instead.
 func (r *Repository) pushImage(image v1.Image, reference ociname.Reference) error {
-	return remote.Write(reference, image, r.remoteOpts...)
+	if r.untagged {
+		return r.pushUntaggedImage(image)
+	}
+
+	if r.isUnchanged(reference, image) {
+		return r.recordManifest(image)
+	}
+
+	if err := remote.Write(reference, image, r.remoteOpts...); err != nil {
+		if err := r.checkExistingManifest(reference, image, err); err != nil {
+			return err
+		}
+	}
+
+	return r.recordManifest(image)
+}
+
+// pushUntaggedImage pushes an OCI image to the repository by its digest. The digest of an image with a streamed
+// layer is only known once the layer has been uploaded, and remote.Write would upload the consumed layer again, so
+// the blobs are uploaded first and only the manifest is written to the digest. Blobs the repository already holds
+// aren't uploaded again.
+func (r *Repository) pushUntaggedImage(image v1.Image) error {
+	layers, err := image.Layers()
+	if err != nil {
+		return fmt.Errorf("failed to get layers: %w", err)
+	}
+
+	for _, layer := range layers {
+		if err := r.pushBlob(layer); err != nil {
+			return fmt.Errorf("failed to upload layer: %w", err)
+		}
+	}
+
+	manifest, err := image.Manifest()
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	config, err := image.RawConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	if err := r.pushBlob(static.NewLayer(config, manifest.Config.MediaType)); err != nil {
+		return fmt.Errorf("failed to upload config: %w", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to compute digest: %w", err)
+	}
+
+	reference := r.Digest(digest.String())
+	if r.isUnchanged(reference, image) {
+		return r.recordManifest(image)
+	}
+
+	if err := remote.Put(reference, image, r.remoteOpts...); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return r.recordManifest(image)
+}
+
+// pushIndex pushes an OCI image index to the repository. Nothing is written if the reference already points at
+// the index. If the repository pushes without tags, the index is pushed by its digest instead, the digests of its
+// images are known before they are pushed.
+func (r *Repository) pushIndex(index v1.ImageIndex, reference ociname.Reference) error {
+	if r.untagged {
+		digest, err := index.Digest()
+		if err != nil {
+			return fmt.Errorf("failed to compute digest: %w", err)
+		}
+
+		reference = r.Digest(digest.String())
+	}
+
+	if r.isUnchanged(reference, index) {
+		return r.recordManifest(index)
+	}
+
+	if err := remote.WriteIndex(reference, index, r.remoteOpts...); err != nil {
+		if err := r.checkExistingManifest(reference, index, err); err != nil {
+			return err
+		}
+	}
+
+	return r.recordManifest(index)
+}
+
+// recordManifest records the digest of the pushed manifest in the push result.
+func (r *Repository) recordManifest(artifact interface{ Digest() (v1.Hash, error) }) error {
+	if r.result == nil {
+		return nil
+	}
+
+	digest, err := artifact.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to compute digest: %w", err)
+	}
+
+	r.result.Manifest = digest.String()
+
+	return nil
+}
+
+// isUnchanged returns whether the reference already points at the artifact, checked with a HEAD request for its
+// manifest before the artifact is written. Registries don't support conditional manifest writes, comparing the
+// digests saves writing the manifest and checking the registry for each of its blobs. The digest of an image with a
+// streamed layer is only known once the layer has been uploaded, such an image is always written. Any error of the
+// request is left to the write.
+func (r *Repository) isUnchanged(reference ociname.Reference, artifact interface{ Digest() (v1.Hash, error) }) bool {
+	want, err := artifact.Digest()
+	if err != nil {
+		return false
+	}
+
+	desc, err := remote.Head(reference, r.remoteOpts...)
+	if err != nil || desc.Digest != want {
+		return false
+	}
+
+	if r.result != nil {
+		r.result.Unchanged = true
+	}
+
+	return true
+}
+
+// checkExistingManifest decides whether a failed push of the artifact to the reference succeeded after all. Two
+// reconciliations of the same object may push the same artifact at the same time, for example while two replicas
+// briefly both consider themselves the leader, and registries with immutable tags reject the second push. The
+// push counts as successful if the tag already points at the same manifest, otherwise pushErr is returned.
+func (r *Repository) checkExistingManifest(
+	reference ociname.Reference,
+	artifact interface{ Digest() (v1.Hash, error) },
+	pushErr error,
+) error {
+	if !isAlreadyExists(pushErr) {
+		return pushErr
+	}
+
+	want, err := artifact.Digest()
+	if err != nil {
+		return errors.Join(pushErr, fmt.Errorf("failed to compute digest: %w", err))
+	}
+
+	desc, err := remote.Head(reference, r.remoteOpts...)
+	if err != nil {
+		return errors.Join(pushErr, fmt.Errorf("failed to get existing manifest: %w", err))
+	}
+
+	if desc.Digest != want {
+		return fmt.Errorf("tag %s already points at %s instead of %s: %w", reference, desc.Digest, want, pushErr)
+	}
+
+	return nil
+}
+
+// isAlreadyExists returns true if the registry rejected a push because the tag already exists.
+func isAlreadyExists(err error) bool {
+	terr := &transport.Error{}
+	if !errors.As(err, &terr) {
+		return false
+	}
+
+	if terr.StatusCode == http.StatusConflict || terr.StatusCode == http.StatusPreconditionFailed {
+		return true
+	}
+
+	for _, diagnostic := range terr.Errors {
+		if strings.Contains(strings.ToLower(diagnostic.Message), "already exists") {
+			return true
+		}
+	}
+
+	return false
 }
 
 // FetchManifest fetches a manifest from the repository.
@@ -479,7 +1934,7 @@ func (r *Repository) FetchManifest(reference string, filters []string) (*ocispec
 }
 
 func (r *Repository) fetchManifestDescriptor(s string) (*remote.Descriptor, error) {
-	return fetchManifestDescriptorFrom(s, r.remoteOpts...)
+	return fetchManifestDescriptorFrom(s, r.nameOpts, r.remoteOpts...)
 }
 
 // manifestToOCIDescriptor converts a manifest to an OCI Manifest struct.
@@ -506,9 +1961,9 @@ func manifestToOCIDescriptor(m *remote.Descriptor) (*ocispec.Manifest, error) {
 	return ociManifest, nil
 }
 
-func fetchManifestDescriptorFrom(s string, opts ...remote.Option) (*remote.Descriptor, error) {
+func fetchManifestDescriptorFrom(s string, nameOpts []ociname.Option, opts ...remote.Option) (*remote.Descriptor, error) {
 	// a manifest reference can be a tag or a digest
-	ref, err := ociname.ParseReference(s)
+	ref, err := ociname.ParseReference(s, nameOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse reference: %w", err)
 	}
@@ -526,7 +1981,7 @@ func parseReference(reference string, r *Repository) (ociname.Reference, error)
 	} else {
 		reference = fmt.Sprintf("%s:%s", r.Repository, reference)
 	}
-	ref, err := ociname.ParseReference(reference)
+	ref, err := ociname.ParseReference(reference, r.nameOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse reference: %w", err)
 	}
@@ -582,8 +2037,32 @@ func filterAnnotations(annotations map[string]string, filters []string) map[stri
 	return filtered
 }
 
-func computeStreamImage(reader io.ReadCloser, mediaType string) (v1.Image, error) {
-	return mutate.AppendLayers(empty.Image, computeStreamBlob(reader, mediaType))
+// baseImage returns the image the layer of the data is appended to, an empty image with the given config if one
+// is set. The config is set before the layer is appended, as the diff ID of a streamed layer is only known once
+// it has been pushed.
+func baseImage(config *cache.ImageConfig) (v1.Image, error) {
+	if config == nil {
+		return empty.Image, nil
+	}
+
+	cfg, err := empty.Image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config of empty image: %w", err)
+	}
+
+	cfg = cfg.DeepCopy()
+	cfg.OS = config.OS
+	cfg.Architecture = config.Architecture
+	cfg.Variant = config.Variant
+	cfg.Created = v1.Time{Time: config.Created}
+	cfg.Config.Labels = maps.Clone(config.Labels)
+
+	image, err := mutate.ConfigFile(empty.Image, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set image config: %w", err)
+	}
+
+	return image, nil
 }
 
 func computeStreamBlob(reader io.ReadCloser, mediaType string) v1.Layer {
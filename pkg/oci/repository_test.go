@@ -6,16 +6,31 @@ package oci
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"fmt"
 	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	_ "github.com/distribution/distribution/v3/registry/storage/driver/inmemory"
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
+	helmregistry "helm.sh/helm/v3/pkg/registry"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -23,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache"
 	"github.com/open-component-model/ocm-controller/pkg/ocm"
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
 )
@@ -105,7 +121,7 @@ func TestRepository_StreamImage(t *testing.T) {
 			reader := io.NopCloser(bytes.NewBuffer(blob))
 			manifest, err := repo.PushStreamingImage("latest", reader, string(types.OCILayer), map[string]string{
 				"org.opencontainers.artifact.created": time.Now().UTC().Format(time.RFC3339),
-			})
+			}, nil)
 			g.Expect(err).NotTo(HaveOccurred())
 			digest := manifest.Layers[0].Digest.String()
 			layer, err := repo.FetchBlob(digest)
@@ -228,6 +244,377 @@ func TestClient_FetchPush(t *testing.T) {
 	}
 }
 
+func TestClient_CopyArtifact(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1.AddToScheme(scheme))
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ocm-registry-tls-certs",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt":  []byte("file"),
+			"tls.crt": []byte("file"),
+			"tls.key": []byte("file"),
+		},
+		Type: "Opaque",
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(secret).WithScheme(scheme).Build()
+	c := NewClient(addr, WithClient(fakeClient), WithCertificateSecret("ocm-registry-tls-certs"), WithNamespace("default"))
+
+	image, err := random.Image(64, 3)
+	assert.NoError(t, err)
+	image = mutate.ConfigMediaType(image, "application/vnd.test.config.v1+json")
+
+	amd64, err := random.Image(64, 2)
+	assert.NoError(t, err)
+	arm64, err := random.Image(64, 2)
+	assert.NoError(t, err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add: amd64,
+			Descriptor: ociv1.Descriptor{
+				Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"},
+			},
+		},
+		mutate.IndexAddendum{
+			Add: arm64,
+			Descriptor: ociv1.Descriptor{
+				Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+		},
+	)
+
+	testCases := []struct {
+		name     string
+		source   remote.Taggable
+		platform *ociv1.Platform
+		// expected is the artifact that should end up in the cache.
+		expected remote.Taggable
+		// data is the image whose first layer is served for the snapshot.
+		data ociv1.Image
+	}{
+		{
+			name:     "multi-layer image",
+			source:   image,
+			expected: image,
+			data:     image,
+		},
+		{
+			name:     "image index",
+			source:   index,
+			expected: index,
+			data:     amd64,
+		},
+		{
+			name:     "single platform of image index",
+			source:   index,
+			platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"},
+			expected: arm64,
+			data:     arm64,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			sourceRef, err := ociname.ParseReference(addr + "/" + generateRandomName("source") + ":v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			switch source := tc.source.(type) {
+			case ociv1.ImageIndex:
+				g.Expect(remote.WriteIndex(sourceRef, source)).To(Succeed())
+			case ociv1.Image:
+				g.Expect(remote.Write(sourceRef, source)).To(Succeed())
+			}
+
+			name := generateRandomName("copy")
+			digest, err := c.CopyArtifact(context.Background(), sourceRef.String(), name, "v0.0.1", authn.Anonymous, tc.platform)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			expectedManifest, err := tc.expected.RawManifest()
+			g.Expect(err).NotTo(HaveOccurred())
+			targetRef, err := ociname.ParseReference(addr + "/" + name + ":v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			targetDesc, err := remote.Get(targetRef)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(targetDesc.Manifest).To(Equal(expectedManifest))
+			g.Expect(digest).To(Equal(targetDesc.Digest.String()))
+
+			layers, err := tc.data.Layers()
+			g.Expect(err).NotTo(HaveOccurred())
+			expected, err := layers[0].Uncompressed()
+			g.Expect(err).NotTo(HaveOccurred())
+			expectedContent, err := io.ReadAll(expected)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			blob, _, err := c.FetchDataByIdentity(context.Background(), name, "v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			content, err := io.ReadAll(blob)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(content).To(Equal(expectedContent))
+		})
+	}
+}
+
+// tokenRegistry serves a registry that requires bearer tokens. Every token is accepted for a limited number of
+// requests, after which the registry answers with 401 Unauthorized, like a registry whose short-lived tokens expired.
+type tokenRegistry struct {
+	registry http.Handler
+	// uses are the number of requests accepted for each token issued, in order. Tokens issued after the last
+	// entry don't expire.
+	uses []int
+	// challenge is whether requests with an expired token are answered with a challenge.
+	challenge bool
+
+	mu        sync.Mutex
+	issued    int
+	remaining map[string]int
+}
+
+func (r *tokenRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		token := fmt.Sprintf("token-%d", r.issued)
+		r.remaining[token] = -1
+		if r.issued < len(r.uses) {
+			r.remaining[token] = r.uses[r.issued]
+		}
+		r.issued++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"token": %q}`, token)
+
+		return
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	remaining, ok := r.remaining[token]
+	if !ok || remaining == 0 {
+		// The challenge is always sent for requests without a token, so the realm of the tokens can be found.
+		if !ok || r.challenge {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, req.Host))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	if remaining > 0 {
+		r.remaining[token] = remaining - 1
+	}
+
+	r.registry.ServeHTTP(w, req)
+}
+
+func TestClient_CopyArtifactTokenExpiry(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	backend := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	seed := httptest.NewServer(backend)
+	defer seed.Close()
+
+	image, err := random.Image(64, 4)
+	assert.NoError(t, err)
+	sourceRef, err := ociname.ParseReference(strings.TrimPrefix(seed.URL, "http://") + "/podinfo:v6.3.5")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(sourceRef, image))
+
+	expected, err := image.Digest()
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		uses      []int
+		challenge bool
+		// tokens is the least number of tokens that should be requested. Layers are fetched concurrently, each
+		// request rejected on challenge may refresh the token.
+		tokens  int
+		wantErr bool
+	}{
+		{
+			name:      "expired token is refreshed on challenge",
+			uses:      []int{2},
+			challenge: true,
+			tokens:    2,
+		},
+		{
+			name:   "copy is started again if expired token is rejected without challenge",
+			uses:   []int{2},
+			tokens: 2,
+		},
+		{
+			name:    "copy fails if the new token is rejected too",
+			uses:    []int{2, 1},
+			tokens:  2,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			upstream := &tokenRegistry{
+				registry:  backend,
+				uses:      tc.uses,
+				challenge: tc.challenge,
+				remaining: map[string]int{},
+			}
+			server := httptest.NewServer(upstream)
+			defer server.Close()
+
+			source := strings.TrimPrefix(server.URL, "http://") + "/podinfo:v6.3.5"
+			auth := &authn.Basic{Username: "user", Password: "password"}
+			digest, err := c.CopyArtifact(context.Background(), source, generateRandomName("token"), "v6.3.5", auth, nil)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("401 Unauthorized"))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(digest).To(Equal(expected.String()))
+			}
+
+			g.Expect(upstream.issued).To(BeNumerically(">=", tc.tokens))
+		})
+	}
+}
+
+func TestClient_ArtifactSize(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	g := NewWithT(t)
+
+	imageSize := func(image ociv1.Image) int64 {
+		size, err := image.Size()
+		g.Expect(err).NotTo(HaveOccurred())
+		manifest, err := image.Manifest()
+		g.Expect(err).NotTo(HaveOccurred())
+		size += manifest.Config.Size
+		for _, layer := range manifest.Layers {
+			size += layer.Size
+		}
+
+		return size
+	}
+
+	amd64, err := random.Image(64, 2)
+	g.Expect(err).NotTo(HaveOccurred())
+	arm64, err := random.Image(128, 3)
+	g.Expect(err).NotTo(HaveOccurred())
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64,
+			Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64,
+			Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+		// Blobs shared by several images are only counted once.
+		mutate.IndexAddendum{
+			Add:        amd64,
+			Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64", Variant: "v2"}},
+		},
+	)
+	indexSize, err := index.Size()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	imageRef := addr + "/" + generateRandomName("size") + ":image"
+	ref, err := ociname.ParseReference(imageRef)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(remote.Write(ref, amd64)).To(Succeed())
+
+	indexRef := addr + "/" + generateRandomName("size") + ":index"
+	ref, err = ociname.ParseReference(indexRef)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(remote.WriteIndex(ref, index)).To(Succeed())
+
+	size, err := c.ArtifactSize(context.Background(), imageRef, authn.Anonymous, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(imageSize(amd64)))
+
+	size, err = c.ArtifactSize(context.Background(), indexRef, authn.Anonymous, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(indexSize + imageSize(amd64) + imageSize(arm64)))
+
+	size, err = c.ArtifactSize(context.Background(), indexRef, authn.Anonymous, &ociv1.Platform{OS: "linux", Architecture: "arm64"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(imageSize(arm64)))
+
+	_, err = c.ArtifactSize(context.Background(), addr+"/missing:v0.0.1", authn.Anonymous, nil)
+	g.Expect(err).To(HaveOccurred())
+
+	// The size of a copy in the cache is counted the same way.
+	name := generateRandomName("size")
+	_, err = c.CopyArtifact(context.Background(), indexRef, name, "v0.0.1", authn.Anonymous, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	size, err = c.DataSize(context.Background(), name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(indexSize + imageSize(amd64) + imageSize(arm64)))
+
+	_, err = c.DataSize(context.Background(), name, "missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestClient_ArtifactLayerDigest(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	g := NewWithT(t)
+
+	zstd, _ := zstdImage(g)
+	arm64, err := random.Image(128, 3)
+	g.Expect(err).NotTo(HaveOccurred())
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        zstd,
+			Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64,
+			Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	)
+
+	indexRef := addr + "/" + generateRandomName("layer") + ":index"
+	ref, err := ociname.ParseReference(indexRef)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(remote.WriteIndex(ref, index)).To(Succeed())
+
+	// The digest matches the digest of the first layer of the copy, which is what FetchDataByIdentity returns.
+	copiedLayerDigest := func(platform *ociv1.Platform, opts ...cache.PushOption) string {
+		name := generateRandomName("copy")
+		_, err := c.CopyArtifact(context.Background(), indexRef, name, "v0.0.1", authn.Anonymous, platform, opts...)
+		g.Expect(err).NotTo(HaveOccurred())
+		reader, digest, err := c.FetchDataByIdentity(context.Background(), name, "v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reader.Close()).To(Succeed())
+
+		return digest
+	}
+
+	digest, err := c.ArtifactLayerDigest(context.Background(), indexRef, authn.Anonymous, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(digest).To(Equal(copiedLayerDigest(nil)))
+
+	digest, err = c.ArtifactLayerDigest(context.Background(), indexRef, authn.Anonymous, nil, cache.WithGzipLayers())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(digest).To(Equal(copiedLayerDigest(nil, cache.WithGzipLayers())), "the zstd layer should be recompressed")
+
+	platform := &ociv1.Platform{OS: "linux", Architecture: "arm64"}
+	digest, err = c.ArtifactLayerDigest(context.Background(), indexRef, authn.Anonymous, platform)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(digest).To(Equal(copiedLayerDigest(platform)))
+
+	_, err = c.ArtifactLayerDigest(context.Background(), addr+"/missing:v0.0.1", authn.Anonymous, nil)
+	g.Expect(err).To(HaveOccurred())
+}
+
 func TestClient_DeleteData(t *testing.T) {
 	scheme := runtime.NewScheme()
 	assert.NoError(t, v1.AddToScheme(scheme))
@@ -309,3 +696,887 @@ func TestClient_DeleteData(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_TagData(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ocm-registry-tls-certs",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt":  []byte("file"),
+			"tls.crt": []byte("file"),
+			"tls.key": []byte("file"),
+		},
+		Type: "Opaque",
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(secret).WithScheme(scheme).Build()
+	c := NewClient(addr, WithClient(fakeClient), WithCertificateSecret("ocm-registry-tls-certs"), WithNamespace("default"))
+
+	g := NewWithT(t)
+
+	name := "tag-data"
+	digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBuffer([]byte("content"))), "", name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	newTag := strings.ReplaceAll(digest, ":", "-")
+	g.Expect(c.TagData(context.Background(), name, "v0.0.1", newTag)).To(Succeed())
+
+	reader, tagDigest, err := c.FetchDataByIdentity(context.Background(), name, newTag)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer reader.Close()
+	g.Expect(tagDigest).To(Equal(digest))
+
+	content, err := io.ReadAll(reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(content).To(Equal([]byte("content")))
+
+	g.Expect(c.TagData(context.Background(), name, "v0.0.2", newTag)).NotTo(Succeed())
+}
+
+func TestClient_ListTags(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	g := NewWithT(t)
+
+	name := "list-tags"
+	digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBuffer([]byte("content"))), "", name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(c.TagData(context.Background(), name, "v0.0.1", "v0.0.2")).To(Succeed())
+
+	repositories, err := c.ListRepositories(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(repositories).To(ContainElement(name))
+
+	tags, err := c.ListTags(context.Background(), name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tags).To(ConsistOf("v0.0.1", "v0.0.2"))
+
+	manifestDigest, err := c.ResolveTag(context.Background(), name, "v0.0.2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(manifestDigest).To(HavePrefix("sha256:"))
+	g.Expect(manifestDigest).NotTo(Equal(digest), "the tag resolves to the manifest, not the layer")
+}
+
+func TestClient_LayerDigest(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	content := strings.Repeat("content", 1024)
+
+	for i, opts := range [][]cache.PushOption{nil, {cache.WithoutCompression()}} {
+		g := NewWithT(t)
+
+		t.Log("computing the digest the data is pushed with")
+		expected, err := c.LayerDigest(strings.NewReader(content), opts...)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		digest, err := c.PushData(context.Background(), io.NopCloser(strings.NewReader(content)), "", fmt.Sprintf("layer-digest-%d", i), "v0.0.1", opts...)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(digest).To(Equal(expected))
+	}
+}
+
+func TestClient_PushDataWithoutCompression(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write([]byte("content"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	testCases := []struct {
+		name      string
+		blob      []byte
+		mediaType types.MediaType
+	}{
+		{
+			name:      "uncompressed data",
+			blob:      []byte("content"),
+			mediaType: types.OCIUncompressedLayer,
+		},
+		{
+			name:      "compressed data",
+			blob:      compressed.Bytes(),
+			mediaType: types.OCILayer,
+		},
+	}
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			name := fmt.Sprintf("push-data-without-compression-%d", i)
+			digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBuffer(tc.blob)), "", name, "v0.0.1", cache.WithoutCompression())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			t.Log("storing the data as it is")
+			hash, _, err := ociv1.SHA256(bytes.NewReader(tc.blob))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(digest).To(Equal(hash.String()))
+
+			ref, err := ociname.ParseReference(fmt.Sprintf("%s/%s:v0.0.1", addr, name))
+			g.Expect(err).NotTo(HaveOccurred())
+			image, err := remote.Image(ref)
+			g.Expect(err).NotTo(HaveOccurred())
+			manifest, err := image.Manifest()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(manifest.Layers).To(HaveLen(1))
+			g.Expect(manifest.Layers[0].MediaType).To(Equal(tc.mediaType))
+			g.Expect(manifest.Layers[0].Size).To(Equal(int64(len(tc.blob))))
+
+			t.Log("reading the data back decompressed")
+			reader, err := c.FetchDataByDigest(context.Background(), name, digest)
+			g.Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(content).To(Equal([]byte("content")))
+		})
+	}
+}
+
+func TestClient_PushDataWithAnnotations(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	annotations := map[string]string{"software.ocm/resource-type": "manifests"}
+
+	for _, uncompressed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("uncompressed %t", uncompressed), func(t *testing.T) {
+			g := NewWithT(t)
+
+			name := fmt.Sprintf("push-data-with-annotations-%t", uncompressed)
+			opts := []cache.PushOption{cache.WithAnnotations(annotations)}
+			if uncompressed {
+				opts = append(opts, cache.WithoutCompression())
+			}
+
+			digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", name, "v0.0.1", opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			ref, err := ociname.ParseReference(fmt.Sprintf("%s/%s:v0.0.1", addr, name))
+			g.Expect(err).NotTo(HaveOccurred())
+			image, err := remote.Image(ref)
+			g.Expect(err).NotTo(HaveOccurred())
+			manifest, err := image.Manifest()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(manifest.Annotations).To(Equal(map[string]string{
+				"software.ocm/resource-type": "manifests",
+				cache.ManagedByAnnotation:    cache.ManagedByValue,
+			}))
+			g.Expect(manifest.Layers[0].Digest.String()).To(Equal(digest), "the digest of the data isn't affected by the annotations")
+
+			fetched, err := c.ManifestAnnotations(context.Background(), name, "v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(fetched).To(Equal(manifest.Annotations))
+		})
+	}
+}
+
+func TestClient_ManifestAnnotationsOfOtherImages(t *testing.T) {
+	g := NewWithT(t)
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	image, err := random.Image(64, 1)
+	g.Expect(err).NotTo(HaveOccurred())
+	ref, err := ociname.ParseReference(addr + "/external-image:v1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(remote.Write(ref, image)).To(Succeed())
+
+	annotations, err := c.ManifestAnnotations(context.Background(), "external-image", "v1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(annotations).NotTo(HaveKey(cache.ManagedByAnnotation), "images pushed by other tools aren't marked")
+
+	_, err = c.ManifestAnnotations(context.Background(), "external-image", "missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestClient_PushDataWithImageConfig(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	config := &cache.ImageConfig{
+		OS:           "linux",
+		Architecture: "arm64",
+		Variant:      "v8",
+		Created:      time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		Labels:       map[string]string{"org.opencontainers.image.source": "https://github.com/open-component-model/ocm-controller"},
+	}
+
+	for _, uncompressed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("uncompressed %t", uncompressed), func(t *testing.T) {
+			g := NewWithT(t)
+
+			name := fmt.Sprintf("push-data-with-image-config-%t", uncompressed)
+			opts := []cache.PushOption{cache.WithImageConfig(config)}
+			if uncompressed {
+				opts = append(opts, cache.WithoutCompression())
+			}
+
+			digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", name, "v0.0.1", opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			ref, err := ociname.ParseReference(fmt.Sprintf("%s/%s:v0.0.1", addr, name))
+			g.Expect(err).NotTo(HaveOccurred())
+			image, err := remote.Image(ref)
+			g.Expect(err).NotTo(HaveOccurred())
+			cfg, err := image.ConfigFile()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cfg.OS).To(Equal("linux"))
+			g.Expect(cfg.Architecture).To(Equal("arm64"))
+			g.Expect(cfg.Variant).To(Equal("v8"))
+			g.Expect(cfg.Created.Time.Equal(config.Created)).To(BeTrue())
+			g.Expect(cfg.Config.Labels).To(Equal(config.Labels))
+
+			t.Log("recording the diff ID of the data layer in the config")
+			layers, err := image.Layers()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(layers).To(HaveLen(1))
+			diffID, err := layers[0].DiffID()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cfg.RootFS.DiffIDs).To(Equal([]ociv1.Hash{diffID}))
+
+			manifest, err := image.Manifest()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(manifest.Layers[0].Digest.String()).To(Equal(digest), "the digest of the data isn't affected by the config")
+		})
+	}
+
+	t.Log("pushing data without a config in an image with an empty config")
+	g := NewWithT(t)
+	_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", "push-data-without-image-config", "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	ref, err := ociname.ParseReference(addr + "/push-data-without-image-config:v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	image, err := remote.Image(ref)
+	g.Expect(err).NotTo(HaveOccurred())
+	cfg, err := image.ConfigFile()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.OS).To(BeEmpty())
+	g.Expect(cfg.Created.IsZero()).To(BeTrue())
+}
+
+func TestClient_PushDataWithArtifactType(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	artifactType := "application/vnd.ocm.software.snapshot.v1"
+
+	for _, uncompressed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("uncompressed %t", uncompressed), func(t *testing.T) {
+			g := NewWithT(t)
+
+			name := fmt.Sprintf("push-data-with-artifact-type-%t", uncompressed)
+			opts := []cache.PushOption{cache.WithArtifactType(artifactType)}
+			if uncompressed {
+				opts = append(opts, cache.WithoutCompression())
+			}
+
+			_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", name, "v0.0.1", opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			ref, err := ociname.ParseReference(fmt.Sprintf("%s/%s:v0.0.1", addr, name))
+			g.Expect(err).NotTo(HaveOccurred())
+			image, err := remote.Image(ref)
+			g.Expect(err).NotTo(HaveOccurred())
+			manifest, err := image.Manifest()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(manifest.MediaType).To(Equal(types.OCIManifestSchema1))
+			g.Expect(manifest.Config.MediaType).To(Equal(types.MediaType(artifactType)))
+
+			t.Log("reading the data back like the data of an image")
+			reader, _, err := c.FetchDataByIdentity(context.Background(), name, "v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(content)).To(Equal("content"))
+		})
+	}
+
+	t.Log("keeping the config media type of helm charts")
+	g := NewWithT(t)
+	_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("chart")), helmregistry.ChartLayerMediaType,
+		"push-chart-with-artifact-type", "v0.0.1", cache.WithArtifactType(artifactType))
+	g.Expect(err).NotTo(HaveOccurred())
+	ref, err := ociname.ParseReference(addr + "/push-chart-with-artifact-type:v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	image, err := remote.Image(ref)
+	g.Expect(err).NotTo(HaveOccurred())
+	manifest, err := image.Manifest()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(manifest.Config.MediaType).To(Equal(types.MediaType(helmregistry.ConfigMediaType)))
+}
+
+// immutableTags rejects pushing a manifest to a tag that has already been pushed, like registries with immutable
+// tags do.
+func immutableTags(next http.Handler) http.Handler {
+	var (
+		mu   sync.Mutex
+		tags = map[string]bool{}
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") {
+			mu.Lock()
+			exists := tags[r.URL.Path]
+			tags[r.URL.Path] = true
+			mu.Unlock()
+
+			if exists {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"DENIED","message":"tag already exists"}]}`))
+
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestClient_PushDataConcurrently(t *testing.T) {
+	server := httptest.NewServer(immutableTags(registry.New(registry.Logger(log.New(io.Discard, "", 0)))))
+	t.Cleanup(server.Close)
+
+	c := NewClient(strings.TrimPrefix(server.URL, "http://"), WithInsecureSkipVerify(true))
+
+	t.Run("the same data is pushed by both reconciliations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var wg sync.WaitGroup
+		digests := make([]string, 2)
+		errs := make([]error, 2)
+		for i := range digests {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				digests[i], errs[i] = c.PushData(context.Background(), io.NopCloser(strings.NewReader("content")), "", "concurrent", "v0.0.1")
+			}(i)
+		}
+		wg.Wait()
+
+		g.Expect(errs).To(HaveEach(Not(HaveOccurred())))
+		g.Expect(digests[0]).To(Equal(digests[1]))
+
+		reader, digest, err := c.FetchDataByIdentity(context.Background(), "concurrent", "v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+		g.Expect(digest).To(Equal(digests[0]))
+	})
+
+	t.Run("the tag holds other data", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := c.PushData(context.Background(), io.NopCloser(strings.NewReader("content")), "", "conflict", "v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.PushData(context.Background(), io.NopCloser(strings.NewReader("other content")), "", "conflict", "v0.0.1")
+		g.Expect(err).To(MatchError(ContainSubstring("already points at")))
+	})
+}
+
+// endlessReader returns data slowly and never ends, like a large resource read from a slow repository.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	time.Sleep(10 * time.Millisecond)
+
+	return copy(p, bytes.Repeat([]byte("a"), len(p))), nil
+}
+
+func TestClient_PushDataCancelled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ocm-registry-tls-certs",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt":  []byte("file"),
+			"tls.crt": []byte("file"),
+			"tls.key": []byte("file"),
+		},
+		Type: "Opaque",
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(secret).WithScheme(scheme).Build()
+	c := NewClient(addr, WithClient(fakeClient), WithCertificateSecret("ocm-registry-tls-certs"), WithNamespace("default"))
+
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.PushData(ctx, io.NopCloser(endlessReader{}), "", "push-data-cancelled", "v0.0.1")
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+
+	cached, err := c.IsCached(context.Background(), "push-data-cancelled", "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cached).To(BeFalse())
+}
+
+func TestClient_PushReferrer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ocm-registry-tls-certs",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt":  []byte("file"),
+			"tls.crt": []byte("file"),
+			"tls.key": []byte("file"),
+		},
+		Type: "Opaque",
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(secret).WithScheme(scheme).Build()
+	c := NewClient(addr, WithClient(fakeClient), WithCertificateSecret("ocm-registry-tls-certs"), WithNamespace("default"))
+
+	g := NewWithT(t)
+
+	name := "push-referrer"
+	_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBuffer([]byte("content"))), "", name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	digest, err := c.PushReferrer(context.Background(), strings.NewReader(`{"spdxVersion":"SPDX-2.3"}`), "application/spdx+json", name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	t.Log("pushing the same referrer again is a no-op")
+	again, err := c.PushReferrer(context.Background(), strings.NewReader(`{"spdxVersion":"SPDX-2.3"}`), "application/spdx+json", name, "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(Equal(digest))
+
+	repo, err := NewRepository(fmt.Sprintf("%s/%s", addr, name), c.WithTransport(context.Background()))
+	g.Expect(err).NotTo(HaveOccurred())
+	subject, err := remote.Head(repo.Tag("v0.0.1"), repo.remoteOpts...)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	referrers, err := remote.Referrers(repo.Digest(subject.Digest.String()), repo.remoteOpts...)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(referrers.Manifests).To(HaveLen(1))
+	g.Expect(referrers.Manifests[0].Digest.String()).To(Equal(digest))
+	g.Expect(string(referrers.Manifests[0].ArtifactType)).To(Equal("application/spdx+json"))
+
+	_, err = c.PushReferrer(context.Background(), strings.NewReader("{}"), "application/spdx+json", name, "v0.0.2")
+	g.Expect(err).To(MatchError(ContainSubstring("failed to fetch subject manifest")))
+}
+
+type recordingRoundTripper struct {
+	urls []string
+}
+
+func (r *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.urls = append(r.urls, req.URL.String())
+
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+}
+
+func TestClient_Scheme(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+
+	t.Log("talking to a local registry over http")
+	c := NewClient(addr, WithInsecureSkipVerify(true), WithScheme("http"))
+	g := NewWithT(t)
+	_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBuffer([]byte("content"))), "", "scheme-http", "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(c.Ping(context.Background())).To(Succeed())
+
+	t.Log("upgrading requests to a registry with a private address to https")
+	c = NewClient(addr, WithInsecureSkipVerify(true), WithScheme("https"))
+	g.Expect(c.Ping(context.Background())).NotTo(Succeed())
+
+	recorder := &recordingRoundTripper{}
+	rt := &httpsRoundTripper{host: "10.96.0.10:5000", next: recorder}
+	for _, url := range []string{"http://10.96.0.10:5000/v2/", "http://ghcr.io/v2/"} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp, err := rt.RoundTrip(req)
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+	}
+	g.Expect(recorder.urls).To(Equal([]string{"https://10.96.0.10:5000/v2/", "http://ghcr.io/v2/"}))
+}
+
+func TestClient_ForRegistry(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000", WithInsecureSkipVerify(true), WithTimeout(time.Minute),
+		WithSnapshotRegistries(addr, "ghcr.io/path", "user@ghcr.io"))
+	g := NewWithT(t)
+
+	t.Log("pushing to the registry of the resource instead of the in-cluster registry")
+	keychain := &recordingKeychain{}
+	store, err := c.ForRegistry("http://"+addr+"/", keychain)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	external, ok := store.(*Client)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(external.OCIRepositoryAddr).To(Equal(addr))
+	g.Expect(external.Scheme).To(Equal("http"))
+	g.Expect(external.Timeout).To(Equal(time.Minute))
+	g.Expect(external.external).To(BeTrue())
+
+	_, err = store.PushData(context.Background(), io.NopCloser(bytes.NewBuffer([]byte("content"))), "", "for-registry", "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keychain.resolved).To(ContainElement(addr), "the push is authenticated with the keychain of the resource")
+	cached, err := NewClient(addr, WithInsecureSkipVerify(true)).IsCached(context.Background(), "for-registry", "v0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cached).To(BeTrue())
+
+	t.Log("reusing the transport of a registry for other credentials")
+	again, err := c.ForRegistry(addr, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again.(*Client).shared).NotTo(BeIdenticalTo(external.shared), "the address has no scheme, so it is a different registry")
+	again, err = c.ForRegistry("HTTP://"+addr, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again.(*Client).shared).To(BeIdenticalTo(external.shared))
+	g.Expect(again.(*Client).keychain).To(BeNil())
+
+	t.Log("rejecting registries that aren't allowed")
+	_, err = c.ForRegistry("ghcr.io", keychain)
+	g.Expect(err).To(MatchError(cache.ErrRegistryNotAllowed))
+	_, err = NewClient("registry.ocm-system.svc.cluster.local:5000").ForRegistry(addr, nil)
+	g.Expect(err).To(MatchError(cache.ErrRegistryNotAllowed), "no registry is allowed by default")
+
+	for _, address := range []string{"", "ftp://ghcr.io", "ghcr.io/path", "user@ghcr.io"} {
+		_, err := c.ForRegistry(address, nil)
+		g.Expect(err).To(HaveOccurred(), "address %q", address)
+	}
+}
+
+func TestClient_ForRegistryEviction(t *testing.T) {
+	g := NewWithT(t)
+
+	hosts := make([]string, 0, registryClientCacheSize+1)
+	for i := 0; i <= registryClientCacheSize; i++ {
+		hosts = append(hosts, fmt.Sprintf("registry-%d.example.com", i))
+	}
+	c := NewClient("registry.ocm-system.svc.cluster.local:5000", WithSnapshotRegistries(hosts...))
+
+	first, err := c.ForRegistry(hosts[0], nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, host := range hosts[1:] {
+		_, err := c.ForRegistry(host, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	g.Expect(c.registries.Len()).To(Equal(registryClientCacheSize), "only the most recently used clients are kept")
+	again, err := c.ForRegistry(hosts[0], nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again.(*Client).shared).NotTo(BeIdenticalTo(first.(*Client).shared), "the evicted client is built again")
+}
+
+// recordingKeychain records the registries it resolves credentials for and resolves all of them to anonymous.
+type recordingKeychain struct {
+	resolved []string
+}
+
+func (k *recordingKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	k.resolved = append(k.resolved, target.RegistryStr())
+
+	return authn.Anonymous, nil
+}
+
+// countingServer returns a registry counting the connections that requests are served on. The https probe of
+// go-containerregistry, which opens a connection per ping to a registry on a local address, isn't counted.
+func countingServer(t testing.TB) (*httptest.Server, func() int) {
+	var (
+		mu    sync.Mutex
+		conns = map[string]struct{}{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = struct{}{}
+		mu.Unlock()
+
+		testServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(conns)
+	}
+}
+
+func TestClient_ReuseTransport(t *testing.T) {
+	server, conns := countingServer(t)
+	addr := strings.TrimPrefix(server.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true), WithTimeout(time.Minute))
+	g := NewWithT(t)
+
+	first, err := c.baseTransport(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	second, err := c.baseTransport(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(second).To(BeIdenticalTo(first))
+
+	for i := 0; i < 10; i++ {
+		_, err := c.IsCached(context.Background(), "reuse-transport", "v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(conns()).To(Equal(1), "all requests should have used the same connection")
+}
+
+func BenchmarkClient_IsCached(b *testing.B) {
+	server, conns := countingServer(b)
+	addr := strings.TrimPrefix(server.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true), WithTimeout(time.Minute))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.IsCached(context.Background(), "benchmark", "v0.0.1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(conns())/float64(b.N), "conns/op")
+}
+
+func TestClient_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	hangingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hangingServer.Close()
+	defer close(done)
+
+	addr := strings.TrimPrefix(hangingServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true), WithTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.IsCached(context.Background(), "hanging", "v0.0.1")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "request to a hanging registry should have timed out")
+
+	start = time.Now()
+	_, err = c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("data")), "", "hanging", "v0.0.1")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "push to a hanging registry should have timed out")
+}
+
+func TestClient_PushDataMountFrom(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		mounts []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Query().Get("mount") != "" {
+			mu.Lock()
+			mounts = append(mounts, r.URL.Path+" from "+r.URL.Query().Get("from"))
+			mu.Unlock()
+		}
+
+		testServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	testCases := []struct {
+		name string
+		opts []cache.PushOption
+	}{
+		{
+			name: "compressed",
+		},
+		{
+			name: "uncompressed",
+			opts: []cache.PushOption{cache.WithoutCompression()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mounts = nil
+
+			prior := "mount-from-prior-" + tc.name
+			previous, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", prior, "v0.0.1", tc.opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			t.Log("mounting the unchanged layer from the previous snapshot")
+			next := "mount-from-next-" + tc.name
+			opts := append([]cache.PushOption{cache.WithMountFrom(prior)}, tc.opts...)
+			digest, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", next, "v0.0.2", opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(digest).To(Equal(previous), "the layer should be the same as the layer of the previous snapshot")
+			g.Expect(mounts).To(ConsistOf(fmt.Sprintf("/v2/%s/blobs/uploads/ from %s", next, prior)))
+
+			reader, err := c.FetchDataByDigest(context.Background(), next, digest)
+			g.Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(content).To(Equal([]byte("content")))
+
+			t.Log("uploading a changed layer")
+			mounts = nil
+			digest, err = c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("changed content")), "", next, "v0.0.3", opts...)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(digest).NotTo(Equal(previous))
+			g.Expect(mounts).To(BeEmpty())
+		})
+	}
+}
+
+func TestClient_PushUnchanged(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		writes []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") {
+			mu.Lock()
+			writes = append(writes, r.URL.Path)
+			mu.Unlock()
+		}
+
+		testServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	g := NewWithT(t)
+
+	push := func(content string) cache.PushResult {
+		var result cache.PushResult
+		_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString(content)), "", "push-unchanged", "v0.0.1",
+			cache.WithoutCompression(), cache.WithPushResult(&result))
+		g.Expect(err).NotTo(HaveOccurred())
+
+		return result
+	}
+
+	t.Log("writing data to a new tag")
+	g.Expect(push("content").Unchanged).To(BeFalse())
+	g.Expect(writes).To(HaveLen(1))
+
+	t.Log("skipping the write of the same data")
+	g.Expect(push("content").Unchanged).To(BeTrue())
+	g.Expect(writes).To(HaveLen(1))
+
+	t.Log("writing changed data")
+	g.Expect(push("changed content").Unchanged).To(BeFalse())
+	g.Expect(writes).To(HaveLen(2))
+
+	t.Log("skipping the copy of the same image")
+	image, err := random.Image(64, 1)
+	g.Expect(err).NotTo(HaveOccurred())
+	source := addr + "/push-unchanged-source:v0.0.1"
+	sourceRef, err := ociname.ParseReference(source)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(remote.Write(sourceRef, image)).To(Succeed())
+	writes = nil
+
+	for _, unchanged := range []bool{false, true} {
+		var result cache.PushResult
+		_, err = c.CopyArtifact(context.Background(), source, "push-unchanged-copy", "v0.0.1", authn.Anonymous, nil, cache.WithPushResult(&result))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Unchanged).To(Equal(unchanged))
+	}
+	g.Expect(writes).To(HaveLen(1))
+}
+
+func TestClient_PushWithoutTag(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	ctx := context.Background()
+
+	for _, compressed := range []bool{true, false} {
+		t.Run(fmt.Sprintf("compressed %t", compressed), func(t *testing.T) {
+			g := NewWithT(t)
+			name := fmt.Sprintf("push-without-tag-%t", compressed)
+
+			push := func() cache.PushResult {
+				opts := []cache.PushOption{cache.WithoutTag()}
+				if !compressed {
+					opts = append(opts, cache.WithoutCompression())
+				}
+
+				var result cache.PushResult
+				_, err := c.PushData(ctx, io.NopCloser(bytes.NewBufferString("content")), "", name, "v0.0.1",
+					append(opts, cache.WithPushResult(&result))...)
+				g.Expect(err).NotTo(HaveOccurred())
+
+				return result
+			}
+
+			result := push()
+			g.Expect(result.Unchanged).To(BeFalse())
+			g.Expect(result.Manifest).To(HavePrefix("sha256:"))
+
+			cached, err := c.IsCached(ctx, name, "v0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cached).To(BeFalse())
+
+			reader, _, err := c.FetchDataByIdentity(ctx, name, result.Manifest)
+			g.Expect(err).NotTo(HaveOccurred())
+			content, err := io.ReadAll(reader)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(reader.Close()).To(Succeed())
+			g.Expect(string(content)).To(Equal("content"))
+
+			t.Log("skipping the write of the same data")
+			again := push()
+			g.Expect(again.Unchanged).To(BeTrue())
+			g.Expect(again.Manifest).To(Equal(result.Manifest))
+
+			t.Log("deleting the manifest by its digest")
+			g.Expect(c.DeleteData(ctx, name, result.Manifest)).To(Succeed())
+			cached, err = c.IsCached(ctx, name, result.Manifest)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cached).To(BeFalse())
+		})
+	}
+
+	t.Run("copied index", func(t *testing.T) {
+		g := NewWithT(t)
+
+		image, err := random.Image(64, 1)
+		g.Expect(err).NotTo(HaveOccurred())
+		index := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: image})
+		source := addr + "/push-without-tag-source:v0.0.1"
+		sourceRef, err := ociname.ParseReference(source)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(remote.WriteIndex(sourceRef, index)).To(Succeed())
+
+		var result cache.PushResult
+		digest, err := c.CopyArtifact(ctx, source, "push-without-tag-copy", "v0.0.1", authn.Anonymous, nil,
+			cache.WithoutTag(), cache.WithPushResult(&result))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Manifest).To(Equal(digest))
+
+		cached, err := c.IsCached(ctx, "push-without-tag-copy", "v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(cached).To(BeFalse())
+
+		cached, err = c.IsCached(ctx, "push-without-tag-copy", digest)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(cached).To(BeTrue())
+	})
+}
@@ -0,0 +1,299 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/stream"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// gzipImage recompresses the zstd-compressed layers of the image with gzip. The layers are decompressed and
+// recompressed while they are uploaded to the repository, they are never held in memory or on disk. The config of
+// the image is kept as it is: the uncompressed data of the layers, and therefore their diffIDs, doesn't change,
+// which is verified for every layer. Returns whether any layer was recompressed, the image is returned as it is
+// otherwise.
+func (r *Repository) gzipImage(image v1.Image) (v1.Image, bool, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	if !hasZstdLayers(manifest) {
+		return image, false, nil
+	}
+
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get layers: %w", err)
+	}
+
+	transcoded := manifest.DeepCopy()
+	gzipped := make(map[v1.Hash]partial.CompressedLayer)
+	for i, desc := range manifest.Layers {
+		if desc.MediaType != types.OCILayerZStd {
+			continue
+		}
+
+		layer, err := r.gzipLayer(layers[i])
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to recompress layer %s: %w", desc.Digest, err)
+		}
+
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, false, err
+		}
+
+		size, err := layer.Size()
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The annotations of a zstd layer, such as the table of contents of zstd:chunked, describe its compressed
+		// data and don't apply to the gzip layer.
+		transcoded.Layers[i] = v1.Descriptor{
+			MediaType: types.OCILayer,
+			Size:      size,
+			Digest:    digest,
+		}
+		gzipped[digest] = layer
+	}
+
+	raw, err := json.Marshal(transcoded)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	result, err := partial.CompressedToImage(&gzippedImage{base: image, manifest: raw, layers: gzipped})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, true, nil
+}
+
+// gzipLayer uploads the data of the layer recompressed with gzip to the repository and returns the uploaded layer.
+func (r *Repository) gzipLayer(layer v1.Layer) (v1.Layer, error) {
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diffID: %w", err)
+	}
+
+	uncompressed, err := layer.Uncompressed()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress layer: %w", err)
+	}
+	defer uncompressed.Close()
+
+	gzipped := stream.NewLayer(uncompressed, stream.WithMediaType(types.OCILayer))
+	if err := remote.WriteLayer(r.Repository, gzipped, r.remoteOpts...); err != nil {
+		return nil, fmt.Errorf("failed to upload layer: %w", err)
+	}
+
+	// The diffID is computed from the data read while uploading, a mismatch means the source layer doesn't match
+	// the config of its image.
+	actual, err := gzipped.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diffID of uploaded layer: %w", err)
+	}
+
+	if actual != diffID {
+		return nil, fmt.Errorf("uncompressed data of layer has digest %s, its image expects %s", actual, diffID)
+	}
+
+	return gzipped, nil
+}
+
+// gzipIndex recompresses the zstd-compressed layers of the images of the index with gzip, including the images of
+// nested indexes. The index is returned as it is if none of its images have zstd-compressed layers.
+func (r *Repository) gzipIndex(index v1.ImageIndex) (v1.ImageIndex, bool, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get index manifest: %w", err)
+	}
+
+	transcoded := manifest.DeepCopy()
+	result := &gzippedIndex{
+		base:    index,
+		images:  make(map[v1.Hash]v1.Image),
+		indexes: make(map[v1.Hash]v1.ImageIndex),
+	}
+
+	var changed bool
+	for i, desc := range manifest.Manifests {
+		var (
+			child    partial.Describable
+			modified bool
+		)
+
+		switch {
+		case desc.MediaType.IsIndex():
+			nested, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get index %s: %w", desc.Digest, err)
+			}
+
+			if nested, modified, err = r.gzipIndex(nested); err != nil {
+				return nil, false, err
+			}
+
+			child = nested
+			result.indexes[desc.Digest] = nested
+		case desc.MediaType.IsImage():
+			image, err := index.Image(desc.Digest)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get image %s: %w", desc.Digest, err)
+			}
+
+			if image, modified, err = r.gzipImage(image); err != nil {
+				return nil, false, err
+			}
+
+			child = image
+			result.images[desc.Digest] = image
+		}
+
+		if !modified {
+			continue
+		}
+
+		if transcoded.Manifests[i].Digest, err = child.Digest(); err != nil {
+			return nil, false, err
+		}
+
+		if transcoded.Manifests[i].Size, err = child.Size(); err != nil {
+			return nil, false, err
+		}
+
+		changed = true
+	}
+
+	if !changed {
+		return index, false, nil
+	}
+
+	// The children are looked up by their new digest.
+	for i, desc := range manifest.Manifests {
+		if digest := transcoded.Manifests[i].Digest; digest != desc.Digest {
+			if image, ok := result.images[desc.Digest]; ok {
+				result.images[digest] = image
+			}
+
+			if nested, ok := result.indexes[desc.Digest]; ok {
+				result.indexes[digest] = nested
+			}
+		}
+	}
+
+	if result.manifest, err = json.Marshal(transcoded); err != nil {
+		return nil, false, fmt.Errorf("failed to marshal index manifest: %w", err)
+	}
+
+	return result, true, nil
+}
+
+// hasZstdLayers returns whether any layer of the manifest is zstd-compressed.
+func hasZstdLayers(manifest *v1.Manifest) bool {
+	for _, desc := range manifest.Layers {
+		if desc.MediaType == types.OCILayerZStd {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gzippedImage is an image whose zstd-compressed layers have been replaced by the uploaded gzip layers.
+type gzippedImage struct {
+	base     v1.Image
+	manifest []byte
+	layers   map[v1.Hash]partial.CompressedLayer
+}
+
+var _ partial.CompressedImageCore = (*gzippedImage)(nil)
+
+// RawConfigFile implements partial.CompressedImageCore.
+func (i *gzippedImage) RawConfigFile() ([]byte, error) {
+	return i.base.RawConfigFile()
+}
+
+// MediaType implements partial.CompressedImageCore.
+func (i *gzippedImage) MediaType() (types.MediaType, error) {
+	return i.base.MediaType()
+}
+
+// RawManifest implements partial.CompressedImageCore.
+func (i *gzippedImage) RawManifest() ([]byte, error) {
+	return i.manifest, nil
+}
+
+// LayerByDigest implements partial.CompressedImageCore.
+func (i *gzippedImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if layer, ok := i.layers[h]; ok {
+		return layer, nil
+	}
+
+	return i.base.LayerByDigest(h)
+}
+
+// gzippedIndex is an image index whose images have been replaced by images with gzip layers.
+type gzippedIndex struct {
+	base     v1.ImageIndex
+	manifest []byte
+	images   map[v1.Hash]v1.Image
+	indexes  map[v1.Hash]v1.ImageIndex
+}
+
+var _ v1.ImageIndex = (*gzippedIndex)(nil)
+
+// MediaType implements v1.ImageIndex.
+func (i *gzippedIndex) MediaType() (types.MediaType, error) {
+	return i.base.MediaType()
+}
+
+// Digest implements v1.ImageIndex.
+func (i *gzippedIndex) Digest() (v1.Hash, error) {
+	return partial.Digest(i)
+}
+
+// Size implements v1.ImageIndex.
+func (i *gzippedIndex) Size() (int64, error) {
+	return partial.Size(i)
+}
+
+// IndexManifest implements v1.ImageIndex.
+func (i *gzippedIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return v1.ParseIndexManifest(bytes.NewReader(i.manifest))
+}
+
+// RawManifest implements v1.ImageIndex.
+func (i *gzippedIndex) RawManifest() ([]byte, error) {
+	return i.manifest, nil
+}
+
+// Image implements v1.ImageIndex.
+func (i *gzippedIndex) Image(h v1.Hash) (v1.Image, error) {
+	if image, ok := i.images[h]; ok {
+		return image, nil
+	}
+
+	return i.base.Image(h)
+}
+
+// ImageIndex implements v1.ImageIndex.
+func (i *gzippedIndex) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	if index, ok := i.indexes[h]; ok {
+		return index, nil
+	}
+
+	return i.base.ImageIndex(h)
+}
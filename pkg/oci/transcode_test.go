@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/compression"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/gomega"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+// zstdImage returns an OCI image with a zstd-compressed and a gzip-compressed layer, and the uncompressed data of
+// the zstd layer.
+func zstdImage(g *WithT) (ociv1.Image, []byte) {
+	source, err := random.Layer(256, types.OCILayer)
+	g.Expect(err).NotTo(HaveOccurred())
+	uncompressed, err := source.Uncompressed()
+	g.Expect(err).NotTo(HaveOccurred())
+	data, err := io.ReadAll(uncompressed)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	zstdLayer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}, tarball.WithCompression(compression.ZStd), tarball.WithMediaType(types.OCILayerZStd))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	gzipLayer, err := random.Layer(64, types.OCILayer)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	base := mutate.MediaType(mutate.ConfigMediaType(empty.Image, types.OCIConfigJSON), types.OCIManifestSchema1)
+	image, err := mutate.AppendLayers(base, zstdLayer, gzipLayer)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	return image, data
+}
+
+func TestClient_CopyArtifactGzipLayers(t *testing.T) {
+	addr := strings.TrimPrefix(testServer.URL, "http://")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+
+	t.Run("zstd layers of an image are recompressed", func(t *testing.T) {
+		g := NewWithT(t)
+		image, data := zstdImage(g)
+
+		sourceRef, err := ociname.ParseReference(addr + "/" + generateRandomName("source") + ":v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(remote.Write(sourceRef, image)).To(Succeed())
+
+		name := generateRandomName("copy")
+		result := &cache.PushResult{}
+		digest, err := c.CopyArtifact(context.Background(), sourceRef.String(), name, "v0.0.1", authn.Anonymous, nil,
+			cache.WithGzipLayers(), cache.WithPushResult(result))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Transcoded).To(BeTrue())
+
+		sourceManifest, err := image.Manifest()
+		g.Expect(err).NotTo(HaveOccurred())
+		sourceDigest, err := image.Digest()
+		g.Expect(err).NotTo(HaveOccurred())
+
+		targetRef, err := ociname.ParseReference(addr + "/" + name + ":v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		copied, err := remote.Image(targetRef)
+		g.Expect(err).NotTo(HaveOccurred())
+		copiedDigest, err := copied.Digest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(digest).To(Equal(copiedDigest.String()))
+		g.Expect(digest).NotTo(Equal(sourceDigest.String()))
+
+		manifest, err := copied.Manifest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(manifest.Config).To(Equal(sourceManifest.Config), "the config and so the diffIDs should be kept")
+		g.Expect(manifest.Layers).To(HaveLen(2))
+		g.Expect(manifest.Layers[0].MediaType).To(Equal(types.OCILayer))
+		g.Expect(manifest.Layers[1]).To(Equal(sourceManifest.Layers[1]), "gzip layers should be copied as they are")
+
+		layer, err := copied.LayerByDigest(manifest.Layers[0].Digest)
+		g.Expect(err).NotTo(HaveOccurred())
+		compressed, err := layer.Compressed()
+		g.Expect(err).NotTo(HaveOccurred())
+		gzipped, err := isGzip(compressed)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(gzipped).To(BeTrue(), "the layer should be gzip-compressed")
+
+		uncompressed, err := layer.Uncompressed()
+		g.Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(uncompressed)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(content).To(Equal(data))
+	})
+
+	t.Run("images of an index are recompressed", func(t *testing.T) {
+		g := NewWithT(t)
+		zstd, _ := zstdImage(g)
+		gzip, err := random.Image(64, 1)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		index := mutate.AppendManifests(empty.Index,
+			mutate.IndexAddendum{
+				Add:        zstd,
+				Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "amd64"}},
+			},
+			mutate.IndexAddendum{
+				Add:        gzip,
+				Descriptor: ociv1.Descriptor{Platform: &ociv1.Platform{OS: "linux", Architecture: "arm64"}},
+			},
+		)
+
+		sourceRef, err := ociname.ParseReference(addr + "/" + generateRandomName("source") + ":v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(remote.WriteIndex(sourceRef, index)).To(Succeed())
+
+		name := generateRandomName("copy")
+		digest, err := c.CopyArtifact(context.Background(), sourceRef.String(), name, "v0.0.1", authn.Anonymous, nil,
+			cache.WithGzipLayers())
+		g.Expect(err).NotTo(HaveOccurred())
+
+		sourceManifest, err := index.IndexManifest()
+		g.Expect(err).NotTo(HaveOccurred())
+
+		targetRef, err := ociname.ParseReference(addr + "/" + name + ":v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		copied, err := remote.Index(targetRef)
+		g.Expect(err).NotTo(HaveOccurred())
+		copiedDigest, err := copied.Digest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(digest).To(Equal(copiedDigest.String()))
+
+		manifest, err := copied.IndexManifest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(manifest.Manifests).To(HaveLen(2))
+		g.Expect(manifest.Manifests[0].Digest).NotTo(Equal(sourceManifest.Manifests[0].Digest))
+		g.Expect(manifest.Manifests[0].Platform).To(Equal(sourceManifest.Manifests[0].Platform))
+		g.Expect(manifest.Manifests[1]).To(Equal(sourceManifest.Manifests[1]))
+
+		image, err := copied.Image(manifest.Manifests[0].Digest)
+		g.Expect(err).NotTo(HaveOccurred())
+		imageManifest, err := image.Manifest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(imageManifest.Layers[0].MediaType).To(Equal(types.OCILayer))
+	})
+
+	t.Run("images are copied as they are without the option", func(t *testing.T) {
+		g := NewWithT(t)
+		image, _ := zstdImage(g)
+
+		sourceRef, err := ociname.ParseReference(addr + "/" + generateRandomName("source") + ":v0.0.1")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(remote.Write(sourceRef, image)).To(Succeed())
+
+		result := &cache.PushResult{}
+		digest, err := c.CopyArtifact(context.Background(), sourceRef.String(), generateRandomName("copy"), "v0.0.1",
+			authn.Anonymous, nil, cache.WithPushResult(result))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Transcoded).To(BeFalse())
+
+		sourceDigest, err := image.Digest()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(digest).To(Equal(sourceDigest.String()))
+	})
+}
+
+// isGzip returns whether the data starts with the gzip header.
+func isGzip(r io.ReadCloser) (bool, error) {
+	defer r.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false, err
+	}
+
+	return header[0] == 0x1f && header[1] == 0x8b, nil
+}
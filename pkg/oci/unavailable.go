@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+// unavailableTransport turns failures to connect to the snapshot registry into a cache.RegistryUnavailableError, so
+// an outage of the registry can be told apart from the failures of single requests and of upstream registries.
+type unavailableTransport struct {
+	host string
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *unavailableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.URL.Host == t.host && isConnectionError(req.Context(), err) {
+		return nil, &cache.RegistryUnavailableError{Host: t.host, Err: err}
+	}
+
+	return resp, err
+}
+
+// isConnectionError returns whether the request failed because the registry couldn't be reached, for example
+// because the connection was refused, the host couldn't be resolved or the connection timed out. A request cancelled
+// by its caller or running out of the time of the whole operation, like a large upload, isn't a failure of the
+// registry.
+func isConnectionError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+
+	switch {
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return true
+	case errors.As(err, &dnsErr):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	}
+
+	return false
+}
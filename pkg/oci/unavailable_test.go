@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-component-model/ocm-controller/pkg/cache"
+)
+
+func TestClient_RegistryUnavailable(t *testing.T) {
+	down := httptest.NewServer(nil)
+	addr := strings.TrimPrefix(down.URL, "http://")
+	down.Close()
+
+	t.Log("reporting the snapshot registry as unavailable if it can't be reached")
+	c := NewClient(addr, WithInsecureSkipVerify(true))
+	_, err := c.PushData(context.Background(), io.NopCloser(bytes.NewBufferString("content")), "", "registry-unavailable", "v0.0.1")
+	var rerr *cache.RegistryUnavailableError
+	require.True(t, errors.As(err, &rerr), "unexpected error %v", err)
+	assert.Equal(t, addr, rerr.Host)
+
+	_, err = c.IsCached(context.Background(), "registry-unavailable", "v0.0.1")
+	assert.True(t, errors.As(err, &rerr), "unexpected error %v", err)
+
+	t.Log("returning errors of upstream registries as they are")
+	c = NewClient(strings.TrimPrefix(testServer.URL, "http://"), WithInsecureSkipVerify(true))
+	_, err = c.ResolveArtifact(context.Background(), addr+"/upstream/app:v1", authn.Anonymous)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &rerr))
+
+	t.Log("not reporting requests cancelled by the caller")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c = NewClient(addr, WithInsecureSkipVerify(true))
+	_, err = c.IsCached(ctx, "registry-unavailable", "v0.0.1")
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &rerr))
+}
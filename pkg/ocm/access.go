@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/mandelsoft/vfs/pkg/memoryfs"
+	"github.com/mandelsoft/vfs/pkg/vfs"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/localblob"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/download"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+	"helm.sh/helm/v3/pkg/registry"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// AccessResolver resolves the data of resources with a specific access type. Resolvers are registered on the
+// Client by the kind of the access type with WithAccessResolver.
+type AccessResolver interface {
+	// Resolve returns the data of the resource. The component version of the resource is passed along to resolve
+	// accesses relative to the repository of the component, like local blobs.
+	Resolve(ctx context.Context, res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (*ResolvedAccess, error)
+}
+
+// ResolvedAccess is the data of a resource returned by an AccessResolver. Either Image or Reader is set.
+type ResolvedAccess struct {
+	// Image is the reference of an OCI artifact. The artifact is copied as it is instead of being stored as a
+	// single layer snapshot.
+	Image string
+
+	// Reader reads the data of the resource. The caller has to close it.
+	Reader io.ReadCloser
+
+	// MediaType is the media type of the data, empty to use the default media type of snapshots.
+	MediaType string
+
+	// Size is the size of the data if it is known before the data is read, -1 otherwise.
+	Size int64
+}
+
+// WithAccessResolver registers the resolver for resources with the given access type, for example to support an
+// access type the OCM library doesn't provide an access method for. The type is matched without its version and
+// the resolver replaces the one the client has for the type by default. Resources with access types that don't have
+// a resolver are read through their OCM access method.
+func WithAccessResolver(accessType string, resolver AccessResolver) ClientOption {
+	return func(c *Client) {
+		if c.accessResolvers == nil {
+			c.accessResolvers = make(map[string]AccessResolver)
+		}
+
+		c.accessResolvers[accessType] = resolver
+	}
+}
+
+// defaultAccessResolvers returns the resolvers of the access types the client handles itself.
+func (c *Client) defaultAccessResolvers() map[string]AccessResolver {
+	download := &downloadAccessResolver{httpClient: c.httpClient}
+	blob := &blobAccessResolver{client: c}
+
+	return map[string]AccessResolver{
+		ociartifact.Type:       imageAccessResolver{},
+		ociartifact.LegacyType: imageAccessResolver{},
+		ociblob.Type:           blob,
+		localblob.Type:         blob,
+		HTTPAccessType:         download,
+		DownloadAccessType:     download,
+	}
+}
+
+// resolveAccess resolves the data of the resource with the resolver registered for the kind of its access type.
+// A resolver that returns neither an image nor a reader is reported as ErrUnsupportedAccess.
+func (c *Client) resolveAccess(ctx context.Context, res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	spec, err := res.Access()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access spec of resource: %w", err)
+	}
+
+	kind, _ := ocmruntime.KindVersion(spec.GetType())
+
+	resolver, ok := c.accessResolvers[kind]
+	if !ok {
+		resolver = accessMethodResolver{}
+	}
+
+	resolved, err := resolver.Resolve(ctx, res, cva)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved == nil || (resolved.Image == "" && resolved.Reader == nil) {
+		return nil, fmt.Errorf("%w: resolver of access type %s returned no data for resource %s", ErrUnsupportedAccess, spec.GetType(), res.Meta().Name)
+	}
+
+	return resolved, nil
+}
+
+// imageAccessResolver resolves resources with an ociArtifact access to their image reference.
+type imageAccessResolver struct{}
+
+// Resolve implements AccessResolver.
+func (imageAccessResolver) Resolve(_ context.Context, res ocm.ResourceAccess, _ ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	ref, err := imageReference(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access spec of resource: %w", err)
+	}
+
+	return &ResolvedAccess{Image: ref}, nil
+}
+
+// blobAccessResolver resolves resources with an ociBlob access, or a local blob with an ociBlob global access, to
+// their image if the digest of the blob is the digest of an image manifest or index. Stored as the data of a single
+// layer snapshot, the manifest would be useless without the blobs it references. All other blobs are read through
+// their access method.
+type blobAccessResolver struct {
+	client *Client
+}
+
+// Resolve implements AccessResolver.
+func (r *blobAccessResolver) Resolve(ctx context.Context, res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	if image := r.manifestReference(ctx, res, cva); image != "" {
+		return &ResolvedAccess{Image: image}, nil
+	}
+
+	return accessMethodResolver{}.Resolve(ctx, res, cva)
+}
+
+// manifestReference returns the image reference of the blob if it is a manifest, empty if it isn't. Unless the media
+// type of the access declares a manifest, the digest is looked up as a manifest in the registry of the blob, which a
+// registry only finds if it is one. The blob is read as it is if the lookup fails for any reason.
+func (r *blobAccessResolver) manifestReference(ctx context.Context, res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) string {
+	spec, err := res.Access()
+	if err != nil {
+		return ""
+	}
+
+	if local, ok := spec.(*localblob.AccessSpec); ok {
+		if local.GlobalAccess == nil {
+			return ""
+		}
+
+		if spec, err = cva.GetContext().AccessSpecForSpec(local.GlobalAccess); err != nil {
+			return ""
+		}
+	}
+
+	blob, ok := spec.(*ociblob.AccessSpec)
+	if !ok || blob.Reference == "" || blob.Digest == "" {
+		return ""
+	}
+
+	reference, err := BlobReference(blob.Reference, blob.Digest.String())
+	if err != nil {
+		return ""
+	}
+
+	ref, err := ociname.NewDigest(reference)
+	if err != nil {
+		return ""
+	}
+
+	if mediaType := types.MediaType(blob.MediaType); mediaType.IsImage() || mediaType.IsIndex() {
+		return ref.String()
+	}
+
+	// Like the image itself, the manifest is looked up in the mirror of the registry.
+	mirrored, err := r.client.mirrorReference(ref)
+	if err != nil {
+		return ""
+	}
+
+	auth, err := registryAuth(cva.GetContext(), mirrored)
+	if err != nil {
+		return ""
+	}
+
+	digest, err := r.client.cache.ResolveArtifact(ctx, mirrored.String(), auth)
+	if err != nil || digest != blob.Digest.String() {
+		log.FromContext(ctx).V(v1alpha1.LevelTrace).Info("reading resource as a blob", v1alpha1.LogKeyResourceName, res.Meta().Name)
+
+		return ""
+	}
+
+	log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("blob of resource is an image manifest, copying the image",
+		v1alpha1.LogKeyResourceName, res.Meta().Name, "reference", ref.String())
+
+	return ref.String()
+}
+
+// downloadAccessResolver downloads the data of resources with an http or download access from their URL.
+type downloadAccessResolver struct {
+	httpClient *http.Client
+}
+
+// Resolve implements AccessResolver.
+func (r *downloadAccessResolver) Resolve(ctx context.Context, res ocm.ResourceAccess, _ ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	access, err := getDownloadAccess(res)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, size, err := r.download(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+
+	// The data is decompressed before it is pushed, use the default media type like for other accesses.
+	return &ResolvedAccess{Reader: reader, Size: size}, nil
+}
+
+// accessMethodResolver reads the data of resources through the access method the OCM library provides for their
+// access type, for example local blobs stored in the repository of the component.
+type accessMethodResolver struct{}
+
+// Resolve implements AccessResolver.
+//
+// We add this decision because OCM is storing the Helm artifact as an ociArtifact at the
+// time of this writing. This means, when fetching the resource via the normal route
+// it will return an OCI blob instead of the actual helm chart content.
+// Because of that, we need to create our own downloader when we are dealing with
+// helm charts.
+func (accessMethodResolver) Resolve(_ context.Context, res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	if res.Meta().Type == "helmChart" {
+		reader, mediaType, err := fetchHelmChartResource(res, cva)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ResolvedAccess{Reader: reader, MediaType: mediaType, Size: -1}, nil
+	}
+
+	access, err := res.AccessMethod()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch access spec: %w", classifyError(err))
+	}
+
+	reader, err := access.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reader: %w", classifyError(err))
+	}
+
+	// Only some access methods know the size of the blob upfront.
+	var size int64 = -1
+	if blob, ok := access.(interface{ Size() int64 }); ok {
+		size = blob.Size()
+	}
+
+	// Ignore the media type as we set it to a default in OCI package
+	return &ResolvedAccess{Reader: reader, Size: size}, nil
+}
+
+func fetchHelmChartResource(res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (_ io.ReadCloser, _ string, err error) {
+	vf := vfs.New(memoryfs.New())
+	defer func() {
+		if rerr := vf.RemoveAll("downloaded"); rerr != nil {
+			// ignore not exist errors that vfs implementation can throw sometimes.
+			if !errors.Is(rerr, os.ErrNotExist) {
+				err = errors.Join(err, rerr)
+			}
+		}
+	}()
+
+	d := download.For(cva.GetContext())
+	// Note that helm downloader does _NOT_ return the path element of the Downloader's output.
+	_, chart, err := d.Download(nil, res, "downloaded", vf)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download helm chart content: %w", classifyError(err))
+	}
+
+	content, rerr := vf.ReadFile(chart)
+	if rerr != nil {
+		return nil, "", fmt.Errorf("failed to find the downloaded file: %w", rerr)
+	}
+	reader := io.NopCloser(bytes.NewBuffer(content))
+
+	return reader, registry.ChartLayerMediaType, nil
+}
@@ -0,0 +1,331 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+)
+
+// staticAccessResolver returns the same data for every resource.
+type staticAccessResolver struct {
+	data     string
+	resolved []string
+}
+
+func (r *staticAccessResolver) Resolve(_ context.Context, res ocm.ResourceAccess, _ ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	r.resolved = append(r.resolved, res.Meta().Name)
+
+	return &ResolvedAccess{Reader: io.NopCloser(strings.NewReader(r.data)), Size: int64(len(r.data))}, nil
+}
+
+// emptyAccessResolver returns the resolved access it is given, which holds no data.
+type emptyAccessResolver struct {
+	resolved *ResolvedAccess
+}
+
+func (r emptyAccessResolver) Resolve(context.Context, ocm.ResourceAccess, ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	return r.resolved, nil
+}
+
+func TestClient_AccessResolver(t *testing.T) {
+	testCases := []struct {
+		name       string
+		accessType string
+		register   string
+		want       string
+	}{
+		{
+			name:       "resolving a custom access type with a registered resolver",
+			accessType: "s3/v1",
+			register:   "s3",
+			want:       "resolved data",
+		},
+		{
+			name:       "replacing the resolver of a default access type",
+			accessType: "localBlob",
+			register:   "localBlob",
+			want:       "resolved data",
+		},
+		{
+			name:       "reading unregistered access types through their access method",
+			accessType: "localBlob",
+			register:   "s3",
+			want:       "blob data",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{
+				Name:    "github.com/skarlso/ocm-demo-index",
+				Version: "v0.0.1",
+			}
+			comp.Resources = append(comp.Resources, &fakeocm.Resource{
+				Name:          "manifests",
+				Version:       "v0.0.1",
+				Component:     comp,
+				Type:          "file",
+				Data:          []byte("blob data"),
+				AccessOptions: []fakeocm.AccessOptionFunc{fakeocm.SetAccessType(tt.accessType)},
+			})
+			require.NoError(t, octx.AddComponent(comp))
+
+			cd := &v1alpha1.ComponentDescriptor{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+				},
+				Spec: v1alpha1.ComponentDescriptorSpec{
+					ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+						Resources: []v3alpha1.Resource{
+							{
+								ElementMeta: v3alpha1.ElementMeta{
+									Name:    "manifests",
+									Version: "v0.0.1",
+								},
+							},
+						},
+					},
+					Version: "v0.0.1",
+				},
+			}
+
+			cache := &fakes.FakeCache{}
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader(tt.want)), nil)
+
+			resolver := &staticAccessResolver{data: "resolved data"}
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, WithAccessResolver(tt.register, resolver))
+
+			cv := &v1alpha1.ComponentVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-name",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ComponentVersionSpec{
+					Component: comp.Name,
+					Version: v1alpha1.Version{
+						Semver: comp.Version,
+					},
+				},
+				Status: v1alpha1.ComponentVersionStatus{
+					ReconciledVersion: comp.Version,
+					ComponentDescriptor: v1alpha1.Reference{
+						Name:    comp.Name,
+						Version: comp.Version,
+						ComponentDescriptorRef: meta.NamespacedObjectReference{
+							Name:      cd.Name,
+							Namespace: cd.Namespace,
+						},
+					},
+				},
+			}
+
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    "manifests",
+					Version: "v0.0.1",
+				},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cache.PushDataCallingArgumentsOnCall(0).Content)
+
+			if tt.want == resolver.data {
+				assert.Equal(t, []string{"manifests"}, resolver.resolved)
+			} else {
+				assert.Empty(t, resolver.resolved)
+			}
+		})
+	}
+}
+
+func TestClient_AccessResolverWithoutData(t *testing.T) {
+	octx := fakeocm.NewFakeOCMContext()
+	comp := &fakeocm.Component{
+		Name:    "github.com/skarlso/ocm-demo-index",
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:          "manifests",
+		Version:       "v0.0.1",
+		Component:     comp,
+		Type:          "file",
+		Data:          []byte("blob data"),
+		AccessOptions: []fakeocm.AccessOptionFunc{fakeocm.SetAccessType("s3/v1")},
+	})
+	require.NoError(t, octx.AddComponent(comp))
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    "manifests",
+							Version: "v0.0.1",
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: comp.Name,
+			Version: v1alpha1.Version{
+				Semver: comp.Version,
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: comp.Version,
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    comp.Name,
+				Version: comp.Version,
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      cd.Name,
+					Namespace: cd.Namespace,
+				},
+			},
+		},
+	}
+
+	for name, resolved := range map[string]*ResolvedAccess{
+		"no resolved access":            nil,
+		"neither an image nor a reader": {MediaType: "text/plain"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache := &fakes.FakeCache{}
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, WithAccessResolver("s3", emptyAccessResolver{resolved: resolved}))
+
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    "manifests",
+					Version: "v0.0.1",
+				},
+			})
+			assert.ErrorIs(t, err, ErrUnsupportedAccess)
+			assert.True(t, cache.PushDataWasNotCalled())
+		})
+	}
+}
+
+func TestDefaultAccessResolvers(t *testing.T) {
+	c := NewClient(nil, nil)
+
+	for _, accessType := range []string{"ociArtifact", "ociRegistry", "ociBlob", "localBlob", "http", "download"} {
+		assert.Contains(t, c.accessResolvers, accessType)
+	}
+
+	assert.IsType(t, imageAccessResolver{}, c.accessResolvers["ociArtifact"])
+	assert.IsType(t, &downloadAccessResolver{}, c.accessResolvers["http"])
+	assert.IsType(t, &blobAccessResolver{}, c.accessResolvers["localBlob"])
+}
+
+func TestBlobAccessResolver(t *testing.T) {
+	const blobDigest = "sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2"
+
+	testCases := []struct {
+		name     string
+		access   []fakeocm.AccessOptionFunc
+		resolved string
+		image    string
+		lookup   bool
+	}{
+		{
+			name:   "blob digest",
+			lookup: true,
+		},
+		{
+			name:     "manifest digest",
+			resolved: blobDigest,
+			image:    "ghcr.io/mandelsoft/cnudie/component-descriptors/github.com/vasu1124/introspect@" + blobDigest,
+			lookup:   true,
+		},
+		{
+			name: "manifest digest of an oci blob access",
+			access: []fakeocm.AccessOptionFunc{func(m map[string]any) {
+				for k := range m {
+					delete(m, k)
+				}
+				m["type"] = "ociBlob"
+				m["ref"] = "ghcr.io/open-component-model/podinfo"
+				m["digest"] = blobDigest
+			}},
+			resolved: blobDigest,
+			image:    "ghcr.io/open-component-model/podinfo@" + blobDigest,
+			lookup:   true,
+		},
+		{
+			name: "manifest declared by its media type",
+			access: []fakeocm.AccessOptionFunc{func(m map[string]any) {
+				m["globalAccess"].(map[string]any)["mediaType"] = "application/vnd.oci.image.manifest.v1+json"
+			}},
+			image: "ghcr.io/mandelsoft/cnudie/component-descriptors/github.com/vasu1124/introspect@" + blobDigest,
+		},
+		{
+			name:     "lookup resolving to another digest",
+			resolved: "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			lookup:   true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &fakes.FakeCache{}
+			if tt.resolved != "" {
+				cache.ResolveArtifactReturns(tt.resolved, nil)
+			} else {
+				cache.ResolveArtifactReturns("", errors.New("MANIFEST_UNKNOWN: manifest unknown"))
+			}
+
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{Name: "github.com/open-component-model/podinfo", Version: "v0.0.1"}
+			res := &fakeocm.Resource{Name: "manifests", Version: "v0.0.1", Data: []byte("blob data"), Component: comp, AccessOptions: tt.access}
+			comp.Resources = append(comp.Resources, res)
+			require.NoError(t, octx.AddComponent(comp))
+
+			c := NewClient(nil, cache)
+			resolved, err := c.resolveAccess(context.Background(), res, comp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.image, resolved.Image)
+			assert.Equal(t, !tt.lookup, cache.ResolveArtifactWasNotCalled())
+
+			if tt.image == "" {
+				require.NotNil(t, resolved.Reader, "the blob should have been read through its access method")
+				defer resolved.Reader.Close()
+				data, err := io.ReadAll(resolved.Reader)
+				require.NoError(t, err)
+				assert.Equal(t, "blob data", string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+)
+
+// The keys of the resource metadata that can be added to the manifests of snapshots.
+const (
+	// AnnotationKeyType adds the type of the resource.
+	AnnotationKeyType = "type"
+	// AnnotationKeyVersion adds the version of the resource. It is always added with the identity of the resource
+	// and only accepted so existing configurations stay valid.
+	AnnotationKeyVersion = "version"
+	// AnnotationKeyExtraIdentity adds the extra identity of the resource as a JSON object.
+	AnnotationKeyExtraIdentity = "extraIdentity"
+	// AnnotationKeyLabels adds all labels of the resource.
+	AnnotationKeyLabels = "labels"
+	// AnnotationKeyLabelPrefix followed by the name of a label adds that label of the resource.
+	AnnotationKeyLabelPrefix = "label:"
+)
+
+// The annotations the resource metadata is added as. All of them are prefixed with "software.ocm/" so they don't
+// collide with the annotations of other tools.
+const (
+	ComponentNameAnnotation         = "software.ocm/component-name"
+	ComponentVersionAnnotation      = "software.ocm/component-version"
+	ResourceNameAnnotation          = "software.ocm/resource-name"
+	ResourceTypeAnnotation          = "software.ocm/resource-type"
+	ResourceVersionAnnotation       = "software.ocm/resource-version"
+	ResourceExtraIdentityAnnotation = "software.ocm/resource-extra-identity"
+	// ResourceLabelAnnotationPrefix followed by the name of a label is the annotation of that label.
+	ResourceLabelAnnotationPrefix = "software.ocm/label/"
+)
+
+// WithSnapshotAnnotations adds the resource metadata with the given keys to the manifests resources are stored in,
+// so the provenance of a snapshot can be inspected from the registry. Keys are validated with
+// ValidateAnnotationKeys. Images are copied as they are and aren't annotated.
+func WithSnapshotAnnotations(keys ...string) ClientOption {
+	return func(c *Client) {
+		for _, key := range keys {
+			if key = strings.TrimSpace(key); key != "" {
+				c.annotationKeys = append(c.annotationKeys, key)
+			}
+		}
+	}
+}
+
+// ValidateAnnotationKeys returns an error if one of the keys isn't one of the annotation key constants or a label
+// key without the name of the label.
+func ValidateAnnotationKeys(keys ...string) error {
+	for _, key := range keys {
+		switch key = strings.TrimSpace(key); key {
+		case "", AnnotationKeyType, AnnotationKeyVersion, AnnotationKeyExtraIdentity, AnnotationKeyLabels:
+		default:
+			if name, ok := strings.CutPrefix(key, AnnotationKeyLabelPrefix); !ok || name == "" {
+				return fmt.Errorf("unknown snapshot annotation key %q, must be one of %s, %s, %s, %s or %s<label name>",
+					key, AnnotationKeyType, AnnotationKeyVersion, AnnotationKeyExtraIdentity, AnnotationKeyLabels, AnnotationKeyLabelPrefix)
+			}
+		}
+	}
+
+	return nil
+}
+
+// snapshotAnnotations returns the annotations of the snapshot manifest for the resource of the component. The
+// name and version of the component and the resource are always added, so a snapshot can be traced back to the
+// component it was created from. Label values that are JSON strings are added unquoted, all other values as JSON.
+// Metadata the resource doesn't set is left out.
+func (c *Client) snapshotAnnotations(component, version string, res *v3alpha1.Resource) map[string]string {
+	annotations := map[string]string{
+		ComponentNameAnnotation:    component,
+		ComponentVersionAnnotation: version,
+		ResourceNameAnnotation:     res.Name,
+		ResourceVersionAnnotation:  res.Version,
+	}
+
+	for _, key := range c.annotationKeys {
+		switch key {
+		case AnnotationKeyVersion:
+		case AnnotationKeyType:
+			annotations[ResourceTypeAnnotation] = res.Type
+		case AnnotationKeyExtraIdentity:
+			if len(res.ExtraIdentity) > 0 {
+				// An identity is a map of strings, which can always be marshalled.
+				data, _ := json.Marshal(res.ExtraIdentity)
+				annotations[ResourceExtraIdentityAnnotation] = string(data)
+			}
+		case AnnotationKeyLabels:
+			for _, label := range res.Labels {
+				annotations[ResourceLabelAnnotationPrefix+label.Name] = labelValue(label.Value)
+			}
+		default:
+			name := strings.TrimPrefix(key, AnnotationKeyLabelPrefix)
+			if label := res.Labels.GetDef(name); label != nil {
+				annotations[ResourceLabelAnnotationPrefix+name] = labelValue(label.Value)
+			}
+		}
+	}
+
+	for k, v := range annotations {
+		if v == "" {
+			delete(annotations, k)
+		}
+	}
+
+	return annotations
+}
+
+// labelValue returns the value of a label as an annotation value.
+func labelValue(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+
+	return string(value)
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+)
+
+func TestValidateAnnotationKeys(t *testing.T) {
+	assert.NoError(t, ValidateAnnotationKeys())
+	assert.NoError(t, ValidateAnnotationKeys("type", " version", "extraIdentity", "labels", "label:ocm.software/provenance", ""))
+	assert.EqualError(t, ValidateAnnotationKeys("type", "relation"), `unknown snapshot annotation key "relation", must be one of type, version, extraIdentity, labels or label:<label name>`)
+	assert.Error(t, ValidateAnnotationKeys("label:"))
+}
+
+func TestClient_SnapshotAnnotations(t *testing.T) {
+	res := &v3alpha1.Resource{
+		ElementMeta: v3alpha1.ElementMeta{
+			Name:          "manifests",
+			Version:       "v1.0.0",
+			ExtraIdentity: ocmmetav1.Identity{"platform": "linux"},
+			Labels: ocmmetav1.Labels{
+				{Name: "ocm.software/provenance", Value: json.RawMessage(`"ci"`)},
+				{Name: "config", Value: json.RawMessage(`{"replicas":2}`)},
+			},
+		},
+		Type: "kustomize",
+	}
+
+	identity := map[string]string{
+		ComponentNameAnnotation:    "github.com/skarlso/ocm-demo-index",
+		ComponentVersionAnnotation: "v0.0.1",
+		ResourceNameAnnotation:     "manifests",
+		ResourceVersionAnnotation:  "v1.0.0",
+	}
+
+	testCases := []struct {
+		name     string
+		keys     []string
+		expected map[string]string
+	}{
+		{
+			name: "no keys",
+		},
+		{
+			name: "type and version",
+			keys: []string{"type", "version"},
+			expected: map[string]string{
+				ResourceTypeAnnotation: "kustomize",
+			},
+		},
+		{
+			name: "extra identity",
+			keys: []string{"extraIdentity"},
+			expected: map[string]string{
+				ResourceExtraIdentityAnnotation: `{"platform":"linux"}`,
+			},
+		},
+		{
+			name: "all labels",
+			keys: []string{"labels"},
+			expected: map[string]string{
+				ResourceLabelAnnotationPrefix + "ocm.software/provenance": "ci",
+				ResourceLabelAnnotationPrefix + "config":                  `{"replicas":2}`,
+			},
+		},
+		{
+			name: "a single label",
+			keys: []string{"label:ocm.software/provenance", "label:missing"},
+			expected: map[string]string{
+				ResourceLabelAnnotationPrefix + "ocm.software/provenance": "ci",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(nil, nil, WithSnapshotAnnotations(tt.keys...))
+
+			expected := maps.Clone(identity)
+			maps.Copy(expected, tt.expected)
+
+			assert.Equal(t, expected, c.snapshotAnnotations("github.com/skarlso/ocm-demo-index", "v0.0.1", res))
+		})
+	}
+
+	t.Log("leaving out metadata the resource doesn't set")
+	c := NewClient(nil, nil, WithSnapshotAnnotations("version", "extraIdentity", "type"))
+	assert.Equal(t, map[string]string{
+		ComponentNameAnnotation:    "github.com/skarlso/ocm-demo-index",
+		ComponentVersionAnnotation: "v0.0.1",
+		ResourceTypeAnnotation:     "kustomize",
+	}, c.snapshotAnnotations("github.com/skarlso/ocm-demo-index", "v0.0.1", &v3alpha1.Resource{Type: "kustomize"}))
+}
+
+func TestClient_GetResourceAnnotations(t *testing.T) {
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    "github.com/skarlso/ocm-demo-index",
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:      "manifests",
+		Version:   "v0.0.1",
+		Data:      []byte("testdata"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+	})
+	require.NoError(t, octx.AddComponent(comp))
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    "manifests",
+							Version: "v0.0.1",
+							Labels: ocmmetav1.Labels{
+								{Name: "ocm.software/provenance", Value: json.RawMessage(`"ci"`)},
+							},
+						},
+						Type: "ociBlob",
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+	cache.FetchDataByDigestReturns(io.NopCloser(nil), nil)
+
+	ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, WithSnapshotAnnotations("type", "labels"))
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: comp.Name,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    comp.Name,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      cd.Name,
+					Namespace: cd.Namespace,
+				},
+			},
+		},
+	}
+
+	_, _, err := ocmClient.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "manifests",
+			Version: "v0.0.1",
+		},
+	}, WithCompression(v1alpha1.CompressionNone))
+	require.NoError(t, err)
+
+	args := cache.PushDataCallingArgumentsOnCall(0)
+	assert.True(t, args.Uncompressed)
+	assert.Equal(t, map[string]string{
+		ComponentNameAnnotation:                                   "github.com/skarlso/ocm-demo-index",
+		ComponentVersionAnnotation:                                "v0.0.1",
+		ResourceNameAnnotation:                                    "manifests",
+		ResourceVersionAnnotation:                                 "v0.0.1",
+		ResourceTypeAnnotation:                                    "ociBlob",
+		ResourceLabelAnnotationPrefix + "ocm.software/provenance": "ci",
+	}, args.Annotations)
+}
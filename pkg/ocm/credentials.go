@@ -1,14 +1,21 @@
 package ocm
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/url"
 
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
 	"github.com/open-component-model/ocm/pkg/common"
 	"github.com/open-component-model/ocm/pkg/contexts/credentials"
+	ocmdockerconfig "github.com/open-component-model/ocm/pkg/contexts/credentials/repositories/dockerconfig"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -31,6 +38,90 @@ func ConfigureCredentials(ctx context.Context, ocmCtx ocm.Context, c client.Clie
 	return nil
 }
 
+// ConfigureDockerConfigCredentials takes the name of a secret containing a .dockerconfigjson and configures
+// access to all the registries that are defined in it.
+func ConfigureDockerConfigCredentials(ctx context.Context, ocmCtx ocm.Context, c client.Client, secretName, namespace string) error {
+	data, err := getDockerConfig(ctx, c, secretName, namespace)
+	if err != nil {
+		return err
+	}
+
+	repository := ocmdockerconfig.NewRepositorySpecForConfig(data, true)
+
+	if _, err := ocmCtx.CredentialsContext().RepositoryForSpec(repository); err != nil {
+		return fmt.Errorf("failed to configure credentials for repository: %w", err)
+	}
+
+	return nil
+}
+
+// DockerConfigKeychain takes the name of a secret containing a .dockerconfigjson and returns a keychain with the
+// credentials of all the registries that are defined in it. Registries without credentials are accessed
+// anonymously.
+func DockerConfigKeychain(ctx context.Context, c client.Client, secretName, namespace string) (authn.Keychain, error) {
+	data, err := getDockerConfig(ctx, c, secretName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := dockerconfig.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .dockerconfigjson in secret %s: %w", secretName, err)
+	}
+
+	return &dockerConfigKeychain{config: config}, nil
+}
+
+// dockerConfigKeychain resolves the credentials of registries like authn.DefaultKeychain, but from a docker config
+// read from a secret instead of the docker config of the controller.
+type dockerConfigKeychain struct {
+	config *configfile.ConfigFile
+}
+
+// Resolve implements authn.Keychain.
+func (k *dockerConfigKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	key := target.RegistryStr()
+	if key == ociname.DefaultRegistry {
+		key = authn.DefaultAuthKey
+	}
+
+	auth, err := k.config.GetAuthConfig(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for %s: %w", key, err)
+	}
+
+	cfg := authn.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		Auth:          auth.Auth,
+		IdentityToken: auth.IdentityToken,
+		RegistryToken: auth.RegistryToken,
+	}
+	if cfg == (authn.AuthConfig{}) {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(cfg), nil
+}
+
+// getDockerConfig returns the .dockerconfigjson of the secret.
+func getDockerConfig(ctx context.Context, c client.Client, secretName, namespace string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      secretName,
+		Namespace: namespace,
+	}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get docker config secret: %w", err)
+	}
+
+	data, ok := secret.Data[dockerConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("failed to find .dockerconfigjson in secret %s", secret.Name)
+	}
+
+	return data, nil
+}
+
 func getConsumerIdentityForRepository(repositoryURL string) (credentials.ConsumerIdentity, error) {
 	regURL, err := url.Parse(repositoryURL)
 	if err != nil {
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// descriptorIndexSize is the number of component descriptors the resource indexes are kept for.
+const descriptorIndexSize = 256
+
+// resourceIndex is the positions of the resources of a component descriptor by their name.
+type resourceIndex struct {
+	// revision is the resource version of the descriptor the index was built from.
+	revision  string
+	positions map[string][]int
+}
+
+// resourcesNamed returns the positions of the resources of the component descriptor with the given name, in the
+// order of the descriptor. The index of a descriptor is built once per revision, so looking up a resource of a
+// descriptor with thousands of resources doesn't scan all of them on every reconciliation.
+func (c *Client) resourcesNamed(cd *v1alpha1.ComponentDescriptor, name string) []int {
+	positions := c.indexResources(cd).positions[name]
+	for _, i := range positions {
+		// A copy of the descriptor that was modified without being updated in the cluster is indexed again.
+		if cd.Spec.Resources[i].Name != name {
+			return newResourceIndex(cd).positions[name]
+		}
+	}
+
+	return positions
+}
+
+// indexResources returns the resource index of the component descriptor. The index is kept by the UID of the
+// descriptor and built again once its resource version changes. Descriptors without UID or resource version, which
+// aren't read from the cluster, are indexed without being cached.
+func (c *Client) indexResources(cd *v1alpha1.ComponentDescriptor) *resourceIndex {
+	if cd.UID == "" || cd.ResourceVersion == "" {
+		return newResourceIndex(cd)
+	}
+
+	if value, ok := c.descriptorIndexes.Get(cd.UID); ok {
+		if index := value.(*resourceIndex); index.revision == cd.ResourceVersion {
+			return index
+		}
+	}
+
+	index := newResourceIndex(cd)
+	c.descriptorIndexes.Add(cd.UID, index)
+
+	return index
+}
+
+func newResourceIndex(cd *v1alpha1.ComponentDescriptor) *resourceIndex {
+	index := &resourceIndex{
+		revision:  cd.ResourceVersion,
+		positions: make(map[string][]int, len(cd.Spec.Resources)),
+	}
+
+	for i, r := range cd.Spec.Resources {
+		index.positions[r.Name] = append(index.positions[r.Name], i)
+	}
+
+	return index
+}
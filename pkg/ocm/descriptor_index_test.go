@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"testing"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+func TestResourcesNamed(t *testing.T) {
+	c := NewClient(nil, nil)
+	cd := largeDescriptor("index-test", 10)
+	cd.Spec.Resources = append(cd.Spec.Resources, v3alpha1.Resource{
+		ElementMeta: v3alpha1.ElementMeta{Name: "resource-3", Version: "v1.1.0"},
+	})
+
+	t.Log("finding all resources with the name in the order of the descriptor")
+	assert.Equal(t, []int{3, 10}, c.resourcesNamed(cd, "resource-3"))
+	assert.Empty(t, c.resourcesNamed(cd, "missing"))
+
+	t.Log("reusing the index of the revision for a new copy of the descriptor")
+	index := c.indexResources(cd)
+	assert.Same(t, index, c.indexResources(cd.DeepCopy()))
+
+	t.Log("indexing a new revision of the descriptor")
+	next := cd.DeepCopy()
+	next.ResourceVersion = "2"
+	next.Spec.Resources[5].Name = "resource-3"
+	assert.NotSame(t, index, c.indexResources(next))
+	assert.Equal(t, []int{3, 5, 10}, c.resourcesNamed(next, "resource-3"))
+
+	t.Log("indexing a descriptor modified without a new revision again")
+	modified := next.DeepCopy()
+	modified.Spec.Resources[0], modified.Spec.Resources[3] = modified.Spec.Resources[3], modified.Spec.Resources[0]
+	assert.Equal(t, []int{0, 5, 10}, c.resourcesNamed(modified, "resource-3"))
+
+	t.Log("not sharing indexes between clients")
+	assert.NotSame(t, c.indexResources(next), NewClient(nil, nil).indexResources(next))
+
+	t.Log("not caching descriptors that aren't read from the cluster")
+	local := largeDescriptor("local", 3)
+	local.UID = ""
+	assert.NotSame(t, c.indexResources(local), c.indexResources(local))
+	assert.Equal(t, []int{1}, c.resourcesNamed(local, "resource-1"))
+}
+
+func BenchmarkDescriptorResource(b *testing.B) {
+	c := NewClient(nil, nil)
+	cd := largeDescriptor("benchmark", 5000)
+	ref := &v1alpha1.ResourceReference{ElementMeta: v1alpha1.ElementMeta{Name: "resource-4999"}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id, err := c.resourceIdentity(cd, ref)
+		require.NoError(b, err)
+		require.Equal(b, "resource-4999", id[ocmmetav1.SystemIdentityName])
+	}
+}
+
+// largeDescriptor returns a component descriptor with the given number of resources named resource-<n>.
+func largeDescriptor(name string, resources int) *v1alpha1.ComponentDescriptor {
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			UID:             types.UID("uid-" + name),
+			Generation:      1,
+			ResourceVersion: "1",
+		},
+	}
+
+	for i := 0; i < resources; i++ {
+		cd.Spec.Resources = append(cd.Spec.Resources, v3alpha1.Resource{
+			ElementMeta: v3alpha1.ElementMeta{Name: fmt.Sprintf("resource-%d", i), Version: "v1.0.0"},
+			Type:        "blob",
+		})
+	}
+
+	return cd
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+	godigest "github.com/opencontainers/go-digest"
+)
+
+// Access types of resources whose data is downloaded from a URL. The OCM library doesn't provide access methods
+// for them, so the client downloads the data itself.
+const (
+	HTTPAccessType     = "http"
+	DownloadAccessType = "download"
+)
+
+// downloadAccess is the access of a resource whose data is downloaded from a URL.
+type downloadAccess struct {
+	// URL is the http or https URL the data is downloaded from.
+	URL string `json:"url"`
+	// Checksum is the digest of the data, for example sha256:<hex>. Optional, if set the downloaded data is
+	// verified against it.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// getDownloadAccess returns the access of the resource if its data is downloaded from a URL, nil otherwise.
+func getDownloadAccess(res ocm.ResourceAccess) (*downloadAccess, error) {
+	spec, err := res.Access()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access spec of resource: %w", err)
+	}
+
+	if kind, _ := ocmruntime.KindVersion(spec.GetType()); kind != HTTPAccessType && kind != DownloadAccessType {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal access spec: %w", err)
+	}
+
+	access := &downloadAccess{}
+	if err := json.Unmarshal(raw, access); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s access: %w", spec.GetType(), ocmerrors.ErrInvalid("access", string(raw)))
+	}
+
+	u, err := url.Parse(access.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("failed to parse %s access: %w", spec.GetType(), ocmerrors.ErrInvalid("url", access.URL))
+	}
+
+	if access.Checksum != "" {
+		if _, err := godigest.Parse(access.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to parse %s access: %w", spec.GetType(), ocmerrors.ErrInvalid("checksum", access.Checksum))
+		}
+	}
+
+	return access, nil
+}
+
+// download returns a reader for the data at the URL of the access and the size of the data, -1 if the server didn't
+// report it. The caller has to close the reader.
+func (r *downloadAccessResolver) download(ctx context.Context, access *downloadAccess) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, access.URL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request for %s: %w", access.URL, err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download %s: %w", access.URL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, 0, fmt.Errorf("failed to download %s: unexpected status %s", access.URL, resp.Status)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	godigest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+)
+
+func TestClient_GetDownloadResource(t *testing.T) {
+	data := "downloaded data"
+	checksum := godigest.FromString(data).String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifests.yaml":
+			_, _ = w.Write([]byte(data))
+		case "/chunked.yaml":
+			// Flushing before all data is written sends the data without a Content-Length.
+			_, _ = w.Write([]byte(data[:4]))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte(data[4:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		url      string
+		checksum string
+		digest   *ocmmetav1.DigestSpec
+		maxSize  int64
+		tooLarge bool
+		errIs    error
+		errStr   string
+	}{
+		{
+			name:     "download with checksum",
+			url:      server.URL + "/manifests.yaml",
+			checksum: checksum,
+		},
+		{
+			name: "download verified against the declared digest",
+			url:  server.URL + "/manifests.yaml",
+			digest: &ocmmetav1.DigestSpec{
+				HashAlgorithm:          "SHA-256",
+				NormalisationAlgorithm: "genericBlobDigest/v1",
+				Value:                  godigest.FromString(data).Encoded(),
+			},
+		},
+		{
+			name:     "checksum mismatch",
+			url:      server.URL + "/manifests.yaml",
+			checksum: godigest.FromString("other data").String(),
+			errIs:    ErrDigestMismatch,
+		},
+		{
+			name:    "download within the maximum size",
+			url:     server.URL + "/chunked.yaml",
+			maxSize: int64(len(data)),
+		},
+		{
+			name:     "download larger than the maximum size",
+			url:      server.URL + "/manifests.yaml",
+			maxSize:  4,
+			tooLarge: true,
+		},
+		{
+			name:     "download of unknown size larger than the maximum size",
+			url:      server.URL + "/chunked.yaml",
+			maxSize:  4,
+			tooLarge: true,
+		},
+		{
+			name:   "unexpected status",
+			url:    server.URL + "/missing.yaml",
+			errStr: "unexpected status 404 Not Found",
+		},
+		{
+			name:   "unsupported scheme",
+			url:    "ftp://example.com/manifests.yaml",
+			errStr: "failed to parse http access",
+		},
+		{
+			name:     "invalid checksum",
+			url:      server.URL + "/manifests.yaml",
+			checksum: "sha256:nope",
+			errStr:   "failed to parse http access",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{
+				Name:    "github.com/skarlso/ocm-demo-index",
+				Version: "v0.0.1",
+			}
+			comp.Resources = append(comp.Resources, &fakeocm.Resource{
+				Name:          "manifests",
+				Version:       "v0.0.1",
+				Component:     comp,
+				Type:          "file",
+				Digest:        tt.digest,
+				AccessOptions: []fakeocm.AccessOptionFunc{fakeocm.SetDownloadAccess(tt.url, tt.checksum)},
+			})
+			require.NoError(t, octx.AddComponent(comp))
+
+			cd := &v1alpha1.ComponentDescriptor{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+				},
+				Spec: v1alpha1.ComponentDescriptorSpec{
+					ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+						Resources: []v3alpha1.Resource{
+							{
+								ElementMeta: v3alpha1.ElementMeta{
+									Name:    "manifests",
+									Version: "v0.0.1",
+								},
+								Access: ocmruntime.NewUnstructuredType("http", ocmruntime.UnstructuredMap{"url": tt.url}),
+							},
+						},
+					},
+					Version: "v0.0.1",
+				},
+			}
+
+			cache := &fakes.FakeCache{}
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader(data)), nil)
+
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, WithMaxResourceSize(tt.maxSize))
+
+			cv := &v1alpha1.ComponentVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-name",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ComponentVersionSpec{
+					Component: comp.Name,
+					Version: v1alpha1.Version{
+						Semver: comp.Version,
+					},
+				},
+				Status: v1alpha1.ComponentVersionStatus{
+					ReconciledVersion: comp.Version,
+					ComponentDescriptor: v1alpha1.Reference{
+						Name:    comp.Name,
+						Version: comp.Version,
+						ComponentDescriptorRef: meta.NamespacedObjectReference{
+							Name:      cd.Name,
+							Namespace: cd.Namespace,
+						},
+					},
+				},
+			}
+
+			var location SourceLocation
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    "manifests",
+					Version: "v0.0.1",
+				},
+			}, WithSourceLocation(&location))
+
+			switch {
+			case tt.tooLarge:
+				assert.ErrorIs(t, err, ErrResourceTooLarge)
+				assert.True(t, cache.FetchDataByDigestWasNotCalled())
+			case tt.errIs != nil:
+				assert.ErrorIs(t, err, tt.errIs)
+				assert.False(t, cache.DeleteDataWasNotCalled(), "data that doesn't match the checksum must be deleted")
+			case tt.errStr != "":
+				assert.ErrorContains(t, err, tt.errStr)
+				assert.True(t, cache.PushDataWasNotCalled())
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, data, cache.PushDataCallingArgumentsOnCall(0).Content)
+				assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), location.Registry)
+			}
+		})
+	}
+}
+
+func TestGetDownloadAccessInvalid(t *testing.T) {
+	res := &fakeocm.Resource{
+		Name:          "manifests",
+		AccessOptions: []fakeocm.AccessOptionFunc{fakeocm.SetDownloadAccess("ftp://example.com/manifests.yaml", "")},
+	}
+
+	_, err := getDownloadAccess(res)
+	assert.True(t, ocmerrors.IsErrInvalid(err), "invalid accesses must be permanent errors, got %v", err)
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+)
+
+// The errors returned by the client wrap one of these errors to categorise the failure. Use errors.Is to check
+// for a category.
+var (
+	// ErrComponentNotFound is returned when the component version doesn't exist in the repository.
+	ErrComponentNotFound = errors.New("component not found")
+
+	// ErrResourceNotFound is returned when the component descriptor doesn't contain the referenced resource.
+	ErrResourceNotFound = errors.New("resource not found")
+
+	// ErrAmbiguousResource is returned when several resources of the component descriptor match the reference,
+	// for example variants of a resource that only differ in their extra identity.
+	ErrAmbiguousResource = errors.New("ambiguous resource")
+
+	// ErrUnsupportedAccess is returned when the access type of a resource is unknown or doesn't support the
+	// requested operation.
+	ErrUnsupportedAccess = errors.New("unsupported access")
+
+	// ErrRegistryAuth is returned when a registry rejects the credentials used to access it.
+	ErrRegistryAuth = errors.New("registry authentication failed")
+
+	// ErrRegistryNotAllowed is returned when the data of a resource would be fetched from a registry that isn't
+	// allowed.
+	ErrRegistryNotAllowed = errors.New("registry not allowed")
+
+	// ErrTransformNotAllowed is returned when the transformation webhook of a resource isn't served from one of the
+	// allowed hosts.
+	ErrTransformNotAllowed = errors.New("transform not allowed")
+
+	// ErrDigestMismatch is returned when the data of a resource doesn't match the digest recorded in its
+	// component descriptor.
+	ErrDigestMismatch = errors.New("digest mismatch")
+
+	// ErrNoFilesMatched is returned if none of the files of a resource matches the extract path.
+	ErrNoFilesMatched = errors.New("no files matched")
+
+	// ErrSnapshotConflict is returned when the reference a resource is stored at already points at an image that
+	// wasn't pushed by the controller.
+	ErrSnapshotConflict = errors.New("snapshot conflict")
+
+	// ErrResourceTooLarge is returned when the data of a resource exceeds the maximum size of a resource.
+	ErrResourceTooLarge = errors.New("resource too large")
+
+	// ErrInvalidSnapshotTag is returned when the version of a resource can't be used as the tag of its snapshot.
+	ErrInvalidSnapshotTag = errors.New("invalid snapshot tag")
+
+	// ErrInvalidReference is returned when the access of a resource holds an image reference or URL that can't be
+	// parsed.
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+// classifyError wraps err with the category of the failure, if it can be determined from the error returned by
+// the OCM library or the registry. Otherwise, err is returned as is.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var terr *transport.Error
+	if errors.As(err, &terr) && (terr.StatusCode == http.StatusUnauthorized || terr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: %w", ErrRegistryAuth, err)
+	}
+
+	if ocmerrors.IsErrUnknownKind(err, ocmerrors.KIND_ACCESSMETHOD) || ocmerrors.IsErrNotSupported(err) {
+		return fmt.Errorf("%w: %w", ErrUnsupportedAccess, err)
+	}
+
+	return err
+}
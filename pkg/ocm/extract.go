@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// extractFiles reads the tar archive and returns a new tar archive containing only the regular files whose
+// path matches the glob pattern. The reader must already be decompressed.
+func extractFiles(r io.Reader, pattern string) (io.Reader, error) {
+	tr := tar.NewReader(r)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	matched := 0
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		ok, err := path.Match(pattern, strings.TrimPrefix(path.Clean(header.Name), "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid extract path %q: %w", pattern, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write header for %s: %w", header.Name, err)
+		}
+
+		//nolint:gosec // the size of the file is limited by the size of the resource
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", header.Name, err)
+		}
+
+		matched++
+	}
+
+	if matched == 0 {
+		return nil, fmt.Errorf("%w: path %q", ErrNoFilesMatched, pattern)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar archive: %w", err)
+	}
+
+	return buf, nil
+}
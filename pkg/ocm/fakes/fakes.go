@@ -13,6 +13,7 @@ import (
 	"github.com/open-component-model/ocm/pkg/contexts/ocm"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache"
 	ocmctrl "github.com/open-component-model/ocm-controller/pkg/ocm"
 )
 
@@ -31,6 +32,9 @@ type MockFetcher struct {
 	getResourceCallCount                int
 	getResourceReturns                  map[int]getResourceReturnValues
 	getResourceCalledWith               [][]any
+	getResourceDigestDigest             string
+	getResourceDigestErr                error
+	getResourceDigestCalledWith         [][]any
 	getComponentVersionMap              map[string]ocm.ComponentVersionAccess
 	getComponentVersionErr              error
 	getComponentVersionCalledWith       [][]any
@@ -43,6 +47,11 @@ type MockFetcher struct {
 	listComponentVersionsVersions       []ocmctrl.Version
 	listComponentVersionsErr            error
 	listComponentVersionsCalledWith     [][]any
+	getReferrersReferrers               []ocmctrl.Referrer
+	getReferrersErr                     error
+	getReferrersCalledWith              [][]any
+	getResourcePushResult               cache.PushResult
+	getResourceWaitsForCancellation     bool
 }
 
 var _ ocmctrl.Contract = &MockFetcher{}
@@ -51,16 +60,34 @@ func (m *MockFetcher) CreateAuthenticatedOCMContext(ctx context.Context, obj *v1
 	return ocm.New(), nil
 }
 
-func (m *MockFetcher) GetResource(ctx context.Context, octx ocm.Context, cv *v1alpha1.ComponentVersion, resource *v1alpha1.ResourceReference) (io.ReadCloser, string, error) {
+func (m *MockFetcher) GetResource(ctx context.Context, octx ocm.Context, cv *v1alpha1.ComponentVersion, resource *v1alpha1.ResourceReference, opts ...ocmctrl.GetResourceOption) (io.ReadCloser, string, error) {
 	if _, ok := m.getResourceReturns[m.getResourceCallCount]; !ok {
 		return nil, "", fmt.Errorf("unexpected number of calls; not enough return values have been configured; call count %d", m.getResourceCallCount)
 	}
 	m.getResourceCalledWith = append(m.getResourceCalledWith, []any{cv, resource})
+	if m.getResourceWaitsForCancellation {
+		<-ctx.Done()
+		m.getResourceCallCount++
+
+		return nil, "", fmt.Errorf("failed to cache image: %w", ctx.Err())
+	}
+
+	ocmctrl.RecordPushResult(opts, m.getResourcePushResult)
 	result := m.getResourceReturns[m.getResourceCallCount]
 	m.getResourceCallCount++
 	return result.reader, result.digest, result.err
 }
 
+// GetResourceRecordsPushResult records result in the push result passed to GetResource.
+func (m *MockFetcher) GetResourceRecordsPushResult(result cache.PushResult) {
+	m.getResourcePushResult = result
+}
+
+// GetResourceWaitsForCancellation makes GetResource hang like a stuck copy until its context is cancelled.
+func (m *MockFetcher) GetResourceWaitsForCancellation() {
+	m.getResourceWaitsForCancellation = true
+}
+
 func (m *MockFetcher) GetResourceReturns(reader io.ReadCloser, digest string, err error) {
 	if m.getResourceReturns == nil {
 		m.getResourceReturns = make(map[int]getResourceReturnValues)
@@ -90,6 +117,42 @@ func (m *MockFetcher) GetResourceWasNotCalled() bool {
 	return len(m.getResourceCalledWith) == 0
 }
 
+func (m *MockFetcher) GetResourceDigest(ctx context.Context, octx ocm.Context, cv *v1alpha1.ComponentVersion, resource *v1alpha1.ResourceReference, _ ...ocmctrl.GetResourceOption) (string, error) {
+	m.getResourceDigestCalledWith = append(m.getResourceDigestCalledWith, []any{cv, resource})
+	return m.getResourceDigestDigest, m.getResourceDigestErr
+}
+
+func (m *MockFetcher) GetResourceDigestReturns(digest string, err error) {
+	m.getResourceDigestDigest = digest
+	m.getResourceDigestErr = err
+}
+
+func (m *MockFetcher) GetResourceDigestCallingArgumentsOnCall(i int) []any {
+	return m.getResourceDigestCalledWith[i]
+}
+
+func (m *MockFetcher) GetResourceDigestWasNotCalled() bool {
+	return len(m.getResourceDigestCalledWith) == 0
+}
+
+func (m *MockFetcher) GetReferrers(ctx context.Context, octx ocm.Context, cv *v1alpha1.ComponentVersion, resource *v1alpha1.ResourceReference) ([]ocmctrl.Referrer, error) {
+	m.getReferrersCalledWith = append(m.getReferrersCalledWith, []any{cv, resource})
+	return m.getReferrersReferrers, m.getReferrersErr
+}
+
+func (m *MockFetcher) GetReferrersReturns(referrers []ocmctrl.Referrer, err error) {
+	m.getReferrersReferrers = referrers
+	m.getReferrersErr = err
+}
+
+func (m *MockFetcher) GetReferrersCallingArgumentsOnCall(i int) []any {
+	return m.getReferrersCalledWith[i]
+}
+
+func (m *MockFetcher) GetReferrersWasNotCalled() bool {
+	return len(m.getReferrersCalledWith) == 0
+}
+
 func (m *MockFetcher) GetComponentVersion(ctx context.Context, octx ocm.Context, obj *v1alpha1.ComponentVersion, name, version string) (ocm.ComponentVersionAccess, error) {
 	m.getComponentVersionCalledWith = append(m.getComponentVersionCalledWith, []any{obj, name, version})
 	return m.getComponentVersionMap[name], m.getComponentVersionErr
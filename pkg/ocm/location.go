@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/repositories/genericocireg/componentmapping"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+)
+
+// SourceLocation is the upstream registry and repository the data of a resource is fetched from.
+type SourceLocation struct {
+	// Registry is the host of the registry, or of the URL the data is downloaded from.
+	Registry string
+
+	// Repository is the repository in the registry, or the path of the URL the data is downloaded from. The data
+	// of local blobs is stored in the repository of the component in the repository of the component version.
+	Repository string
+}
+
+// WithSourceLocation records the registry and repository the data of the resource is fetched from in loc. The
+// location is taken from the access of the resource in its component descriptor, so it is recorded whether or not
+// the data has to be fetched.
+func WithSourceLocation(loc *SourceLocation) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.location = loc
+	}
+}
+
+// sourceLocation returns the location the data of a resource with the given access in its component descriptor is
+// fetched from. Images and OCI blobs are fetched from their reference and downloads from their URL, the data of
+// other accesses, like local blobs, is stored in the repository of the component in repositoryURL.
+func sourceLocation(access *ocmruntime.UnstructuredTypedObject, repositoryURL, component string) (SourceLocation, error) {
+	if access == nil {
+		return SourceLocation{}, nil
+	}
+
+	kind, _ := ocmruntime.KindVersion(access.GetType())
+	switch kind {
+	case HTTPAccessType, DownloadAccessType:
+		raw, _ := access.Object["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil {
+			return SourceLocation{}, fmt.Errorf("failed to parse url %q: %w", raw, err)
+		}
+
+		return SourceLocation{Registry: u.Host, Repository: strings.TrimPrefix(u.Path, "/")}, nil
+	case ociartifact.Type, ociartifact.LegacyType:
+		reference, _ := access.Object["imageReference"].(string)
+
+		return referenceLocation(reference)
+	case ociblob.Type:
+		reference, _ := access.Object["reference"].(string)
+
+		return referenceLocation(reference)
+	}
+
+	// The repository URL may be given with a scheme, which isn't part of a repository name.
+	if _, rest, ok := strings.Cut(repositoryURL, "://"); ok {
+		repositoryURL = rest
+	}
+
+	loc, err := referenceLocation(repositoryURL)
+	if err != nil {
+		return SourceLocation{}, err
+	}
+
+	loc.Repository = path.Join(loc.Repository, componentmapping.ComponentDescriptorNamespace, component)
+
+	return loc, nil
+}
+
+// referenceLocation returns the registry and repository of an OCI reference.
+func referenceLocation(reference string) (SourceLocation, error) {
+	ref, err := ociname.ParseReference(reference)
+	if err != nil {
+		return SourceLocation{}, fmt.Errorf("failed to parse reference %q: %w", reference, err)
+	}
+
+	return SourceLocation{
+		Registry:   ref.Context().RegistryStr(),
+		Repository: ref.Context().RepositoryStr(),
+	}, nil
+}
+
+// BlobReference returns the reference of the blob with the given digest in the repository of reference, the way an
+// ociBlob access, or a global access, locates its blob. Access references are usually plain repositories, but some
+// are already pinned to a tag or digest, which is replaced by the digest of the blob instead of being appended to it.
+// The reference is kept as it was written otherwise, for example without the implicit registry of Docker Hub.
+func BlobReference(reference, digest string) (string, error) {
+	ref, err := ociname.ParseReference(reference)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to parse reference %q: %w", ErrInvalidReference, reference, err)
+	}
+
+	repository := reference
+	switch x := ref.(type) {
+	case ociname.Digest:
+		repository, _, _ = strings.Cut(reference, "@")
+		// A digest may follow a tag, which is dropped like it is without a digest.
+		if tag, err := ociname.NewTag(repository); err == nil {
+			repository = strings.TrimSuffix(repository, ":"+tag.TagStr())
+		}
+	case ociname.Tag:
+		repository = strings.TrimSuffix(reference, ":"+x.TagStr())
+	}
+
+	blob, err := ociname.NewDigest(repository + "@" + digest)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to construct reference of blob %s in %q: %w", ErrInvalidReference, digest, reference, err)
+	}
+
+	return blob.String(), nil
+}
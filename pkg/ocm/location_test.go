@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"testing"
+
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceLocation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		access   *ocmruntime.UnstructuredTypedObject
+		expected SourceLocation
+		errStr   string
+	}{
+		{
+			name:     "image",
+			access:   ocmruntime.NewUnstructuredType("ociArtifact", ocmruntime.UnstructuredMap{"imageReference": "ghcr.io/open-component-model/podinfo:6.3.5"}),
+			expected: SourceLocation{Registry: "ghcr.io", Repository: "open-component-model/podinfo"},
+		},
+		{
+			name:     "image with a legacy access type",
+			access:   ocmruntime.NewUnstructuredType("ociRegistry/v1", ocmruntime.UnstructuredMap{"imageReference": "registry.local:5000/podinfo@sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2"}),
+			expected: SourceLocation{Registry: "registry.local:5000", Repository: "podinfo"},
+		},
+		{
+			name:     "oci blob",
+			access:   ocmruntime.NewUnstructuredType("ociBlob", ocmruntime.UnstructuredMap{"reference": "ghcr.io/open-component-model/blobs", "digest": "sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2"}),
+			expected: SourceLocation{Registry: "ghcr.io", Repository: "open-component-model/blobs"},
+		},
+		{
+			name:     "download",
+			access:   ocmruntime.NewUnstructuredType("http", ocmruntime.UnstructuredMap{"url": "https://example.com/releases/manifests.yaml"}),
+			expected: SourceLocation{Registry: "example.com", Repository: "releases/manifests.yaml"},
+		},
+		{
+			name:     "local blob",
+			access:   ocmruntime.NewUnstructuredType("localBlob", ocmruntime.UnstructuredMap{"localReference": "sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2"}),
+			expected: SourceLocation{Registry: "ghcr.io", Repository: "open-component-model/components/component-descriptors/github.com/open-component-model/podinfo"},
+		},
+		{
+			name:   "invalid image reference",
+			access: ocmruntime.NewUnstructuredType("ociArtifact", ocmruntime.UnstructuredMap{"imageReference": "ghcr.io/Podinfo"}),
+			errStr: `failed to parse reference "ghcr.io/Podinfo"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := sourceLocation(tt.access, "https://ghcr.io/open-component-model/components", "github.com/open-component-model/podinfo")
+			if tt.errStr != "" {
+				assert.ErrorContains(t, err, tt.errStr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, loc)
+		})
+	}
+
+	loc, err := sourceLocation(nil, "ghcr.io/open-component-model/components", "github.com/open-component-model/podinfo")
+	require.NoError(t, err)
+	assert.Empty(t, loc, "elements without an access have no location")
+}
+
+func TestBlobReference(t *testing.T) {
+	const (
+		digest = "sha256:7f0168496f273c1e2095703a050128114d339c580b0906cd124a93b66ae471e2"
+		pinned = "sha256:0d6b4eafe5cf1c9e9d4d3e4ba3b1f6b5d2a4e8c44c6c9d8d11a8f0a1c2cf1c3a"
+	)
+
+	testCases := []struct {
+		name      string
+		reference string
+		expected  string
+		errStr    string
+	}{
+		{
+			name:      "repository",
+			reference: "ghcr.io/open-component-model/blobs",
+			expected:  "ghcr.io/open-component-model/blobs@" + digest,
+		},
+		{
+			name:      "repository on a registry with a port",
+			reference: "registry.local:5000/blobs",
+			expected:  "registry.local:5000/blobs@" + digest,
+		},
+		{
+			name:      "repository on Docker Hub",
+			reference: "open-component-model/blobs",
+			expected:  "open-component-model/blobs@" + digest,
+		},
+		{
+			name:      "tagged reference",
+			reference: "registry.local:5000/blobs:v1.0.0",
+			expected:  "registry.local:5000/blobs@" + digest,
+		},
+		{
+			name:      "reference pinned to a digest",
+			reference: "ghcr.io/open-component-model/blobs@" + pinned,
+			expected:  "ghcr.io/open-component-model/blobs@" + digest,
+		},
+		{
+			name:      "tagged reference pinned to a digest",
+			reference: "registry.local:5000/blobs:v1.0.0@" + pinned,
+			expected:  "registry.local:5000/blobs@" + digest,
+		},
+		{
+			name:      "invalid reference",
+			reference: "ghcr.io/Blobs",
+			errStr:    `failed to parse reference "ghcr.io/Blobs"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := BlobReference(tt.reference, digest)
+			if tt.errStr != "" {
+				assert.ErrorIs(t, err, ErrInvalidReference)
+				assert.ErrorContains(t, err, tt.errStr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+
+	t.Run("invalid digest", func(t *testing.T) {
+		_, err := BlobReference("ghcr.io/open-component-model/blobs", "sha256:invalid")
+		assert.ErrorIs(t, err, ErrInvalidReference)
+	})
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"strings"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// ParseRegistryMirrors parses mirrors of the form host=mirror into a map of the registry host to the mirror, for
+// example docker.io=mirror.corp/docker.io. The mirror is a registry host optionally followed by the path the
+// repositories of the mirrored registry are found under. Empty mirrors are ignored.
+func ParseRegistryMirrors(mirrors ...string) (map[string]string, error) {
+	result := make(map[string]string, len(mirrors))
+	for _, mirror := range mirrors {
+		if mirror = strings.TrimSpace(mirror); mirror == "" {
+			continue
+		}
+
+		host, to, ok := strings.Cut(mirror, "=")
+		host, to = strings.TrimSpace(host), strings.TrimSuffix(strings.TrimSpace(to), "/")
+		if !ok || host == "" || to == "" {
+			return nil, fmt.Errorf("invalid registry mirror %q, must be of the form host=mirror", mirror)
+		}
+
+		registry, err := ociname.NewRegistry(strings.ToLower(host))
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q of mirror %q: %w", host, mirror, err)
+		}
+
+		// A mirror without a registry host would be read as a repository of Docker Hub.
+		repository, err := ociname.NewRepository(to + "/mirrored")
+		if err != nil || !strings.HasPrefix(to, repository.RegistryStr()) {
+			return nil, fmt.Errorf("invalid mirror %q of registry %s, must start with a registry host", to, host)
+		}
+
+		if _, ok := result[registry.RegistryStr()]; ok {
+			return nil, fmt.Errorf("registry %s is mirrored more than once", host)
+		}
+
+		result[registry.RegistryStr()] = to
+	}
+
+	return result, nil
+}
+
+// WithRegistryMirrors fetches images from the mirrors of their registries, for example a pull-through cache in an
+// air-gapped environment, as returned by ParseRegistryMirrors. The credentials of the mirror are used to fetch the
+// images. The registry of an image is still checked against the allowed registries and recorded as its source, and
+// its data is verified against the digest of the resource. Resources of other access types, like local blobs, are
+// fetched from the repository of their component as usual.
+func WithRegistryMirrors(mirrors map[string]string) ClientOption {
+	return func(c *Client) {
+		c.registryMirrors = mirrors
+	}
+}
+
+// mirrorReference returns the reference of the image in the mirror of its registry, the reference as it is if the
+// registry isn't mirrored.
+func (c *Client) mirrorReference(ref ociname.Reference) (ociname.Reference, error) {
+	mirror, ok := c.registryMirrors[ref.Context().RegistryStr()]
+	if !ok {
+		return ref, nil
+	}
+
+	repository := mirror + "/" + ref.Context().RepositoryStr()
+	if digest, ok := ref.(ociname.Digest); ok {
+		return ociname.NewDigest(repository + "@" + digest.DigestStr())
+	}
+
+	return ociname.NewTag(repository + ":" + ref.Identifier())
+}
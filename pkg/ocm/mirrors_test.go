@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"testing"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistryMirrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mirrors  []string
+		expected map[string]string
+		errStr   string
+	}{
+		{
+			name:     "no mirrors",
+			mirrors:  []string{""},
+			expected: map[string]string{},
+		},
+		{
+			name:    "mirrors with and without a path",
+			mirrors: []string{"docker.io=mirror.corp/docker.io/", " GHCR.io = mirror.corp:5000 "},
+			expected: map[string]string{
+				"index.docker.io": "mirror.corp/docker.io",
+				"ghcr.io":         "mirror.corp:5000",
+			},
+		},
+		{
+			name:    "missing mirror",
+			mirrors: []string{"docker.io"},
+			errStr:  `invalid registry mirror "docker.io", must be of the form host=mirror`,
+		},
+		{
+			name:    "mirror without a registry host",
+			mirrors: []string{"docker.io=mirror"},
+			errStr:  `invalid mirror "mirror" of registry docker.io, must start with a registry host`,
+		},
+		{
+			name:    "registry mirrored twice",
+			mirrors: []string{"docker.io=mirror.corp/docker.io", "index.docker.io=other.corp"},
+			errStr:  "registry index.docker.io is mirrored more than once",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mirrors, err := ParseRegistryMirrors(tt.mirrors...)
+			if tt.errStr != "" {
+				assert.EqualError(t, err, tt.errStr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mirrors)
+		})
+	}
+}
+
+func TestClient_MirrorReference(t *testing.T) {
+	mirrors, err := ParseRegistryMirrors("docker.io=mirror.corp/docker.io")
+	require.NoError(t, err)
+	c := NewClient(nil, nil, WithRegistryMirrors(mirrors))
+
+	testCases := []struct {
+		reference string
+		expected  string
+	}{
+		{
+			reference: "nginx:1.25",
+			expected:  "mirror.corp/docker.io/library/nginx:1.25",
+		},
+		{
+			reference: "docker.io/bitnami/redis@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+			expected:  "mirror.corp/docker.io/bitnami/redis@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+		},
+		{
+			reference: "ghcr.io/open-component-model/podinfo:6.3.5",
+			expected:  "ghcr.io/open-component-model/podinfo:6.3.5",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.reference, func(t *testing.T) {
+			ref, err := ociname.ParseReference(tt.reference)
+			require.NoError(t, err)
+
+			mirrored, err := c.mirrorReference(ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mirrored.String())
+		})
+	}
+}
@@ -5,37 +5,49 @@
 package ocm
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"maps"
+	"net/http"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/containers/image/v5/pkg/compression"
 	"github.com/go-logr/logr"
-	"github.com/mandelsoft/vfs/pkg/memoryfs"
-	"github.com/mandelsoft/vfs/pkg/vfs"
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/mitchellh/hashstructure/v2"
-	"github.com/open-component-model/ocm/pkg/contexts/credentials/repositories/dockerconfig"
+	"github.com/open-component-model/ocm/pkg/contexts/credentials"
+	ociidentity "github.com/open-component-model/ocm/pkg/contexts/oci/identity"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/attrs/signingattr"
 	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
-	"github.com/open-component-model/ocm/pkg/contexts/ocm/download"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/digester/digesters/artifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/digester/digesters/blob"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/repositories/ocireg"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/signing"
 	"github.com/open-component-model/ocm/pkg/contexts/ocm/utils"
-	"helm.sh/helm/v3/pkg/registry"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
+	"github.com/open-component-model/ocm/pkg/signing/hasher/sha256"
+	godigest "github.com/opencontainers/go-digest"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/lru"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
 	"github.com/open-component-model/ocm-controller/pkg/cache"
 	"github.com/open-component-model/ocm-controller/pkg/component"
+	"github.com/open-component-model/ocm-controller/pkg/tracing"
 )
 
 const dockerConfigKey = ".dockerconfigjson"
@@ -48,7 +60,21 @@ type Contract interface {
 		octx ocm.Context,
 		cv *v1alpha1.ComponentVersion,
 		resource *v1alpha1.ResourceReference,
+		opts ...GetResourceOption,
 	) (io.ReadCloser, string, error)
+	GetResourceDigest(
+		ctx context.Context,
+		octx ocm.Context,
+		cv *v1alpha1.ComponentVersion,
+		resource *v1alpha1.ResourceReference,
+		opts ...GetResourceOption,
+	) (string, error)
+	GetReferrers(
+		ctx context.Context,
+		octx ocm.Context,
+		cv *v1alpha1.ComponentVersion,
+		resource *v1alpha1.ResourceReference,
+	) ([]Referrer, error)
 	GetComponentVersion(
 		ctx context.Context,
 		octx ocm.Context,
@@ -60,228 +86,1514 @@ type Contract interface {
 	VerifyComponent(ctx context.Context, octx ocm.Context, obj *v1alpha1.ComponentVersion, version string) (bool, error)
 }
 
-// Client implements the OCM fetcher interface.
-type Client struct {
-	client client.Client
-	cache  cache.Cache
-}
+// GetResourceOption configures how a resource is fetched by GetResource.
+type GetResourceOption func(o *getResourceOptions)
+
+type getResourceOptions struct {
+	platform    string
+	extractPath string
+	namespace   string
+	compression string
+	registry    string
+	keychain    authn.Keychain
+	maxSize     int64
+	source      bool
+	unpinned    *UnpinnedReference
+	location    *SourceLocation
+	pushResult  *cache.PushResult
+	annotations map[string]string
+	config      *cache.ImageConfig
+	artifact    string
+	guard       bool
+	overwrite   bool
+	mountFrom   string
+	untagged    bool
+
+	transformURL     string
+	transformTimeout time.Duration
+}
+
+// WithPlatform selects a single platform of a multi-arch image resource in the form os/arch[/variant].
+func WithPlatform(platform string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.platform = platform
+	}
+}
+
+// WithExtractPath selects the files of a tar archive resource matching the glob pattern. Only these files
+// are cached instead of the complete resource.
+func WithExtractPath(pattern string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.extractPath = pattern
+	}
+}
+
+// WithSnapshotNamespace records the namespace of the snapshot the resource is written to in its identity, so it is
+// available to the repository name template.
+func WithSnapshotNamespace(namespace string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithSnapshotRegistry pushes the resource to the registry at the address instead of the in-cluster registry,
+// authenticated with the keychain. The push is anonymous if the keychain is nil.
+func WithSnapshotRegistry(address string, keychain authn.Keychain) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.registry = address
+		o.keychain = keychain
+	}
+}
+
+// WithCompression sets the compression of the layer the resource data is cached in, one of the v1alpha1
+// Compression constants. The data is gzip-compressed by default. Image resources are copied with their layers as
+// they are, except that zstd-compressed layers are recompressed with gzip unless the compression is none or
+// passthrough.
+func WithCompression(compression string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		if compression == v1alpha1.CompressionGzip {
+			compression = ""
+		}
+
+		o.compression = compression
+	}
+}
+
+// WithImageConfig stores the resource data in an image with the given config instead of an empty config. Image
+// resources are always copied as they are.
+func WithImageConfig(config *cache.ImageConfig) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.config = config
+	}
+}
+
+// WithArtifactType stores the resource data with the artifact type as the media type of the config of its manifest.
+// Image resources are always copied as they are.
+func WithArtifactType(artifactType string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.artifact = artifactType
+	}
+}
+
+// WithAdoptionGuard is used for the first write of a new snapshot. Data that is already stored at the reference of
+// the snapshot is only used if it was pushed by the controller, otherwise ErrSnapshotConflict is returned, or the data
+// is replaced if overwrite is set. Data without annotations in the in-cluster registry was pushed before the
+// controller marked its data and is used as well. Image resources are copied as they are and can't be told apart
+// from other images, so they aren't guarded.
+func WithAdoptionGuard(overwrite bool) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.guard = true
+		o.overwrite = overwrite
+	}
+}
+
+// WithMountFrom mounts the layer of the resource data from the repository of the previous version of its snapshot
+// if the repository already holds the layer, instead of uploading the data again. Image resources are always
+// copied as they are.
+func WithMountFrom(repository string) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.mountFrom = repository
+	}
+}
+
+// UnpinnedReference is the image reference of a resource that references the image by tag without a digest.
+type UnpinnedReference struct {
+	// Reference is the image reference of the resource.
+	Reference string
+	// Digest is the digest the tag was resolved to. It is empty if the image didn't have to be copied because it
+	// has already been stored in the in-cluster registry.
+	Digest string
+}
+
+// WithUnpinnedReference records the image reference of the resource in ref if the resource is an image referenced
+// by tag without a digest. Such an image is resolved to a digest before it is copied, the data behind the tag may
+// however change without the component version changing. The reference is left empty for all other resources.
+func WithUnpinnedReference(ref *UnpinnedReference) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.unpinned = ref
+	}
+}
+
+// WithPushResult records in result whether the data of the resource was written to the snapshot registry. It is
+// unchanged if the data was already stored at the reference of the snapshot, either because it was found in the
+// cache or because the pushed manifest matched the one the tag already pointed at.
+func WithPushResult(result *cache.PushResult) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.pushResult = result
+	}
+}
+
+// RecordPushResult records result in the push result of the options if they contain WithPushResult. It is used by
+// implementations of Contract other than Client that don't push the data themselves.
+func RecordPushResult(opts []GetResourceOption, result cache.PushResult) {
+	options := &getResourceOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	if options.pushResult != nil {
+		*options.pushResult = result
+	}
+}
+
+// WithoutTag pushes the resource data by the digest of its manifest only, the snapshot isn't tagged with the version
+// of the resource. The digest is recorded in the push result of WithPushResult, data stored at the tag is never used.
+func WithoutTag() GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.untagged = true
+	}
+}
+
+// pushedReference returns the reference the resource data has been pushed to, the digest of its manifest if it was
+// pushed without a tag.
+func (o *getResourceOptions) pushedReference(tag string) string {
+	if o.untagged {
+		return o.pushResult.Manifest
+	}
+
+	return tag
+}
+
+// pushOptions returns the options for pushing the resource data to the cache.
+func (o *getResourceOptions) pushOptions() []cache.PushOption {
+	var opts []cache.PushOption
+	if o.compression != "" {
+		opts = append(opts, cache.WithoutCompression())
+	}
+
+	if len(o.annotations) > 0 {
+		opts = append(opts, cache.WithAnnotations(o.annotations))
+	}
+
+	if o.config != nil {
+		opts = append(opts, cache.WithImageConfig(o.config))
+	}
+
+	if o.artifact != "" {
+		opts = append(opts, cache.WithArtifactType(o.artifact))
+	}
+
+	if o.mountFrom != "" {
+		opts = append(opts, cache.WithMountFrom(o.mountFrom))
+	}
+
+	if o.pushResult != nil {
+		opts = append(opts, cache.WithPushResult(o.pushResult))
+	}
+
+	if o.untagged {
+		opts = append(opts, cache.WithoutTag())
+	}
+
+	return opts
+}
+
+// Client implements the OCM fetcher interface.
+type Client struct {
+	client     client.Client
+	cache      cache.Cache
+	resources  *resourceCache
+	httpClient *http.Client
+
+	// descriptorIndexes are the resource indexes of recently read component descriptors by their UID.
+	descriptorIndexes *lru.Cache
+
+	// allowedRegistries are the hosts resources may be fetched from. Any host is allowed if empty.
+	allowedRegistries map[string]struct{}
+
+	// allowedTransformHosts are the hosts transformation webhooks may be served from. No host is allowed if empty.
+	allowedTransformHosts []string
+
+	// maxResourceSize is the maximum size in bytes of a resource. The size isn't limited if zero.
+	maxResourceSize int64
+
+	// annotationKeys are the keys of the resource metadata added to the manifests of snapshots.
+	annotationKeys []string
+
+	// accessResolvers resolve the data of resources by the kind of their access type.
+	accessResolvers map[string]AccessResolver
+
+	// registryMirrors are the mirrors images are fetched from by the host of their registry.
+	registryMirrors map[string]string
+
+	// repositoryNamer names the repositories resources are pushed to.
+	repositoryNamer *RepositoryNamer
+}
+
+// ClientOption configures the Client.
+type ClientOption func(c *Client)
+
+// WithResourceCacheSize sets the number of pushed resources the client remembers by the digest of their source.
+// A resource with a known digest is copied within the in-cluster registry instead of being downloaded again.
+// Zero disables the cache.
+func WithResourceCacheSize(size int) ClientOption {
+	return func(c *Client) {
+		if size > 0 {
+			c.resources = newResourceCache(size)
+		}
+	}
+}
+
+// WithHTTPClient sets the client used to download resources with an http access. Defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+var _ Contract = &Client{}
+
+// NewClient creates a new fetcher Client using the provided k8s client.
+func NewClient(client client.Client, cache cache.Cache, opts ...ClientOption) *Client {
+	c := &Client{
+		client:     client,
+		cache:      cache,
+		httpClient: http.DefaultClient,
+
+		descriptorIndexes: lru.New(descriptorIndexSize),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// The defaults are added once the options are applied, as the download resolver uses the http client.
+	for accessType, resolver := range c.defaultAccessResolvers() {
+		if _, ok := c.accessResolvers[accessType]; !ok {
+			WithAccessResolver(accessType, resolver)(c)
+		}
+	}
+
+	return c
+}
+
+func (c *Client) CreateAuthenticatedOCMContext(ctx context.Context, obj *v1alpha1.ComponentVersion) (ocm.Context, error) {
+	octx := ocm.New()
+
+	if obj.Spec.ServiceAccountName != "" {
+		if err := c.configureServiceAccountAccess(ctx, octx, obj.Spec.ServiceAccountName, obj.Namespace); err != nil {
+			return nil, fmt.Errorf("failed to configure service account access: %w", err)
+		}
+	}
+
+	if err := c.configureAccessCredentials(ctx, octx, obj.Spec.Repository, obj.Namespace); err != nil {
+		return nil, fmt.Errorf("failed to configure credentials for source: %w", err)
+	}
+
+	return octx, nil
+}
+
+// configureAccessCredentials configures access credentials if needed for a source/destination repository.
+func (c *Client) configureAccessCredentials(
+	ctx context.Context,
+	ocmCtx ocm.Context,
+	repository v1alpha1.Repository,
+	namespace string,
+) error {
+	// If there are no credentials, this call is a no-op.
+	if repository.SecretRef == nil {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	if err := ConfigureCredentials(ctx, ocmCtx, c.client, repository.URL, repository.SecretRef.Name, namespace); err != nil {
+		logger.V(v1alpha1.LevelDebug).Error(err, "failed to find credentials")
+
+		// we don't ignore not found errors
+		return fmt.Errorf("failed to configure credentials for component: %w", err)
+	}
+
+	logger.V(v1alpha1.LevelDebug).Info("credentials configured")
+
+	return nil
+}
+
+func (c *Client) configureServiceAccountAccess(
+	ctx context.Context,
+	octx ocm.Context,
+	serviceAccountName, namespace string,
+) error {
+	logger := log.FromContext(ctx)
+
+	logger.V(v1alpha1.LevelDebug).Info("configuring service account credentials")
+	account := &corev1.ServiceAccount{}
+	if err := c.client.Get(ctx, types.NamespacedName{
+		Name:      serviceAccountName,
+		Namespace: namespace,
+	}, account); err != nil {
+		return fmt.Errorf("failed to fetch service account: %w", err)
+	}
+
+	logger.V(v1alpha1.LevelDebug).Info("got service account", "name", account.GetName())
+
+	for _, imagePullSecret := range account.ImagePullSecrets {
+		if err := ConfigureDockerConfigCredentials(ctx, octx, c.client, imagePullSecret.Name, namespace); err != nil {
+			return fmt.Errorf("failed to configure image pull secret: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetResource returns a reader for the resource data. It is the responsibility of the caller to close the reader.
+func (c *Client) GetResource(
+	ctx context.Context,
+	octx ocm.Context,
+	cv *v1alpha1.ComponentVersion,
+	resource *v1alpha1.ResourceReference,
+	opts ...GetResourceOption,
+) (io.ReadCloser, string, error) {
+	ctx, span := tracing.Start(ctx, "ocm.GetResource",
+		tracing.ComponentKey.String(cv.Spec.Component),
+		tracing.ComponentVersionKey.String(cv.Status.ReconciledVersion),
+		tracing.ResourceNameKey.String(resource.Name),
+		tracing.ResourceVersionKey.String(resource.Version),
+	)
+
+	reader, digest, err := c.getResource(ctx, octx, cv, resource, opts...)
+	span.SetAttributes(tracing.SourceDigestKey.String(digest))
+	tracing.End(span, err)
+
+	return reader, digest, err
+}
+
+func (c *Client) getResource(
+	ctx context.Context,
+	octx ocm.Context,
+	cv *v1alpha1.ComponentVersion,
+	resource *v1alpha1.ResourceReference,
+	opts ...GetResourceOption,
+) (io.ReadCloser, string, error) {
+	logger := log.FromContext(ctx).WithName("ocm")
+
+	options := &getResourceOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	// Data pushed without a tag can only be found by the manifest digest recorded in the push result.
+	if options.untagged && options.pushResult == nil {
+		options.pushResult = &cache.PushResult{}
+	}
+
+	// All the data of the resource is read from and written to the snapshot registry.
+	if options.registry != "" {
+		store, err := c.cache.ForRegistry(options.registry, options.keychain)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid snapshot registry: %w", err)
+		}
+
+		client := *c
+		client.cache = store
+		c = &client
+	}
+
+	version := "latest"
+	if resource.ElementMeta.Version != "" {
+		version = resource.ElementMeta.Version
+	}
+
+	// The data is stored with the version as tag, which therefore has to be a valid tag.
+	tag, err := SnapshotTag(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cd, err := component.GetComponentDescriptor(ctx, c.client, resource.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find component descriptor for reference: %w", err)
+	}
+
+	if cd == nil {
+		return nil, "", fmt.Errorf(
+			"component descriptor not found for reference path: %+v",
+			resource.ReferencePath,
+		)
+	}
+
+	logger.V(v1alpha1.LevelTrace).Info("found component descriptor", "descriptor", cd.Spec)
+
+	// Fail early if the component doesn't contain the resource, there is nothing to fetch or to find in the cache.
+	descriptor, err := c.descriptorElement(cd, resource, options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if options.unpinned != nil {
+		*options.unpinned = UnpinnedReference{Reference: unpinnedImageReference(descriptor.Access)}
+	}
+
+	componentName, err := component.GetComponentName(ctx, c.client, resource.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get component name for reference: %w", err)
+	}
+
+	options.annotations = c.snapshotAnnotations(componentName, cd.Spec.Version, descriptor)
+
+	// The location is only recorded, a location that can't be determined doesn't keep the resource from being fetched.
+	if options.location != nil {
+		location, err := sourceLocation(descriptor.Access, cv.Spec.Repository.URL, componentName)
+		if err != nil {
+			logger.Error(err, "failed to determine source location of resource")
+		}
+
+		*options.location = location
+	}
+
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    cd.Name,
+		v1alpha1.ComponentVersionKey: cd.Spec.Version,
+		v1alpha1.ResourceNameKey:     resource.ElementMeta.Name,
+		v1alpha1.ResourceVersionKey:  version,
+	}
+
+	// Add extra identity.
+	for k, v := range resource.ElementMeta.ExtraIdentity {
+		identity[k] = v
+	}
+
+	// A single platform of an image is cached separately from the complete image.
+	if options.platform != "" {
+		identity[v1alpha1.ResourcePlatformKey] = options.platform
+	}
+
+	// So are the files extracted from a resource.
+	if options.extractPath != "" {
+		identity[v1alpha1.ResourceExtractPathKey] = options.extractPath
+	}
+
+	// As well as data stored with a compression other than the default.
+	if options.compression != "" {
+		identity[v1alpha1.ResourceCompressionKey] = options.compression
+	}
+
+	if options.namespace != "" {
+		identity[v1alpha1.SnapshotNamespaceKey] = options.namespace
+	}
+
+	if options.source {
+		identity[v1alpha1.ResourceKindKey] = v1alpha1.ResourceKindSource
+	}
+
+	// Data stored with an image config has a different manifest than the same data stored without one.
+	if options.config != nil {
+		hash, err := options.config.Hash()
+		if err != nil {
+			return nil, "", err
+		}
+
+		identity[v1alpha1.ResourceConfigKey] = hash
+	}
+
+	// So does data stored with an artifact type.
+	if options.artifact != "" {
+		identity[v1alpha1.ResourceArtifactTypeKey] = options.artifact
+	}
+
+	// Transformed data is cached separately from the data as it is fetched.
+	if options.transformURL != "" {
+		identity[v1alpha1.ResourceTransformKey] = options.transformURL
+	}
+
+	name, err := c.repositoryNamer.RepositoryName(identity)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to construct name: %w", err)
+	}
+
+	// Data pushed without a tag isn't looked up at the tag, pushing it again finds the unchanged manifest instead.
+	var cached bool
+	if !options.untagged {
+		if cached, err = c.cache.IsCached(ctx, name, tag); err != nil {
+			return nil, "", fmt.Errorf("failed to check cache: %w", err)
+		}
+	}
+
+	if cached && options.guard {
+		image, err := c.isImageResource(ctx, octx, cv, cd, resource, options)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !image {
+			if cached, err = c.adoptCachedData(ctx, name, tag, options); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	// The limit may have been lowered since the data was pushed, so cached data is checked against it too.
+	limit := c.sizeLimit(resource.Name, options)
+	if cached && limit.enabled() {
+		size, err := c.cache.DataSize(ctx, name, tag)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get size of cached data: %w", err)
+		}
+
+		if err := limit.check(size); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if cached {
+		if options.pushResult != nil {
+			*options.pushResult = cache.PushResult{Unchanged: true}
+		}
+
+		return c.cache.FetchDataByIdentity(ctx, name, tag)
+	}
+
+	// The same resource data may already have been pushed for another identity, e.g. an older component version.
+	cacheKey := c.resourceCacheKey(cd, resource, options)
+	if reader, digest, ok := c.copyCachedResource(ctx, cacheKey, name, tag, limit, options); ok {
+		return reader, digest, nil
+	}
+
+	logger.V(v1alpha1.LevelDebug).Info("resource is not cached, fetching it",
+		v1alpha1.LogKeyResourceName, resource.Name, v1alpha1.LogKeySnapshotRef, name+":"+tag)
+
+	cva, err := c.GetComponentVersion(ctx, octx, cv, cv.Spec.Component, cv.Status.ReconciledVersion)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get component Version: %w", err)
+	}
+
+	defer func() {
+		if cerr := cva.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	res, err := c.resolveElement(cva, cd, resource, options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := c.checkRegistryAllowed(res, cv); err != nil {
+		return nil, "", err
+	}
+
+	resolved, err := c.resolveAccess(ctx, res, cva)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch reader for resource: %w", err)
+	}
+
+	// Images are copied as they are instead of being flattened into a single layer.
+	if resolved.Image != "" {
+		if options.extractPath != "" {
+			return nil, "", fmt.Errorf(
+				"failed to extract %s from resource %s: %w",
+				options.extractPath,
+				resource.Name,
+				classifyError(ocmerrors.ErrNotSupported("extracting files for access type", res.Meta().Type)),
+			)
+		}
+
+		if options.transformURL != "" {
+			return nil, "", fmt.Errorf(
+				"failed to transform resource %s: %w",
+				resource.Name,
+				classifyError(ocmerrors.ErrNotSupported("transforming resources for access type", res.Meta().Type)),
+			)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, "", fmt.Errorf("failed to copy image: %w", err)
+		}
+
+		return c.copyImageResource(ctx, octx, res, resolved.Image, name, tag, options)
+	}
+
+	reader, mediaType, size := resolved.Reader, resolved.MediaType, resolved.Size
+	defer func() {
+		if cerr := reader.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if options.platform != "" {
+		return nil, "", fmt.Errorf(
+			"failed to select platform %s of resource %s: %w",
+			options.platform,
+			resource.Name,
+			classifyError(ocmerrors.ErrNotSupported("platform selection for access type", res.Meta().Type)),
+		)
+	}
+
+	// Reject a resource of known size before reading any of it, the size of the data may however only be known
+	// once it has been read, or grow when it is decompressed.
+	if err := limit.check(size); err != nil {
+		return nil, "", err
+	}
+
+	// If the component descriptor carries a blob digest for the resource, verify the fetched data against it.
+	var source io.Reader = limit.reader(reader)
+	verifier, err := resourceDigestVerifier(res)
+	if err != nil {
+		return nil, "", err
+	}
+	if verifier != nil {
+		source = io.TeeReader(source, verifier)
+	}
+
+	// Passthrough keeps the data as it is fetched, unless files have to be extracted from it.
+	data := source
+	if options.compression != v1alpha1.CompressionPassthrough || options.extractPath != "" {
+		decompressedReader, decompressed, err := compression.AutoDecompress(source)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to autodecompress content: %w", err)
+		}
+		if decompressed {
+			logger.V(v1alpha1.LevelDebug).Info("resource data was automatically decompressed")
+		}
+
+		data = decompressedReader
+		if options.extractPath != "" {
+			if data, err = extractFiles(decompressedReader, options.extractPath); err != nil {
+				return nil, "", fmt.Errorf("failed to extract files from resource %s: %w", resource.Name, err)
+			}
+		}
+	}
+
+	// Fetching the component version and resolving the resource can take a while, don't start a push of the
+	// resource if the reconciliation has been cancelled in the meantime.
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to cache blob: %w", err)
+	}
+
+	// The transformation webhook receives the data as it would be pushed, the data it returns is pushed instead.
+	var transformed io.ReadCloser
+	if options.transformURL != "" {
+		// The identity of the snapshot is named after the ComponentDescriptor, the webhook gets the component.
+		resourceIdentity := ocmmetav1.Identity{
+			v1alpha1.ComponentNameKey:    componentName,
+			v1alpha1.ComponentVersionKey: cd.Spec.Version,
+			v1alpha1.ResourceNameKey:     resource.Name,
+			v1alpha1.ResourceVersionKey:  version,
+		}
+		if transformed, err = c.transformResource(ctx, data, mediaType, resourceIdentity, limit, options); err != nil {
+			return nil, "", fmt.Errorf("failed to transform resource %s: %w", resource.Name, err)
+		}
+
+		logger.V(v1alpha1.LevelDebug).Info("transforming resource data", v1alpha1.LogKeyResourceName, resource.Name, "url", options.transformURL)
+		data = transformed
+	}
+
+	// We need to push the media type... And construct the right layers I guess.
+	pushed := &countingReader{r: limit.reader(data)}
+	digest, err := c.cache.PushData(ctx, io.NopCloser(pushed), mediaType, name, tag, options.pushOptions()...)
+	if transformed != nil {
+		// Only once the request to the webhook is done, the size limit and the digest of the data can be checked.
+		if cerr := transformed.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	if lerr := limit.err(); lerr != nil {
+		return nil, "", fmt.Errorf("failed to cache blob: %w", lerr)
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to cache blob: %w", classifyError(err))
+	}
+
+	if verifier != nil {
+		// drain whatever the decompression didn't consume, so the verifier sees the complete blob.
+		if _, err := io.Copy(io.Discard, source); err != nil {
+			return nil, "", fmt.Errorf("failed to read resource data for verification: %w", err)
+		}
+
+		if !verifier.Verified() {
+			err := fmt.Errorf("%w: data of resource %s does not match its digest", ErrDigestMismatch, resource.Name)
+			if derr := c.cache.DeleteData(ctx, name, options.pushedReference(tag)); derr != nil {
+				err = errors.Join(err, derr)
+			}
+
+			return nil, "", err
+		}
+	}
+
+	logger.V(v1alpha1.LevelDebug).Info("pushed resource data", v1alpha1.LogKeySnapshotRef, name+":"+tag, v1alpha1.LogKeySourceDigest, digest)
+
+	if c.resources != nil && cacheKey != "" {
+		c.resources.add(cacheKey, pushedResource{name: name, version: tag, mediaType: mediaType, digest: digest, size: pushed.n})
+	}
+	// re-fetch the resource to have a streamed reader available
+	dataReader, err := c.cache.FetchDataByDigest(ctx, name, digest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch resource: %w", err)
+	}
+
+	return dataReader, digest, nil
+}
+
+// Referrer is a resource of a component that describes another resource of the component, for example its SBOM.
+type Referrer struct {
+	// Name is the name of the resource.
+	Name string
+	// ArtifactType is the media type of the resource data.
+	ArtifactType string
+	// Data is the content of the resource.
+	Data []byte
+}
+
+// GetReferrers returns the resources of the component that are labelled with ReferrerSubjectLabel to describe the
+// given resource. The data of the referrers is read completely, as their digest has to be known before they are
+// pushed.
+func (c *Client) GetReferrers(
+	ctx context.Context,
+	octx ocm.Context,
+	cv *v1alpha1.ComponentVersion,
+	resource *v1alpha1.ResourceReference,
+) (_ []Referrer, err error) {
+	cd, err := component.GetComponentDescriptor(ctx, c.client, resource.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find component descriptor for reference: %w", err)
+	}
+
+	if cd == nil {
+		return nil, fmt.Errorf("component descriptor not found for reference path: %+v", resource.ReferencePath)
+	}
+
+	var refs []v1alpha1.ResourceReference
+	for _, res := range cd.Spec.Resources {
+		var subject string
+		if ok, err := res.Labels.GetValue(v1alpha1.ReferrerSubjectLabel, &subject); err != nil || !ok || subject != resource.Name {
+			continue
+		}
+
+		refs = append(refs, v1alpha1.ResourceReference{
+			ElementMeta: v1alpha1.ElementMeta{
+				Name:    res.Name,
+				Version: res.Version,
+			},
+			ReferencePath: resource.ReferencePath,
+		})
+	}
+
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	cva, err := c.GetComponentVersion(ctx, octx, cv, cv.Spec.Component, cv.Status.ReconciledVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get component Version: %w", err)
+	}
+
+	defer func() {
+		if cerr := cva.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	referrers := make([]Referrer, 0, len(refs))
+	for i := range refs {
+		referrer, err := c.readReferrer(cva, cd, &refs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read referrer %s of resource %s: %w", refs[i].Name, resource.Name, err)
+		}
+
+		referrers = append(referrers, referrer)
+	}
+
+	return referrers, nil
+}
+
+// readReferrer reads the data of the referenced resource through its access method.
+func (c *Client) readReferrer(cva ocm.ComponentVersionAccess, cd *v1alpha1.ComponentDescriptor, ref *v1alpha1.ResourceReference) (_ Referrer, err error) {
+	res, err := c.resolveResource(cva, cd, ref)
+	if err != nil {
+		return Referrer{}, err
+	}
+
+	access, err := res.AccessMethod()
+	if err != nil {
+		return Referrer{}, fmt.Errorf("failed to fetch access spec: %w", classifyError(err))
+	}
+
+	defer func() {
+		if cerr := access.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	data, err := access.Get()
+	if err != nil {
+		return Referrer{}, fmt.Errorf("failed to fetch data: %w", classifyError(err))
+	}
+
+	return Referrer{
+		Name:         ref.Name,
+		ArtifactType: access.MimeType(),
+		Data:         data,
+	}, nil
+}
+
+// GetResourceDigest resolves the resource and returns the digest GetResource would push its data with, without
+// pushing it. For an image, that is the digest of its first layer, only the manifests of the image are fetched for
+// it. A resource pushed with the passthrough compression uses the blob digest in the component descriptor if it
+// records one. Other resources are read and compressed like they would be pushed. Transformations aren't applied.
+func (c *Client) GetResourceDigest(
+	ctx context.Context,
+	octx ocm.Context,
+	cv *v1alpha1.ComponentVersion,
+	resource *v1alpha1.ResourceReference,
+	opts ...GetResourceOption,
+) (string, error) {
+	options := &getResourceOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	cd, err := component.GetComponentDescriptor(ctx, c.client, resource.ReferencePath, cv.Status.ComponentDescriptor)
+	if err != nil {
+		return "", fmt.Errorf("failed to find component descriptor for reference: %w", err)
+	}
+
+	if cd == nil {
+		return "", fmt.Errorf(
+			"component descriptor not found for reference path: %+v",
+			resource.ReferencePath,
+		)
+	}
+
+	if _, err := c.descriptorElement(cd, resource, options); err != nil {
+		return "", err
+	}
+
+	cva, err := c.GetComponentVersion(ctx, octx, cv, cv.Spec.Component, cv.Status.ReconciledVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get component Version: %w", err)
+	}
+	defer cva.Close()
+
+	res, err := c.resolveElement(cva, cd, resource, options)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.checkRegistryAllowed(res, cv); err != nil {
+		return "", err
+	}
+
+	resolved, err := c.resolveAccess(ctx, res, cva)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch reader for resource: %w", err)
+	}
+
+	if resolved.Image != "" {
+		return c.imageLayerDigest(ctx, octx, resolved.Image, options)
+	}
+
+	reader := resolved.Reader
+	defer reader.Close()
+
+	// Passthrough pushes the blob as it is, whose digest the component descriptor may already record.
+	if options.compression == v1alpha1.CompressionPassthrough && options.extractPath == "" {
+		if digest := descriptorDigest(res, blob.GenericBlobDigestV1); digest != "" {
+			return digest, nil
+		}
+	}
+
+	var source io.Reader = reader
+	verifier, err := resourceDigestVerifier(res)
+	if err != nil {
+		return "", err
+	}
+	if verifier != nil {
+		source = io.TeeReader(reader, verifier)
+	}
+
+	// The data is read like GetResource reads it before it is pushed.
+	data := source
+	if options.compression != v1alpha1.CompressionPassthrough || options.extractPath != "" {
+		decompressedReader, _, err := compression.AutoDecompress(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to autodecompress content: %w", err)
+		}
+
+		data = decompressedReader
+		if options.extractPath != "" {
+			if data, err = extractFiles(decompressedReader, options.extractPath); err != nil {
+				return "", fmt.Errorf("failed to extract files from resource %s: %w", resource.Name, err)
+			}
+		}
+	}
+
+	digest, err := c.cache.LayerDigest(data, options.pushOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute digest of resource: %w", err)
+	}
+
+	if verifier != nil {
+		if _, err := io.Copy(io.Discard, source); err != nil {
+			return "", fmt.Errorf("failed to read resource data for verification: %w", err)
+		}
+
+		if !verifier.Verified() {
+			return "", fmt.Errorf("%w: data of resource %s does not match its digest", ErrDigestMismatch, resource.Name)
+		}
+	}
+
+	return digest, nil
+}
+
+// descriptorDigest returns the sha256 digest the component descriptor records for the resource if it is normalised
+// with the given algorithm, empty otherwise.
+func descriptorDigest(res ocm.ResourceAccess, normalisation string) string {
+	d := res.Meta().Digest
+	if d == nil || d.NormalisationAlgorithm != normalisation || d.HashAlgorithm != sha256.Algorithm {
+		return ""
+	}
+
+	return godigest.NewDigestFromEncoded(godigest.SHA256, d.Value).String()
+}
+
+// imageLayerDigest returns the digest of the first layer of the image as copyImageResource would copy it, which is
+// the digest GetResource returns for an image. Only the manifests of the image are fetched, from the registry mirror
+// of the image if it has one.
+func (c *Client) imageLayerDigest(ctx context.Context, octx ocm.Context, source string, options *getResourceOptions) (string, error) {
+	ref, err := ociname.ParseReference(source)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to parse image reference %q: %w", ErrInvalidReference, source, err)
+	}
+
+	if ref, err = c.mirrorReference(ref); err != nil {
+		return "", fmt.Errorf("failed to construct mirror reference of %q: %w", source, err)
+	}
+
+	auth, err := registryAuth(octx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get credentials for %s: %w", ref, err)
+	}
+
+	var p *ociv1.Platform
+	if options.platform != "" {
+		if p, err = ociv1.ParsePlatform(options.platform); err != nil {
+			return "", fmt.Errorf("failed to parse platform %q: %w", options.platform, ocmerrors.ErrInvalid("platform", options.platform))
+		}
+	}
+
+	var copyOpts []cache.PushOption
+	if options.compression == "" {
+		copyOpts = append(copyOpts, cache.WithGzipLayers())
+	}
+
+	digest, err := c.cache.ArtifactLayerDigest(ctx, ref.String(), auth, p, copyOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve layer digest of image %s: %w", ref, classifyError(err))
+	}
+
+	return digest, nil
+}
+
+// copyImageResource copies the image or image index of the resource to the cache, preserving its manifests,
+// config, layers and media types. If a platform is given, only the image for that platform is copied.
+// The credentials for the source registry are looked up in the OCM context.
+func (c *Client) copyImageResource(
+	ctx context.Context,
+	octx ocm.Context,
+	res ocm.ResourceAccess,
+	source, name, version string,
+	options *getResourceOptions,
+) (io.ReadCloser, string, error) {
+	ref, err := ociname.ParseReference(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: failed to parse image reference %q: %w", ErrInvalidReference, source, err)
+	}
+
+	// The image is fetched from the mirror with its credentials, the original reference is what the resource records.
+	original := source
+	if ref, err = c.mirrorReference(ref); err != nil {
+		return nil, "", fmt.Errorf("failed to construct mirror reference of %q: %w", source, err)
+	}
+
+	if source = ref.String(); source != original {
+		log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("fetching image from registry mirror",
+			v1alpha1.LogKeyResourceName, res.Meta().Name, "reference", original, "mirror", source)
+	}
+
+	auth, err := registryAuth(octx, ref)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get credentials for %s: %w", source, err)
+	}
+
+	var p *ociv1.Platform
+	if options.platform != "" {
+		if p, err = ociv1.ParsePlatform(options.platform); err != nil {
+			return nil, "", fmt.Errorf("failed to parse platform %q: %w", options.platform, ocmerrors.ErrInvalid("platform", options.platform))
+		}
+	}
+
+	// A tag may be moved while the image is copied, so the image is copied by the digest the tag resolves to.
+	if _, ok := ref.(ociname.Digest); !ok {
+		digest, err := c.cache.ResolveArtifact(ctx, source, auth)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to resolve image reference %s: %w", source, classifyError(err))
+		}
+
+		log.FromContext(ctx).Info("image reference of resource has no digest, copying the image the tag resolved to",
+			v1alpha1.LogKeyResourceName, res.Meta().Name, "reference", original, v1alpha1.LogKeySourceDigest, digest)
 
-var _ Contract = &Client{}
+		if options.unpinned != nil {
+			*options.unpinned = UnpinnedReference{Reference: original, Digest: digest}
+		}
 
-// NewClient creates a new fetcher Client using the provided k8s client.
-func NewClient(client client.Client, cache cache.Cache) *Client {
-	return &Client{
-		client: client,
-		cache:  cache,
+		source = ref.Context().Digest(digest).String()
 	}
-}
 
-func (c *Client) CreateAuthenticatedOCMContext(ctx context.Context, obj *v1alpha1.ComponentVersion) (ocm.Context, error) {
-	octx := ocm.New()
+	if limit := c.sizeLimit(res.Meta().Name, options); limit.enabled() {
+		size, err := c.cache.ArtifactSize(ctx, source, auth, p)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get size of image %s: %w", source, classifyError(err))
+		}
 
-	if obj.Spec.ServiceAccountName != "" {
-		if err := c.configureServiceAccountAccess(ctx, octx, obj.Spec.ServiceAccountName, obj.Namespace); err != nil {
-			return nil, fmt.Errorf("failed to configure service account access: %w", err)
+		if err := limit.check(size); err != nil {
+			return nil, "", err
 		}
 	}
 
-	if err := c.configureAccessCredentials(ctx, octx, obj.Spec.Repository, obj.Namespace); err != nil {
-		return nil, fmt.Errorf("failed to configure credentials for source: %w", err)
+	result := options.pushResult
+	if result == nil {
+		result = &cache.PushResult{}
 	}
 
-	return octx, nil
-}
+	copyOpts := []cache.PushOption{cache.WithPushResult(result)}
+	// Snapshots are gzip-compressed by default, zstd layers that consumers may not support are recompressed.
+	if options.compression == "" {
+		copyOpts = append(copyOpts, cache.WithGzipLayers())
+	}
 
-// configureAccessCredentials configures access credentials if needed for a source/destination repository.
-func (c *Client) configureAccessCredentials(
-	ctx context.Context,
-	ocmCtx ocm.Context,
-	repository v1alpha1.Repository,
-	namespace string,
-) error {
-	// If there are no credentials, this call is a no-op.
-	if repository.SecretRef == nil {
-		return nil
+	if options.untagged {
+		copyOpts = append(copyOpts, cache.WithoutTag())
 	}
 
-	logger := log.FromContext(ctx)
+	digest, err := c.cache.CopyArtifact(ctx, source, name, version, auth, p, copyOpts...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to cache image: %w", classifyError(err))
+	}
 
-	if err := ConfigureCredentials(ctx, ocmCtx, c.client, repository.URL, repository.SecretRef.Name, namespace); err != nil {
-		logger.V(v1alpha1.LevelDebug).Error(err, "failed to find credentials")
+	// A recompressed image has a digest of its own, the source it was copied from by digest is verified instead.
+	copied := digest
+	if result.Transcoded {
+		if ref, err := ociname.NewDigest(source); err == nil {
+			copied = ref.DigestStr()
+		}
 
-		// we don't ignore not found errors
-		return fmt.Errorf("failed to configure credentials for component: %w", err)
+		log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("recompressed zstd layers of image with gzip",
+			v1alpha1.LogKeyResourceName, res.Meta().Name, v1alpha1.LogKeySourceDigest, copied)
 	}
 
-	logger.V(v1alpha1.LevelDebug).Info("credentials configured")
+	// The digest of the resource covers the complete image index, it can't be compared to a single platform.
+	if d := res.Meta().Digest; p == nil && d != nil && d.NormalisationAlgorithm == artifact.OciArtifactDigestV1 &&
+		d.HashAlgorithm == sha256.Algorithm && copied != godigest.NewDigestFromEncoded(godigest.SHA256, d.Value).String() {
+		err := fmt.Errorf("%w: image of resource %s does not match digest %s", ErrDigestMismatch, res.Meta().Name, d.Value)
+		if derr := c.cache.DeleteData(ctx, name, options.pushedReference(version)); derr != nil {
+			err = errors.Join(err, derr)
+		}
 
-	return nil
+		return nil, "", err
+	}
+
+	return c.cache.FetchDataByIdentity(ctx, name, options.pushedReference(version))
 }
 
-func (c *Client) configureServiceAccountAccess(
-	ctx context.Context,
-	octx ocm.Context,
-	serviceAccountName, namespace string,
-) error {
-	logger := log.FromContext(ctx)
+// registryAuth returns the credentials the OCM context has for the repository of the reference, anonymous access if
+// it has none. The credentials are looked up again whenever the token of the registry is refreshed, so credentials
+// that expire, such as the passwords of cloud registries, are replaced during long copies.
+func registryAuth(octx ocm.Context, ref ociname.Reference) (authn.Authenticator, error) {
+	auth := &credentialsAuthenticator{
+		octx:       octx,
+		registry:   ref.Context().RegistryStr(),
+		repository: ref.Context().RepositoryStr(),
+	}
 
-	logger.V(v1alpha1.LevelDebug).Info("configuring service account credentials")
-	account := &corev1.ServiceAccount{}
-	if err := c.client.Get(ctx, types.NamespacedName{
-		Name:      serviceAccountName,
-		Namespace: namespace,
-	}, account); err != nil {
-		return fmt.Errorf("failed to fetch service account: %w", err)
+	creds, err := auth.credentials()
+	if err != nil {
+		return nil, err
 	}
 
-	logger.V(v1alpha1.LevelDebug).Info("got service account", "name", account.GetName())
+	if creds == nil {
+		return authn.Anonymous, nil
+	}
 
-	for _, imagePullSecret := range account.ImagePullSecrets {
-		secret := &corev1.Secret{}
+	return auth, nil
+}
 
-		if err := c.client.Get(ctx, types.NamespacedName{
-			Name:      imagePullSecret.Name,
-			Namespace: namespace,
-		}, secret); err != nil {
-			return fmt.Errorf("failed to get image pull secret: %w", err)
-		}
+// credentialsAuthenticator is an authn.Authenticator that returns the credentials the OCM context has for a
+// repository at the time they are requested.
+type credentialsAuthenticator struct {
+	octx       ocm.Context
+	registry   string
+	repository string
+}
 
-		data, ok := secret.Data[dockerConfigKey]
-		if !ok {
-			return fmt.Errorf("failed to find .dockerconfigjson in secret %s", secret.Name)
-		}
+var _ authn.Authenticator = (*credentialsAuthenticator)(nil)
 
-		repository := dockerconfig.NewRepositorySpecForConfig(data, true)
+// Authorization implements authn.Authenticator.
+func (a *credentialsAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	creds, err := a.credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for %s/%s: %w", a.registry, a.repository, err)
+	}
 
-		if _, err := octx.CredentialsContext().RepositoryForSpec(repository); err != nil {
-			return fmt.Errorf("failed to configure credentials for repository: %w", err)
-		}
+	if creds == nil {
+		return &authn.AuthConfig{}, nil
 	}
 
-	return nil
+	return &authn.AuthConfig{
+		Username:      creds.GetProperty(credentials.ATTR_USERNAME),
+		Password:      creds.GetProperty(credentials.ATTR_PASSWORD),
+		IdentityToken: creds.GetProperty(credentials.ATTR_IDENTITY_TOKEN),
+	}, nil
 }
 
-// GetResource returns a reader for the resource data. It is the responsibility of the caller to close the reader.
-func (c *Client) GetResource(
+func (a *credentialsAuthenticator) credentials() (credentials.Credentials, error) {
+	return ociidentity.GetCredentials(a.octx, a.registry, a.repository)
+}
+
+// adoptCachedData returns whether the data stored at name:version can be used for a new snapshot, which is the case
+// if it was pushed by the controller. Data without any annotations in the in-cluster registry was pushed by a version
+// of the controller that didn't mark its data yet, nothing else pushes to the repositories named after the identity
+// of a snapshot there. Data pushed by other tools is reported as ErrSnapshotConflict, unless overwrite is set, in
+// which case false is returned so the data is replaced.
+func (c *Client) adoptCachedData(ctx context.Context, name, version string, options *getResourceOptions) (bool, error) {
+	annotations, err := c.cache.ManifestAnnotations(ctx, name, version)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existing snapshot data: %w", err)
+	}
+
+	if annotations[cache.ManagedByAnnotation] == cache.ManagedByValue {
+		return true, nil
+	}
+
+	if len(annotations) == 0 && options.registry == "" {
+		log.FromContext(ctx).V(v1alpha1.LevelDebug).Info("adopting existing data without annotations", v1alpha1.LogKeySnapshotRef, name+":"+version)
+
+		return true, nil
+	}
+
+	if !options.overwrite {
+		return false, fmt.Errorf("%w: %s:%s already exists and wasn't pushed by the controller, set forceOverwrite to overwrite it",
+			ErrSnapshotConflict, name, version)
+	}
+
+	log.FromContext(ctx).Info("overwriting existing data that wasn't pushed by the controller", v1alpha1.LogKeySnapshotRef, name+":"+version)
+
+	return false, nil
+}
+
+// isImageResource returns whether the resource resolves to an image, which is copied as it is instead of being
+// stored as a single layer snapshot. Its access is resolved like it is to fetch the resource, a reader it returns is
+// closed without being read.
+func (c *Client) isImageResource(
 	ctx context.Context,
 	octx ocm.Context,
 	cv *v1alpha1.ComponentVersion,
+	cd *v1alpha1.ComponentDescriptor,
 	resource *v1alpha1.ResourceReference,
-) (io.ReadCloser, string, error) {
-	logger := log.FromContext(ctx).WithName("ocm")
-	version := "latest"
-	if resource.ElementMeta.Version != "" {
-		version = resource.ElementMeta.Version
+	options *getResourceOptions,
+) (_ bool, err error) {
+	cva, err := c.GetComponentVersion(ctx, octx, cv, cv.Spec.Component, cv.Status.ReconciledVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to get component Version: %w", err)
 	}
 
-	cd, err := component.GetComponentDescriptor(ctx, c.client, resource.ReferencePath, cv.Status.ComponentDescriptor)
+	defer func() {
+		if cerr := cva.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	res, err := c.resolveElement(cva, cd, resource, options)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to find component descriptor for reference: %w", err)
+		return false, err
 	}
 
-	if cd == nil {
-		return nil, "", fmt.Errorf(
-			"component descriptor not found for reference path: %+v",
-			resource.ReferencePath,
-		)
+	resolved, err := c.resolveAccess(ctx, res, cva)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch reader for resource: %w", err)
 	}
 
-	identity := ocmmetav1.Identity{
-		v1alpha1.ComponentNameKey:    cd.Name,
-		v1alpha1.ComponentVersionKey: cd.Spec.Version,
-		v1alpha1.ResourceNameKey:     resource.ElementMeta.Name,
-		v1alpha1.ResourceVersionKey:  version,
+	if resolved.Reader != nil {
+		if err := resolved.Reader.Close(); err != nil {
+			return false, fmt.Errorf("failed to close reader of resource: %w", err)
+		}
 	}
 
-	// Add extra identity.
-	for k, v := range resource.ElementMeta.ExtraIdentity {
-		identity[k] = v
+	return resolved.Image != "", nil
+}
+
+// isImageAccess returns whether the access of a resource in a component descriptor is an OCI artifact with an
+// image reference.
+func isImageAccess(access *ocmruntime.UnstructuredTypedObject) bool {
+	if access == nil {
+		return false
 	}
-	name, err := ConstructRepositoryName(identity)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to construct name: %w", err)
+
+	kind, _ := ocmruntime.KindVersion(access.GetType())
+
+	return kind == ociartifact.Type || kind == ociartifact.LegacyType
+}
+
+// unpinnedImageReference returns the image reference of the access of a resource in a component descriptor if the
+// resource is an OCI artifact referenced by tag without a digest.
+func unpinnedImageReference(access *ocmruntime.UnstructuredTypedObject) string {
+	if !isImageAccess(access) {
+		return ""
 	}
 
-	cached, err := c.cache.IsCached(ctx, name, version)
+	reference, _ := access.Object["imageReference"].(string)
+	ref, err := ociname.ParseReference(reference)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to check cache: %w", err)
+		return ""
 	}
 
-	if cached {
-		return c.cache.FetchDataByIdentity(ctx, name, version)
+	if _, ok := ref.(ociname.Digest); ok {
+		return ""
 	}
-	logger.V(v1alpha1.LevelDebug).
-		Info("object with name is NOT cached, proceeding to fetch", "resource", resource, "name", name, "Version", version)
 
-	cva, err := c.GetComponentVersion(ctx, octx, cv, cv.Spec.Component, cv.Status.ReconciledVersion)
+	return reference
+}
+
+// imageReference returns the image reference of the resource if it is accessed as an OCI artifact.
+func imageReference(res ocm.ResourceAccess) (string, error) {
+	spec, err := res.Access()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get component Version: %w", err)
+		return "", err
 	}
 
-	defer func() {
-		if cerr := cva.Close(); cerr != nil {
-			err = errors.Join(err, cerr)
-		}
-	}()
+	if x, ok := spec.(*ociartifact.AccessSpec); ok {
+		return x.ImageReference, nil
+	}
+
+	return "", nil
+}
 
+// resolveResource resolves the referenced resource in the component version, following the reference path.
+func (c *Client) resolveResource(
+	cva ocm.ComponentVersionAccess,
+	cd *v1alpha1.ComponentDescriptor,
+	resource *v1alpha1.ResourceReference,
+) (ocm.ResourceAccess, error) {
 	var identities []ocmmetav1.Identity
 	identities = append(identities, resource.ReferencePath...)
 
+	resourceID, err := c.resourceIdentity(cd, resource)
+	if err != nil {
+		return nil, err
+	}
+
 	res, _, err := utils.ResolveResourceReference(
 		cva,
-		ocmmetav1.NewNestedResourceRef(ocmmetav1.NewIdentity(resource.Name), identities),
+		ocmmetav1.NewNestedResourceRef(resourceID, identities),
 		cva.Repository(),
 	)
 	if err != nil {
-		return nil, "", fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to resolve reference path to resource: %s %w",
 			resource.Name,
 			err,
 		)
 	}
 
-	reader, mediaType, err := c.fetchResourceReader(res, cva)
+	return res, nil
+}
+
+// resourceIdentity constructs the OCM identity of the referenced resource in the given component descriptor.
+// If the reference doesn't define a version, the highest semver version of the matching resources is selected.
+// Like OCM, the identity consists of the name and extra identity of the resource and only includes the version if
+// another resource has the same name and extra identity.
+func (c *Client) resourceIdentity(cd *v1alpha1.ComponentDescriptor, resource *v1alpha1.ResourceReference) (ocmmetav1.Identity, error) {
+	res, err := c.descriptorResource(cd, resource)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch reader for resource: %w", err)
+		return nil, err
 	}
 
-	defer func() {
-		if cerr := reader.Close(); cerr != nil {
-			err = errors.Join(err, cerr)
+	identity := res.ExtraIdentity.Copy()
+	if identity == nil {
+		identity = ocmmetav1.Identity{}
+	}
+	identity[ocmmetav1.SystemIdentityName] = res.Name
+
+	for _, i := range c.resourcesNamed(cd, res.Name) {
+		other := &cd.Spec.Resources[i]
+		if other != res && other.ExtraIdentity.Equals(res.ExtraIdentity) {
+			identity[ocmmetav1.SystemIdentityVersion] = res.Version
+
+			break
 		}
-	}()
+	}
 
-	decompressedReader, decompressed, err := compression.AutoDecompress(reader)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to autodecompress content: %w", err)
+	return identity, nil
+}
+
+// descriptorResource returns the resource of the component descriptor selected by the reference. Resources are
+// matched by their name and, if the reference sets one, by their extra identity. If the reference doesn't define a
+// version, the resource with the highest semver version is selected. Several resources matching the reference,
+// for example variants of a resource for different platforms, are reported as ErrAmbiguousResource.
+func (c *Client) descriptorResource(cd *v1alpha1.ComponentDescriptor, resource *v1alpha1.ResourceReference) (*v3alpha1.Resource, error) {
+	candidates := c.matchingResources(cd, resource)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no resource with name %s%s in component descriptor %s",
+			ErrResourceNotFound, resource.Name, extraIdentityDescription(resource.ExtraIdentity), cd.Name)
+	}
+
+	version := resource.Version
+	if version == "" {
+		versions := make([]string, 0, len(candidates))
+		for _, r := range candidates {
+			versions = append(versions, r.Version)
+		}
+
+		version = highestVersion(versions)
 	}
-	if decompressed {
-		logger.V(v1alpha1.LevelDebug).Info("resource data was automatically decompressed")
+
+	var selected []*v3alpha1.Resource
+	for _, r := range candidates {
+		if r.Version == version {
+			selected = append(selected, r)
+		}
 	}
 
-	// We need to push the media type... And construct the right layers I guess.
-	digest, err := c.cache.PushData(ctx, decompressedReader, mediaType, name, version)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to cache blob: %w", err)
+	switch len(selected) {
+	case 0:
+		return nil, fmt.Errorf("%w: no resource with name %s and version %s%s in component descriptor %s",
+			ErrResourceNotFound, resource.Name, version, extraIdentityDescription(resource.ExtraIdentity), cd.Name)
+	case 1:
+		return selected[0], nil
 	}
 
-	logger.V(v1alpha1.LevelDebug).Info("pushed data with digest", "digest", digest)
-	// re-fetch the resource to have a streamed reader available
-	dataReader, err := c.cache.FetchDataByDigest(ctx, name, digest)
+	identities := make([]string, 0, len(selected))
+	for _, r := range selected {
+		identities = append(identities, "{"+r.ExtraIdentity.String()+"}")
+	}
+
+	return nil, fmt.Errorf("%w: %d resources with name %s and version %s in component descriptor %s match, set the "+
+		"extraIdentity of the reference to select one of %s", ErrAmbiguousResource, len(selected), resource.Name, version,
+		cd.Name, strings.Join(identities, ", "))
+}
+
+// matchingResources returns the resources of the component descriptor with the name of the reference whose extra
+// identity contains the extra identity of the reference. Resources are looked up by name in the resource index of
+// the descriptor.
+func (c *Client) matchingResources(cd *v1alpha1.ComponentDescriptor, resource *v1alpha1.ResourceReference) []*v3alpha1.Resource {
+	var matching []*v3alpha1.Resource
+	for _, i := range c.resourcesNamed(cd, resource.Name) {
+		if r := &cd.Spec.Resources[i]; containsIdentity(r.ExtraIdentity, resource.ExtraIdentity) {
+			matching = append(matching, r)
+		}
+	}
+
+	return matching
+}
+
+// containsIdentity returns whether identity contains all attributes of subset.
+func containsIdentity(identity, subset ocmmetav1.Identity) bool {
+	for k, v := range subset {
+		if value, ok := identity[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// extraIdentityDescription describes the extra identity of a reference in error messages, empty if it has none.
+func extraIdentityDescription(identity ocmmetav1.Identity) string {
+	if len(identity) == 0 {
+		return ""
+	}
+
+	return " and extra identity {" + identity.String() + "}"
+}
+
+// highestVersion returns the highest semver version of the given list. Versions that aren't valid semver are
+// ignored. If none of them are, the first version is returned.
+func highestVersion(versions []string) string {
+	var latest *semver.Version
+
+	result := versions[0]
+	for _, v := range versions {
+		parsed, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+
+		if latest == nil || parsed.GreaterThan(latest) {
+			latest = parsed
+			result = v
+		}
+	}
+
+	return result
+}
+
+// resourceDigestVerifier returns a verifier for the digest of the resource if it is a plain sha256 blob digest.
+// Other normalisations, like the manifest digest of an OCI artifact, can't be verified against the raw blob and
+// are ignored. Resources downloaded from a URL are also verified against the checksum of their access.
+// Returns nil if there is nothing to verify.
+func resourceDigestVerifier(res ocm.ResourceAccess) (godigest.Verifier, error) {
+	var verifiers digestVerifiers
+	if d := res.Meta().Digest; d != nil && d.NormalisationAlgorithm == blob.GenericBlobDigestV1 && d.HashAlgorithm == sha256.Algorithm {
+		verifiers = append(verifiers, godigest.NewDigestFromEncoded(godigest.SHA256, d.Value).Verifier())
+	}
+
+	access, err := getDownloadAccess(res)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch resource: %w", err)
+		return nil, err
 	}
 
-	return dataReader, digest, nil
+	if access != nil && access.Checksum != "" {
+		verifiers = append(verifiers, godigest.Digest(access.Checksum).Verifier())
+	}
+
+	if len(verifiers) == 0 {
+		return nil, nil
+	}
+
+	return verifiers, nil
+}
+
+// digestVerifiers verifies the data written to it against all of its verifiers.
+type digestVerifiers []godigest.Verifier
+
+// Write implements io.Writer.
+func (v digestVerifiers) Write(p []byte) (int, error) {
+	for _, verifier := range v {
+		if _, err := verifier.Write(p); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Verified returns true if the data matches all digests.
+func (v digestVerifiers) Verified() bool {
+	for _, verifier := range v {
+		if !verifier.Verified() {
+			return false
+		}
+	}
+
+	return true
 }
 
 // GetComponentVersion returns a component Version. It's the caller's responsibility to clean it up and close the component Version once done with it.
 func (c *Client) GetComponentVersion(
-	_ context.Context,
+	ctx context.Context,
 	octx ocm.Context,
 	obj *v1alpha1.ComponentVersion,
 	name, version string,
-) (ocm.ComponentVersionAccess, error) {
+) (_ ocm.ComponentVersionAccess, err error) {
+	_, span := tracing.Start(ctx, "ocm.GetComponentVersion",
+		tracing.ComponentKey.String(name),
+		tracing.ComponentVersionKey.String(version),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	repoSpec := ocireg.NewRepositorySpec(obj.Spec.Repository.URL, nil)
 	repo, err := octx.RepositoryForSpec(repoSpec)
 	if err != nil {
@@ -291,7 +1603,11 @@ func (c *Client) GetComponentVersion(
 
 	cv, err := repo.LookupComponentVersion(name, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to look up component Version: %w", err)
+		if ocmerrors.IsErrNotFound(err) {
+			err = fmt.Errorf("%w: %w", ErrComponentNotFound, err)
+		}
+
+		return nil, fmt.Errorf("failed to look up component Version: %w", classifyError(err))
 	}
 
 	return cv, nil
@@ -494,76 +1810,14 @@ func (c *Client) ListComponentVersions(
 	return result, nil
 }
 
-// We add this decision because OCM is storing the Helm artifact as an ociArtifact at the
-// time of this writing. This means, when fetching the resource via the normal route
-// it will return an OCI blob instead of the actual helm chart content.
-// Because of that, we need to create our own downloader when we are dealing with
-// helm charts.
-func (c *Client) fetchResourceReader(res ocm.ResourceAccess, cva ocm.ComponentVersionAccess) (_ io.ReadCloser, _ string, err error) {
-	if res.Meta().Type == "helmChart" {
-		return c.fetchHelmChartResource(res, cva, err)
-	}
-
-	// use the plain resource reader
-	access, err := res.AccessMethod()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch access spec: %w", err)
-	}
-
-	reader, err := access.Reader()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch reader: %w", err)
-	}
-
-	// Ignore the media type as we set it to a default in OCI package
-	return reader, "", nil
-}
-
-func (c *Client) fetchHelmChartResource(res ocm.ResourceAccess, cva ocm.ComponentVersionAccess, err error) (io.ReadCloser, string, error) {
-	vf := vfs.New(memoryfs.New())
-	defer func() {
-		if rerr := vf.RemoveAll("downloaded"); rerr != nil {
-			// ignore not exist errors that vfs implementation can throw sometimes.
-			if !errors.Is(rerr, os.ErrNotExist) {
-				err = errors.Join(err, rerr)
-			}
-		}
-	}()
-
-	d := download.For(cva.GetContext())
-	// Note that helm downloader does _NOT_ return the path element of the Downloader's output.
-	_, chart, err := d.Download(nil, res, "downloaded", vf)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to download helm chart content: %w", err)
-	}
-
-	content, rerr := vf.ReadFile(chart)
-	if rerr != nil {
-		return nil, "", fmt.Errorf("failed to find the downloaded file: %w", rerr)
-	}
-	reader := io.NopCloser(bytes.NewBuffer(content))
-
-	return reader, registry.ChartLayerMediaType, nil
-}
-
-// ConstructRepositoryName hashes the name and passes it back.
-func ConstructRepositoryName(identity ocmmetav1.Identity) (string, error) {
-	repositoryName, err := HashIdentity(identity)
-	if err != nil {
-		return "", fmt.Errorf("failed to create hash for identity: %w", err)
-	}
-
-	// Append the name of the helm chart to the repository. That's because flux helm resolver
-	// doesn't look at the root of an OCI repository, it appends the name of the chart at the end.
-	if v, ok := identity[v1alpha1.ResourceHelmChartNameKey]; ok {
-		repositoryName = fmt.Sprintf("%s/%s", repositoryName, v)
+// HashIdentity returns the string hash of an ocm identity. The namespace of the snapshot is ignored, it is only
+// recorded in the identity to render repository name templates.
+func HashIdentity(id ocmmetav1.Identity) (string, error) {
+	if _, ok := id[v1alpha1.SnapshotNamespaceKey]; ok {
+		id = maps.Clone(id)
+		delete(id, v1alpha1.SnapshotNamespaceKey)
 	}
 
-	return repositoryName, nil
-}
-
-// HashIdentity returns the string hash of an ocm identity.
-func HashIdentity(id ocmmetav1.Identity) (string, error) {
 	hash, err := hashstructure.Hash(id, hashstructure.FormatV2, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash identity: %w", err)
@@ -5,10 +5,15 @@
 package ocm
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,15 +22,27 @@ import (
 	"github.com/containers/image/v5/pkg/compression"
 	_ "github.com/distribution/distribution/v3/registry/storage/driver/inmemory"
 	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-containerregistry/pkg/authn"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	godigest "github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/open-component-model/ocm/pkg/contexts/credentials/cpi"
 	"github.com/open-component-model/ocm/pkg/contexts/oci/identity"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	ocmerrors "github.com/open-component-model/ocm/pkg/errors"
+	ocmruntime "github.com/open-component-model/ocm/pkg/runtime"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	ocmcache "github.com/open-component-model/ocm-controller/pkg/cache"
 	"github.com/open-component-model/ocm-controller/pkg/cache/fakes"
 	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
 )
@@ -54,6 +71,1298 @@ func TestClient_GetResource(t *testing.T) {
 
 	_ = octx.AddComponent(comp)
 
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    resource,
+							Version: resourceVersion,
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
+	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+
+	ocmClient := NewClient(fakeKubeClient, cache)
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	resourceRef := &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "remote-controller-demo",
+			Version: "v0.0.1",
+		},
+	}
+
+	reader, digest, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef)
+	assert.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "mockdata", string(content))
+	assert.Equal(t, "sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", digest)
+
+	// verify that the cache has been called with the right resource data to cache.
+	args := cache.PushDataCallingArgumentsOnCall(0)
+
+	assert.Equal(t, data, args.Content)
+
+	assert.Equal(t, "sha-2705577397727487661", args.Name, "pushed name did not match constructed name from identity of the resource")
+	assert.Equal(t, resourceRef.Version, args.Version)
+}
+
+func TestClient_GetSource(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	name := "remote-controller-demo"
+
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    component,
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:      name,
+		Version:   "v0.0.1",
+		Data:      []byte("resource data"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+	})
+	comp.Sources = append(comp.Sources, &fakeocm.Source{Resource: fakeocm.Resource{
+		Name:      name,
+		Version:   "v0.0.1",
+		Data:      []byte("source data"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "git",
+	}})
+
+	_ = octx.AddComponent(comp)
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{ElementMeta: v3alpha1.ElementMeta{Name: name, Version: "v0.0.1"}},
+				},
+				Sources: []v3alpha1.Source{
+					{SourceMeta: v3alpha1.SourceMeta{ElementMeta: v3alpha1.ElementMeta{Name: name, Version: "v0.0.1"}, Type: "git"}},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
+	cache.FetchDataByDigestReturnsOnCall(1, io.NopCloser(strings.NewReader("mockdata")), nil)
+	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+
+	ocmClient := NewClient(fakeKubeClient, cache)
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	ref := &v1alpha1.ResourceReference{ElementMeta: v1alpha1.ElementMeta{Name: name}}
+
+	reader, _, err := ocmClient.GetResource(context.Background(), octx, cv, ref, WithSource())
+	require.NoError(t, err)
+	reader.Close()
+
+	reader, _, err = ocmClient.GetResource(context.Background(), octx, cv, ref)
+	require.NoError(t, err)
+	reader.Close()
+
+	source := cache.PushDataCallingArgumentsOnCall(0)
+	resource := cache.PushDataCallingArgumentsOnCall(1)
+	assert.Equal(t, "source data", source.Content)
+	assert.Equal(t, "resource data", resource.Content)
+	assert.NotEqual(t, resource.Name, source.Name, "the source must not share the data of the resource with the same name")
+
+	t.Log("failing for a source that isn't part of the component")
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{Name: "missing"},
+	}, WithSource())
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+}
+
+func TestClient_GetResourceFromResourceCache(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+
+	octx := fakeocm.NewFakeOCMContext()
+	for _, version := range []string{"v0.0.1", "v0.0.2"} {
+		comp := &fakeocm.Component{
+			Name:    component,
+			Version: version,
+		}
+		comp.Resources = append(comp.Resources, &fakeocm.Resource{
+			Name:      resource,
+			Version:   resourceVersion,
+			Data:      []byte("testdata"),
+			Component: comp,
+			Kind:      "localBlob",
+			Type:      "ociBlob",
+		})
+		_ = octx.AddComponent(comp)
+	}
+
+	var objects []client.Object
+	var cvs []*v1alpha1.ComponentVersion
+	for _, version := range []string{"v0.0.1", "v0.0.2"} {
+		cd := &v1alpha1.ComponentDescriptor{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "github.com-skarlso-ocm-demo-index-" + version,
+			},
+			Spec: v1alpha1.ComponentDescriptorSpec{
+				ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+					Resources: []v3alpha1.Resource{
+						{
+							ElementMeta: v3alpha1.ElementMeta{
+								Name:    resource,
+								Version: resourceVersion,
+							},
+							Digest: &ocmmetav1.DigestSpec{
+								HashAlgorithm:          "SHA-256",
+								NormalisationAlgorithm: "genericBlobDigest/v1",
+								Value:                  "810ff2fb242a5dee4220f2cb0e6a519891fb67f2f828a6cab4ef8894633b1f50",
+							},
+						},
+					},
+				},
+				Version: version,
+			},
+		}
+		objects = append(objects, cd)
+		cvs = append(cvs, &v1alpha1.ComponentVersion{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-name",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ComponentVersionSpec{
+				Component: component,
+				Version: v1alpha1.Version{
+					Semver: version,
+				},
+			},
+			Status: v1alpha1.ComponentVersionStatus{
+				ReconciledVersion: version,
+				ComponentDescriptor: v1alpha1.Reference{
+					Name:    component,
+					Version: version,
+					ComponentDescriptorRef: meta.NamespacedObjectReference{
+						Name:      cd.Name,
+						Namespace: cd.Namespace,
+					},
+				},
+			},
+		})
+	}
+
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+	cache.FetchDataByDigestReturnsOnCall(0, io.NopCloser(strings.NewReader("testdata")), nil)
+	cache.FetchDataByDigestReturnsOnCall(1, io.NopCloser(strings.NewReader("previously pushed")), nil)
+	cache.FetchDataByDigestReturnsOnCall(2, io.NopCloser(strings.NewReader("testdata")), nil)
+
+	ocmClient := NewClient(env.FakeKubeClient(WithObjects(objects...)), cache, WithResourceCacheSize(10))
+	resourceRef := &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    resource,
+			Version: resourceVersion,
+		},
+	}
+
+	t.Log("fetching the resource of the first component version from upstream")
+	_, _, err := ocmClient.GetResource(context.Background(), octx, cvs[0], resourceRef)
+	require.NoError(t, err)
+	first := cache.PushDataCallingArgumentsOnCall(0)
+
+	t.Log("copying the unchanged resource of the second component version from the in-cluster registry")
+	reader, _, err := ocmClient.GetResource(context.Background(), octx, cvs[1], resourceRef)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "testdata", string(content))
+
+	assert.Equal(t, []any{first.Name, "sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38"}, cache.FetchDataByDigestCallingArgumentsOnCall(1))
+	second := cache.PushDataCallingArgumentsOnCall(1)
+	assert.NotEqual(t, first.Name, second.Name)
+	assert.Equal(t, "previously pushed", second.Content)
+
+	t.Log("fetching the resource from upstream if the previously pushed data exceeds the size limit")
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cvs[1], resourceRef, WithMaxSize(4))
+	assert.ErrorIs(t, err, ErrResourceTooLarge)
+	assert.ErrorContains(t, err, "failed to cache blob", "the resource should have been fetched from upstream")
+
+	t.Log("checking data cached for the identity against the size limit")
+	cached := &fakes.FakeCache{}
+	cached.IsCachedReturns(true, nil)
+	cached.DataSizeReturns(2048, nil)
+	ocmClient = NewClient(env.FakeKubeClient(WithObjects(objects...)), cached)
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cvs[0], resourceRef, WithMaxSize(1024))
+	assert.ErrorIs(t, err, ErrResourceTooLarge)
+	assert.True(t, cached.FetchDataByIdentityWasNotCalled())
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cvs[0], resourceRef, WithMaxSize(2048))
+	require.NoError(t, err)
+	assert.False(t, cached.FetchDataByIdentityWasNotCalled())
+}
+
+func TestClient_GetResourceExtract(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected map[string]string
+		err      error
+	}{
+		{
+			name: "matching files are extracted",
+			path: "manifests/*.yaml",
+			expected: map[string]string{
+				"manifests/deployment.yaml": "kind: Deployment",
+				"manifests/service.yaml":    "kind: Service",
+			},
+		},
+		{
+			name: "no matching files",
+			path: "charts/*.tgz",
+			err:  ErrNoFilesMatched,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createGzipTar(t, map[string]string{
+				"manifests/deployment.yaml": "kind: Deployment",
+				"manifests/service.yaml":    "kind: Service",
+				"README.md":                 "readme",
+			})
+
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{
+				Name:    component,
+				Version: "v0.0.1",
+			}
+			comp.Resources = append(comp.Resources, &fakeocm.Resource{
+				Name:      resource,
+				Version:   resourceVersion,
+				Data:      data,
+				Component: comp,
+				Kind:      "localBlob",
+				Type:      "ociBlob",
+			})
+			_ = octx.AddComponent(comp)
+
+			cd := &v1alpha1.ComponentDescriptor{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+				},
+				Spec: v1alpha1.ComponentDescriptorSpec{
+					ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+						Resources: []v3alpha1.Resource{
+							{
+								ElementMeta: v3alpha1.ElementMeta{
+									Name:    resource,
+									Version: resourceVersion,
+								},
+							},
+						},
+					},
+					Version: "v0.0.1",
+				},
+			}
+
+			cache := &fakes.FakeCache{}
+			cache.IsCachedReturns(false, nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache)
+
+			cv := &v1alpha1.ComponentVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-name",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ComponentVersionSpec{
+					Component: component,
+					Version: v1alpha1.Version{
+						Semver: "v0.0.1",
+					},
+				},
+				Status: v1alpha1.ComponentVersionStatus{
+					ReconciledVersion: "v0.0.1",
+					ComponentDescriptor: v1alpha1.Reference{
+						Name:    component,
+						Version: "v0.0.1",
+						ComponentDescriptorRef: meta.NamespacedObjectReference{
+							Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+							Namespace: "default",
+						},
+					},
+				},
+			}
+			resourceRef := &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    resource,
+					Version: resourceVersion,
+				},
+			}
+
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithExtractPath(tt.path))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.True(t, cache.PushDataWasNotCalled())
+
+				return
+			}
+			require.NoError(t, err)
+
+			args := cache.PushDataCallingArgumentsOnCall(0)
+			assert.Equal(t, tt.expected, readTar(t, []byte(args.Content)))
+		})
+	}
+}
+
+func TestClient_GetResourceCompression(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write([]byte("testdata"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	testCases := []struct {
+		name         string
+		compression  string
+		content      string
+		uncompressed bool
+	}{
+		{
+			name:        "gzip",
+			compression: v1alpha1.CompressionGzip,
+			content:     "testdata",
+		},
+		{
+			name:         "none",
+			compression:  v1alpha1.CompressionNone,
+			content:      "testdata",
+			uncompressed: true,
+		},
+		{
+			name:         "passthrough",
+			compression:  v1alpha1.CompressionPassthrough,
+			content:      compressed.String(),
+			uncompressed: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{
+				Name:    component,
+				Version: "v0.0.1",
+			}
+			comp.Resources = append(comp.Resources, &fakeocm.Resource{
+				Name:      resource,
+				Version:   resourceVersion,
+				Data:      compressed.Bytes(),
+				Component: comp,
+				Kind:      "localBlob",
+				Type:      "ociBlob",
+			})
+			require.NoError(t, octx.AddComponent(comp))
+
+			cd := &v1alpha1.ComponentDescriptor{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+				},
+				Spec: v1alpha1.ComponentDescriptorSpec{
+					ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+						Resources: []v3alpha1.Resource{
+							{
+								ElementMeta: v3alpha1.ElementMeta{
+									Name:    resource,
+									Version: resourceVersion,
+								},
+							},
+						},
+					},
+					Version: "v0.0.1",
+				},
+			}
+
+			cache := &fakes.FakeCache{}
+			cache.IsCachedReturns(false, nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache)
+
+			cv := &v1alpha1.ComponentVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-name",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ComponentVersionSpec{
+					Component: component,
+					Version: v1alpha1.Version{
+						Semver: "v0.0.1",
+					},
+				},
+				Status: v1alpha1.ComponentVersionStatus{
+					ReconciledVersion: "v0.0.1",
+					ComponentDescriptor: v1alpha1.Reference{
+						Name:    component,
+						Version: "v0.0.1",
+						ComponentDescriptorRef: meta.NamespacedObjectReference{
+							Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+							Namespace: "default",
+						},
+					},
+				},
+			}
+			resourceRef := &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    resource,
+					Version: resourceVersion,
+				},
+			}
+
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithCompression(tt.compression))
+			require.NoError(t, err)
+
+			args := cache.PushDataCallingArgumentsOnCall(0)
+			assert.Equal(t, tt.content, args.Content)
+			assert.Equal(t, tt.uncompressed, args.Uncompressed)
+
+			t.Log("storing data with another compression than the default under a separate name")
+			expected := ocmmetav1.Identity{
+				v1alpha1.ComponentNameKey:    cd.Name,
+				v1alpha1.ComponentVersionKey: cd.Spec.Version,
+				v1alpha1.ResourceNameKey:     resource,
+				v1alpha1.ResourceVersionKey:  resourceVersion,
+			}
+			if tt.compression != v1alpha1.CompressionGzip {
+				expected[v1alpha1.ResourceCompressionKey] = tt.compression
+			}
+			name, err := ConstructRepositoryName(expected)
+			require.NoError(t, err)
+			assert.Equal(t, name, args.Name)
+		})
+	}
+}
+
+func TestClient_GetReferrers(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+
+	octx := fakeocm.NewFakeOCMContext()
+	comp := &fakeocm.Component{
+		Name:    component,
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources,
+		&fakeocm.Resource{
+			Name:      "image",
+			Version:   "v0.0.1",
+			Data:      []byte("image"),
+			Component: comp,
+			Kind:      "localBlob",
+			Type:      "ociImage",
+		},
+		&fakeocm.Resource{
+			Name:      "image-sbom",
+			Version:   "v0.0.1",
+			Data:      []byte(`{"spdxVersion":"SPDX-2.3"}`),
+			Component: comp,
+			Kind:      "localBlob",
+			Type:      "application/spdx+json",
+		},
+	)
+	_ = octx.AddComponent(comp)
+
+	subjectLabel := ocmmetav1.Labels{{Name: v1alpha1.ReferrerSubjectLabel, Value: []byte(`"image"`)}}
+	otherLabel := ocmmetav1.Labels{{Name: v1alpha1.ReferrerSubjectLabel, Value: []byte(`"other"`)}}
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{ElementMeta: v3alpha1.ElementMeta{Name: "image", Version: "v0.0.1"}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "image-sbom", Version: "v0.0.1", Labels: subjectLabel}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "other-sbom", Version: "v0.0.1", Labels: otherLabel}},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), &fakes.FakeCache{})
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      cd.Name,
+					Namespace: cd.Namespace,
+				},
+			},
+		},
+	}
+
+	referrers, err := ocmClient.GetReferrers(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{Name: "image"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Referrer{
+		{
+			Name:         "image-sbom",
+			ArtifactType: "application/spdx+json",
+			Data:         []byte(`{"spdxVersion":"SPDX-2.3"}`),
+		},
+	}, referrers)
+
+	referrers, err = ocmClient.GetReferrers(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{Name: "image-sbom"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+}
+
+func createGzipTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func readTar(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestClient_GetImageResource(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+	data := "testdata"
+
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    component,
+		Version: "v0.0.1",
+	}
+	res := &fakeocm.Resource{
+		Name:      resource,
+		Version:   resourceVersion,
+		Data:      []byte(data),
+		Component: comp,
+		Kind:      "ociArtifact",
+		Type:      "ociImage",
+		Digest: &ocmmetav1.DigestSpec{
+			HashAlgorithm:          "SHA-256",
+			NormalisationAlgorithm: "ociArtifactDigest/v1",
+			Value:                  "6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+		},
+		AccessOptions: []fakeocm.AccessOptionFunc{
+			fakeocm.SetImageReference("ghcr.io/open-component-model/podinfo:6.3.5"),
+		},
+	}
+	comp.Resources = append(comp.Resources, res)
+
+	_ = octx.AddComponent(comp)
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    resource,
+							Version: resourceVersion,
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.ResolveArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	cache.CopyArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	cache.FetchDataByIdentityReturns(io.NopCloser(strings.NewReader("layer")), nil)
+
+	ocmClient := NewClient(fakeKubeClient, cache)
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	resourceRef := &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "remote-controller-demo",
+			Version: "v0.0.1",
+		},
+	}
+
+	var unpinned UnpinnedReference
+	reader, _, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithUnpinnedReference(&unpinned))
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "layer", string(content))
+
+	// the image should have been copied instead of being pushed as a single layer.
+	assert.True(t, cache.PushDataWasNotCalled())
+	args := cache.CopyArtifactCallingArgumentsOnCall(0)
+	assert.Equal(t, []any{
+		"ghcr.io/open-component-model/podinfo@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+		"sha-2705577397727487661",
+		resourceRef.Version,
+		(*ociv1.Platform)(nil),
+	}, args, "the tag should have been resolved to the digest it points at")
+	assert.Equal(t, []any{"ghcr.io/open-component-model/podinfo:6.3.5"}, cache.ResolveArtifactCallingArgumentsOnCall(0))
+	assert.Equal(t, UnpinnedReference{
+		Reference: "ghcr.io/open-component-model/podinfo:6.3.5",
+		Digest:    "sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+	}, unpinned)
+
+	t.Log("copied image doesn't match the digest of the resource")
+	cache.CopyArtifactReturns("sha256:0000000000000000000000000000000000000000000000000000000000000000", nil)
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef)
+	assert.ErrorIs(t, err, ErrDigestMismatch)
+	assert.False(t, cache.DeleteDataWasNotCalled())
+	assert.True(t, cache.CopyArtifactGzipLayersOnCall(1), "zstd layers should be recompressed by default")
+
+	t.Log("verifying the source of an image whose layers were recompressed")
+	cache.CopyArtifactTranscodes(true)
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef)
+	require.NoError(t, err, "the digest of the copy differs from the digest of the resource")
+	cache.CopyArtifactTranscodes(false)
+	cache.CopyArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+
+	t.Log("copying the layers of the image as they are with passthrough")
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithCompression(v1alpha1.CompressionPassthrough))
+	require.NoError(t, err)
+	assert.False(t, cache.CopyArtifactGzipLayersOnCall(3))
+
+	t.Log("selecting a single platform of the image")
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithPlatform("linux/arm64"))
+	require.NoError(t, err)
+	args = cache.CopyArtifactCallingArgumentsOnCall(4)
+	assert.NotEqual(t, "sha-2705577397727487661", args[1], "a single platform should be cached separately")
+	assert.Equal(t, &ociv1.Platform{OS: "linux", Architecture: "arm64"}, args[3])
+	assert.True(t, cache.ArtifactSizeWasNotCalled(), "the size of the image is only needed if it is limited")
+
+	t.Log("rejecting an image larger than the maximum size before copying it")
+	cache.ArtifactSizeReturns(2048, nil)
+	cache.CopyArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithMaxSize(1024))
+	assert.ErrorIs(t, err, ErrResourceTooLarge)
+	assert.Equal(t, []any{
+		"ghcr.io/open-component-model/podinfo@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+		(*ociv1.Platform)(nil),
+	}, cache.ArtifactSizeCallingArgumentsOnCall(0))
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithMaxSize(2048))
+	require.NoError(t, err)
+	args = cache.CopyArtifactCallingArgumentsOnCall(5)
+	assert.Equal(t, "sha-2705577397727487661", args[1], "the image within the maximum size should have been copied")
+
+	t.Log("copying the image without a tag")
+	untagged := &fakes.FakeCache{}
+	untagged.ResolveArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	untagged.CopyArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	untagged.FetchDataByIdentityReturns(io.NopCloser(strings.NewReader("layer")), nil)
+	ocmClient = NewClient(fakeKubeClient, untagged)
+	var result ocmcache.PushResult
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithoutTag(), WithPushResult(&result))
+	require.NoError(t, err)
+	assert.True(t, untagged.CopyArtifactUntaggedOnCall(0))
+	assert.Equal(t, "sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", result.Manifest)
+	assert.Equal(t, []any{"sha-2705577397727487661", result.Manifest}, untagged.FetchDataByIdentityCallingArgumentsOnCall(0),
+		"the copy should have been fetched by its digest")
+
+	t.Log("fetching the image from the mirror of its registry")
+	mirrored := &fakes.FakeCache{}
+	mirrored.ResolveArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	mirrored.CopyArtifactReturns("sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c", nil)
+	mirrored.FetchDataByIdentityReturns(io.NopCloser(strings.NewReader("layer")), nil)
+	ocmClient = NewClient(fakeKubeClient, mirrored,
+		WithAllowedRegistries("ghcr.io"), WithRegistryMirrors(map[string]string{"ghcr.io": "mirror.corp/ghcr.io"}))
+	_, _, err = ocmClient.GetResource(context.Background(), octx, cv, resourceRef, WithUnpinnedReference(&unpinned))
+	require.NoError(t, err, "the original registry should have been checked against the allowed registries")
+	assert.Equal(t, []any{"mirror.corp/ghcr.io/open-component-model/podinfo:6.3.5"}, mirrored.ResolveArtifactCallingArgumentsOnCall(0))
+	assert.Equal(t,
+		"mirror.corp/ghcr.io/open-component-model/podinfo@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+		mirrored.CopyArtifactCallingArgumentsOnCall(0)[0])
+	assert.Equal(t, "sha-2705577397727487661", mirrored.CopyArtifactCallingArgumentsOnCall(0)[1], "the snapshot should be the same as without the mirror")
+	assert.Equal(t, "ghcr.io/open-component-model/podinfo:6.3.5", unpinned.Reference)
+
+	t.Log("computing the digest of the first layer of the image without copying it")
+	mirrored.ArtifactLayerDigestReturns("sha256:layer", nil)
+	digest, err := ocmClient.GetResourceDigest(context.Background(), octx, cv, resourceRef, WithPlatform("linux/arm64"))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:layer", digest, "the digest is the digest GetResource returns for the image")
+	assert.Equal(t, []any{
+		"mirror.corp/ghcr.io/open-component-model/podinfo:6.3.5",
+		&ociv1.Platform{OS: "linux", Architecture: "arm64"},
+		true,
+	}, mirrored.ArtifactLayerDigestCallingArgumentsOnCall(0))
+	_, err = ocmClient.GetResourceDigest(context.Background(), octx, cv, resourceRef, WithCompression(v1alpha1.CompressionPassthrough))
+	require.NoError(t, err)
+	assert.False(t, mirrored.ArtifactLayerDigestCallingArgumentsOnCall(1)[2].(bool), "passthrough keeps zstd layers")
+}
+
+func TestUnpinnedImageReference(t *testing.T) {
+	testCases := []struct {
+		name     string
+		access   *ocmruntime.UnstructuredTypedObject
+		expected string
+	}{
+		{
+			name: "image referenced by tag",
+			access: ocmruntime.NewUnstructuredType("ociArtifact", ocmruntime.UnstructuredMap{
+				"imageReference": "ghcr.io/open-component-model/podinfo:6.3.5",
+			}),
+			expected: "ghcr.io/open-component-model/podinfo:6.3.5",
+		},
+		{
+			name: "image referenced by tag and digest",
+			access: ocmruntime.NewUnstructuredType("ociArtifact/v1", ocmruntime.UnstructuredMap{
+				"imageReference": "ghcr.io/open-component-model/podinfo:6.3.5@sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+			}),
+		},
+		{
+			name: "legacy access type",
+			access: ocmruntime.NewUnstructuredType("ociRegistry", ocmruntime.UnstructuredMap{
+				"imageReference": "ghcr.io/open-component-model/podinfo:6.3.5",
+			}),
+			expected: "ghcr.io/open-component-model/podinfo:6.3.5",
+		},
+		{
+			name: "other access type",
+			access: ocmruntime.NewUnstructuredType("localBlob", ocmruntime.UnstructuredMap{
+				"localReference": "sha256:6a1c7637a528ab5957ab60edf73b5298a0a03de02a96be0313ee89b22544840c",
+			}),
+		},
+		{
+			name: "no access",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, unpinnedImageReference(tt.access))
+		})
+	}
+}
+
+func TestClient_GetResourceDigest(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+	data := "testdata"
+
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    component,
+		Version: "v0.0.1",
+	}
+	res := &fakeocm.Resource{
+		Name:      resource,
+		Version:   resourceVersion,
+		Data:      []byte(data),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+	}
+	comp.Resources = append(comp.Resources, res)
+
+	_ = octx.AddComponent(comp)
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    resource,
+							Version: resourceVersion,
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
+	cache := &fakes.FakeCache{}
+	cache.LayerDigestReturns("sha256:layer", nil)
+
+	ocmClient := NewClient(fakeKubeClient, cache)
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	resourceRef := &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "remote-controller-demo",
+			Version: "v0.0.1",
+		},
+	}
+
+	digest, err := ocmClient.GetResourceDigest(context.Background(), octx, cv, resourceRef)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:layer", digest, "the digest is the digest of the layer the data would be pushed in")
+	assert.Equal(t, fakes.PushDataArguments{Content: data}, cache.LayerDigestCallingArgumentsOnCall(0))
+
+	// nothing should have been pushed to the registry.
+	assert.True(t, cache.PushDataWasNotCalled())
+	assert.True(t, cache.IsCachedWasNotCalled())
+
+	t.Log("computing the digest of data pushed uncompressed")
+	digest, err = ocmClient.GetResourceDigest(context.Background(), octx, cv, resourceRef, WithCompression(v1alpha1.CompressionNone))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:layer", digest)
+	assert.Equal(t, fakes.PushDataArguments{Content: data, Uncompressed: true}, cache.LayerDigestCallingArgumentsOnCall(1))
+
+	t.Log("using the digest of the component descriptor for data pushed as it is")
+	res.Digest = &ocmmetav1.DigestSpec{
+		HashAlgorithm:          "SHA-256",
+		NormalisationAlgorithm: "genericBlobDigest/v1",
+		Value:                  godigest.FromString(data).Encoded(),
+	}
+	cache = &fakes.FakeCache{}
+	ocmClient = NewClient(fakeKubeClient, cache)
+	digest, err = ocmClient.GetResourceDigest(context.Background(), octx, cv, resourceRef, WithCompression(v1alpha1.CompressionPassthrough))
+	require.NoError(t, err)
+	assert.Equal(t, godigest.FromString(data).String(), digest)
+	assert.True(t, cache.LayerDigestWasNotCalled(), "the resource isn't read")
+}
+
+func TestClient_GetResourceDigestMismatch(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "remote-controller-demo"
+	resourceVersion := "v0.0.1"
+
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    component,
+		Version: "v0.0.1",
+	}
+	res := &fakeocm.Resource{
+		Name:      resource,
+		Version:   resourceVersion,
+		Data:      []byte("tampered"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+		Digest: &ocmmetav1.DigestSpec{
+			HashAlgorithm:          "SHA-256",
+			NormalisationAlgorithm: "genericBlobDigest/v1",
+			// sha256 of "testdata"
+			Value: "810ff2fb242a5dee4220f2cb0e6a519891fb67f2f828a6cab4ef8894633b1f50",
+		},
+	}
+	comp.Resources = append(comp.Resources, res)
+
+	_ = octx.AddComponent(comp)
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    resource,
+							Version: resourceVersion,
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
+	cache := &fakes.FakeCache{}
+	cache.IsCachedReturns(false, nil)
+	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+
+	ocmClient := NewClient(fakeKubeClient, cache)
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: component,
+			Version: v1alpha1.Version{
+				Semver: "v0.0.1",
+			},
+			Repository: v1alpha1.Repository{
+				URL: "localhost",
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    component,
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	resourceRef := &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "remote-controller-demo",
+			Version: "v0.0.1",
+		},
+	}
+
+	_, _, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+	assert.True(t, cache.FetchDataByDigestWasNotCalled())
+	assert.False(t, cache.DeleteDataWasNotCalled(), "mismatching data should have been removed from the cache")
+}
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{
+			name:   "unauthorized",
+			err:    &transport.Error{StatusCode: http.StatusUnauthorized},
+			target: ErrRegistryAuth,
+		},
+		{
+			name:   "forbidden",
+			err:    fmt.Errorf("failed to push: %w", &transport.Error{StatusCode: http.StatusForbidden}),
+			target: ErrRegistryAuth,
+		},
+		{
+			name:   "unknown access method",
+			err:    ocmerrors.ErrUnknown(ocmerrors.KIND_ACCESSMETHOD, "custom"),
+			target: ErrUnsupportedAccess,
+		},
+		{
+			name:   "not supported",
+			err:    ocmerrors.ErrNotSupported("access"),
+			target: ErrUnsupportedAccess,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			assert.ErrorIs(t, err, tt.target)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("unclassified errors are returned as they are", func(t *testing.T) {
+		err := &transport.Error{StatusCode: http.StatusInternalServerError}
+		assert.Equal(t, error(err), classifyError(err))
+		assert.NoError(t, classifyError(nil))
+	})
+}
+
+func TestResourceIdentity(t *testing.T) {
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-descriptor",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{ElementMeta: v3alpha1.ElementMeta{Name: "multi", Version: "v1.2.0"}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "multi", Version: "v1.10.0"}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "multi", Version: "v1.3.0"}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "single", Version: "v0.1.0"}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "image", Version: "v1.0.0", ExtraIdentity: ocmmetav1.Identity{"os": "linux", "architecture": "amd64"}}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "image", Version: "v1.0.0", ExtraIdentity: ocmmetav1.Identity{"os": "linux", "architecture": "arm64"}}},
+					{ElementMeta: v3alpha1.ElementMeta{Name: "image", Version: "v1.1.0", ExtraIdentity: ocmmetav1.Identity{"os": "linux", "architecture": "arm64"}}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		ref         v1alpha1.ElementMeta
+		expected    ocmmetav1.Identity
+		expectError string
+	}{
+		{
+			name:     "single resource without version",
+			ref:      v1alpha1.ElementMeta{Name: "single"},
+			expected: ocmmetav1.Identity{"name": "single"},
+		},
+		{
+			name:     "ambiguous resource selects the highest version",
+			ref:      v1alpha1.ElementMeta{Name: "multi"},
+			expected: ocmmetav1.Identity{"name": "multi", "version": "v1.10.0"},
+		},
+		{
+			name:     "ambiguous resource with pinned version",
+			ref:      v1alpha1.ElementMeta{Name: "multi", Version: "v1.2.0"},
+			expected: ocmmetav1.Identity{"name": "multi", "version": "v1.2.0"},
+		},
+		{
+			name:        "version does not exist",
+			ref:         v1alpha1.ElementMeta{Name: "multi", Version: "v2.0.0"},
+			expectError: "resource not found: no resource with name multi and version v2.0.0 in component descriptor test-descriptor",
+		},
+		{
+			name: "variant selected by its extra identity",
+			ref:  v1alpha1.ElementMeta{Name: "image", Version: "v1.0.0", ExtraIdentity: ocmmetav1.Identity{"architecture": "amd64"}},
+			expected: ocmmetav1.Identity{
+				"name":         "image",
+				"os":           "linux",
+				"architecture": "amd64",
+			},
+		},
+		{
+			name: "variant with several versions selects the highest version",
+			ref:  v1alpha1.ElementMeta{Name: "image", ExtraIdentity: ocmmetav1.Identity{"architecture": "arm64"}},
+			expected: ocmmetav1.Identity{
+				"name":         "image",
+				"version":      "v1.1.0",
+				"os":           "linux",
+				"architecture": "arm64",
+			},
+		},
+		{
+			name:        "several variants match",
+			ref:         v1alpha1.ElementMeta{Name: "image", Version: "v1.0.0", ExtraIdentity: ocmmetav1.Identity{"os": "linux"}},
+			expectError: "ambiguous resource: 2 resources with name image and version v1.0.0 in component descriptor test-descriptor match",
+		},
+		{
+			name:        "no variant matches",
+			ref:         v1alpha1.ElementMeta{Name: "image", ExtraIdentity: ocmmetav1.Identity{"architecture": "s390x"}},
+			expectError: "resource not found: no resource with name image and extra identity",
+		},
+		{
+			name:        "name does not exist",
+			ref:         v1alpha1.ElementMeta{Name: "missing"},
+			expectError: "resource not found: no resource with name missing in component descriptor test-descriptor",
+		},
+	}
+
+	ocmClient := NewClient(nil, nil)
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ocmClient.resourceIdentity(cd, &v1alpha1.ResourceReference{ElementMeta: tt.ref})
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				assert.True(t, errors.Is(err, ErrResourceNotFound) || errors.Is(err, ErrAmbiguousResource))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+}
+
+func TestClient_GetResourceNotInDescriptor(t *testing.T) {
 	cd := &v1alpha1.ComponentDescriptor{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: "default",
@@ -64,13 +1373,86 @@ func TestClient_GetResource(t *testing.T) {
 		},
 	}
 
-	fakeKubeClient := env.FakeKubeClient(WithObjects(cd))
 	cache := &fakes.FakeCache{}
-	cache.IsCachedReturns(false, nil)
-	cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
-	cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+	ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache)
 
-	ocmClient := NewClient(fakeKubeClient, cache)
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: "github.com/skarlso/ocm-demo-index",
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: "v0.0.1",
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    "github.com/skarlso/ocm-demo-index",
+				Version: "v0.0.1",
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      cd.Name,
+					Namespace: cd.Namespace,
+				},
+			},
+		},
+	}
+
+	_, _, err := ocmClient.GetResource(context.Background(), fakeocm.NewFakeOCMContext(), cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name: "remote-controller-demo",
+		},
+	})
+	assert.ErrorIs(t, err, ErrResourceNotFound)
+	assert.ErrorContains(t, err, "no resource with name remote-controller-demo in component descriptor")
+	assert.True(t, cache.PushDataWasNotCalled())
+}
+
+// imageResolver resolves every resource to the same image.
+type imageResolver struct {
+	image string
+}
+
+func (r imageResolver) Resolve(context.Context, ocm.ResourceAccess, ocm.ComponentVersionAccess) (*ResolvedAccess, error) {
+	return &ResolvedAccess{Image: r.image}, nil
+}
+
+func TestClient_GetResourceAdoptionGuard(t *testing.T) {
+	octx := fakeocm.NewFakeOCMContext()
+
+	comp := &fakeocm.Component{
+		Name:    "github.com/skarlso/ocm-demo-index",
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:      "manifests",
+		Version:   "v0.0.1",
+		Data:      []byte("testdata"),
+		Component: comp,
+		Kind:      "localBlob",
+		Type:      "ociBlob",
+	})
+	require.NoError(t, octx.AddComponent(comp))
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    "manifests",
+							Version: "v0.0.1",
+						},
+						Type: "ociBlob",
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
 
 	cv := &v1alpha1.ComponentVersion{
 		ObjectMeta: metav1.ObjectMeta{
@@ -78,7 +1460,7 @@ func TestClient_GetResource(t *testing.T) {
 			Namespace: "default",
 		},
 		Spec: v1alpha1.ComponentVersionSpec{
-			Component: component,
+			Component: comp.Name,
 			Version: v1alpha1.Version{
 				Semver: "v0.0.1",
 			},
@@ -89,37 +1471,120 @@ func TestClient_GetResource(t *testing.T) {
 		Status: v1alpha1.ComponentVersionStatus{
 			ReconciledVersion: "v0.0.1",
 			ComponentDescriptor: v1alpha1.Reference{
-				Name:    component,
+				Name:    comp.Name,
 				Version: "v0.0.1",
 				ComponentDescriptorRef: meta.NamespacedObjectReference{
-					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
-					Namespace: "default",
+					Name:      cd.Name,
+					Namespace: cd.Namespace,
 				},
 			},
 		},
 	}
 
-	resourceRef := &v1alpha1.ResourceReference{
+	ref := &v1alpha1.ResourceReference{
 		ElementMeta: v1alpha1.ElementMeta{
-			Name:    "remote-controller-demo",
+			Name:    "manifests",
 			Version: "v0.0.1",
 		},
 	}
 
-	reader, digest, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef)
-	assert.NoError(t, err)
-	content, err := io.ReadAll(reader)
-	require.NoError(t, err)
-	assert.Equal(t, "mockdata", string(content))
-	assert.Equal(t, "sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", digest)
-
-	// verify that the cache has been called with the right resource data to cache.
-	args := cache.PushDataCallingArgumentsOnCall(0)
-
-	assert.Equal(t, data, args.Content)
+	managed := map[string]string{"software.ocm/managed-by": "ocm-controller"}
+	var adopted ocmcache.PushResult
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		opts        []GetResourceOption
+		clientOpts  []ClientOption
+		assert      func(t *testing.T, cache *fakes.FakeCache, err error)
+	}{
+		{
+			name:        "existing data isn't checked without the guard",
+			annotations: nil,
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				require.NoError(t, err)
+				assert.True(t, cache.ManifestAnnotationsWasNotCalled())
+				assert.False(t, cache.FetchDataByIdentityWasNotCalled())
+			},
+		},
+		{
+			name:        "data pushed by the controller is adopted",
+			annotations: managed,
+			opts:        []GetResourceOption{WithAdoptionGuard(false), WithPushResult(&adopted)},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, cache.IsCachedCallingArgumentsOnCall(0), cache.ManifestAnnotationsCallingArgumentsOnCall(0))
+				assert.False(t, cache.FetchDataByIdentityWasNotCalled())
+				assert.True(t, cache.PushDataWasNotCalled())
+				assert.True(t, adopted.Unchanged, "nothing should have been written")
+			},
+		},
+		{
+			name:        "data pushed by another tool is a conflict",
+			annotations: map[string]string{"org.opencontainers.image.source": "https://github.com/acme/app"},
+			opts:        []GetResourceOption{WithAdoptionGuard(false)},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				assert.ErrorIs(t, err, ErrSnapshotConflict)
+				assert.ErrorContains(t, err, "set forceOverwrite to overwrite it")
+				assert.True(t, cache.FetchDataByIdentityWasNotCalled())
+				assert.True(t, cache.PushDataWasNotCalled())
+			},
+		},
+		{
+			name:        "resources resolving to an image aren't guarded",
+			annotations: map[string]string{"org.opencontainers.image.source": "https://github.com/acme/app"},
+			opts:        []GetResourceOption{WithAdoptionGuard(false)},
+			clientOpts:  []ClientOption{WithAccessResolver("localBlob", imageResolver{image: "ghcr.io/acme/app@sha256:abc"})},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				require.NoError(t, err)
+				assert.True(t, cache.ManifestAnnotationsWasNotCalled())
+				assert.False(t, cache.FetchDataByIdentityWasNotCalled())
+			},
+		},
+		{
+			name:        "data without annotations in the in-cluster registry was pushed by an older controller",
+			annotations: nil,
+			opts:        []GetResourceOption{WithAdoptionGuard(false)},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				require.NoError(t, err)
+				assert.False(t, cache.FetchDataByIdentityWasNotCalled())
+				assert.True(t, cache.PushDataWasNotCalled())
+			},
+		},
+		{
+			name:        "data without annotations in another registry is a conflict",
+			annotations: nil,
+			opts:        []GetResourceOption{WithAdoptionGuard(false), WithSnapshotRegistry("registry.example.com", nil)},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				assert.ErrorIs(t, err, ErrSnapshotConflict)
+				assert.True(t, cache.PushDataWasNotCalled())
+			},
+		},
+		{
+			name:        "data pushed by another tool is overwritten if forced",
+			annotations: map[string]string{"org.opencontainers.image.source": "https://github.com/acme/app"},
+			opts:        []GetResourceOption{WithAdoptionGuard(true)},
+			assert: func(t *testing.T, cache *fakes.FakeCache, err error) {
+				require.NoError(t, err)
+				assert.True(t, cache.FetchDataByIdentityWasNotCalled())
+				assert.Equal(t, "v0.0.1", cache.PushDataCallingArgumentsOnCall(0).Version)
+			},
+		},
+	}
 
-	assert.Equal(t, "sha-2705577397727487661", args.Name, "pushed name did not match constructed name from identity of the resource")
-	assert.Equal(t, resourceRef.Version, args.Version)
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &fakes.FakeCache{}
+			cache.IsCachedReturns(true, nil)
+			cache.ManifestAnnotationsReturns(tt.annotations, nil)
+			cache.FetchDataByIdentityReturns(io.NopCloser(bytes.NewBufferString("testdata")), nil)
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(nil), nil)
+
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, tt.clientOpts...)
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, ref, tt.opts...)
+			tt.assert(t, cache, err)
+		})
+	}
 }
 
 func TestClient_GetHelmResource(t *testing.T) {
@@ -153,6 +1618,16 @@ func TestClient_GetHelmResource(t *testing.T) {
 			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
 		},
 		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    resource,
+							Version: resourceVersion,
+						},
+					},
+				},
+			},
 			Version: "v0.0.1",
 		},
 	}
@@ -376,6 +1851,102 @@ func TestClient_CreateAuthenticatedOCMContextWithServiceAccount(t *testing.T) {
 	assert.Equal(t, "localhost", consumer.Properties()["serverAddress"])
 }
 
+func TestConfigureDockerConfigCredentials(t *testing.T) {
+	testSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-docker-config",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			".dockerconfigjson": []byte(`{
+  "auths": {
+    "ghcr.io": {
+      "username": "skarlso",
+      "password": "password",
+      "auth": "c2thcmxzbzpwYXNzd29yZAo="
+    }
+  }
+}`),
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+	}
+	invalidSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-invalid",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"username": []byte("skarlso"),
+		},
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(testSecret, invalidSecret))
+	octx := ocm.New()
+
+	err := ConfigureDockerConfigCredentials(context.Background(), octx, fakeKubeClient, "test-docker-config", "default")
+	require.NoError(t, err)
+
+	id := cpi.ConsumerIdentity{
+		cpi.ID_TYPE:          identity.CONSUMER_TYPE,
+		identity.ID_HOSTNAME: "ghcr.io",
+	}
+	creds, err := octx.CredentialsContext().GetCredentialsForConsumer(id)
+	require.NoError(t, err)
+	consumer, err := creds.Credentials(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "password", consumer.Properties()["password"])
+	assert.Equal(t, "skarlso", consumer.Properties()["username"])
+
+	err = ConfigureDockerConfigCredentials(context.Background(), octx, fakeKubeClient, "test-invalid", "default")
+	assert.EqualError(t, err, "failed to find .dockerconfigjson in secret test-invalid")
+
+	err = ConfigureDockerConfigCredentials(context.Background(), octx, fakeKubeClient, "missing", "default")
+	assert.ErrorContains(t, err, "failed to get docker config secret")
+}
+
+func TestDockerConfigKeychain(t *testing.T) {
+	testSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-docker-config",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			".dockerconfigjson": []byte(`{
+  "auths": {
+    "ghcr.io": {
+      "auth": "c2thcmxzbzpwYXNzd29yZA=="
+    }
+  }
+}`),
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+	}
+
+	fakeKubeClient := env.FakeKubeClient(WithObjects(testSecret))
+
+	keychain, err := DockerConfigKeychain(context.Background(), fakeKubeClient, "test-docker-config", "default")
+	require.NoError(t, err)
+
+	ref, err := ociname.NewRepository("ghcr.io/open-component-model/snapshot")
+	require.NoError(t, err)
+	auth, err := keychain.Resolve(ref)
+	require.NoError(t, err)
+	cfg, err := auth.Authorization()
+	require.NoError(t, err)
+	assert.Equal(t, "skarlso", cfg.Username)
+	assert.Equal(t, "password", cfg.Password)
+
+	t.Log("accessing registries without credentials anonymously")
+	ref, err = ociname.NewRepository("registry.example.com/snapshot")
+	require.NoError(t, err)
+	auth, err = keychain.Resolve(ref)
+	require.NoError(t, err)
+	assert.Equal(t, authn.Anonymous, auth)
+
+	_, err = DockerConfigKeychain(context.Background(), fakeKubeClient, "missing", "default")
+	assert.ErrorContains(t, err, "failed to get docker config secret")
+}
+
 func TestClient_GetLatestValidComponentVersion(t *testing.T) {
 	testCases := []struct {
 		name             string
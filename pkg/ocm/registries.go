@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociartifact"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/accessmethods/ociblob"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// WithAllowedRegistries restricts the registries resources are fetched from to the given hosts, for example
+// ghcr.io or registry.local:5000. If no hosts are given, resources are fetched from any registry.
+func WithAllowedRegistries(hosts ...string) ClientOption {
+	return func(c *Client) {
+		for _, host := range hosts {
+			if host = strings.TrimSpace(host); host != "" {
+				if c.allowedRegistries == nil {
+					c.allowedRegistries = make(map[string]struct{}, len(hosts))
+				}
+
+				c.allowedRegistries[strings.ToLower(host)] = struct{}{}
+			}
+		}
+	}
+}
+
+// checkRegistryAllowed returns an error wrapping ErrRegistryNotAllowed if the data of the resource would be fetched
+// from a registry that isn't allowed. Images and OCI blobs are fetched from the registry of their reference and
+// downloads from the host of their URL. The data of other accesses, like local blobs, is stored in the repository
+// of the component.
+func (c *Client) checkRegistryAllowed(res ocm.ResourceAccess, cv *v1alpha1.ComponentVersion) error {
+	if len(c.allowedRegistries) == 0 {
+		return nil
+	}
+
+	host, err := resourceRegistry(res, cv)
+	if err != nil {
+		return fmt.Errorf("failed to determine registry of resource %s: %w", res.Meta().Name, err)
+	}
+
+	if _, ok := c.allowedRegistries[strings.ToLower(host)]; !ok {
+		return fmt.Errorf("%w: resource %s is fetched from %s", ErrRegistryNotAllowed, res.Meta().Name, host)
+	}
+
+	return nil
+}
+
+// resourceRegistry returns the host the data of the resource is fetched from.
+func resourceRegistry(res ocm.ResourceAccess, cv *v1alpha1.ComponentVersion) (string, error) {
+	download, err := getDownloadAccess(res)
+	if err != nil {
+		return "", err
+	}
+
+	if download != nil {
+		u, err := url.Parse(download.URL)
+		if err != nil {
+			return "", fmt.Errorf("%w: failed to parse url %q: %w", ErrInvalidReference, download.URL, err)
+		}
+
+		return u.Host, nil
+	}
+
+	spec, err := res.Access()
+	if err != nil {
+		return "", fmt.Errorf("failed to get access spec of resource: %w", err)
+	}
+
+	reference := cv.Spec.Repository.URL
+	switch x := spec.(type) {
+	case *ociartifact.AccessSpec:
+		reference = x.ImageReference
+	case *ociblob.AccessSpec:
+		reference = x.Reference
+	default:
+		// The repository URL may be given with a scheme, which isn't part of a repository name.
+		if _, rest, ok := strings.Cut(reference, "://"); ok {
+			reference = rest
+		}
+	}
+
+	ref, err := ociname.ParseReference(reference)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to parse reference %q: %w", ErrInvalidReference, reference, err)
+	}
+
+	return ref.Context().RegistryStr(), nil
+}
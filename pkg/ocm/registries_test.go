@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+)
+
+func TestClient_CheckRegistryAllowed(t *testing.T) {
+	cv := &v1alpha1.ComponentVersion{
+		Spec: v1alpha1.ComponentVersionSpec{
+			Repository: v1alpha1.Repository{
+				URL: "https://ghcr.io/open-component-model/components",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		access  []fakeocm.AccessOptionFunc
+		allowed []string
+		errIs   error
+		errStr  string
+	}{
+		{
+			name:   "any registry without an allowlist",
+			access: []fakeocm.AccessOptionFunc{fakeocm.SetImageReference("docker.io/library/nginx:1.25")},
+		},
+		{
+			name:    "allowed image registry",
+			access:  []fakeocm.AccessOptionFunc{fakeocm.SetImageReference("registry.local:5000/podinfo:6.3.5")},
+			allowed: []string{"ghcr.io", "Registry.Local:5000"},
+		},
+		{
+			name:    "image registry not allowed",
+			access:  []fakeocm.AccessOptionFunc{fakeocm.SetImageReference("nginx:1.25")},
+			allowed: []string{"ghcr.io"},
+			errIs:   ErrRegistryNotAllowed,
+			errStr:  "registry not allowed: resource podinfo is fetched from index.docker.io",
+		},
+		{
+			name:    "local blob in an allowed component repository",
+			allowed: []string{"ghcr.io"},
+		},
+		{
+			name:    "local blob in a component repository that isn't allowed",
+			allowed: []string{"registry.local:5000"},
+			errIs:   ErrRegistryNotAllowed,
+			errStr:  "resource podinfo is fetched from ghcr.io",
+		},
+		{
+			name:    "download from a host that isn't allowed",
+			access:  []fakeocm.AccessOptionFunc{fakeocm.SetDownloadAccess("https://github.com/podinfo/manifests.yaml", "")},
+			allowed: []string{"ghcr.io"},
+			errIs:   ErrRegistryNotAllowed,
+			errStr:  "resource podinfo is fetched from github.com",
+		},
+		{
+			name:    "malformed image reference",
+			access:  []fakeocm.AccessOptionFunc{fakeocm.SetImageReference("ghcr.io/Podinfo:6.3.5")},
+			allowed: []string{"ghcr.io"},
+			errIs:   ErrInvalidReference,
+			errStr:  `failed to parse reference "ghcr.io/Podinfo:6.3.5"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(nil, nil, WithAllowedRegistries(tt.allowed...))
+			res := &fakeocm.Resource{
+				Name:          "podinfo",
+				AccessOptions: tt.access,
+			}
+
+			err := c.checkRegistryAllowed(res, cv)
+			if tt.errStr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.errIs)
+			assert.ErrorContains(t, err, tt.errStr)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+	"github.com/open-component-model/ocm-controller/pkg/oci"
+)
+
+// registryHarness fetches resources from a fake component version into an in-memory in-cluster registry. Images
+// referenced by the resources are served by a second in-memory registry standing in for the upstream registry.
+type registryHarness struct {
+	upstream string
+	cache    string
+	client   *Client
+}
+
+// newRegistryHarness starts the in-memory registries and returns a client pushing to the in-cluster one.
+func newRegistryHarness(t *testing.T) *registryHarness {
+	t.Helper()
+
+	h := &registryHarness{
+		upstream: startRegistry(t),
+		cache:    startRegistry(t),
+	}
+
+	cd := &v1alpha1.ComponentDescriptor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+		},
+		Spec: v1alpha1.ComponentDescriptorSpec{
+			ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+				Resources: []v3alpha1.Resource{
+					{
+						ElementMeta: v3alpha1.ElementMeta{
+							Name:    "podinfo",
+							Version: "v6.3.5",
+						},
+					},
+				},
+			},
+			Version: "v0.0.1",
+		},
+	}
+
+	h.client = NewClient(env.FakeKubeClient(WithObjects(cd)), oci.NewClient(h.cache, oci.WithInsecureSkipVerify(true)))
+
+	return h
+}
+
+func startRegistry(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// pushImage pushes a random image to the upstream registry and returns its reference.
+func (h *registryHarness) pushImage(t *testing.T, repository string) (string, ociv1.Image) {
+	t.Helper()
+
+	image, err := random.Image(64, 2)
+	require.NoError(t, err)
+	image = mutate.ConfigMediaType(image, "application/vnd.test.config.v1+json")
+
+	ref := fmt.Sprintf("%s/%s:6.3.5", h.upstream, repository)
+	tag, err := ociname.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, image))
+
+	return ref, image
+}
+
+// getResource fetches the podinfo resource with the given access and digest from a fake component version.
+func (h *registryHarness) getResource(t *testing.T, digest *ocmmetav1.DigestSpec, access ...fakeocm.AccessOptionFunc) (io.ReadCloser, string, error) {
+	t.Helper()
+
+	octx := fakeocm.NewFakeOCMContext()
+	comp := &fakeocm.Component{
+		Name:    "github.com/skarlso/ocm-demo-index",
+		Version: "v0.0.1",
+	}
+	comp.Resources = append(comp.Resources, &fakeocm.Resource{
+		Name:          "podinfo",
+		Version:       "v6.3.5",
+		Component:     comp,
+		Type:          "ociImage",
+		Digest:        digest,
+		AccessOptions: access,
+	})
+	require.NoError(t, octx.AddComponent(comp))
+
+	cv := &v1alpha1.ComponentVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ComponentVersionSpec{
+			Component: comp.Name,
+			Version: v1alpha1.Version{
+				Semver: comp.Version,
+			},
+		},
+		Status: v1alpha1.ComponentVersionStatus{
+			ReconciledVersion: comp.Version,
+			ComponentDescriptor: v1alpha1.Reference{
+				Name:    comp.Name,
+				Version: comp.Version,
+				ComponentDescriptorRef: meta.NamespacedObjectReference{
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	return h.client.GetResource(context.Background(), octx, cv, &v1alpha1.ResourceReference{
+		ElementMeta: v1alpha1.ElementMeta{
+			Name:    "podinfo",
+			Version: "v6.3.5",
+		},
+	})
+}
+
+// cachedImage returns the image the resource has been copied to in the in-cluster registry.
+func (h *registryHarness) cachedImage(t *testing.T) *remote.Descriptor {
+	t.Helper()
+
+	registryName, err := ociname.NewRegistry(h.cache)
+	require.NoError(t, err)
+
+	repositories, err := remote.Catalog(context.Background(), registryName)
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	ref, err := ociname.NewTag(fmt.Sprintf("%s/%s:v6.3.5", h.cache, repositories[0]))
+	require.NoError(t, err)
+
+	desc, err := remote.Get(ref)
+	require.NoError(t, err)
+
+	return desc
+}
+
+func TestClient_GetImageResourceFromRegistry(t *testing.T) {
+	h := newRegistryHarness(t)
+	ref, image := h.pushImage(t, "podinfo")
+
+	imageDigest, err := image.Digest()
+	require.NoError(t, err)
+
+	reader, digest, err := h.getResource(t, &ocmmetav1.DigestSpec{
+		HashAlgorithm:          "SHA-256",
+		NormalisationAlgorithm: "ociArtifactDigest/v1",
+		Value:                  imageDigest.Hex,
+	}, fakeocm.SetImageReference(ref))
+	require.NoError(t, err)
+	defer reader.Close()
+
+	t.Log("returning the data of the first layer")
+	layers, err := image.Layers()
+	require.NoError(t, err)
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+	assert.Equal(t, layerDigest.String(), digest)
+
+	uncompressed, err := layers[0].Uncompressed()
+	require.NoError(t, err)
+	expected, err := io.ReadAll(uncompressed)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, expected, content)
+
+	t.Log("preserving the manifest of the image in the in-cluster registry")
+	desc := h.cachedImage(t)
+	assert.Equal(t, imageDigest, desc.Digest)
+
+	cached, err := desc.Image()
+	require.NoError(t, err)
+	manifest, err := cached.Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.test.config.v1+json", string(manifest.Config.MediaType))
+	assert.Len(t, manifest.Layers, 2)
+}
+
+func TestClient_GetImageResourceFromRegistryFailures(t *testing.T) {
+	testCases := []struct {
+		name   string
+		access func(h *registryHarness) []fakeocm.AccessOptionFunc
+		digest *ocmmetav1.DigestSpec
+		errIs  error
+		errStr string
+	}{
+		{
+			name: "malformed image reference",
+			access: func(h *registryHarness) []fakeocm.AccessOptionFunc {
+				return []fakeocm.AccessOptionFunc{fakeocm.SetImageReference(h.upstream + "/Podinfo::6.3.5")}
+			},
+			errStr: "failed to parse image reference",
+		},
+		{
+			name: "undecodable access",
+			access: func(h *registryHarness) []fakeocm.AccessOptionFunc {
+				return []fakeocm.AccessOptionFunc{
+					fakeocm.SetImageReference(h.upstream + "/podinfo:6.3.5"),
+					func(m map[string]any) {
+						m["imageReference"] = 42
+					},
+				}
+			},
+			errStr: "failed to get access spec of resource",
+		},
+		{
+			name: "image not found upstream",
+			access: func(h *registryHarness) []fakeocm.AccessOptionFunc {
+				return []fakeocm.AccessOptionFunc{fakeocm.SetImageReference(h.upstream + "/missing:6.3.5")}
+			},
+			errStr: "failed to resolve image reference",
+		},
+		{
+			name: "image doesn't match the digest",
+			access: func(h *registryHarness) []fakeocm.AccessOptionFunc {
+				ref, _ := h.pushImage(t, "podinfo")
+
+				return []fakeocm.AccessOptionFunc{fakeocm.SetImageReference(ref)}
+			},
+			digest: &ocmmetav1.DigestSpec{
+				HashAlgorithm:          "SHA-256",
+				NormalisationAlgorithm: "ociArtifactDigest/v1",
+				Value:                  "0000000000000000000000000000000000000000000000000000000000000000",
+			},
+			errIs: ErrDigestMismatch,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newRegistryHarness(t)
+
+			_, _, err := h.getResource(t, tt.digest, tt.access(h)...)
+			require.Error(t, err)
+
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+			}
+
+			if tt.errStr != "" {
+				assert.ErrorContains(t, err, tt.errStr)
+			}
+		})
+	}
+}
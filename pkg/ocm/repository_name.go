@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	ociname "github.com/google/go-containerregistry/pkg/name"
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// RepositoryNamer names the repositories snapshots are pushed to after a Go template. A nil RepositoryNamer names
+// them after the hash of the identity of the snapshot, like ConstructRepositoryName.
+type RepositoryNamer struct {
+	tmpl *template.Template
+}
+
+// RepositoryNameData is the data the repository name template is rendered with.
+type RepositoryNameData struct {
+	// Hash is the hash of the identity, which is the default repository name.
+	Hash string
+	// Namespace is the namespace of the snapshot.
+	Namespace string
+	// ComponentName is the name of the component the snapshotted resource belongs to.
+	ComponentName string
+	// ComponentVersion is the version of the component.
+	ComponentVersion string
+	// ResourceName is the name of the snapshotted resource.
+	ResourceName string
+	// ResourceVersion is the version of the snapshotted resource.
+	ResourceVersion string
+	// Identity is the complete identity of the snapshot.
+	Identity ocmmetav1.Identity
+}
+
+// NewRepositoryNamer returns a RepositoryNamer rendering the names of snapshot repositories with the Go template,
+// for example "{{ .Namespace }}/{{ .ComponentName }}/{{ .ResourceName }}". The fields of RepositoryNameData can be
+// used. An empty template returns nil, which names the repository after the hash of the identity. The template is
+// checked by rendering it for an example identity.
+func NewRepositoryNamer(text string) (*RepositoryNamer, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("repository-name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository name template: %w", err)
+	}
+
+	if _, err := renderRepositoryName(tmpl, ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:     "ocm.software/example",
+		v1alpha1.ComponentVersionKey:  "v1.0.0",
+		v1alpha1.ResourceNameKey:      "example",
+		v1alpha1.ResourceVersionKey:   "v1.0.0",
+		v1alpha1.SnapshotNamespaceKey: "default",
+	}); err != nil {
+		return nil, err
+	}
+
+	return &RepositoryNamer{tmpl: tmpl}, nil
+}
+
+// WithRepositoryNamer sets how the repositories resources are pushed to are named. Defaults to the hash of the
+// identity of the resource.
+func WithRepositoryNamer(namer *RepositoryNamer) ClientOption {
+	return func(c *Client) {
+		c.repositoryNamer = namer
+	}
+}
+
+// RepositoryName returns the name of the repository the snapshot with the given identity is pushed to. Snapshots
+// record the repository in Spec.Repository, readers use SnapshotRepositoryName instead, so changing the template
+// only affects snapshots pushed afterwards.
+func (n *RepositoryNamer) RepositoryName(identity ocmmetav1.Identity) (string, error) {
+	if n == nil {
+		return ConstructRepositoryName(identity)
+	}
+
+	name, err := renderRepositoryName(n.tmpl, identity)
+	if err != nil {
+		return "", fmt.Errorf("failed to create repository name for identity: %w", err)
+	}
+
+	return withHelmChartName(name, identity), nil
+}
+
+// SnapshotRepositoryName returns the name of the repository the data of the snapshot is stored in, as recorded in
+// its Spec.Repository when it was pushed. Snapshots that don't record it were pushed before repositories could be
+// named by a template, their repository is named after the hash of their identity.
+func SnapshotRepositoryName(snapshot *v1alpha1.Snapshot) (string, error) {
+	if snapshot.Spec.Repository != "" {
+		return snapshot.Spec.Repository, nil
+	}
+
+	return ConstructRepositoryName(snapshot.Spec.Identity)
+}
+
+// ConstructRepositoryName returns the name of the repository named after the hash of the identity, which is where
+// snapshots are pushed to unless a RepositoryNamer with a template is configured.
+func ConstructRepositoryName(identity ocmmetav1.Identity) (string, error) {
+	repositoryName, err := HashIdentity(identity)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create repository name for identity: %w", err)
+	}
+
+	return withHelmChartName(repositoryName, identity), nil
+}
+
+// withHelmChartName appends the name of the helm chart of the identity to the repository. That's because flux helm
+// resolver doesn't look at the root of an OCI repository, it appends the name of the chart at the end.
+func withHelmChartName(repositoryName string, identity ocmmetav1.Identity) string {
+	if v, ok := identity[v1alpha1.ResourceHelmChartNameKey]; ok {
+		return fmt.Sprintf("%s/%s", repositoryName, v)
+	}
+
+	return repositoryName
+}
+
+// renderRepositoryName renders the template for the identity and checks that the result is a valid repository name.
+func renderRepositoryName(tmpl *template.Template, identity ocmmetav1.Identity) (string, error) {
+	hash, err := HashIdentity(identity)
+	if err != nil {
+		return "", err
+	}
+
+	data := RepositoryNameData{
+		Hash:             hash,
+		Namespace:        identity[v1alpha1.SnapshotNamespaceKey],
+		ComponentName:    identity[v1alpha1.ComponentNameKey],
+		ComponentVersion: identity[v1alpha1.ComponentVersionKey],
+		ResourceName:     identity[v1alpha1.ResourceNameKey],
+		ResourceVersion:  identity[v1alpha1.ResourceVersionKey],
+		Identity:         identity,
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render repository name template: %w", err)
+	}
+
+	// Repository names must be lowercase, component names often aren't, for example github.com/Org/App. The
+	// registry is only added to validate the name, the template must not contain it.
+	rendered := strings.Trim(sb.String(), "/")
+	name := strings.ToLower(rendered)
+	if _, err := ociname.NewRepository("registry.local/"+name, ociname.StrictValidation); err != nil {
+		return "", fmt.Errorf("rendered repository name %q is invalid, repository names consist of path components of "+
+			"lowercase letters and digits separated by '.', '_', '__' or '-', joined by '/': %w", rendered, err)
+	}
+
+	return name, nil
+}
+
+// tagPattern matches valid OCI tags.
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+
+// SnapshotTag returns the tag the snapshot data of the given version of a resource is stored with. Most versions
+// are valid tags already, but the '+' of semver build metadata isn't allowed in tags and is replaced by '_', like
+// Helm does for the versions of charts stored in OCI registries. ErrInvalidSnapshotTag is returned for versions
+// with other characters that aren't allowed in tags, instead of failing once the reference is parsed.
+func SnapshotTag(version string) (string, error) {
+	tag := strings.ReplaceAll(version, "+", "_")
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: version %q can't be used as tag, tags consist of at most 128 letters, digits, '_', "+
+			"'.' and '-' and don't start with '.' or '-'", ErrInvalidSnapshotTag, version)
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+func TestRepositoryNamer(t *testing.T) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:     "github.com/open-component-model/podinfo",
+		v1alpha1.ComponentVersionKey:  "v1.0.0",
+		v1alpha1.ResourceNameKey:      "manifests",
+		v1alpha1.ResourceVersionKey:   "v6.3.5",
+		v1alpha1.SnapshotNamespaceKey: "default",
+	}
+
+	hash, err := HashIdentity(identity)
+	require.NoError(t, err)
+
+	withoutNamespace := maps.Clone(identity)
+	delete(withoutNamespace, v1alpha1.SnapshotNamespaceKey)
+	hashWithoutNamespace, err := HashIdentity(withoutNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, hashWithoutNamespace, hash, "the namespace must not change the hash")
+
+	chartIdentity := maps.Clone(identity)
+	chartIdentity[v1alpha1.ResourceHelmChartNameKey] = "podinfo"
+	chartHash, err := HashIdentity(chartIdentity)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		template string
+		identity ocmmetav1.Identity
+		want     string
+		errStr   string
+	}{
+		{
+			name:     "default",
+			identity: identity,
+			want:     hash,
+		},
+		{
+			name:     "template",
+			template: "{{ .Namespace }}/{{ .ComponentName }}/{{ .ResourceName }}",
+			identity: identity,
+			want:     "default/github.com/open-component-model/podinfo/manifests",
+		},
+		{
+			name:     "template with hash and helm chart",
+			template: "snapshots/{{ .Namespace }}/{{ .Hash }}",
+			identity: chartIdentity,
+			want:     "snapshots/default/" + chartHash + "/podinfo",
+		},
+		{
+			name:     "uppercase component name",
+			template: "{{ .ComponentName }}/{{ .ResourceName }}",
+			identity: ocmmetav1.Identity{
+				v1alpha1.ComponentNameKey: "github.com/Open-Component-Model/PodInfo",
+				v1alpha1.ResourceNameKey:  "manifests",
+			},
+			want: "github.com/open-component-model/podinfo/manifests",
+		},
+		{
+			name:     "invalid rendered name",
+			template: "{{ .ComponentName }}",
+			identity: ocmmetav1.Identity{
+				v1alpha1.ComponentNameKey: "Invalid Name",
+			},
+			errStr: `rendered repository name "Invalid Name" is invalid`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			namer, err := NewRepositoryNamer(tt.template)
+			require.NoError(t, err)
+
+			name, err := namer.RepositoryName(tt.identity)
+			if tt.errStr != "" {
+				assert.ErrorContains(t, err, tt.errStr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestNewRepositoryNamer(t *testing.T) {
+	for template, errStr := range map[string]string{
+		"{{ .Namespace":           "failed to parse repository name template",
+		"{{ .Unknown }}":          "failed to render repository name template",
+		"{{ .Namespace }}:latest": "is invalid",
+	} {
+		_, err := NewRepositoryNamer(template)
+		assert.ErrorContains(t, err, errStr, template)
+	}
+}
+
+func TestSnapshotRepositoryName(t *testing.T) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey: "github.com/open-component-model/podinfo",
+		v1alpha1.ResourceNameKey:  "manifests",
+	}
+	hash, err := HashIdentity(identity)
+	require.NoError(t, err)
+
+	t.Log("reading the repository recorded on the snapshot")
+	snapshot := &v1alpha1.Snapshot{Spec: v1alpha1.SnapshotSpec{Identity: identity, Repository: "default/podinfo/manifests"}}
+	name, err := SnapshotRepositoryName(snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "default/podinfo/manifests", name)
+
+	t.Log("naming the repository of snapshots that don't record it after the hash of the identity")
+	snapshot.Spec.Repository = ""
+	name, err = SnapshotRepositoryName(snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, hash, name)
+}
+
+func TestSnapshotTag(t *testing.T) {
+	testCases := []struct {
+		version string
+		want    string
+		errStr  string
+	}{
+		{version: "v1.0.0", want: "v1.0.0"},
+		{version: "latest", want: "latest"},
+		{version: "1.0.0-RC.1", want: "1.0.0-RC.1"},
+		{version: "1.0.0+build.5", want: "1.0.0_build.5"},
+		{version: "1.0.0/rc", errStr: `invalid snapshot tag: version "1.0.0/rc" can't be used as tag`},
+		{version: "v1:0", errStr: "invalid snapshot tag"},
+		{version: ".hidden", errStr: "invalid snapshot tag"},
+		{version: "", errStr: "invalid snapshot tag"},
+		{version: strings.Repeat("1", 129), errStr: "invalid snapshot tag"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.version, func(t *testing.T) {
+			tag, err := SnapshotTag(tt.version)
+			if tt.errStr != "" {
+				assert.ErrorIs(t, err, ErrInvalidSnapshotTag)
+				assert.ErrorContains(t, err, tt.errStr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tag)
+		})
+	}
+}
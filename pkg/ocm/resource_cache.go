@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/metrics"
+)
+
+// pushedResource is the location of resource data that has been pushed to the in-cluster registry.
+type pushedResource struct {
+	name      string
+	version   string
+	mediaType string
+	digest    string
+	// size is the number of bytes of the data that was pushed.
+	size int64
+}
+
+// resourceCache remembers recently pushed resource data by the digest of the source resource. Resources with the
+// same digest, for example the unchanged resource of a new component version, are then copied within the
+// in-cluster registry instead of being downloaded from the upstream repository again.
+type resourceCache struct {
+	entries *lru.Cache
+}
+
+func newResourceCache(size int) *resourceCache {
+	return &resourceCache{entries: lru.New(size)}
+}
+
+func (r *resourceCache) get(key string) (pushedResource, bool) {
+	value, ok := r.entries.Get(key)
+	if !ok {
+		metrics.ResourceCacheMissesTotal.Inc()
+
+		return pushedResource{}, false
+	}
+
+	metrics.ResourceCacheHitsTotal.Inc()
+
+	return value.(pushedResource), true
+}
+
+func (r *resourceCache) add(key string, resource pushedResource) {
+	r.entries.Add(key, resource)
+}
+
+// resourceCacheKey returns the key of the resource in the resource cache. The key is empty if the component
+// descriptor doesn't record a digest for the resource, as its content can't be identified without fetching it.
+// It is also empty for data stored with passthrough compression, which is read back decompressed from the
+// in-cluster registry and therefore can't be copied as it was fetched. Data is only copied within a registry, so
+// the snapshot registry is part of the key. Sources have no digest and are never copied.
+func (c *Client) resourceCacheKey(cd *v1alpha1.ComponentDescriptor, resource *v1alpha1.ResourceReference, options *getResourceOptions) string {
+	if options.compression == v1alpha1.CompressionPassthrough {
+		return ""
+	}
+
+	res, err := c.descriptorElement(cd, resource, options)
+	if err != nil || res.Digest == nil || res.Digest.Value == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/%s|%s|%s|%s|%s|%s",
+		res.Digest.HashAlgorithm,
+		res.Digest.NormalisationAlgorithm,
+		res.Digest.Value,
+		options.platform,
+		options.extractPath,
+		options.compression,
+		options.registry,
+		options.transformURL,
+	)
+}
+
+// copyCachedResource copies resource data that has already been pushed for another identity to the given name and
+// version. Returns false if the data isn't available or exceeds the size limit, in which case the resource has to
+// be fetched from upstream.
+func (c *Client) copyCachedResource(
+	ctx context.Context,
+	key, name, version string,
+	limit *sizeLimit,
+	options *getResourceOptions,
+) (io.ReadCloser, string, bool) {
+	if c.resources == nil || key == "" {
+		return nil, "", false
+	}
+
+	pushed, ok := c.resources.get(key)
+	if !ok {
+		return nil, "", false
+	}
+
+	logger := log.FromContext(ctx).WithName("ocm")
+
+	// The data may have been pushed with a higher limit, fetching it from upstream rejects it with the current one.
+	if err := limit.check(pushed.size); err != nil {
+		logger.V(v1alpha1.LevelDebug).Info("previously pushed resource exceeds the size limit, fetching from upstream", "from", pushed.name+":"+pushed.version, "error", err.Error())
+
+		return nil, "", false
+	}
+
+	source, err := c.cache.FetchDataByDigest(ctx, pushed.name, pushed.digest)
+	if err != nil {
+		logger.V(v1alpha1.LevelDebug).Info("previously pushed resource is not available, fetching from upstream", "from", pushed.name+":"+pushed.version, "error", err.Error())
+
+		return nil, "", false
+	}
+	defer source.Close()
+
+	digest, err := c.cache.PushData(ctx, source, pushed.mediaType, name, version, options.pushOptions()...)
+	if err != nil {
+		logger.V(v1alpha1.LevelDebug).Info("failed to copy previously pushed resource, fetching from upstream", "from", pushed.name+":"+pushed.version, "error", err.Error())
+
+		return nil, "", false
+	}
+
+	c.resources.add(key, pushedResource{name: name, version: version, mediaType: pushed.mediaType, digest: digest, size: pushed.size})
+
+	reader, err := c.cache.FetchDataByDigest(ctx, name, digest)
+	if err != nil {
+		return nil, "", false
+	}
+
+	logger.V(v1alpha1.LevelDebug).Info("copied previously pushed resource",
+		"from", pushed.name+":"+pushed.version, v1alpha1.LogKeySnapshotRef, name+":"+version, v1alpha1.LogKeySourceDigest, digest)
+
+	return reader, digest, true
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithMaxResourceSize sets the maximum size in bytes of a resource that is fetched and pushed to the registry.
+// Zero disables the limit.
+func WithMaxResourceSize(size int64) ClientOption {
+	return func(c *Client) {
+		c.maxResourceSize = size
+	}
+}
+
+// WithMaxSize sets the maximum size in bytes of the resource. If the client has a maximum size as well, the
+// smaller of both applies. Zero disables the limit of the resource.
+func WithMaxSize(size int64) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.maxSize = size
+	}
+}
+
+// sizeLimit returns the size limit of the resource with the given name.
+func (c *Client) sizeLimit(name string, options *getResourceOptions) *sizeLimit {
+	limit := &sizeLimit{name: name, max: c.maxResourceSize}
+	if options.maxSize > 0 && (limit.max <= 0 || options.maxSize < limit.max) {
+		limit.max = options.maxSize
+	}
+
+	return limit
+}
+
+// sizeLimit rejects resources exceeding the maximum size. Resources whose size is known are rejected before they
+// are fetched, the data of all other resources is cut off once it exceeds the maximum size.
+type sizeLimit struct {
+	name     string
+	max      int64
+	exceeded error
+}
+
+// enabled returns whether the size of the resource is limited.
+func (l *sizeLimit) enabled() bool {
+	return l.max > 0
+}
+
+// check returns an error wrapping ErrResourceTooLarge if the size exceeds the maximum size. A negative size is
+// unknown and passes the check.
+func (l *sizeLimit) check(size int64) error {
+	if !l.enabled() || size <= l.max {
+		return nil
+	}
+
+	return fmt.Errorf("%w: resource %s has %d bytes, the maximum size is %d bytes", ErrResourceTooLarge, l.name, size, l.max)
+}
+
+// reader returns a reader that fails with an error wrapping ErrResourceTooLarge once more than the maximum size
+// has been read from r.
+func (l *sizeLimit) reader(r io.Reader) io.Reader {
+	if !l.enabled() {
+		return r
+	}
+
+	return &limitedReader{r: r, limit: l}
+}
+
+// err returns the error of a reader of the limit that has exceeded the maximum size, nil otherwise. Readers may
+// fail with a wrapped error, so the error of a push is replaced with this one.
+func (l *sizeLimit) err() error {
+	return l.exceeded
+}
+
+type limitedReader struct {
+	r     io.Reader
+	limit *sizeLimit
+	read  int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit.max {
+		r.limit.exceeded = fmt.Errorf("%w: resource %s exceeds the maximum size of %d bytes", ErrResourceTooLarge, r.limit.name, r.limit.max)
+
+		return n, r.limit.exceeded
+	}
+
+	return n, err
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
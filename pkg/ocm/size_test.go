@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SizeLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   int64
+		resource int64
+		expected int64
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name:     "limit of the client",
+			client:   100,
+			expected: 100,
+		},
+		{
+			name:     "limit of the resource",
+			resource: 100,
+			expected: 100,
+		},
+		{
+			name:     "smaller limit of the resource",
+			client:   100,
+			resource: 10,
+			expected: 10,
+		},
+		{
+			name:     "smaller limit of the client",
+			client:   10,
+			resource: 100,
+			expected: 10,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(nil, nil, WithMaxResourceSize(tt.client))
+			options := &getResourceOptions{}
+			WithMaxSize(tt.resource)(options)
+
+			limit := c.sizeLimit("manifests", options)
+			assert.Equal(t, tt.expected, limit.max)
+			assert.Equal(t, tt.expected > 0, limit.enabled())
+		})
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	limit := &sizeLimit{name: "manifests", max: 4}
+
+	assert.NoError(t, limit.check(-1), "an unknown size passes the check")
+	assert.NoError(t, limit.check(4))
+	err := limit.check(5)
+	assert.ErrorIs(t, err, ErrResourceTooLarge)
+	assert.EqualError(t, err, "resource too large: resource manifests has 5 bytes, the maximum size is 4 bytes")
+
+	content, err := io.ReadAll(limit.reader(strings.NewReader("data")))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+	assert.NoError(t, limit.err())
+
+	_, err = io.ReadAll(limit.reader(strings.NewReader("more data")))
+	assert.ErrorIs(t, err, ErrResourceTooLarge)
+	assert.Equal(t, err, limit.err())
+
+	t.Log("not limiting the size without a maximum size")
+	limit = &sizeLimit{name: "manifests"}
+	assert.NoError(t, limit.check(1<<40))
+	r := strings.NewReader("data")
+	assert.Same(t, r, limit.reader(r))
+}
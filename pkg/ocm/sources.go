@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"fmt"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm"
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/utils"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// WithSource resolves the reference against the sources of the component instead of its resources. The source is
+// fetched and cached like a resource, its identity is marked with the ResourceKindKey so it is stored separately
+// from a resource with the same name.
+func WithSource() GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.source = true
+	}
+}
+
+// descriptorElement returns the resource, or the source if the options select one, of the component descriptor
+// selected by the reference. A source is returned as a resource without a digest.
+func (c *Client) descriptorElement(cd *v1alpha1.ComponentDescriptor, ref *v1alpha1.ResourceReference, options *getResourceOptions) (*v3alpha1.Resource, error) {
+	if !options.source {
+		return c.descriptorResource(cd, ref)
+	}
+
+	source, err := descriptorSource(cd, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v3alpha1.Resource{
+		ElementMeta: source.ElementMeta,
+		Type:        source.Type,
+		Access:      source.Access,
+	}, nil
+}
+
+// resolveElement resolves the referenced resource, or the source if the options select one, in the component
+// version.
+func (c *Client) resolveElement(
+	cva ocm.ComponentVersionAccess,
+	cd *v1alpha1.ComponentDescriptor,
+	ref *v1alpha1.ResourceReference,
+	options *getResourceOptions,
+) (ocm.ResourceAccess, error) {
+	if !options.source {
+		return c.resolveResource(cva, cd, ref)
+	}
+
+	return resolveSource(cva, cd, ref)
+}
+
+// descriptorSource returns the source of the component descriptor selected by the reference. If the reference
+// doesn't define a version, the source with the highest semver version is selected.
+func descriptorSource(cd *v1alpha1.ComponentDescriptor, ref *v1alpha1.ResourceReference) (*v3alpha1.Source, error) {
+	candidates := sourceVersions(cd, ref.Name)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no source with name %s in component descriptor %s", ErrResourceNotFound, ref.Name, cd.Name)
+	}
+
+	version := ref.Version
+	if version == "" {
+		version = highestVersion(candidates)
+	}
+
+	for i, s := range cd.Spec.Sources {
+		if s.Name == ref.Name && s.Version == version {
+			return &cd.Spec.Sources[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no source with name %s and version %s in component descriptor %s", ErrResourceNotFound, ref.Name, version, cd.Name)
+}
+
+// sourceVersions returns the versions of the sources with the given name in the component descriptor.
+func sourceVersions(cd *v1alpha1.ComponentDescriptor, name string) []string {
+	var versions []string
+	for _, s := range cd.Spec.Sources {
+		if s.Name == name {
+			versions = append(versions, s.Version)
+		}
+	}
+
+	return versions
+}
+
+// resolveSource resolves the referenced source in the component version, following the reference path. Like for
+// resources, the version is only part of the identity if the name is ambiguous.
+func resolveSource(cva ocm.ComponentVersionAccess, cd *v1alpha1.ComponentDescriptor, ref *v1alpha1.ResourceReference) (ocm.ResourceAccess, error) {
+	source, err := descriptorSource(cd, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := ocmmetav1.NewIdentity(ref.Name)
+	for k, v := range ref.ExtraIdentity {
+		identity[k] = v
+	}
+	if len(sourceVersions(cd, ref.Name)) > 1 {
+		identity[ocmmetav1.SystemIdentityVersion] = source.Version
+	}
+
+	eff, err := utils.ResolveReferencePath(cva, ref.ReferencePath, cva.Repository())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reference path to source: %s %w", ref.Name, err)
+	}
+
+	src, err := eff.GetSource(identity)
+	if err != nil {
+		eff.Close()
+
+		return nil, fmt.Errorf("failed to resolve reference path to source: %s %w", ref.Name, err)
+	}
+
+	return &sourceAccess{SourceAccess: src}, nil
+}
+
+// sourceAccess accesses a source like a resource. Sources have no relation or digest, so the metadata of the
+// resource only carries the element metadata and the type of the source.
+type sourceAccess struct {
+	ocm.SourceAccess
+}
+
+// Meta returns the metadata of the source as the metadata of a resource.
+func (s *sourceAccess) Meta() *ocm.ResourceMeta {
+	meta := s.SourceAccess.Meta()
+
+	return &ocm.ResourceMeta{
+		ElementMeta: *meta.ElementMeta.Copy(),
+		Type:        meta.Type,
+	}
+}
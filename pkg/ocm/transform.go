@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+)
+
+// DefaultTransformTimeout is how long a transformation webhook may take to transform a resource if no timeout is
+// set.
+const DefaultTransformTimeout = time.Minute
+
+// The headers the identity of the resource is sent to a transformation webhook with.
+const (
+	TransformComponentNameHeader    = "X-OCM-Component-Name"
+	TransformComponentVersionHeader = "X-OCM-Component-Version"
+	TransformResourceNameHeader     = "X-OCM-Resource-Name"
+	TransformResourceVersionHeader  = "X-OCM-Resource-Version"
+)
+
+// ErrTransformFailed is returned when the transformation webhook of a resource fails or rejects the resource.
+var ErrTransformFailed = errors.New("transform failed")
+
+// WithAllowedTransformHosts sets the hosts transformation webhooks may be served from, as described by
+// v1alpha1.CheckTransformURL. Resources can't be transformed without allowed hosts.
+func WithAllowedTransformHosts(hosts ...string) ClientOption {
+	return func(c *Client) {
+		c.allowedTransformHosts = append(c.allowedTransformHosts, hosts...)
+	}
+}
+
+// WithTransform posts the data of the resource to the webhook at the URL before it is pushed, and pushes the data
+// the webhook responds with instead. The data is sent as it would be pushed, decompressed unless the compression
+// is passthrough and with only the extracted files if an extract path is set. The timeout covers the complete
+// exchange with the webhook, zero uses DefaultTransformTimeout. Transformed data is cached separately from the
+// data of the resource as it is.
+func WithTransform(url string, timeout time.Duration) GetResourceOption {
+	return func(o *getResourceOptions) {
+		o.transformURL = url
+		o.transformTimeout = timeout
+	}
+}
+
+// transformResource posts the data to the transformation webhook and returns the transformed data. The returned
+// reader has to be closed once the data has been read, closing it waits until the webhook request stopped reading
+// the data of the resource. Webhooks that aren't served from an allowed host fail with ErrTransformNotAllowed, and
+// redirects aren't followed, so a webhook can't point the request at another host.
+func (c *Client) transformResource(
+	ctx context.Context,
+	data io.Reader,
+	mediaType string,
+	identity ocmmetav1.Identity,
+	limit *sizeLimit,
+	options *getResourceOptions,
+) (io.ReadCloser, error) {
+	if err := v1alpha1.CheckTransformURL(options.transformURL, c.allowedTransformHosts); err != nil {
+		return nil, fmt.Errorf("%w: webhook %s: %w", ErrTransformNotAllowed, options.transformURL, err)
+	}
+
+	timeout := options.transformTimeout
+	if timeout <= 0 {
+		timeout = DefaultTransformTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	body := &transformRequestBody{r: data, done: make(chan struct{})}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, options.transformURL, body)
+	if err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("%w: failed to create request for %s: %w", ErrTransformFailed, options.transformURL, err)
+	}
+
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set(TransformComponentNameHeader, identity[v1alpha1.ComponentNameKey])
+	req.Header.Set(TransformComponentVersionHeader, identity[v1alpha1.ComponentVersionKey])
+	req.Header.Set(TransformResourceNameHeader, identity[v1alpha1.ResourceNameKey])
+	req.Header.Set(TransformResourceVersionHeader, identity[v1alpha1.ResourceVersionKey])
+
+	// The transport closes the request body once it is done with it, even if the request can't be sent.
+	httpClient := *c.httpClient
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := httpClient.Do(req) //nolint:bodyclose // closed by the returned reader
+	if err != nil {
+		cancel()
+		body.wait()
+
+		return nil, c.transformError(options, limit, err)
+	}
+
+	response := &transformResponse{body: resp.Body, request: body, cancel: cancel}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = response.Close()
+
+		err := fmt.Errorf("unexpected status %s", resp.Status)
+		if text := strings.TrimSpace(string(message)); text != "" {
+			err = fmt.Errorf("unexpected status %s: %s", resp.Status, text)
+		}
+
+		return nil, c.transformError(options, limit, err)
+	}
+
+	// The transformed data is limited like the data of the resource, reject it early if its size is known.
+	if err := limit.check(resp.ContentLength); err != nil {
+		_ = response.Close()
+
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// transformError returns the error of a failed exchange with the transformation webhook. The data of the resource
+// exceeding its maximum size fails the request as well, in which case the size limit is reported instead.
+func (c *Client) transformError(options *getResourceOptions, limit *sizeLimit, err error) error {
+	if lerr := limit.err(); lerr != nil {
+		return lerr
+	}
+
+	return fmt.Errorf("%w: webhook %s: %w", ErrTransformFailed, options.transformURL, err)
+}
+
+// transformRequestBody is the body of the request to the transformation webhook. It records when the transport is
+// done reading it, the data of the resource is read from the same reader afterward to verify its digest.
+type transformRequestBody struct {
+	r    io.Reader
+	done chan struct{}
+	once sync.Once
+}
+
+// Read implements io.Reader.
+func (b *transformRequestBody) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// Close implements io.Closer.
+func (b *transformRequestBody) Close() error {
+	b.once.Do(func() { close(b.done) })
+
+	return nil
+}
+
+// wait blocks until the transport closed the body.
+func (b *transformRequestBody) wait() {
+	<-b.done
+}
+
+// transformResponse reads the transformed data from the response of the webhook.
+type transformResponse struct {
+	body    io.ReadCloser
+	request *transformRequestBody
+	cancel  context.CancelFunc
+}
+
+// Read implements io.Reader.
+func (r *transformResponse) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: failed to read transformed data: %w", ErrTransformFailed, err)
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer. Cancelling the request stops the transport from sending the rest of the data if the
+// webhook responded before reading all of it.
+func (r *transformResponse) Close() error {
+	err := r.body.Close()
+	r.cancel()
+	r.request.wait()
+
+	return err
+}
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/versions/ocm.software/v3alpha1"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+	fakeocm "github.com/open-component-model/ocm-controller/pkg/fakes"
+)
+
+func TestClient_GetResourceTransform(t *testing.T) {
+	component := "github.com/skarlso/ocm-demo-index"
+	resource := "chart"
+	resourceVersion := "v0.0.1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/relocate":
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			_, _ = w.Write([]byte(strings.ReplaceAll(string(data), "ghcr.io", "mirror.corp") + " " +
+				r.Header.Get(TransformComponentNameHeader) + ":" + r.Header.Get(TransformResourceNameHeader)))
+		case "/slow":
+			time.Sleep(time.Second)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 2048)))
+		case "/redirect":
+			http.Redirect(w, r, "/relocate", http.StatusFound)
+		default:
+			http.Error(w, "chart is not relocatable", http.StatusUnprocessableEntity)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	testCases := []struct {
+		name     string
+		path     string
+		hosts    []string
+		timeout  time.Duration
+		maxSize  int64
+		expected string
+		errIs    error
+		errStr   string
+	}{
+		{
+			name:     "data is replaced with the transformed data",
+			path:     "/relocate",
+			expected: "image: mirror.corp/podinfo github.com/skarlso/ocm-demo-index:chart",
+		},
+		{
+			name:   "webhook rejecting the resource",
+			path:   "/reject",
+			errIs:  ErrTransformFailed,
+			errStr: "unexpected status 422 Unprocessable Entity: chart is not relocatable",
+		},
+		{
+			name:    "webhook exceeding the timeout",
+			path:    "/slow",
+			timeout: 50 * time.Millisecond,
+			errIs:   ErrTransformFailed,
+			errStr:  "context deadline exceeded",
+		},
+		{
+			name:    "transformed data exceeding the maximum size",
+			path:    "/large",
+			maxSize: 1024,
+			errIs:   ErrResourceTooLarge,
+		},
+		{
+			name:   "webhook redirecting the request",
+			path:   "/redirect",
+			errIs:  ErrTransformFailed,
+			errStr: "unexpected status 302 Found",
+		},
+		{
+			name:   "webhook that isn't allowed",
+			path:   "/relocate",
+			hosts:  []string{"relocator.ocm-system.svc"},
+			errIs:  ErrTransformNotAllowed,
+			errStr: "host " + host + " is not allowed by the controller",
+		},
+		{
+			name:   "webhook that is only allowed over https",
+			path:   "/relocate",
+			hosts:  []string{host},
+			errIs:  ErrTransformNotAllowed,
+			errStr: "may only be accessed over https",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			octx := fakeocm.NewFakeOCMContext()
+			comp := &fakeocm.Component{
+				Name:    component,
+				Version: "v0.0.1",
+			}
+			comp.Resources = append(comp.Resources, &fakeocm.Resource{
+				Name:      resource,
+				Version:   resourceVersion,
+				Data:      []byte("image: ghcr.io/podinfo"),
+				Component: comp,
+				Kind:      "localBlob",
+				Type:      "ociBlob",
+			})
+			_ = octx.AddComponent(comp)
+
+			cd := &v1alpha1.ComponentDescriptor{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+				},
+				Spec: v1alpha1.ComponentDescriptorSpec{
+					ComponentVersionSpec: v3alpha1.ComponentVersionSpec{
+						Resources: []v3alpha1.Resource{
+							{
+								ElementMeta: v3alpha1.ElementMeta{
+									Name:    resource,
+									Version: resourceVersion,
+								},
+							},
+						},
+					},
+					Version: "v0.0.1",
+				},
+			}
+
+			cache := &fakes.FakeCache{}
+			cache.IsCachedReturns(false, nil)
+			cache.FetchDataByDigestReturns(io.NopCloser(strings.NewReader("mockdata")), nil)
+			cache.PushDataReturns("sha256:8fa155245ea8d3f2ea3add7d090d42dfb0e22799018fded6aae24f0c1a1c3f38", nil)
+			hosts := tt.hosts
+			if hosts == nil {
+				hosts = []string{"http://" + host}
+			}
+
+			ocmClient := NewClient(env.FakeKubeClient(WithObjects(cd)), cache, WithAllowedTransformHosts(hosts...))
+
+			cv := &v1alpha1.ComponentVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-name",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ComponentVersionSpec{
+					Component: component,
+					Version: v1alpha1.Version{
+						Semver: "v0.0.1",
+					},
+				},
+				Status: v1alpha1.ComponentVersionStatus{
+					ReconciledVersion: "v0.0.1",
+					ComponentDescriptor: v1alpha1.Reference{
+						Name:    component,
+						Version: "v0.0.1",
+						ComponentDescriptorRef: meta.NamespacedObjectReference{
+							Name:      "github.com-skarlso-ocm-demo-index-v0.0.1-12345",
+							Namespace: "default",
+						},
+					},
+				},
+			}
+			resourceRef := &v1alpha1.ResourceReference{
+				ElementMeta: v1alpha1.ElementMeta{
+					Name:    resource,
+					Version: resourceVersion,
+				},
+			}
+
+			_, _, err := ocmClient.GetResource(context.Background(), octx, cv, resourceRef,
+				WithTransform(server.URL+tt.path, tt.timeout), WithMaxSize(tt.maxSize))
+			if tt.errIs != nil {
+				assert.ErrorIs(t, err, tt.errIs)
+				if tt.errStr != "" {
+					assert.ErrorContains(t, err, tt.errStr)
+				}
+
+				return
+			}
+			require.NoError(t, err)
+
+			args := cache.PushDataCallingArgumentsOnCall(0)
+			assert.Equal(t, tt.expected, args.Content)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/metrics"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+)
+
+// Registry is the part of the in-cluster registry the GarbageCollector needs.
+type Registry interface {
+	ListRepositories(ctx context.Context) ([]string, error)
+	ListTags(ctx context.Context, name string) ([]string, error)
+	ResolveTag(ctx context.Context, name, tag string) (string, error)
+	DeleteData(ctx context.Context, name, tag string) error
+}
+
+// GarbageCollector periodically deletes tags from the in-cluster registry that no Snapshot refers to, for example
+// because the controller was stopped while a Snapshot was deleted or a snapshot was re-tagged. Tags of snapshots
+// retained by a Resource are kept. The registry is assumed to be owned by the controller, every tag of every
+// repository is considered.
+//
+// A tag is only deleted once it has been found orphaned for longer than the grace period, so data that has just
+// been pushed for a Snapshot that doesn't exist yet is kept. The time a tag was first found orphaned is kept in
+// memory and starts over when the controller restarts.
+type GarbageCollector struct {
+	client      client.Reader
+	registry    Registry
+	interval    time.Duration
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	// orphanedSince is the time each orphaned tag, keyed by repository, tag and digest, was first found.
+	orphanedSince map[string]time.Time
+}
+
+// NewGarbageCollector returns a GarbageCollector that sweeps the registry every interval. The reader must list the
+// Snapshots and Resources of all namespaces, a sweep fails if it can't.
+func NewGarbageCollector(c client.Reader, registry Registry, interval, gracePeriod time.Duration) *GarbageCollector {
+	return &GarbageCollector{
+		client:        c,
+		registry:      registry,
+		interval:      interval,
+		gracePeriod:   gracePeriod,
+		now:           time.Now,
+		orphanedSince: map[string]time.Time{},
+	}
+}
+
+// Start implements manager.Runnable. It sweeps the registry until the context is done.
+func (g *GarbageCollector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("snapshot-garbage-collector")
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := g.Sweep(log.IntoContext(ctx, logger)); err != nil {
+				logger.Error(err, "failed to collect orphaned snapshots")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the leader deletes data.
+func (g *GarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Sweep deletes the tags of the registry that have been orphaned for longer than the grace period.
+func (g *GarbageCollector) Sweep(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	live, err := g.liveTags(ctx)
+	if err != nil {
+		return err
+	}
+
+	repositories, err := g.registry.ListRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	var (
+		errs       []error
+		candidates []orphan
+		now        = g.now()
+		orphaned   = map[string]time.Time{}
+	)
+
+	for _, repository := range repositories {
+		found, err := g.orphans(ctx, repository, live[repository])
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		for _, o := range found {
+			since, ok := g.orphanedSince[o.key()]
+			if !ok {
+				since = now
+			}
+
+			orphaned[o.key()] = since
+
+			if now.Sub(since) >= g.gracePeriod {
+				candidates = append(candidates, o)
+			}
+		}
+	}
+
+	if len(candidates) > 0 {
+		// A Snapshot may have been created for one of the tags in the meantime.
+		if live, err = g.liveTags(ctx); err != nil {
+			g.orphanedSince = orphaned
+
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	for _, o := range candidates {
+		if _, ok := live[o.repository][o.tag]; ok {
+			delete(orphaned, o.key())
+
+			continue
+		}
+
+		if err := g.registry.DeleteData(ctx, o.repository, o.tag); err != nil {
+			metrics.SnapshotGarbageCollectedTotal.WithLabelValues("failed").Inc()
+			errs = append(errs, fmt.Errorf("failed to delete %s:%s: %w", o.repository, o.tag, err))
+
+			continue
+		}
+
+		metrics.SnapshotGarbageCollectedTotal.WithLabelValues("deleted").Inc()
+		logger.Info("deleted orphaned snapshot", "repository", o.repository, "tag", o.tag, "digest", o.digest)
+		delete(orphaned, o.key())
+	}
+
+	g.orphanedSince = orphaned
+
+	return errors.Join(errs...)
+}
+
+// orphan is a tag no Snapshot refers to.
+type orphan struct {
+	repository string
+	tag        string
+	digest     string
+}
+
+// key identifies the orphan. The digest is part of it, so a tag that is pushed again starts a new grace period.
+func (o orphan) key() string {
+	return fmt.Sprintf("%s:%s@%s", o.repository, o.tag, o.digest)
+}
+
+// orphans returns the tags of the repository that aren't live. Deleting a tag deletes the manifest it points at,
+// including all other tags of that manifest, so tags sharing the manifest of a live tag are kept. So are tags of
+// the referrers tag schema, named after the digest of a live manifest.
+func (g *GarbageCollector) orphans(ctx context.Context, repository string, live map[string]struct{}) ([]orphan, error) {
+	tags, err := g.registry.ListTags(ctx, repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", repository, err)
+	}
+
+	digests := make(map[string]string, len(tags))
+	liveDigests := map[string]struct{}{}
+	for _, tag := range tags {
+		digest, err := g.registry.ResolveTag(ctx, repository, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s:%s: %w", repository, tag, err)
+		}
+
+		digests[tag] = digest
+		if _, ok := live[tag]; ok {
+			liveDigests[digest] = struct{}{}
+		}
+	}
+
+	var result []orphan
+	for _, tag := range tags {
+		if _, ok := live[tag]; ok {
+			continue
+		}
+
+		if _, ok := liveDigests[digests[tag]]; ok {
+			continue
+		}
+
+		if _, ok := liveDigests[strings.Replace(tag, "-", ":", 1)]; ok {
+			continue
+		}
+
+		result = append(result, orphan{repository: repository, tag: tag, digest: digests[tag]})
+	}
+
+	return result, nil
+}
+
+// liveTags returns the tags Snapshots refer to and the tags of the snapshots retained by Resources by repository.
+func (g *GarbageCollector) liveTags(ctx context.Context) (map[string]map[string]struct{}, error) {
+	snapshots := &v1alpha1.SnapshotList{}
+	if err := g.client.List(ctx, snapshots); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	live := map[string]map[string]struct{}{}
+	for i := range snapshots.Items {
+		snapshot := &snapshots.Items[i]
+
+		// The data of the snapshot is stored in another registry.
+		if snapshot.Spec.Registry != "" {
+			continue
+		}
+
+		name, err := ocm.SnapshotRepositoryName(snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct repository name of snapshot %s/%s: %w", snapshot.Namespace, snapshot.Name, err)
+		}
+
+		if live[name] == nil {
+			live[name] = map[string]struct{}{}
+		}
+
+		for _, tag := range []string{snapshot.Spec.Tag, snapshot.Status.LastReconciledTag} {
+			if tag != "" {
+				live[name][tag] = struct{}{}
+			}
+		}
+	}
+
+	// The snapshots retained by Resources are kept too, their retention deletes them.
+	resources := &v1alpha1.ResourceList{}
+	if err := g.client.List(ctx, resources); err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	for _, resource := range resources.Items {
+		for _, entry := range resource.Status.SnapshotHistory {
+			if entry.Registry != "" {
+				continue
+			}
+
+			if live[entry.Repository] == nil {
+				live[entry.Repository] = map[string]struct{}{}
+			}
+
+			for _, tag := range entry.Tags {
+				live[entry.Repository][tag] = struct{}{}
+			}
+		}
+	}
+
+	return live, nil
+}
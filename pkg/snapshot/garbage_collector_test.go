@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	"github.com/open-component-model/ocm-controller/pkg/ocm"
+)
+
+// fakeRegistry holds the digest of each tag by repository.
+type fakeRegistry struct {
+	tags    map[string]map[string]string
+	deleted []string
+}
+
+func (f *fakeRegistry) ListRepositories(context.Context) ([]string, error) {
+	var repositories []string
+	for repository := range f.tags {
+		repositories = append(repositories, repository)
+	}
+
+	return repositories, nil
+}
+
+func (f *fakeRegistry) ListTags(_ context.Context, name string) ([]string, error) {
+	var tags []string
+	for tag := range f.tags[name] {
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+func (f *fakeRegistry) ResolveTag(_ context.Context, name, tag string) (string, error) {
+	return f.tags[name][tag], nil
+}
+
+func (f *fakeRegistry) DeleteData(_ context.Context, name, tag string) error {
+	digest := f.tags[name][tag]
+	for t, d := range f.tags[name] {
+		if d == digest {
+			delete(f.tags[name], t)
+		}
+	}
+
+	f.deleted = append(f.deleted, name+":"+tag)
+
+	return nil
+}
+
+func TestGarbageCollectorSweep(t *testing.T) {
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    "github.com/skarlso/ocm-demo-index",
+		v1alpha1.ComponentVersionKey: "v0.0.1",
+		v1alpha1.ResourceNameKey:     "introspect-image",
+	}
+	live, err := ocm.ConstructRepositoryName(identity)
+	require.NoError(t, err)
+
+	liveDigest := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	registry := &fakeRegistry{tags: map[string]map[string]string{
+		live: {
+			"v0.0.1": liveDigest,
+			// Shares the manifest of the live tag.
+			"sha256-aaaa": liveDigest,
+			// Lists the referrers of the live manifest.
+			"sha256-1111111111111111111111111111111111111111111111111111111111111111": "sha256:2222",
+			"v0.0.0": "sha256:3333",
+		},
+		"orphaned": {
+			"v1.0.0": "sha256:4444",
+		},
+		// Recorded by a snapshot pushed with a repository name template.
+		"templated/introspect-image": {
+			"v0.0.2": "sha256:7777",
+		},
+		// Retained by the snapshot history of a Resource.
+		"retained": {
+			"v2.0.0": "sha256:6666",
+		},
+	}}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "snapshot",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Identity: identity,
+			Tag:      "v0.0.1",
+		},
+	}, &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "templated-snapshot",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.SnapshotSpec{
+			Identity:   identity,
+			Tag:        "v0.0.2",
+			Repository: "templated/introspect-image",
+		},
+	}, &v1alpha1.Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "resource",
+			Namespace: "default",
+		},
+		Status: v1alpha1.ResourceStatus{
+			SnapshotHistory: []v1alpha1.SnapshotHistoryEntry{
+				{SnapshotName: "snapshot", Repository: "retained", Tags: []string{"v2.0.0"}, Digest: "sha256:6666"},
+			},
+		},
+	}).Build()
+
+	now := time.Now()
+	gc := NewGarbageCollector(client, registry, time.Minute, time.Hour)
+	gc.now = func() time.Time { return now }
+
+	require.NoError(t, gc.Sweep(context.Background()))
+	assert.Empty(t, registry.deleted, "orphans within the grace period must be kept")
+
+	// The orphaned repository is pushed again, which starts its grace period over.
+	registry.tags["orphaned"]["v1.0.0"] = "sha256:5555"
+	now = now.Add(time.Hour)
+
+	require.NoError(t, gc.Sweep(context.Background()))
+	assert.Equal(t, []string{live + ":v0.0.0"}, registry.deleted)
+
+	now = now.Add(time.Hour)
+
+	require.NoError(t, gc.Sweep(context.Background()))
+	assert.Equal(t, []string{live + ":v0.0.0", "orphaned:v1.0.0"}, registry.deleted)
+	assert.Len(t, registry.tags[live], 3)
+	assert.Len(t, registry.tags["templated/introspect-image"], 1)
+	assert.Len(t, registry.tags["retained"], 1)
+	assert.Empty(t, gc.orphanedSince)
+}
+
+// forbiddenReader fails every request, like a reader without the permission to list cluster-wide.
+type forbiddenReader struct {
+	client.Reader
+}
+
+func (forbiddenReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return errors.New("snapshots.delivery.ocm.software is forbidden")
+}
+
+func TestGarbageCollectorSweepListFails(t *testing.T) {
+	registry := &fakeRegistry{tags: map[string]map[string]string{
+		"orphaned": {
+			"v1.0.0": "sha256:4444",
+		},
+	}}
+
+	now := time.Now()
+	gc := NewGarbageCollector(forbiddenReader{}, registry, time.Minute, time.Hour)
+	gc.now = func() time.Time { return now }
+
+	require.Error(t, gc.Sweep(context.Background()))
+	now = now.Add(2 * time.Hour)
+	require.Error(t, gc.Sweep(context.Background()))
+	assert.Empty(t, registry.deleted)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 
 	"github.com/open-component-model/ocm-controller/api/v1alpha1"
@@ -29,6 +30,14 @@ type OCIWriter struct {
 	Client client.Client
 	Cache  cache.Cache
 	Scheme *runtime.Scheme
+
+	// FieldManager is the field manager Snapshots are created and updated with. If empty, the API server
+	// derives it from the user agent of the client.
+	FieldManager string
+
+	// RepositoryNamer names the repositories snapshots are pushed to. The repository is recorded on the Snapshot.
+	// If nil, repositories are named after the hash of the identity.
+	RepositoryNamer *ocm.RepositoryNamer
 }
 
 // NewOCIWriter creates a new OCI cache writer.
@@ -74,7 +83,11 @@ func (w *OCIWriter) Write(
 		}
 	}()
 
-	name, err := ocm.ConstructRepositoryName(identity)
+	// The namespace of the snapshot is available to the repository name template.
+	identity = maps.Clone(identity)
+	identity[v1alpha1.SnapshotNamespaceKey] = owner.GetNamespace()
+
+	name, err := w.RepositoryNamer.RepositoryName(identity)
 	if err != nil {
 		return "", fmt.Errorf("failed to construct name: %w", err)
 	}
@@ -100,16 +113,18 @@ func (w *OCIWriter) Write(
 		},
 	}
 
-	_, err = controllerutil.CreateOrUpdate(ctx, w.Client, snapshotCR, func() error {
-		if snapshotCR.ObjectMeta.CreationTimestamp.IsZero() {
-			if err := controllerutil.SetOwnerReference(owner, snapshotCR, w.Scheme); err != nil {
-				return fmt.Errorf("failed to set owner reference on snapshot: %w", err)
-			}
+	_, err = controllerutil.CreateOrUpdate(ctx, w.client(), snapshotCR, func() error {
+		// The owner reference is set on existing Snapshots too, so Snapshots created without it, for example by
+		// an earlier version or by hand, are adopted and garbage collected with their owner. Owner references
+		// are merged by UID, setting it again doesn't change the Snapshot.
+		if err := controllerutil.SetOwnerReference(owner, snapshotCR, w.Scheme); err != nil {
+			return fmt.Errorf("failed to set owner reference on snapshot: %w", err)
 		}
 		snapshotCR.Spec = v1alpha1.SnapshotSpec{
-			Identity: identity,
-			Digest:   snapshotDigest,
-			Tag:      owner.GetResourceVersion(),
+			Identity:   identity,
+			Digest:     snapshotDigest,
+			Tag:        owner.GetResourceVersion(),
+			Repository: name,
 		}
 
 		return nil
@@ -119,7 +134,39 @@ func (w *OCIWriter) Write(
 		return "", fmt.Errorf("failed to create or update component descriptor: %w", err)
 	}
 
-	logger.Info("snapshot successfully created/updated", "digest", snapshotDigest, "snapshot", snapshotCR)
+	logger.Info("snapshot successfully created/updated",
+		"snapshot", snapshotCR.Name, v1alpha1.LogKeySnapshotRef, name+":"+owner.GetResourceVersion(), v1alpha1.LogKeySourceDigest, snapshotDigest)
 
 	return snapshotDigest, nil
 }
+
+// client returns the client Snapshots are written with, creating and updating them with the field manager if
+// one is set.
+func (w *OCIWriter) client() client.Client {
+	if w.FieldManager == "" {
+		return w.Client
+	}
+
+	return &fieldManagerClient{Client: w.Client, fieldManager: client.FieldOwner(w.FieldManager)}
+}
+
+// fieldManagerClient creates, updates and patches objects with the field manager.
+type fieldManagerClient struct {
+	client.Client
+	fieldManager client.FieldOwner
+}
+
+// Create implements client.Client.
+func (c *fieldManagerClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.Client.Create(ctx, obj, append([]client.CreateOption{c.fieldManager}, opts...)...)
+}
+
+// Update implements client.Client.
+func (c *fieldManagerClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.Client.Update(ctx, obj, append([]client.UpdateOption{c.fieldManager}, opts...)...)
+}
+
+// Patch implements client.Client.
+func (c *fieldManagerClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Patch(ctx, obj, patch, append([]client.PatchOption{c.fieldManager}, opts...)...)
+}
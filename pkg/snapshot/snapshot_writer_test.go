@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocmmetav1 "github.com/open-component-model/ocm/pkg/contexts/ocm/compdesc/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/open-component-model/ocm-controller/api/v1alpha1"
+	cachefakes "github.com/open-component-model/ocm-controller/pkg/cache/fakes"
+)
+
+// fieldManagerRecorder records the field managers objects are updated with.
+type fieldManagerRecorder struct {
+	client.Client
+	fieldManagers []string
+}
+
+func (r *fieldManagerRecorder) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	options := &client.UpdateOptions{}
+	options.ApplyOptions(opts)
+	r.fieldManagers = append(r.fieldManagers, options.FieldManager)
+
+	return r.Client.Update(ctx, obj, opts...)
+}
+
+func TestOCIWriterAdoptsSnapshot(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	owner := &v1alpha1.Localization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "localization",
+			Namespace:       "default",
+			UID:             types.UID("localization-uid"),
+			ResourceVersion: "1",
+		},
+		Status: v1alpha1.MutationStatus{
+			SnapshotName: "localization-snapshot",
+		},
+	}
+	// A Snapshot created without an owner reference, for example by an earlier version of the controller.
+	existing := &v1alpha1.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "localization-snapshot",
+			Namespace:         "default",
+			CreationTimestamp: metav1.Now(),
+		},
+	}
+	recorder := &fieldManagerRecorder{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()}
+
+	cache := &cachefakes.FakeCache{}
+	cache.PushDataReturns("sha256:digest", nil)
+
+	writer := NewOCIWriter(recorder, cache, scheme)
+	writer.FieldManager = "ocm-controller-test"
+
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "file.yaml"), []byte("key: value"), 0o600))
+
+	identity := ocmmetav1.Identity{
+		v1alpha1.ComponentNameKey:    "github.com/open-component-model/test",
+		v1alpha1.ComponentVersionKey: "v0.1.0",
+		v1alpha1.ResourceNameKey:     "config",
+		v1alpha1.ResourceVersionKey:  "v0.1.0",
+	}
+
+	t.Log("adopting the existing Snapshot")
+	digest, err := writer.Write(context.Background(), owner, sourceDir, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:digest", digest)
+
+	snapshot := &v1alpha1.Snapshot{}
+	require.NoError(t, recorder.Get(context.Background(), client.ObjectKeyFromObject(existing), snapshot))
+	require.Len(t, snapshot.OwnerReferences, 1)
+	assert.Equal(t, owner.UID, snapshot.OwnerReferences[0].UID)
+	assert.Equal(t, "Localization", snapshot.OwnerReferences[0].Kind)
+	assert.Equal(t, "sha256:digest", snapshot.Spec.Digest)
+	assert.Equal(t, cache.PushDataCallingArgumentsOnCall(0).Name, snapshot.Spec.Repository)
+	assert.Equal(t, []string{"ocm-controller-test"}, recorder.fieldManagers)
+
+	t.Log("keeping a single owner reference when the Snapshot is written again")
+	cache.PushDataReturns("sha256:updated", nil)
+	_, err = writer.Write(context.Background(), owner, sourceDir, identity)
+	require.NoError(t, err)
+
+	require.NoError(t, recorder.Get(context.Background(), client.ObjectKeyFromObject(existing), snapshot))
+	assert.Len(t, snapshot.OwnerReferences, 1)
+	assert.Equal(t, "sha256:updated", snapshot.Spec.Digest)
+}
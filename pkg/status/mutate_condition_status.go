@@ -15,6 +15,14 @@ func MarkNotReady(recorder kuberecorder.EventRecorder, obj conditions.Setter, re
 	event.New(recorder, obj, eventv1.EventSeverityError, msg, nil)
 }
 
+// MarkRetrying sets the condition status of an Object to `Not Ready` after a failure that is retried. Unlike
+// MarkNotReady, the Object keeps `Reconciling` with the ProgressingWithRetry reason, as it hasn't given up yet.
+func MarkRetrying(recorder kuberecorder.EventRecorder, obj conditions.Setter, reason, msg string) {
+	conditions.MarkReconciling(obj, meta.ProgressingWithRetryReason, msg)
+	conditions.MarkFalse(obj, meta.ReadyCondition, reason, msg)
+	event.New(recorder, obj, eventv1.EventSeverityError, msg, nil)
+}
+
 // MarkAsStalled sets the condition status of an Object to `Stalled`.
 func MarkAsStalled(recorder kuberecorder.EventRecorder, obj conditions.Setter, reason, msg string) {
 	conditions.Delete(obj, meta.ReconcilingCondition)
@@ -27,5 +35,5 @@ func MarkAsStalled(recorder kuberecorder.EventRecorder, obj conditions.Setter, r
 func MarkReady(recorder kuberecorder.EventRecorder, obj conditions.Setter, msg string, messageArgs ...any) {
 	conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, msg, messageArgs...)
 	conditions.Delete(obj, meta.ReconcilingCondition)
-	event.New(recorder, obj, eventv1.EventSeverityInfo, msg, nil)
+	event.New(recorder, obj, eventv1.EventSeverityInfo, conditions.GetMessage(obj, meta.ReadyCondition), nil)
 }
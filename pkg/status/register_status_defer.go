@@ -13,7 +13,8 @@ import (
 	kuberecorder "k8s.io/client-go/tools/record"
 )
 
-// UpdateStatus takes an object which can identify itself and updates its status including ObservedGeneration.
+// UpdateStatus takes an object which can identify itself and updates its status including ObservedGeneration. The
+// ObservedGeneration is updated once the object is ready, or stalled, as neither changes before the object does.
 func UpdateStatus(
 	ctx context.Context,
 	patchHelper *patch.SerialPatcher,
@@ -22,8 +23,9 @@ func UpdateStatus(
 	requeue time.Duration,
 ) error {
 	// If still reconciling then reconciliation did not succeed, set to ProgressingWithRetry to
-	// indicate that reconciliation will be retried.
-	if conditions.IsReconciling(obj) {
+	// indicate that reconciliation will be retried. The reason is left alone if MarkRetrying already set
+	// ProgressingWithRetry, its message and event already report the retry delay.
+	if conditions.IsReconciling(obj) && conditions.GetReason(obj, meta.ReconcilingCondition) != meta.ProgressingWithRetryReason {
 		reconciling := conditions.Get(obj, meta.ReconcilingCondition)
 		reconciling.Reason = meta.ProgressingWithRetryReason
 		conditions.Set(obj, reconciling)
@@ -38,6 +40,11 @@ func UpdateStatus(
 		event.New(recorder, obj, eventv1.EventSeverityInfo, msg, obj.GetVID())
 	}
 
+	// A stalled object isn't retried until it changes, the stall applies to this generation.
+	if conditions.IsStalled(obj) {
+		obj.SetObservedGeneration(obj.GetGeneration())
+	}
+
 	// Update the object.
 	return patchHelper.Patch(ctx, obj)
 }
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Open Component Model contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/open-component-model/ocm-controller"
+	serviceName = "ocm-controller"
+)
+
+// Attributes of the spans.
+const (
+	NamespaceKey        = attribute.Key("ocm.namespace")
+	NameKey             = attribute.Key("ocm.name")
+	ComponentKey        = attribute.Key("ocm.component.name")
+	ComponentVersionKey = attribute.Key("ocm.component.version")
+	ResourceNameKey     = attribute.Key("ocm.resource.name")
+	ResourceVersionKey  = attribute.Key("ocm.resource.version")
+	SourceDigestKey     = attribute.Key("ocm.resource.digest")
+	SourceRefKey        = attribute.Key("ocm.resource.ref")
+	SnapshotKey         = attribute.Key("ocm.snapshot.name")
+	SnapshotRefKey      = attribute.Key("ocm.snapshot.ref")
+)
+
+// Setup exports the spans of the controller to the OTLP endpoint over http. Tracing is disabled if the endpoint is
+// empty. The returned function flushes the remaining spans and has to be called before the controller exits.
+func Setup(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the controller.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error on the span, if there is one, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Transport propagates the trace context of requests to the server, so the spans of a registry that supports
+// tracing are part of the trace of the reconciliation.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	carrier := propagation.HeaderCarrier{}
+	otel.GetTextMapPropagator().Inject(req.Context(), carrier)
+	if len(carrier) == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+	for key, values := range carrier {
+		req.Header[key] = values
+	}
+
+	return t.next.RoundTrip(req)
+}